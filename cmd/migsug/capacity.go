@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+)
+
+// runCapacity implements "migsug capacity": it counts how many more VMs of a
+// given shape the cluster can take and, with --target, how many hosts of a
+// given spec must be added to bring the average utilization down to it
+func runCapacity(args []string) int {
+	var vcpus, hostCores, addVMs int
+	var ramGB, diskGB, cpuUsage, target, hostRAMGB, hostStorageGB float64
+
+	fs := flag.NewFlagSet("capacity", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(apiToken, "api-token", "", "Proxmox API token (format: user@realm!tokenid=secret)")
+	fs.StringVar(apiHost, "api-host", "https://localhost:8006", "Proxmox API host URL")
+	fs.StringVar(username, "username", "", "Proxmox username (alternative to API token)")
+	fs.StringVar(password, "password", "", "Proxmox password (alternative to API token)")
+	fs.BoolVar(debug, "debug", false, "Enable debug logging")
+	fs.StringVar(configFile, "config", "", "Config file (default: /etc/migsug/config.yaml, then ~/.config/migsug/config.yaml)")
+	fs.StringVar(profileName, "profile", "", "Cluster profile from the config file (default: default_profile)")
+	fs.StringVar(snapshotFile, "snapshot", "", "Load cluster data from a snapshot file instead of connecting to Proxmox")
+	fs.StringVar(fixtureDir, "fixtures", "", "Read cluster data from a fixture directory instead of Proxmox (see 'migsug fixtures')")
+	fs.StringVar(policyFile, "policy", "", "Capacity policy file (YAML) with target limits; defaults to 95% CPU, 90% RAM, 85% storage")
+	fs.StringVar(recordFile, "record", "", "Save the collected cluster data to a snapshot file (.gz to compress)")
+	fs.IntVar(&vcpus, "vcpus", 0, "vCPUs of the VM template (required)")
+	fs.Float64Var(&ramGB, "ram", 0, "RAM of the VM template in GB (required)")
+	fs.Float64Var(&diskGB, "disk", 0, "Local disk of the VM template in GB")
+	fs.Float64Var(&cpuUsage, "cpu", 0, "Expected CPU usage of the VM template in % of its vCPUs (0 = average of the running VMs)")
+	fs.Float64Var(&target, "target", 0, "Target average utilization in %: print how many hosts must be added to reach it")
+	fs.IntVar(&hostCores, "host-cores", 0, "CPU cores of an added host (with --target)")
+	fs.Float64Var(&hostRAMGB, "host-ram", 0, "RAM of an added host in GB (with --target)")
+	fs.Float64Var(&hostStorageGB, "host-storage", 0, "Storage of an added host in GB (with --target)")
+	fs.IntVar(&addVMs, "add-vms", 0, "VMs of the template to add before computing the hosts needed (with --target)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migsug capacity --vcpus=N --ram=GB [--disk=GB] [--cpu=PCT] [options]")
+		fmt.Fprintln(os.Stderr, "       migsug capacity --target=PCT --host-cores=N --host-ram=GB [--host-storage=GB] [--add-vms=N --vcpus=N --ram=GB ...]")
+		fmt.Fprintln(os.Stderr, "\nCounts how many VMs of a template each node can still take within the capacity policy.")
+		fmt.Fprintln(os.Stderr, "Only provisioning hosts (P flag) are counted, or every host if the cluster has none.")
+		fmt.Fprintln(os.Stderr, "With --target, also prints how many hosts of the given spec bring the cluster average")
+		fmt.Fprintln(os.Stderr, "host CPU, RAM and storage utilization down to the target.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+
+	template := analyzer.VMTemplate{
+		VCPUs:    vcpus,
+		RAM:      int64(ramGB * 1024 * 1024 * 1024),
+		Disk:     int64(diskGB * 1024 * 1024 * 1024),
+		CPUUsage: cpuUsage,
+	}
+	spec := analyzer.HostSpec{
+		CPUCores: hostCores,
+		RAM:      int64(hostRAMGB * 1024 * 1024 * 1024),
+		Storage:  int64(hostStorageGB * 1024 * 1024 * 1024),
+	}
+	hasTemplate := vcpus > 0 || ramGB > 0
+	if !hasTemplate && target == 0 {
+		fmt.Fprintln(os.Stderr, "--vcpus and --ram, or --target, are required")
+		fs.Usage()
+		return exitUsage
+	}
+	if hasTemplate || addVMs > 0 {
+		if err := template.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid VM template: %v\n", err)
+			return exitUsage
+		}
+	}
+	if target != 0 {
+		if err := spec.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid host spec: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	set := flagsSet(fs)
+	cfg, err := loadConfig(set)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return exitError
+	}
+	policy, err := loadCapacityPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if *debug {
+		logFile, err := os.OpenFile("migsug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			return exitError
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	cluster, _, err := loadPlanCluster(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+
+	if hasTemplate {
+		plan, err := analyzer.AnalyzeCapacity(cluster, policy, template)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+			return exitError
+		}
+		printCapacity(os.Stdout, plan)
+	}
+
+	if target != 0 {
+		needed, err := analyzer.HostsForTarget(cluster, template, addVMs, spec, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+		if hasTemplate {
+			fmt.Println()
+		}
+		printHostsNeeded(os.Stdout, needed)
+	}
+	return exitOK
+}
+
+// printCapacity writes the room per node as plain text
+func printCapacity(w io.Writer, plan *analyzer.CapacityPlan) {
+	fmt.Fprintf(w, "Capacity for VMs with %s\n", plan.Template)
+	if plan.ProvisioningOnly {
+		fmt.Fprintf(w, "New VMs go to provisioning hosts (P flag) only\n")
+	}
+
+	fmt.Fprintf(w, "\n  %-16s %6s  %-18s %-18s %s\n", "NODE", "FITS", "NOW CPU/RAM/STO", "FULL CPU/RAM/STO", "LIMITED BY")
+	for _, nc := range plan.Nodes {
+		if !nc.Eligible {
+			fmt.Fprintf(w, "  %-16s %6s  %-18s %-18s %s\n", nc.Node, "-", formatHostUsage(nc.Before), "", nc.Limit)
+			continue
+		}
+		fmt.Fprintf(w, "  %-16s %6d  %-18s %-18s %s\n", nc.Node, nc.Fits, formatHostUsage(nc.Before), formatHostUsage(nc.After), nc.Limit)
+	}
+	fmt.Fprintf(w, "\nTotal: %d more VMs fit\n", plan.Total)
+}
+
+// printHostsNeeded writes the number of hosts to add as plain text
+func printHostsNeeded(w io.Writer, needed *analyzer.HostsNeeded) {
+	fmt.Fprintf(w, "Hosts needed for an average of at most %.0f%%", needed.TargetPercent)
+	if needed.AddedVMs > 0 {
+		fmt.Fprintf(w, " with %d more VMs", needed.AddedVMs)
+	}
+	fmt.Fprintf(w, "\n  Host spec: %d cores, %.0f GB RAM, %.0f GB storage\n",
+		needed.Spec.CPUCores, float64(needed.Spec.RAM)/(1024*1024*1024), float64(needed.Spec.Storage)/(1024*1024*1024))
+	if needed.Hosts == 0 {
+		fmt.Fprintf(w, "  No hosts needed\n")
+	} else if needed.Hosts == 1 {
+		fmt.Fprintf(w, "  Add 1 host (limited by %s)\n", needed.Limit)
+	} else {
+		fmt.Fprintf(w, "  Add %d hosts (limited by %s)\n", needed.Hosts, needed.Limit)
+	}
+	fmt.Fprintf(w, "  Average CPU/RAM/storage: now %s, then %s\n", formatClusterUsage(needed.Before), formatClusterUsage(needed.After))
+}
+
+// formatHostUsage formats a simulated node's host CPU, RAM and storage utilization
+func formatHostUsage(s analyzer.NodeState) string {
+	return fmt.Sprintf("%.0f%%/%.0f%%/%.0f%%", s.HostCPUPercent, s.RAMPercent, s.StoragePercent)
+}
+
+// formatClusterUsage formats the cluster averages, e.g. "42%/61%/30%"
+func formatClusterUsage(u analyzer.ClusterUsage) string {
+	return fmt.Sprintf("%.0f%%/%.0f%%/%.0f%%", u.HostCPUPercent, u.RAMPercent, u.StoragePercent)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/config"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// flagsSet returns the names of the flags given on the command line
+func flagsSet(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// loadConfig reads the config file and fills in the connection settings that
+// were not given on the command line. Precedence: flag > environment > file
+func loadConfig(set map[string]bool) (*config.Config, error) {
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("MIGSUG_CONFIG")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := *profileName
+	if name == "" {
+		name = os.Getenv("MIGSUG_PROFILE")
+	}
+	profile, err := cfg.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	*profileName = name
+
+	if !set["api-host"] {
+		if host := os.Getenv("PVE_API_HOST"); host != "" {
+			*apiHost = host
+		} else if profile.APIHost != "" {
+			*apiHost = profile.APIHost
+		}
+	}
+
+	// Credentials: flags, then PVE_* variables, then the profile
+	applyEnvCredentials()
+	if *apiToken == "" && (*username == "" || *password == "") {
+		if profile.APIToken != "" {
+			*apiToken = profile.APIToken
+		} else if profile.Username != "" {
+			*username = profile.Username
+			*password = profile.Password
+		}
+	}
+
+	if !set["source"] && profile.Source != "" {
+		*sourceNode = profile.Source
+	}
+	if !set["fixtures"] && profile.Fixtures != "" {
+		*fixtureDir = profile.Fixtures
+	}
+
+	// Collection settings
+	proxmox.StorageRules = cfg.StorageRules
+	proxmox.RecentlyCreatedThresholdDays = cfg.RecentlyCreatedDays
+
+	return cfg, nil
+}
+
+// defaultConstraints converts the config file defaults into analyzer constraints
+func defaultConstraints(d config.Defaults) analyzer.MigrationConstraints {
+	constraints := analyzer.MigrationConstraints{
+		ExcludeNodes: d.Exclude,
+	}
+	// Validated when the config file was loaded
+	constraints.Strategy, _ = analyzer.ParsePlacementStrategy(d.Strategy)
+	if d.MaxVMsPerHost > 0 {
+		maxVMs := d.MaxVMsPerHost
+		constraints.MaxVMsPerHost = &maxVMs
+	}
+	if d.MinCPUFree > 0 {
+		minCPU := d.MinCPUFree
+		constraints.MinCPUFree = &minCPU
+	}
+	if d.MinRAMFreeGB > 0 {
+		bytes := int64(d.MinRAMFreeGB * 1024 * 1024 * 1024)
+		constraints.MinRAMFree = &bytes
+	}
+	if d.MaxMigrations > 0 {
+		maxMigrations := d.MaxMigrations
+		constraints.MaxMigrations = &maxMigrations
+	}
+	if d.MaxTransferGB > 0 {
+		bytes := int64(d.MaxTransferGB * 1024 * 1024 * 1024)
+		constraints.MaxTransfer = &bytes
+	}
+	if d.MaxLiveRAMGB > 0 {
+		bytes := int64(d.MaxLiveRAMGB * 1024 * 1024 * 1024)
+		constraints.MaxLiveRAM = &bytes
+	}
+	constraints.ExcludeVMs = d.ExcludeVMs
+	return constraints
+}
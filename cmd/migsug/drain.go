@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/executor"
+	"github.com/yourusername/migsug/internal/export"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// runDrain implements "migsug drain": it plans the evacuation of a host for
+// maintenance, prints it like "migsug plan --mode=all" and writes the plan
+// that moves the VMs back afterwards to a file. Optionally sets the host's
+// hoststate once both plans are written.
+func runDrain(args []string) int {
+	var opts planOptions
+	var returnFile string
+	var hostState int
+
+	fs := flag.NewFlagSet("drain", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(apiToken, "api-token", "", "Proxmox API token (format: user@realm!tokenid=secret)")
+	fs.StringVar(apiHost, "api-host", "https://localhost:8006", "Proxmox API host URL")
+	fs.StringVar(username, "username", "", "Proxmox username (alternative to API token)")
+	fs.StringVar(password, "password", "", "Proxmox password (alternative to API token)")
+	fs.StringVar(sourceNode, "source", "", "Node to drain (required)")
+	fs.BoolVar(debug, "debug", false, "Enable debug logging")
+	fs.StringVar(configFile, "config", "", "Config file (default: /etc/migsug/config.yaml, then ~/.config/migsug/config.yaml)")
+	fs.StringVar(profileName, "profile", "", "Cluster profile from the config file (default: default_profile)")
+	fs.StringVar(snapshotFile, "snapshot", "", "Load cluster data from a snapshot file instead of connecting to Proxmox")
+	fs.StringVar(fixtureDir, "fixtures", "", "Read cluster data from a fixture directory instead of Proxmox (see 'migsug fixtures')")
+	fs.StringVar(policyFile, "policy", "", "Capacity policy file (YAML) with target limits; defaults to 95% CPU, 90% RAM, 85% storage")
+	fs.StringVar(recordFile, "record", "", "Save the collected cluster data to a snapshot file (.gz to compress)")
+	fs.IntVar(maxPerSource, "max-per-source", executor.DefaultLimits.PerSource, "Concurrent migrations per source node assumed for the time estimate (0 = unlimited)")
+	fs.IntVar(maxPerTarget, "max-per-target", executor.DefaultLimits.PerTarget, "Concurrent migrations per target node assumed for the time estimate (0 = unlimited)")
+	fs.StringVar(&opts.exclude, "exclude", "", "Comma-separated nodes that must not receive VMs")
+	fs.IntVar(&opts.maxVMsPerHost, "max-vms-per-host", 0, "Limit VMs migrated to each target host (0 = no limit)")
+	fs.Float64Var(&opts.minCPUFree, "min-cpu-free", 0, "Require at least N% CPU free on target (0 = no limit)")
+	fs.Float64Var(&opts.minRAMFreeGB, "min-ram-free", 0, "Require at least N GB RAM free on target (0 = no limit)")
+	fs.StringVar(&opts.strategy, "strategy", "", "Placement strategy ranking the targets: default, binpack, spread or cpu_generation")
+	fs.BoolVar(&opts.commands, "commands", false, "Append pvesh migrate commands to the plan")
+	fs.StringVar(&opts.output, "output", "text", "Output format of the evacuation plan: text, json or yaml")
+	fs.StringVar(&opts.outFile, "out-file", "", "Write the evacuation plan to this file instead of stdout")
+	fs.StringVar(&returnFile, "return-file", "", "Return plan file, .json or .yaml (default: migsug-return-<node>-<timestamp>.json)")
+	fs.IntVar(&hostState, "hoststate", -1, "Set hoststate=N (0-3) in the node config once the plans are written (-1 = leave unchanged)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migsug drain --source=NODE [--return-file=FILE] [--hoststate=N] [options]")
+		fmt.Fprintln(os.Stderr, "\nPlans moving every VM off a node for maintenance, and the plan that moves them back.")
+		fmt.Fprintln(os.Stderr, "VMs with nomigrate=true stay on the node.")
+		fmt.Fprintln(os.Stderr, "\nExit codes: 0 = node can be drained, 1 = error, 2 = usage error, 3 = some VMs have no target")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+
+	set := flagsSet(fs)
+	cfg, err := loadConfig(set)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return exitError
+	}
+	applyPlanDefaults(&opts, cfg.Defaults, set)
+
+	if *sourceNode == "" {
+		fmt.Fprintln(os.Stderr, "--source is required")
+		fs.Usage()
+		return exitUsage
+	}
+	if hostState < -1 || hostState > 3 {
+		fmt.Fprintln(os.Stderr, "--hoststate must be between 0 and 3 (or -1)")
+		return exitUsage
+	}
+	if hostState >= 0 && *snapshotFile != "" {
+		fmt.Fprintln(os.Stderr, "--hoststate can't be set on a snapshot")
+		return exitUsage
+	}
+
+	var format export.Format
+	if opts.output != "text" {
+		if format, err = export.ParseFormat(opts.output); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+	returnFormat := export.FormatJSON
+	if returnFile != "" {
+		if returnFormat, err = export.ParseFormat(strings.TrimPrefix(filepath.Ext(returnFile), ".")); err != nil {
+			fmt.Fprintf(os.Stderr, "--return-file: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	policy, err := loadCapacityPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+	constraints, err := buildPlanConstraints(analyzer.ModeAll, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+	constraints.Policy = &policy
+	if constraints.Strategy, err = analyzer.ParsePlacementStrategy(opts.strategy); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if *debug {
+		logFile, err := os.OpenFile("migsug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			return exitError
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	cluster, client, err := loadPlanCluster(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+
+	drain, err := analyzer.AnalyzeDrain(cluster, constraints)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+		return exitError
+	}
+	drain.Evacuate.Estimate = cfg.MigrationEstimate.EstimateResult(drain.Evacuate, *maxPerSource, *maxPerTarget)
+	drain.Return.Estimate = cfg.MigrationEstimate.EstimateResult(drain.Return, *maxPerSource, *maxPerTarget)
+
+	// The return plan is written first, so it exists before anything changes on the cluster
+	returnPlan := export.NewPlan(drain.Return, cluster, appVersion)
+	if returnFile == "" {
+		returnFile = export.DefaultFileName(returnPlan, returnFormat)
+	}
+	if err := export.WriteFile(returnFile, returnPlan, returnFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+
+	var out io.Writer = os.Stdout
+	if opts.outFile != "" {
+		f, err := os.Create(opts.outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format != "" {
+		if err := export.Write(out, export.NewPlan(drain.Evacuate, cluster, appVersion), format); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+	} else {
+		printPlan(out, drain.Evacuate, analyzer.ModeAll, drain.Node, opts.commands)
+		fmt.Fprintf(out, "\nReturn plan: %d migrations back to %s", len(drain.Return.Suggestions), drain.Node)
+		if est := drain.Return.Estimate; est != nil && len(est.Migrations) > 0 {
+			fmt.Fprintf(out, ", estimated %s", analyzer.FormatDuration(est.WallClock))
+		}
+		fmt.Fprintf(out, "\n")
+	}
+	fmt.Fprintf(os.Stderr, "Return plan written to %s\n", returnFile)
+
+	if hostState >= 0 {
+		previous, err := proxmox.SetNodeHostState(client, drain.Node, hostState)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+		was := "was not set"
+		if previous >= 0 {
+			was = fmt.Sprintf("was %d", previous)
+		}
+		fmt.Fprintf(os.Stderr, "Set hoststate=%d on %s (%s)\n", hostState, drain.Node, was)
+	}
+
+	if !drain.Complete() || drain.Evacuate.Staged.HasUnsafe() {
+		return exitUnplaced
+	}
+	return exitOK
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+)
+
+// runFailures implements "migsug failures": it simulates the loss of every
+// combination of --nodes hosts and reports which ones the cluster survives
+func runFailures(args []string) int {
+	var k int
+
+	fs := flag.NewFlagSet("failures", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(apiToken, "api-token", "", "Proxmox API token (format: user@realm!tokenid=secret)")
+	fs.StringVar(apiHost, "api-host", "https://localhost:8006", "Proxmox API host URL")
+	fs.StringVar(username, "username", "", "Proxmox username (alternative to API token)")
+	fs.StringVar(password, "password", "", "Proxmox password (alternative to API token)")
+	fs.BoolVar(debug, "debug", false, "Enable debug logging")
+	fs.StringVar(configFile, "config", "", "Config file (default: /etc/migsug/config.yaml, then ~/.config/migsug/config.yaml)")
+	fs.StringVar(profileName, "profile", "", "Cluster profile from the config file (default: default_profile)")
+	fs.StringVar(snapshotFile, "snapshot", "", "Load cluster data from a snapshot file instead of connecting to Proxmox")
+	fs.StringVar(fixtureDir, "fixtures", "", "Read cluster data from a fixture directory instead of Proxmox (see 'migsug fixtures')")
+	fs.StringVar(policyFile, "policy", "", "Capacity policy file (YAML) with target limits; defaults to 95% CPU, 90% RAM, 85% storage")
+	fs.StringVar(recordFile, "record", "", "Save the collected cluster data to a snapshot file (.gz to compress)")
+	fs.IntVar(&k, "nodes", 0, "Nodes failing at once (default: failure_nodes from the config file, else 1)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migsug failures [--nodes=K] [options]")
+		fmt.Fprintln(os.Stderr, "\nSimulates the loss of every combination of K online nodes: their VMs, nomigrate ones")
+		fmt.Fprintln(os.Stderr, "included, are placed on the remaining hosts within the capacity policy.")
+		fmt.Fprintln(os.Stderr, "\nExit codes: 0 = every failure survivable, 1 = error, 2 = usage error, 3 = some failures strand VMs")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+
+	set := flagsSet(fs)
+	cfg, err := loadConfig(set)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return exitError
+	}
+	if !set["nodes"] {
+		k = max(cfg.FailureNodes, 1)
+	}
+	if k < 1 {
+		fmt.Fprintln(os.Stderr, "--nodes must be at least 1")
+		return exitUsage
+	}
+
+	policy, err := loadCapacityPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if *debug {
+		logFile, err := os.OpenFile("migsug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			return exitError
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	cluster, _, err := loadPlanCluster(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+
+	analysis, err := analyzer.AnalyzeFailures(cluster, policy, k)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+		return exitError
+	}
+
+	printFailures(os.Stdout, analysis)
+	if len(analysis.NonSurvivable()) > 0 {
+		return exitUnplaced
+	}
+	return exitOK
+}
+
+// printFailures writes the failure simulation as plain text
+func printFailures(w io.Writer, analysis *analyzer.FailureAnalysis) {
+	fmt.Fprintf(w, "Failure simulation: %s\n", analysis.Summary())
+
+	if failed := analysis.NonSurvivable(); len(failed) > 0 {
+		fmt.Fprintf(w, "\nNot survivable:\n")
+		for _, s := range failed {
+			vms := "VMs"
+			if len(s.Stranded) == 1 {
+				vms = "VM"
+			}
+			fmt.Fprintf(w, "  %s: %d %s with nowhere to go\n", s.Name(), len(s.Stranded), vms)
+			for _, vm := range s.Stranded {
+				fmt.Fprintf(w, "    %-6d %-24s %s\n", vm.VMID, vm.VMName, vm.Reason)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nWorst case per host:\n")
+	fmt.Fprintf(w, "  %-16s %-22s %-22s %s\n", "NODE", "NOW CPU/RAM/STO", "WORST CPU/RAM/STO", "WHEN LOST")
+	for _, h := range analysis.WorstCase {
+		when := "-"
+		if len(h.Failed) > 0 {
+			when = strings.Join(h.Failed, " + ")
+		}
+		fmt.Fprintf(w, "  %-16s %-22s %-22s %s\n", h.Node, formatUsage(h.Before), formatUsage(h.After), when)
+	}
+}
+
+// formatUsage formats a node's CPU, RAM and storage utilization, e.g. "42%/61%/30%"
+func formatUsage(s analyzer.NodeState) string {
+	return fmt.Sprintf("%.0f%%/%.0f%%/%.0f%%", s.CPUPercent, s.RAMPercent, s.StoragePercent)
+}
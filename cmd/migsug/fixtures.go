@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// runFixtures implements "migsug fixtures": it writes a synthetic cluster in
+// fixture layout, for use with --fixtures or proxmox.NewFixtureClient
+func runFixtures(args []string) int {
+	var opts proxmox.FixtureOptions
+	var dir string
+
+	fs := flag.NewFlagSet("fixtures", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(&dir, "dir", "", "Directory to write the fixture files to (required)")
+	fs.IntVar(&opts.Nodes, "nodes", 5, "Number of nodes")
+	fs.IntVar(&opts.VMs, "vms", 100, "Number of VMs")
+	fs.Float64Var(&opts.Skew, "skew", 0.3, "VM placement skew: 0 = even, 1 = all VMs on the first node")
+	fs.Int64Var(&opts.Seed, "seed", 1, "Random seed (same seed, same cluster)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migsug fixtures --dir=DIR [--nodes=N] [--vms=M] [--skew=S] [--seed=N]")
+		fmt.Fprintln(os.Stderr, "\nGenerates a synthetic cluster that 'migsug --fixtures=DIR' and 'migsug plan --fixtures=DIR' can load.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "--dir is required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	if err := proxmox.GenerateFixtures(dir, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	fmt.Printf("Wrote %d nodes and %d VMs to %s\n", opts.Nodes, opts.VMs, dir)
+	return exitOK
+}
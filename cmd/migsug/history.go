@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourusername/migsug/internal/config"
+	"github.com/yourusername/migsug/internal/history"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// historyClusterName returns the name collections are recorded under: the
+// profile, "fixtures" for fixture data without one, else "default"
+func historyClusterName() string {
+	switch {
+	case *profileName != "":
+		return *profileName
+	case *fixtureDir != "":
+		return "fixtures"
+	}
+	return "default"
+}
+
+// openHistory opens the history database from the config file
+// Returns a nil store when history is disabled (history_file: off)
+func openHistory(cfg *config.Config) (*history.Store, error) {
+	path := cfg.HistoryFile
+	switch path {
+	case "off":
+		return nil, nil
+	case "":
+		path = history.DefaultPath()
+	}
+	return history.Open(path)
+}
+
+// recordHistory adds a collected cluster to the history database and prunes
+// old collections. Failures are logged only: history never stops a command.
+func recordHistory(cfg *config.Config, cluster *proxmox.Cluster) {
+	store, err := openHistory(cfg)
+	if err != nil {
+		log.Printf("History disabled: %v", err)
+		return
+	}
+	if store == nil {
+		return
+	}
+	defer store.Close()
+
+	if err := store.Record(historyClusterName(), cluster); err != nil {
+		log.Printf("Failed to record history: %v", err)
+	}
+	if cfg.HistoryRetentionDays > 0 {
+		if _, err := store.Prune(time.Duration(cfg.HistoryRetentionDays) * 24 * time.Hour); err != nil {
+			log.Printf("Failed to prune history: %v", err)
+		}
+	}
+}
+
+// runHistory implements "migsug history": it prints the growth of every host
+// and VM recorded in the history database, when each host is forecast to
+// cross the hard limits of the capacity policy, and which VMs moved when
+func runHistory(args []string) int {
+	var days, maxVMs, maxMoves int
+
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.BoolVar(debug, "debug", false, "Enable debug logging")
+	fs.StringVar(configFile, "config", "", "Config file (default: /etc/migsug/config.yaml, then ~/.config/migsug/config.yaml)")
+	fs.StringVar(profileName, "profile", "", "Cluster profile from the config file (default: default_profile)")
+	fs.StringVar(fixtureDir, "fixtures", "", "Show the history recorded from a fixture directory without a profile")
+	fs.StringVar(policyFile, "policy", "", "Capacity policy file (YAML) with target limits; defaults to 95% CPU, 90% RAM, 85% storage")
+	fs.IntVar(&days, "days", 30, "Days of history to analyze")
+	fs.IntVar(&maxVMs, "vms", 20, "Fastest growing VMs to list (0 = all)")
+	fs.IntVar(&maxMoves, "moves", 20, "Most recent VM moves to list (0 = all)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migsug history [--days=N] [options]")
+		fmt.Fprintln(os.Stderr, "\nEvery collection and refresh is recorded in a local history database (history_file")
+		fmt.Fprintln(os.Stderr, "in the config). This prints the growth per host and per VM, when each host is")
+		fmt.Fprintln(os.Stderr, "forecast to cross the hard limits of the capacity policy, and which VMs moved when.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+	if days < 1 {
+		fmt.Fprintln(os.Stderr, "--days must be at least 1")
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(flagsSet(fs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return exitError
+	}
+	policy, err := loadCapacityPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	if *debug {
+		logFile, err := os.OpenFile("migsug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			return exitError
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	store, err := openHistory(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	if store == nil {
+		fmt.Fprintln(os.Stderr, "History is disabled (history_file: off)")
+		return exitError
+	}
+	defer store.Close()
+
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := store.Report(historyClusterName(), since, policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	if report.Collections == 0 {
+		fmt.Fprintf(os.Stderr, "No history for cluster %s in the last %d days (%s)\n", report.Cluster, days, store.Path())
+		return exitError
+	}
+	printHistory(os.Stdout, report, maxVMs, maxMoves)
+	return exitOK
+}
+
+// printHistory writes the trends report as plain text
+func printHistory(w io.Writer, report *history.Report, maxVMs, maxMoves int) {
+	fmt.Fprintf(w, "History of %s: %d collections from %s to %s\n", report.Cluster, report.Collections,
+		report.First.Local().Format("2006-01-02 15:04"), report.Last.Local().Format("2006-01-02 15:04"))
+
+	fmt.Fprintf(w, "\nHosts (growth in percentage points per day)\n")
+	fmt.Fprintf(w, "  %-16s %-18s %-20s %-6s %s\n", "NODE", "CPU/RAM/STO", "GROWTH/DAY", "VMS", "FIRST LIMIT")
+	for _, h := range report.Hosts {
+		usage := fmt.Sprintf("%.0f%%/%.0f%%/%.0f%%", h.Last.HostCPUPercent(), h.Last.RAMPercent(), h.Last.StoragePercent())
+		growth := fmt.Sprintf("%+.2f/%+.2f/%+.2f", h.HostCPUPerDay, h.RAMPerDay, h.StoragePerDay)
+		vms := fmt.Sprintf("%d", h.Last.VMCount)
+		if change := h.Last.VMCount - h.First.VMCount; change != 0 {
+			vms += fmt.Sprintf(" (%+d)", change)
+		}
+		fmt.Fprintf(w, "  %-16s %-18s %-20s %-6s %s\n", h.Node, usage, growth, vms, h.LimitSummary())
+	}
+
+	fmt.Fprintf(w, "\nForecasts (linear, against the hard limits of the capacity policy)\n")
+	for _, h := range report.Hosts {
+		if h.Forecasts == nil {
+			fmt.Fprintf(w, "  %-16s needs %s of history\n", h.Node, history.FormatDays(history.MinForecastSpan.Hours()/24))
+			continue
+		}
+		for i, f := range h.Forecasts {
+			name := h.Node
+			if i > 0 {
+				name = ""
+			}
+			fmt.Fprintf(w, "  %-16s %s\n", name, f)
+		}
+	}
+
+	vms := report.VMs
+	if maxVMs > 0 && len(vms) > maxVMs {
+		vms = vms[:maxVMs]
+	}
+	fmt.Fprintf(w, "\nFastest growing VMs (%d of %d)\n", len(vms), len(report.VMs))
+	fmt.Fprintf(w, "  %-8s %-24s %-16s %-10s %-14s %s\n", "VMID", "NAME", "NODE", "CPU/DAY", "RAM USED/DAY", "DISK/DAY")
+	for _, vm := range vms {
+		fmt.Fprintf(w, "  %-8d %-24s %-16s %-10s %-14s %s\n", vm.VMID, vm.Name, vm.Last.Node,
+			fmt.Sprintf("%+.2f%%", vm.CPUPerDay), history.FormatBytesPerDay(vm.MemPerDay), history.FormatBytesPerDay(vm.DiskPerDay))
+	}
+
+	moves := report.Moves
+	if maxMoves > 0 && len(moves) > maxMoves {
+		moves = moves[:maxMoves]
+	}
+	fmt.Fprintf(w, "\nVM moves (%d of %d, newest first)\n", len(moves), len(report.Moves))
+	if len(moves) == 0 {
+		fmt.Fprintf(w, "  None\n")
+	}
+	for _, mv := range moves {
+		fmt.Fprintf(w, "  %-17s %-8d %-24s %s → %s\n", mv.By.Local().Format("2006-01-02 15:04"), mv.VMID, mv.Name, mv.From, mv.To)
+	}
+}
@@ -12,6 +12,9 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/config"
+	"github.com/yourusername/migsug/internal/executor"
 	"github.com/yourusername/migsug/internal/proxmox"
 	"github.com/yourusername/migsug/internal/ui"
 	"golang.org/x/term"
@@ -25,6 +28,19 @@ var (
 	sourceNode = flag.String("source", "", "Source node to migrate from (optional, can select in UI)")
 	debug      = flag.Bool("debug", false, "Enable debug logging")
 	version    = flag.Bool("version", false, "Show version information")
+
+	configFile  = flag.String("config", "", "Config file (default: /etc/migsug/config.yaml, then ~/.config/migsug/config.yaml)")
+	profileName = flag.String("profile", "", "Cluster profile from the config file (default: default_profile)")
+
+	snapshotFile = flag.String("snapshot", "", "Load cluster data from a snapshot file instead of connecting to Proxmox")
+	recordFile   = flag.String("record", "", "Save the collected cluster data to a snapshot file (.gz to compress)")
+	fixtureDir   = flag.String("fixtures", "", "Read cluster data from a fixture directory instead of Proxmox (see 'migsug fixtures')")
+
+	policyFile = flag.String("policy", "", "Capacity policy file (YAML) with target limits; defaults to 95% CPU, 90% RAM, 85% storage")
+	whatIfFile = flag.String("what-if", "", "What-if file (YAML) with hypothetical nodes to add and nodes to remove")
+
+	maxPerSource = flag.Int("max-per-source", executor.DefaultLimits.PerSource, "Concurrent migrations per source node when executing a plan (0 = unlimited)")
+	maxPerTarget = flag.Int("max-per-target", executor.DefaultLimits.PerTarget, "Concurrent migrations per target node when executing a plan (0 = unlimited)")
 )
 
 // Version is set at build time via -ldflags
@@ -51,7 +67,83 @@ func promptForPassword(prompt string) string {
 	return string(bytePassword)
 }
 
+// applyEnvCredentials fills in missing credentials from PVE_* environment variables
+func applyEnvCredentials() {
+	if *apiToken == "" && (*username == "" || *password == "") {
+		*apiToken = os.Getenv("PVE_API_TOKEN")
+		if *apiToken == "" {
+			*username = os.Getenv("PVE_USERNAME")
+			*password = os.Getenv("PVE_PASSWORD")
+		}
+	}
+}
+
+// loadCapacityPolicy returns the policy from --policy, or the config file policy when unset
+// Either way it carries the affinity groups of the config file
+func loadCapacityPolicy(cfg *config.Config) (analyzer.CapacityPolicy, error) {
+	policy := cfg.CapacityPolicy
+	if *policyFile != "" {
+		var err error
+		if policy, err = analyzer.LoadCapacityPolicy(*policyFile); err != nil {
+			return policy, err
+		}
+	}
+	policy.AffinityGroups = cfg.AffinityGroups
+	return policy, nil
+}
+
+// applyWhatIf loads the --what-if file and applies it to the cluster
+// Returns the cluster unchanged and a nil simulation without the flag
+func applyWhatIf(cluster *proxmox.Cluster) (*proxmox.Cluster, *analyzer.WhatIf, error) {
+	if *whatIfFile == "" {
+		return cluster, nil, nil
+	}
+	whatIf, err := analyzer.LoadWhatIf(*whatIfFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := whatIf.Check(cluster); err != nil {
+		return nil, nil, fmt.Errorf("what-if %s: %w", *whatIfFile, err)
+	}
+	return whatIf.Apply(cluster), whatIf, nil
+}
+
+// newAPIClient creates an API client from the resolved credential flags
+// Username/password clients are authenticated before being returned; the
+// returned error is the authentication failure
+func newAPIClient() (proxmox.ProxmoxClient, error) {
+	if *apiToken != "" {
+		log.Println("Using API token authentication")
+		return proxmox.NewClient(*apiHost, *apiToken), nil
+	}
+
+	client := proxmox.NewClientWithCredentials(*apiHost, *username, *password)
+	log.Println("Using username/password authentication")
+	if err := client.Authenticate(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 func main() {
+	// Subcommands are dispatched before the TUI flags are parsed
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "plan":
+			os.Exit(runPlan(os.Args[2:]))
+		case "drain":
+			os.Exit(runDrain(os.Args[2:]))
+		case "capacity":
+			os.Exit(runCapacity(os.Args[2:]))
+		case "failures":
+			os.Exit(runFailures(os.Args[2:]))
+		case "fixtures":
+			os.Exit(runFixtures(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		}
+	}
+
 	flag.Parse()
 
 	// Show version
@@ -62,6 +154,16 @@ func main() {
 		os.Exit(0)
 	}
 
+	cfg, err := loadConfig(flagsSet(flag.CommandLine))
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ui.ApplyTheme(cfg.Theme); err != nil {
+		fmt.Printf("Invalid config: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Set up logging
 	if *debug {
 		logFile, err := os.OpenFile("migsug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
@@ -74,11 +176,114 @@ func main() {
 		log.SetOutput(io.Discard)
 	}
 
+	policy, err := loadCapacityPolicy(cfg)
+	if err != nil {
+		fmt.Printf("Failed to load capacity policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	var client proxmox.ProxmoxClient
+	var cluster *proxmox.Cluster
+	if *snapshotFile != "" {
+		snap, err := proxmox.LoadSnapshot(*snapshotFile)
+		if err != nil {
+			fmt.Printf("Failed to load snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Loaded snapshot %s (recorded %s)\n", *snapshotFile, snap.RecordedAt.Local().Format("2006-01-02 15:04:05"))
+		cluster = snap.Cluster
+	} else {
+		client = connectInteractive()
+		cluster = collectWithProgress(client)
+		recordHistory(cfg, cluster)
+		if *recordFile != "" {
+			if err := proxmox.SaveSnapshot(*recordFile, cluster, appVersion); err != nil {
+				fmt.Printf("Failed to record snapshot: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Snapshot recorded to %s\n", *recordFile)
+		}
+	}
+
+	if len(cluster.Nodes) == 0 {
+		fmt.Println("No nodes found in cluster")
+		os.Exit(1)
+	}
+
+	cluster, whatIf, err := applyWhatIf(cluster)
+	if err != nil {
+		fmt.Printf("Failed to load what-if simulation: %v\n", err)
+		os.Exit(1)
+	}
+
+	log.Printf("Loaded cluster with %d nodes and %d VMs\n", len(cluster.Nodes), cluster.TotalVMs)
+
+	// Create and run TUI
+	model := ui.NewModelWithVersion(cluster, client, appVersion)
+	if *snapshotFile != "" {
+		model.SetSnapshot(*snapshotFile)
+	}
+	model.SetExecutionLimits(executor.Limits{PerSource: *maxPerSource, PerTarget: *maxPerTarget})
+	model.SetCapacityPolicy(policy)
+	model.SetEstimateSettings(cfg.MigrationEstimate)
+	model.SetOptimizerSettings(cfg.Optimizer)
+	model.SetRefreshInterval(cfg.RefreshInterval)
+	model.SetFailureNodes(cfg.FailureNodes)
+	if store, err := openHistory(cfg); err != nil {
+		log.Printf("History disabled: %v", err)
+	} else if store != nil {
+		defer store.Close()
+		model.SetHistory(store, historyClusterName())
+	}
+	if whatIf != nil {
+		model.SetWhatIf(whatIf)
+	}
+	if len(cfg.Profiles) > 0 {
+		model.SetClusterProfiles(clusterProfiles(cfg), *profileName)
+	}
+	model.SetDefaultConstraints(defaultConstraints(cfg.Defaults))
+	if cfg.Defaults.Mode != "" {
+		if mode, err := analyzer.ParseMigrationMode(cfg.Defaults.Mode); err == nil {
+			model.SetDefaultMode(mode)
+		}
+	}
+
+	// If source node is specified, pre-select it
+	if *sourceNode != "" {
+		for i, node := range cluster.Nodes {
+			if node.Name == *sourceNode {
+				// We can't easily set the internal state here without modifying the model
+				// So we'll just log it
+				log.Printf("Source node specified: %s (index %d)\n", *sourceNode, i)
+				break
+			}
+		}
+	}
+
+	p := tea.NewProgram(model, tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Printf("Error running application: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print the final view so it stays visible after quitting
+	if m, ok := finalModel.(ui.Model); ok {
+		fmt.Println(m.View())
+	}
+}
+
+// connectInteractive creates and tests a Proxmox client, prompting for
+// credentials when none are given; exits the process on failure
+func connectInteractive() proxmox.ProxmoxClient {
 	// Create Proxmox client
 	var client proxmox.ProxmoxClient
 
-	// Check if running on Proxmox host - if so, use shell client (no credentials needed)
-	if proxmox.IsProxmoxHost() {
+	if *fixtureDir != "" {
+		fmt.Printf("Using fixture data from %s\n", *fixtureDir)
+		client = proxmox.NewFixtureClient(*fixtureDir)
+	} else if proxmox.IsProxmoxHost() {
+		// Running on Proxmox host - use shell client (no credentials needed)
 		fmt.Println("Detected Proxmox host - using local pvesh commands (no credentials needed)")
 		log.Println("Using shell client with pvesh")
 		client = proxmox.NewShellClient()
@@ -93,14 +298,7 @@ func main() {
 		fmt.Println("Not running on Proxmox host - API credentials required")
 
 		// Check for authentication
-		if *apiToken == "" && (*username == "" || *password == "") {
-			// Try environment variables
-			*apiToken = os.Getenv("PVE_API_TOKEN")
-			if *apiToken == "" {
-				*username = os.Getenv("PVE_USERNAME")
-				*password = os.Getenv("PVE_PASSWORD")
-			}
-		}
+		applyEnvCredentials()
 
 		// If still no credentials, prompt interactively
 		if *apiToken == "" && (*username == "" || *password == "") {
@@ -143,19 +341,14 @@ func main() {
 		}
 
 		// Create API-based client
-		if *apiToken != "" {
-			client = proxmox.NewClient(*apiHost, *apiToken)
-			log.Println("Using API token authentication")
-		} else {
-			client = proxmox.NewClientWithCredentials(*apiHost, *username, *password)
-			log.Println("Using username/password authentication")
-
-			// Authenticate
+		if *username != "" && *apiToken == "" {
 			fmt.Println("Authenticating...")
-			if err := client.Authenticate(); err != nil {
-				fmt.Printf("Authentication failed: %v\n", err)
-				os.Exit(1)
-			}
+		}
+		var err error
+		client, err = newAPIClient()
+		if err != nil {
+			fmt.Printf("Authentication failed: %v\n", err)
+			os.Exit(1)
 		}
 	}
 
@@ -168,6 +361,8 @@ func main() {
 			fmt.Println("  • Ensure you're running as root")
 			fmt.Println("  • Check that pvesh command is available")
 			fmt.Println("  • Verify Proxmox services are running")
+		} else if _, ok := client.(*proxmox.FixtureClient); ok {
+			fmt.Println("\nGenerate a fixture directory with: migsug fixtures --dir=" + *fixtureDir)
 		} else {
 			fmt.Println("\nTroubleshooting:")
 			fmt.Println("  • Check that the API host is correct:", *apiHost)
@@ -176,7 +371,12 @@ func main() {
 		}
 		os.Exit(1)
 	}
+	return client
+}
 
+// collectWithProgress loads cluster data while drawing a progress bar
+// Exits the process on failure
+func collectWithProgress(client proxmox.ProxmoxClient) *proxmox.Cluster {
 	// Collect cluster data with progress bar
 	fmt.Println("Loading cluster data...")
 	startTime := time.Now()
@@ -201,38 +401,5 @@ func main() {
 		fmt.Printf("Failed to collect cluster data: %v\n", err)
 		os.Exit(1)
 	}
-
-	if len(cluster.Nodes) == 0 {
-		fmt.Println("No nodes found in cluster")
-		os.Exit(1)
-	}
-
-	log.Printf("Loaded cluster with %d nodes and %d VMs\n", len(cluster.Nodes), cluster.TotalVMs)
-
-	// Create and run TUI
-	model := ui.NewModelWithVersion(cluster, client, appVersion)
-
-	// If source node is specified, pre-select it
-	if *sourceNode != "" {
-		for i, node := range cluster.Nodes {
-			if node.Name == *sourceNode {
-				// We can't easily set the internal state here without modifying the model
-				// So we'll just log it
-				log.Printf("Source node specified: %s (index %d)\n", *sourceNode, i)
-				break
-			}
-		}
-	}
-
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	finalModel, err := p.Run()
-	if err != nil {
-		fmt.Printf("Error running application: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Print the final view so it stays visible after quitting
-	if m, ok := finalModel.(ui.Model); ok {
-		fmt.Println(m.View())
-	}
+	return cluster
 }
@@ -0,0 +1,696 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/config"
+	"github.com/yourusername/migsug/internal/executor"
+	"github.com/yourusername/migsug/internal/export"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// Exit codes for the plan subcommand
+const (
+	exitOK       = 0 // Plan generated, every selected VM has a target
+	exitError    = 1 // Connection, collection or analysis failure
+	exitUsage    = 2 // Invalid command line
+	exitUnplaced = 3 // Plan generated, but some VMs have no suitable target, violations remain or a wave is unsafe
+)
+
+// planOptions holds the parsed command line of the plan subcommand
+type planOptions struct {
+	mode          string
+	value         string
+	vms           string
+	exclude       string
+	maxVMsPerHost int
+	minCPUFree    float64
+	minRAMFreeGB  float64
+	commands      bool
+	output        string
+	outFile       string
+	cpuMetric     string
+	strategy      string
+
+	// Budget for balance_cluster
+	maxMigrations int
+	maxTransferGB float64
+	maxLiveRAMGB  float64
+	excludeVMs    string
+
+	// Global optimizer for balance_cluster
+	optimize   bool
+	timeBudget time.Duration
+}
+
+// runPlan implements "migsug plan": it runs the analyzer without the TUI and
+// prints the resulting migration plan to stdout. Errors go to stderr.
+func runPlan(args []string) int {
+	var opts planOptions
+
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.StringVar(apiToken, "api-token", "", "Proxmox API token (format: user@realm!tokenid=secret)")
+	fs.StringVar(apiHost, "api-host", "https://localhost:8006", "Proxmox API host URL")
+	fs.StringVar(username, "username", "", "Proxmox username (alternative to API token)")
+	fs.StringVar(password, "password", "", "Proxmox password (alternative to API token)")
+	fs.StringVar(sourceNode, "source", "", "Source node to migrate from (optional for balance_cluster, unused by constraint_audit)")
+	fs.BoolVar(debug, "debug", false, "Enable debug logging")
+	fs.StringVar(configFile, "config", "", "Config file (default: /etc/migsug/config.yaml, then ~/.config/migsug/config.yaml)")
+	fs.StringVar(profileName, "profile", "", "Cluster profile from the config file (default: default_profile)")
+	fs.StringVar(snapshotFile, "snapshot", "", "Load cluster data from a snapshot file instead of connecting to Proxmox")
+	fs.StringVar(fixtureDir, "fixtures", "", "Read cluster data from a fixture directory instead of Proxmox (see 'migsug fixtures')")
+	fs.StringVar(policyFile, "policy", "", "Capacity policy file (YAML) with target limits; defaults to 95% CPU, 90% RAM, 85% storage")
+	fs.StringVar(recordFile, "record", "", "Save the collected cluster data to a snapshot file (.gz to compress)")
+	fs.StringVar(whatIfFile, "what-if", "", "What-if file (YAML) with hypothetical nodes to add and nodes to remove")
+	fs.IntVar(maxPerSource, "max-per-source", executor.DefaultLimits.PerSource, "Concurrent migrations per source node assumed for the time estimate (0 = unlimited)")
+	fs.IntVar(maxPerTarget, "max-per-target", executor.DefaultLimits.PerTarget, "Concurrent migrations per target node assumed for the time estimate (0 = unlimited)")
+	fs.StringVar(&opts.mode, "mode", "", "Migration mode: vm_count, vcpu, cpu_usage, ram, storage, specific, all, creation_date, balance_cluster, constraint_audit")
+	fs.StringVar(&opts.value, "value", "", "Mode value: VM count, vCPUs, CPU %, RAM GB, storage GB or age in days")
+	fs.StringVar(&opts.vms, "vms", "", "Comma-separated VMIDs (mode specific)")
+	fs.StringVar(&opts.exclude, "exclude", "", "Comma-separated nodes that must not receive VMs")
+	fs.IntVar(&opts.maxVMsPerHost, "max-vms-per-host", 0, "Limit VMs migrated to each target host (0 = no limit)")
+	fs.Float64Var(&opts.minCPUFree, "min-cpu-free", 0, "Require at least N% CPU free on target (0 = no limit)")
+	fs.Float64Var(&opts.minRAMFreeGB, "min-ram-free", 0, "Require at least N GB RAM free on target (0 = no limit)")
+	fs.BoolVar(&opts.commands, "commands", false, "Append pvesh migrate commands to the plan")
+	fs.StringVar(&opts.output, "output", "text", "Output format: text, json or yaml")
+	fs.StringVar(&opts.outFile, "out-file", "", "Write the plan to this file instead of stdout")
+	fs.StringVar(&opts.strategy, "strategy", "", "Placement strategy ranking the targets: default, binpack, spread or cpu_generation")
+	fs.BoolVar(&opts.optimize, "optimize", false, "Balance the cluster with the global optimizer instead of the greedy passes (balance_cluster)")
+	fs.IntVar(&opts.maxMigrations, "max-migrations", 0, "Balance budget: at most N migrations (0 = unlimited, balance_cluster)")
+	fs.Float64Var(&opts.maxTransferGB, "max-transfer", 0, "Balance budget: GB of RAM and local disk the migrations may copy (0 = unlimited, balance_cluster)")
+	fs.Float64Var(&opts.maxLiveRAMGB, "max-live-ram", 0, "Balance budget: don't live-migrate VMs with more than N GB RAM (0 = no limit, balance_cluster)")
+	fs.StringVar(&opts.excludeVMs, "exclude-vms", "", "Balance budget: comma-separated VMIDs that must not be migrated (balance_cluster)")
+	fs.DurationVar(&opts.timeBudget, "time-budget", 0, "Optimizer: search time, e.g. 30s (default 10s, implies --optimize)")
+	fs.StringVar(&opts.cpuMetric, "cpu-metric", "current", "CPU usage to plan with: current, or RRD history hour-avg, hour-p95, day-avg, day-p95, week-avg, week-p95")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migsug plan --mode=MODE [--source=NODE] [--value=N] [options]")
+		fmt.Fprintln(os.Stderr, "\nRuns the migration analyzer without the TUI and prints the plan to stdout.")
+		fmt.Fprintln(os.Stderr, "\nExit codes: 0 = all VMs placed, 1 = error, 2 = usage error, 3 = some VMs have no target or violations remain")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+
+	set := flagsSet(fs)
+	cfg, err := loadConfig(set)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return exitError
+	}
+	applyPlanDefaults(&opts, cfg.Defaults, set)
+
+	mode, err := analyzer.ParseMigrationMode(opts.mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		fs.Usage()
+		return exitUsage
+	}
+
+	var format export.Format
+	if opts.output != "text" {
+		if format, err = export.ParseFormat(opts.output); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+
+	cpuMetric, err := proxmox.ParseCPUMetric(opts.cpuMetric)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+	strategy, err := analyzer.ParsePlacementStrategy(opts.strategy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+	optimizer := planOptimizerSettings(opts, cfg.Optimizer, set)
+	if err := optimizer.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid optimizer settings: %v\n", err)
+		return exitUsage
+	}
+
+	// Cluster-wide balance and the constraint audit run without a source node
+	clusterWide := (mode == analyzer.ModeBalanceCluster && *sourceNode == "") || mode == analyzer.ModeConstraintAudit
+
+	policy, err := loadCapacityPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	var constraints analyzer.MigrationConstraints
+	if clusterWide {
+		constraints = analyzer.MigrationConstraints{
+			BalanceCluster: true,
+			ExcludeNodes:   splitList(opts.exclude),
+			Policy:         &policy,
+			Strategy:       strategy,
+		}
+		if err := applyPlanBudget(&constraints, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+	} else {
+		constraints, err = buildPlanConstraints(mode, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitUsage
+		}
+		if err := constraints.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid constraints: %v\n", err)
+			return exitUsage
+		}
+		constraints.Policy = &policy
+		constraints.Strategy = strategy
+	}
+
+	if *debug {
+		logFile, err := os.OpenFile("migsug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			return exitError
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	cluster, client, err := loadPlanCluster(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	if len(cluster.Nodes) == 0 {
+		fmt.Fprintln(os.Stderr, "No nodes found in cluster")
+		return exitError
+	}
+	if !cpuMetric.IsCurrent() {
+		if client == nil {
+			fmt.Fprintln(os.Stderr, "--cpu-metric needs the RRD history from Proxmox, not available with --snapshot")
+			return exitUsage
+		}
+		if cluster, err = proxmox.CollectCPUHistory(client, cluster, cpuMetric, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+	}
+	cluster, whatIf, err := applyWhatIf(cluster)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	var result *analyzer.AnalysisResult
+	switch {
+	case mode == analyzer.ModeConstraintAudit:
+		result, err = analyzer.AnalyzeConstraintAudit(cluster, policy)
+	case clusterWide && whatIf != nil:
+		result, err = analyzer.AnalyzeWhatIf(cluster, constraints, optimizer, nil)
+	case clusterWide && optimizer.Enabled:
+		result, err = analyzer.AnalyzeClusterWideOptimizedWithConstraints(cluster, constraints, optimizer, nil)
+	case clusterWide:
+		result, err = analyzer.AnalyzeClusterWideBalanceWithConstraints(cluster, constraints, nil)
+	default:
+		result, err = analyzer.Analyze(cluster, constraints)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+		return exitError
+	}
+	if whatIf != nil {
+		// Any mode may pick simulated targets
+		result.WhatIf = analyzer.ClusterWhatIf(cluster)
+	}
+	result.CPUMetric = cluster.CPUMetric
+	result.Estimate = cfg.MigrationEstimate.EstimateResult(result, *maxPerSource, *maxPerTarget)
+
+	var out io.Writer = os.Stdout
+	if opts.outFile != "" {
+		f, err := os.Create(opts.outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format != "" {
+		plan := export.NewPlan(result, cluster, appVersion)
+		if err := export.Write(out, plan, format); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+	} else {
+		printPlan(out, result, mode, constraints.SourceNode, opts.commands)
+	}
+
+	if len(result.UnmigrateableVMs) > 0 {
+		return exitUnplaced
+	}
+	if result.Audit != nil && result.Audit.Repaired() < len(result.Audit.Violations) {
+		return exitUnplaced
+	}
+	if result.Staged.HasUnsafe() {
+		return exitUnplaced
+	}
+	for _, sug := range result.Suggestions {
+		if sug.TargetNode == "NONE" {
+			return exitUnplaced
+		}
+	}
+	return exitOK
+}
+
+// loadPlanCluster returns the cluster to plan against: either replayed from
+// --snapshot or collected live (and optionally recorded with --record)
+// Live collections are added to the history database. The client is nil for a snapshot.
+func loadPlanCluster(cfg *config.Config) (*proxmox.Cluster, proxmox.ProxmoxClient, error) {
+	if *snapshotFile != "" {
+		snap, err := proxmox.LoadSnapshot(*snapshotFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return snap.Cluster, nil, nil
+	}
+
+	client, err := connectNonInteractive()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cluster, err := proxmox.CollectClusterData(client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect cluster data: %w", err)
+	}
+	recordHistory(cfg, cluster)
+
+	if *recordFile != "" {
+		if err := proxmox.SaveSnapshot(*recordFile, cluster, appVersion); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cluster, client, nil
+}
+
+// connectNonInteractive creates and tests a Proxmox client without prompting
+// Credentials come from flags or PVE_* environment variables only
+func connectNonInteractive() (proxmox.ProxmoxClient, error) {
+	var client proxmox.ProxmoxClient
+
+	if *fixtureDir != "" {
+		client = proxmox.NewFixtureClient(*fixtureDir)
+	} else if proxmox.IsProxmoxHost() {
+		log.Println("Using shell client with pvesh")
+		client = proxmox.NewShellClient()
+	} else {
+		applyEnvCredentials()
+		if *apiToken == "" && (*username == "" || *password == "") {
+			return nil, fmt.Errorf("no credentials: use --api-token, --username/--password or PVE_API_TOKEN / PVE_USERNAME + PVE_PASSWORD")
+		}
+
+		var err error
+		client, err = newAPIClient()
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Proxmox: %w", err)
+	}
+	return client, nil
+}
+
+// applyPlanDefaults fills in plan options not given on the command line from the config file
+func applyPlanDefaults(opts *planOptions, d config.Defaults, set map[string]bool) {
+	if !set["mode"] && d.Mode != "" {
+		opts.mode = d.Mode
+	}
+	if !set["exclude"] && len(d.Exclude) > 0 {
+		opts.exclude = strings.Join(d.Exclude, ",")
+	}
+	if !set["max-vms-per-host"] {
+		opts.maxVMsPerHost = d.MaxVMsPerHost
+	}
+	if !set["min-cpu-free"] {
+		opts.minCPUFree = d.MinCPUFree
+	}
+	if !set["min-ram-free"] {
+		opts.minRAMFreeGB = d.MinRAMFreeGB
+	}
+	if !set["strategy"] && d.Strategy != "" {
+		opts.strategy = d.Strategy
+	}
+	if !set["max-migrations"] {
+		opts.maxMigrations = d.MaxMigrations
+	}
+	if !set["max-transfer"] {
+		opts.maxTransferGB = d.MaxTransferGB
+	}
+	if !set["max-live-ram"] {
+		opts.maxLiveRAMGB = d.MaxLiveRAMGB
+	}
+	if !set["exclude-vms"] && len(d.ExcludeVMs) > 0 {
+		vmids := make([]string, len(d.ExcludeVMs))
+		for i, vmid := range d.ExcludeVMs {
+			vmids[i] = strconv.Itoa(vmid)
+		}
+		opts.excludeVMs = strings.Join(vmids, ",")
+	}
+}
+
+// planOptimizerSettings applies the optimizer flags on top of the config file
+// A time budget given on the command line turns the optimizer on. The
+// migration and transfer budgets are balance constraints, see applyPlanBudget.
+func planOptimizerSettings(opts planOptions, settings analyzer.OptimizerSettings, set map[string]bool) analyzer.OptimizerSettings {
+	if set["optimize"] {
+		settings.Enabled = opts.optimize
+	}
+	if set["time-budget"] {
+		settings.TimeBudgetSeconds = opts.timeBudget.Seconds()
+		settings.Enabled = true
+	}
+	return settings
+}
+
+// applyPlanBudget adds the balance budget of the command line to the constraints
+// Zero values mean unlimited; negative ones are rejected.
+func applyPlanBudget(constraints *analyzer.MigrationConstraints, opts planOptions) error {
+	if opts.maxMigrations != 0 {
+		constraints.MaxMigrations = &opts.maxMigrations
+	}
+	if opts.maxTransferGB != 0 {
+		bytes := int64(opts.maxTransferGB * 1024 * 1024 * 1024)
+		constraints.MaxTransfer = &bytes
+	}
+	if opts.maxLiveRAMGB != 0 {
+		bytes := int64(opts.maxLiveRAMGB * 1024 * 1024 * 1024)
+		constraints.MaxLiveRAM = &bytes
+	}
+	for _, s := range splitList(opts.excludeVMs) {
+		vmid, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid VMID %q: %w", s, err)
+		}
+		constraints.ExcludeVMs = append(constraints.ExcludeVMs, vmid)
+	}
+	if err := constraints.ValidateBudget(); err != nil {
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+	return nil
+}
+
+// buildPlanConstraints converts the plan command line into analyzer constraints
+// Value units match the criteria view: RAM and storage in GB, CPU usage in percent
+func buildPlanConstraints(mode analyzer.MigrationMode, opts planOptions) (analyzer.MigrationConstraints, error) {
+	constraints := analyzer.MigrationConstraints{
+		SourceNode:   *sourceNode,
+		ExcludeNodes: splitList(opts.exclude),
+	}
+
+	if opts.maxVMsPerHost > 0 {
+		constraints.MaxVMsPerHost = &opts.maxVMsPerHost
+	}
+	if opts.minCPUFree > 0 {
+		constraints.MinCPUFree = &opts.minCPUFree
+	}
+	if opts.minRAMFreeGB > 0 {
+		bytes := int64(opts.minRAMFreeGB * 1024 * 1024 * 1024)
+		constraints.MinRAMFree = &bytes
+	}
+
+	// Modes without a value
+	switch mode {
+	case analyzer.ModeAll:
+		constraints.MigrateAll = true
+		return constraints, nil
+	case analyzer.ModeBalanceCluster:
+		constraints.BalanceCluster = true
+		return constraints, nil
+	case analyzer.ModeSpecific:
+		for _, s := range splitList(opts.vms) {
+			vmid, err := strconv.Atoi(s)
+			if err != nil {
+				return constraints, fmt.Errorf("invalid VMID %q: %w", s, err)
+			}
+			constraints.SpecificVMs = append(constraints.SpecificVMs, vmid)
+		}
+		if len(constraints.SpecificVMs) == 0 {
+			return constraints, fmt.Errorf("--vms is required for mode %s", mode)
+		}
+		return constraints, nil
+	}
+
+	value := opts.value
+	if value == "" && mode == analyzer.ModeCreationDate {
+		value = "75" // Same default as the criteria view
+	}
+	if value == "" {
+		return constraints, fmt.Errorf("--value is required for mode %s", mode)
+	}
+
+	switch mode {
+	case analyzer.ModeVMCount, analyzer.ModeVCPU, analyzer.ModeCreationDate:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return constraints, fmt.Errorf("invalid value for mode %s: %w", mode, err)
+		}
+		switch mode {
+		case analyzer.ModeVMCount:
+			constraints.VMCount = &n
+		case analyzer.ModeVCPU:
+			constraints.VCPUCount = &n
+		default:
+			constraints.CreationAge = &n
+		}
+	case analyzer.ModeCPUUsage, analyzer.ModeRAM, analyzer.ModeStorage:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return constraints, fmt.Errorf("invalid value for mode %s: %w", mode, err)
+		}
+		switch mode {
+		case analyzer.ModeCPUUsage:
+			constraints.CPUUsage = &f
+		case analyzer.ModeRAM:
+			bytes := int64(f * 1024 * 1024 * 1024)
+			constraints.RAMAmount = &bytes
+		default:
+			bytes := int64(f * 1024 * 1024 * 1024)
+			constraints.StorageAmount = &bytes
+		}
+	}
+
+	return constraints, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// printPlan writes a plain-text migration plan
+func printPlan(w io.Writer, result *analyzer.AnalysisResult, mode analyzer.MigrationMode, source string, commands bool) {
+	if result.IsBalanceCluster || source == "" {
+		fmt.Fprintf(w, "Migration plan (mode %s, cluster-wide)\n", mode)
+	} else {
+		fmt.Fprintf(w, "Migration plan (mode %s, source %s)\n", mode, source)
+	}
+	if result.ImprovementInfo != "" {
+		fmt.Fprintf(w, "%s\n", result.ImprovementInfo)
+	}
+	if result.Optimization != nil {
+		fmt.Fprintf(w, "Optimizer: %s\n", result.Optimization.BudgetSummary())
+	}
+	if result.Budget != nil {
+		fmt.Fprintf(w, "Budget: %s\n", result.Budget.Summary())
+		if restrictions := result.Budget.Restrictions(); restrictions != "" {
+			fmt.Fprintf(w, "Kept in place: %s\n", restrictions)
+		}
+	}
+	if result.CPUMetric != "" {
+		fmt.Fprintf(w, "CPU usage: %s from the RRD history\n", result.CPUMetric)
+	}
+	if result.WhatIf != nil {
+		fmt.Fprintln(w, "What-if simulation: simulated nodes don't exist, don't run this plan")
+	}
+	fmt.Fprintln(w)
+
+	if audit := result.Audit; audit != nil {
+		if len(audit.Violations) == 0 {
+			fmt.Fprintln(w, "No placement constraint violations found")
+			return
+		}
+		fmt.Fprintf(w, "Constraint violations (%s):\n", audit.Summary())
+		for _, v := range audit.Violations {
+			status := "repaired"
+			if !v.Repaired {
+				status = "NOT repaired: " + v.Blocked
+			}
+			fmt.Fprintf(w, "  [%s] %s (%s)\n", v.Rule, v.Message, status)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// Repeated moves of a VM are listed once, like the staged plan runs them
+	suggestions := result.PlannedSuggestions()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VMID\tNAME\tSTATUS\tSOURCE\tTARGET\tVCPU\tRAM\tSTORAGE\tTRANSFER\tTIME\tSCORE")
+	placed := 0
+	for _, sug := range suggestions {
+		eta := "-"
+		if sug.TargetNode != "NONE" {
+			placed++
+			if result.Estimate != nil {
+				eta = analyzer.FormatDuration(result.Estimate.VMDuration(sug.VMID))
+			}
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%.1f\n",
+			sug.VMID, sug.VMName, sug.Status, sug.SourceNode, sug.TargetNode,
+			sug.VCPUs, proxmox.FormatBytes(sug.RAM), proxmox.FormatBytes(sug.Storage), proxmox.FormatBytes(sug.Transfer), eta, sug.Score)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d of %d VMs placed\n", placed, len(suggestions))
+	if est := result.Estimate; est != nil && len(est.Migrations) > 0 {
+		fmt.Fprintf(w, "Estimated time: %s (%s one at a time), %s to transfer, %s per source / %s per target\n",
+			analyzer.FormatDuration(est.WallClock), analyzer.FormatDuration(est.Sequential),
+			proxmox.FormatBytes(est.Bytes), formatLimit(est.PerSource), formatLimit(est.PerTarget))
+		if n := est.NonConverging(); n > 0 {
+			fmt.Fprintf(w, "Warning: %d live migrations dirty RAM faster than the link copies it and may not converge\n", n)
+		}
+	}
+
+	if len(result.UnmigrateableVMs) > 0 {
+		fmt.Fprintf(w, "\nUnmigrateable VMs (%d):\n", len(result.UnmigrateableVMs))
+		for _, vm := range result.UnmigrateableVMs {
+			fmt.Fprintf(w, "  %d %s: %s\n", vm.VMID, vm.VMName, vm.Reason)
+		}
+	}
+
+	if len(result.AffinityViolations) > 0 {
+		fmt.Fprintf(w, "\nAffinity group violations after migration (%d):\n", len(result.AffinityViolations))
+		for _, v := range result.AffinityViolations {
+			fmt.Fprintf(w, "  %s\n", v.Message)
+		}
+	}
+
+	if result.Staged != nil && len(result.Staged.Waves) > 0 {
+		fmt.Fprintf(w, "\nExecution order: %s\n", result.Staged.Summary())
+		for i, wave := range result.Staged.Waves {
+			fmt.Fprintf(w, "%s\n", wave.Title(i))
+			for _, m := range wave.Migrations {
+				note := ""
+				if m.Temporary {
+					note = "  # " + m.Reason
+				}
+				fmt.Fprintf(w, "  %d %s: %s -> %s%s\n", m.VMID, m.VMName, m.SourceNode, m.TargetNode, note)
+			}
+		}
+	}
+
+	// Node impact (before -> after)
+	fmt.Fprintln(w, "\nNode impact:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tVMS\tCPU%\tRAM%\tSTORAGE%")
+	if !result.IsBalanceCluster && result.SourceBefore.Name != "" {
+		printNodeImpact(tw, result.SourceBefore, result.SourceAfter)
+	}
+	for _, name := range sortedNodeNames(result.TargetsBefore) {
+		before := result.TargetsBefore[name]
+		if label := result.WhatIf.Label(name); label != "" {
+			before.Name += " (" + label + ")"
+		}
+		printNodeImpact(tw, before, result.TargetsAfter[name])
+	}
+	tw.Flush()
+
+	if commands && placed > 0 && result.WhatIf == nil {
+		fmt.Fprintln(w, "\nCommands:")
+		if result.Staged == nil {
+			for _, sug := range suggestions {
+				if sug.TargetNode != "NONE" {
+					printCommand(w, sug)
+				}
+			}
+			return
+		}
+		for i, wave := range result.Staged.Waves {
+			if wave.Unsafe {
+				// Commented out: running them may push hosts past the limits
+				fmt.Fprintf(w, "# %s, review before running\n", wave.Title(i))
+				for _, m := range wave.Migrations {
+					fmt.Fprint(w, "# ")
+					printCommand(w, m.MigrationSuggestion)
+				}
+				continue
+			}
+			fmt.Fprintf(w, "# %s, wait for all before the next wave\n", wave.Title(i))
+			for _, m := range wave.Migrations {
+				printCommand(w, m.MigrationSuggestion)
+			}
+		}
+	}
+}
+
+// printCommand writes the migrate command of a suggestion
+func printCommand(w io.Writer, sug analyzer.MigrationSuggestion) {
+	if sug.NeedsRestart() {
+		fmt.Fprintf(w, "%s  # container restart (downtime)\n", sug.MigrateCommand())
+	} else {
+		fmt.Fprintln(w, sug.MigrateCommand())
+	}
+}
+
+// formatLimit formats a concurrency limit (0 = unlimited)
+func formatLimit(n int) string {
+	if n <= 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(n)
+}
+
+// printNodeImpact writes one before -> after row of the node impact table
+func printNodeImpact(w io.Writer, before, after analyzer.NodeState) {
+	fmt.Fprintf(w, "%s\t%d -> %d\t%.1f -> %.1f\t%.1f -> %.1f\t%.1f -> %.1f\n",
+		before.Name, before.VMCount, after.VMCount,
+		before.CPUPercent, after.CPUPercent,
+		before.RAMPercent, after.RAMPercent,
+		before.StoragePercent, after.StoragePercent)
+}
+
+// sortedNodeNames returns the keys of a node state map in name order
+func sortedNodeNames(states map[string]analyzer.NodeState) []string {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/yourusername/migsug/internal/config"
+	"github.com/yourusername/migsug/internal/proxmox"
+	"github.com/yourusername/migsug/internal/ui"
+)
+
+// connectProfile creates and tests a client for a config profile without prompting
+func connectProfile(p config.Profile) (proxmox.ProxmoxClient, error) {
+	host := p.APIHost
+	if host == "" {
+		host = "https://localhost:8006"
+	}
+
+	var client proxmox.ProxmoxClient
+	switch {
+	case p.Fixtures != "":
+		client = proxmox.NewFixtureClient(p.Fixtures)
+	case p.APIToken != "":
+		client = proxmox.NewClient(host, p.APIToken)
+	case p.Username != "" && p.Password != "":
+		c := proxmox.NewClientWithCredentials(host, p.Username, p.Password)
+		if err := c.Authenticate(); err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+		client = c
+	default:
+		return nil, fmt.Errorf("no credentials in profile (api_token or username + password)")
+	}
+
+	if err := client.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return client, nil
+}
+
+// clusterProfiles converts the config file profiles for the TUI cluster switcher
+func clusterProfiles(cfg *config.Config) []ui.ClusterProfile {
+	var profiles []ui.ClusterProfile
+	for _, name := range cfg.ProfileNames() {
+		p := cfg.Profiles[name]
+		profiles = append(profiles, ui.ClusterProfile{
+			Name: name,
+			Connect: func() (proxmox.ProxmoxClient, error) {
+				return connectProfile(p)
+			},
+		})
+	}
+	return profiles
+}
@@ -0,0 +1,299 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// Affinity group modes
+const (
+	AffinitySpread = "spread" // Members on different hosts, at most MaxPerHost per host
+	AffinityPack   = "pack"   // All members on one host
+)
+
+// AffinityGroup is a named set of VMs placed relative to each other
+// Members are the VMs matching one of the Members globs and the VMs tagged
+// group=<name> in their config comment
+type AffinityGroup struct {
+	Name       string   `json:"name" yaml:"name"`
+	Mode       string   `json:"mode,omitempty" yaml:"mode,omitempty"`                 // spread (default) or pack
+	MaxPerHost int      `json:"max_per_host,omitempty" yaml:"max_per_host,omitempty"` // spread: members allowed per host (default 1)
+	Members    []string `json:"members,omitempty" yaml:"members,omitempty"`           // filepath.Match globs on VM names
+}
+
+// Validate checks the group's mode, limit and member globs
+func (g AffinityGroup) Validate() error {
+	if g.Name == "" {
+		return &ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	if g.Mode != "" && g.Mode != AffinitySpread && g.Mode != AffinityPack {
+		return &ValidationError{Field: "mode", Message: fmt.Sprintf("unknown mode %q (use spread or pack)", g.Mode)}
+	}
+	if g.MaxPerHost < 0 {
+		return &ValidationError{Field: "max_per_host", Message: "must not be negative"}
+	}
+	for i, member := range g.Members {
+		if _, err := filepath.Match(member, ""); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("members[%d]", i), Message: "bad pattern"}
+		}
+	}
+	return nil
+}
+
+// Contains returns true if the VM is a member of the group
+func (g AffinityGroup) Contains(vm proxmox.VM) bool {
+	if vm.Group == g.Name {
+		return true
+	}
+	for _, member := range g.Members {
+		if ok, _ := filepath.Match(member, vm.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPack returns true if all members must share one host
+func (g AffinityGroup) IsPack() bool {
+	return g.Mode == AffinityPack
+}
+
+// Limit returns how many members may share a host in spread mode
+func (g AffinityGroup) Limit() int {
+	if g.MaxPerHost > 0 {
+		return g.MaxPerHost
+	}
+	return 1
+}
+
+// String describes the group, e.g. "db-cluster (spread, max 1 per host)"
+func (g AffinityGroup) String() string {
+	if g.IsPack() {
+		return fmt.Sprintf("%s (pack)", g.Name)
+	}
+	return fmt.Sprintf("%s (spread, max %d per host)", g.Name, g.Limit())
+}
+
+// inAffinityGroup returns true if the VM is a member of one of the groups
+func inAffinityGroup(vm proxmox.VM, groups []AffinityGroup) bool {
+	for _, g := range groups {
+		if g.Contains(vm) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveAffinityGroups returns the configured groups followed by the groups
+// only named in VM config comments, sorted by name. A group's mode and limit
+// come from the config file; when it sets none, from the first member (by
+// VMID) with groupmode= or groupmax= in its config comment.
+// Analyses resolve the groups once and pass them to the placement checks.
+func ResolveAffinityGroups(cluster *proxmox.Cluster, configured []AffinityGroup) []AffinityGroup {
+	groups := append([]AffinityGroup(nil), configured...)
+	index := make(map[string]int, len(groups))
+	for i, g := range groups {
+		index[g.Name] = i
+	}
+	numConfigured := len(groups)
+
+	var tagged []proxmox.VM
+	if cluster != nil {
+		for _, node := range cluster.Nodes {
+			for _, vm := range node.VMs {
+				if vm.Group != "" {
+					tagged = append(tagged, vm)
+				}
+			}
+		}
+	}
+	sort.Slice(tagged, func(i, j int) bool { return tagged[i].VMID < tagged[j].VMID })
+
+	modeSet := make(map[string]bool)
+	maxSet := make(map[string]bool)
+	for i := 0; i < numConfigured; i++ {
+		modeSet[groups[i].Name] = groups[i].Mode != ""
+		maxSet[groups[i].Name] = groups[i].MaxPerHost > 0
+	}
+	for _, vm := range tagged {
+		i, ok := index[vm.Group]
+		if !ok {
+			i = len(groups)
+			index[vm.Group] = i
+			groups = append(groups, AffinityGroup{Name: vm.Group})
+		}
+		if (vm.GroupMode == AffinitySpread || vm.GroupMode == AffinityPack) && !modeSet[vm.Group] {
+			groups[i].Mode = vm.GroupMode
+			modeSet[vm.Group] = true
+		}
+		if vm.GroupMax > 0 && !maxSet[vm.Group] {
+			groups[i].MaxPerHost = vm.GroupMax
+			maxSet[vm.Group] = true
+		}
+	}
+
+	extra := groups[numConfigured:]
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Name < extra[j].Name })
+	return groups
+}
+
+// vmAffinityGroups returns the groups the VM belongs to
+func vmAffinityGroups(vm proxmox.VM, groups []AffinityGroup) []AffinityGroup {
+	var result []AffinityGroup
+	for _, g := range groups {
+		if g.Contains(vm) {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// checkAffinityGroups checks the VM's affinity groups for a placement on targetNode
+// Other members are located like withvm partners: planned migrations first, then the cluster
+func checkAffinityGroups(vm proxmox.VM, targetNode string, cluster *proxmox.Cluster, groups []AffinityGroup, plannedMigrations map[string]string) VMPlacementConstraint {
+	for _, g := range vmAffinityGroups(vm, groups) {
+		perNode := make(map[string]int)
+		for _, node := range cluster.Nodes {
+			for _, member := range node.VMs {
+				if member.VMID == vm.VMID || !g.Contains(member) {
+					continue
+				}
+				location := node.Name
+				if planned, ok := plannedMigrations[member.Name]; ok {
+					location = planned
+				}
+				perNode[location]++
+			}
+		}
+
+		if g.IsPack() {
+			// Follow the host with the most members, so a split group only converges
+			best := 0
+			for _, count := range perNode {
+				if count > best {
+					best = count
+				}
+			}
+			if best > 0 && perNode[targetNode] < best {
+				return VMPlacementConstraint{
+					Violated: true,
+					Reason:   fmt.Sprintf("Group '%s' (pack): must be with members on %s", g.Name, strings.Join(busiestNodes(perNode, best), ", ")),
+				}
+			}
+			continue
+		}
+
+		if perNode[targetNode] >= g.Limit() {
+			return VMPlacementConstraint{
+				Violated: true,
+				Reason:   fmt.Sprintf("Group '%s' (spread): %d members already on %s (max %d per host)", g.Name, perNode[targetNode], targetNode, g.Limit()),
+			}
+		}
+	}
+	return VMPlacementConstraint{}
+}
+
+// busiestNodes returns the nodes hosting count members, sorted by name
+func busiestNodes(perNode map[string]int, count int) []string {
+	var nodes []string
+	for node, n := range perNode {
+		if n == count {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// AffinityViolation is a group whose members are placed against its mode
+type AffinityViolation struct {
+	Group   string
+	Mode    string
+	Nodes   []string // Hosts involved
+	Members []string // Members on those hosts
+	Excess  int      // Members that have to move to resolve it
+	Message string
+}
+
+// FindAffinityViolations lists the resolved groups violated once the planned
+// migrations (VM name -> target node) are applied; nil checks the cluster as it is
+func FindAffinityViolations(cluster *proxmox.Cluster, groups []AffinityGroup, plannedMigrations map[string]string) []AffinityViolation {
+	if len(groups) == 0 || cluster == nil {
+		return nil
+	}
+
+	var violations []AffinityViolation
+	for _, g := range groups {
+		membersByNode := make(map[string][]string)
+		for _, node := range cluster.Nodes {
+			for _, vm := range node.VMs {
+				if !g.Contains(vm) {
+					continue
+				}
+				location := node.Name
+				if planned, ok := plannedMigrations[vm.Name]; ok {
+					location = planned
+				}
+				membersByNode[location] = append(membersByNode[location], vm.Name)
+			}
+		}
+		nodes := make([]string, 0, len(membersByNode))
+		for node := range membersByNode {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+
+		if g.IsPack() {
+			if len(nodes) < 2 {
+				continue
+			}
+			v := AffinityViolation{Group: g.Name, Mode: AffinityPack, Nodes: nodes}
+			var parts []string
+			busiest := 0
+			for _, node := range nodes {
+				v.Members = append(v.Members, membersByNode[node]...)
+				parts = append(parts, fmt.Sprintf("%s (%d)", node, len(membersByNode[node])))
+				if len(membersByNode[node]) > busiest {
+					busiest = len(membersByNode[node])
+				}
+			}
+			v.Excess = len(v.Members) - busiest
+			v.Message = fmt.Sprintf("Group %s: members split across %s", g, strings.Join(parts, ", "))
+			violations = append(violations, v)
+			continue
+		}
+
+		for _, node := range nodes {
+			members := membersByNode[node]
+			if len(members) <= g.Limit() {
+				continue
+			}
+			sort.Strings(members)
+			violations = append(violations, AffinityViolation{
+				Group:   g.Name,
+				Mode:    AffinitySpread,
+				Nodes:   []string{node},
+				Members: members,
+				Excess:  len(members) - g.Limit(),
+				Message: fmt.Sprintf("Group %s: %d members on %s: %s", g, len(members), node, strings.Join(members, ", ")),
+			})
+		}
+	}
+	return violations
+}
+
+// PlannedLocations maps each migrated VM's name to its final target node
+func PlannedLocations(suggestions []MigrationSuggestion) map[string]string {
+	planned := make(map[string]string)
+	for _, sug := range suggestions {
+		if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			continue
+		}
+		planned[sug.VMName] = sug.TargetNode
+	}
+	return planned
+}
@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// affinityTestCluster has web1 and web2 on pve1, db1 (tagged group=db) on
+// pve2, db2 (tagged group=db, groupmode=pack) on pve3
+func affinityTestCluster() *proxmox.Cluster {
+	return &proxmox.Cluster{Nodes: []proxmox.Node{
+		{Name: "pve1", Status: "online", VMs: []proxmox.VM{
+			{VMID: 100, Name: "web1", Status: "running"},
+			{VMID: 101, Name: "web2", Status: "running"},
+		}},
+		{Name: "pve2", Status: "online", VMs: []proxmox.VM{
+			{VMID: 102, Name: "db1", Status: "running", Group: "db"},
+		}},
+		{Name: "pve3", Status: "online", VMs: []proxmox.VM{
+			{VMID: 103, Name: "db2", Status: "running", Group: "db", GroupMode: AffinityPack},
+		}},
+	}}
+}
+
+func TestResolveAffinityGroups(t *testing.T) {
+	cluster := affinityTestCluster()
+	tests := []struct {
+		name       string
+		configured []AffinityGroup
+		want       []AffinityGroup
+	}{
+		{"tags only", nil, []AffinityGroup{{Name: "db", Mode: AffinityPack}}},
+		{
+			"configured first",
+			[]AffinityGroup{{Name: "web", Members: []string{"web*"}}},
+			[]AffinityGroup{{Name: "web", Members: []string{"web*"}}, {Name: "db", Mode: AffinityPack}},
+		},
+		{
+			"config file mode wins",
+			[]AffinityGroup{{Name: "db", Mode: AffinitySpread, MaxPerHost: 2}},
+			[]AffinityGroup{{Name: "db", Mode: AffinitySpread, MaxPerHost: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveAffinityGroups(cluster, tt.configured); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveAffinityGroups() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAffinityGroupsFromPolicy(t *testing.T) {
+	cluster := affinityTestCluster()
+	web := []AffinityGroup{{Name: "web", Members: []string{"web*"}}}
+	webPack := []AffinityGroup{{Name: "web", Mode: AffinityPack, Members: []string{"web*"}}}
+
+	tests := []struct {
+		name       string
+		configured []AffinityGroup
+		vm         string
+		target     string
+		violated   bool
+		violations int // Groups violated as the cluster is
+	}{
+		{"no config file groups", nil, "web1", "pve2", false, 1},
+		{"spread keeps web apart", web, "web1", "pve2", false, 2},
+		{"spread rejects the crowded host", web, "web1", "pve1", true, 2},
+		{"pack keeps web together", webPack, "web2", "pve2", true, 1},
+		{"tagged pack group", nil, "db1", "pve1", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultCapacityPolicy
+			policy.AffinityGroups = tt.configured
+			groups := ResolveAffinityGroups(cluster, policy.AffinityGroups)
+
+			var vm proxmox.VM
+			for _, node := range cluster.Nodes {
+				for _, v := range node.VMs {
+					if v.Name == tt.vm {
+						vm = v
+					}
+				}
+			}
+			target := proxmox.GetNodeByName(cluster, tt.target)
+			if got := CheckVMPlacementConstraints(vm, target, cluster, groups, nil).Violated; got != tt.violated {
+				t.Errorf("%s on %s violated = %v, want %v", tt.vm, tt.target, got, tt.violated)
+			}
+			if got := len(FindAffinityViolations(cluster, groups, nil)); got != tt.violations {
+				t.Errorf("%d groups violated, want %d", got, tt.violations)
+			}
+		})
+	}
+
+	// An analysis only sees the groups its own policy carries
+	policy := DefaultCapacityPolicy
+	policy.AffinityGroups = web
+	for _, tt := range []struct {
+		policy CapacityPolicy
+		want   bool
+	}{{policy, true}, {DefaultCapacityPolicy, false}} {
+		result, err := AnalyzeConstraintAudit(cluster, tt.policy)
+		if err != nil {
+			t.Fatalf("AnalyzeConstraintAudit: %v", err)
+		}
+		found := false
+		for _, v := range result.Audit.Violations {
+			found = found || v.Other == "web"
+		}
+		if found != tt.want {
+			t.Errorf("audit with groups %v reports web: %v, want %v", tt.policy.AffinityGroups, found, tt.want)
+		}
+	}
+}
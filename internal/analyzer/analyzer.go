@@ -37,8 +37,8 @@ func Analyze(cluster *proxmox.Cluster, constraints MigrationConstraints) (*Analy
 	if constraints.MigrateAll {
 		for _, vm := range sourceNode.VMs {
 			if vm.NoMigrate {
-				// Use actual thin provisioning size
-				storage := vm.GetEffectiveDisk()
+				// Local disk only, shared disks don't use the node's storage
+				storage := vm.GetLocalDisk()
 				// Build reason with actual parsed metadata for debugging
 				reason := "NoMigrate=true in VM config"
 				if vm.ConfigMeta != nil {
@@ -73,11 +73,14 @@ func Analyze(cluster *proxmox.Cluster, constraints MigrationConstraints) (*Analy
 
 	var suggestions []MigrationSuggestion
 
+	// Affinity groups, resolved once for every placement check
+	groups := ResolveAffinityGroups(cluster, constraints.GetPolicy().AffinityGroups)
+
 	// Use specialized algorithm for ModeAll and ModeBalanceCluster to ensure balanced distribution
 	if constraints.MigrateAll || constraints.BalanceCluster {
-		suggestions = GenerateSuggestionsBalanced(vmsToMigrate, targets, cluster, sourceNode, constraints)
+		suggestions = GenerateSuggestionsBalanced(vmsToMigrate, targets, cluster, groups, sourceNode, constraints)
 	} else {
-		suggestions = GenerateSuggestions(vmsToMigrate, targets, cluster, sourceNode, constraints)
+		suggestions = GenerateSuggestions(vmsToMigrate, targets, cluster, groups, sourceNode, constraints)
 	}
 
 	// Track VMs that couldn't find a suitable target (TargetNode="NONE")
@@ -111,6 +114,10 @@ func Analyze(cluster *proxmox.Cluster, constraints MigrationConstraints) (*Analy
 	// Calculate before/after states
 	result := BuildAnalysisResult(sourceNode, targets, suggestions, vmsToMigrate)
 	result.UnmigrateableVMs = unmigrateableVMs
+	result.Constraints = constraints
+	result.ClusterCollectedAt = cluster.CollectedAt
+	result.Staged = BuildStagedPlan(cluster, suggestions, constraints.GetPolicy())
+	result.AffinityViolations = FindAffinityViolations(cluster, groups, PlannedLocations(suggestions))
 
 	return result, nil
 }
@@ -151,9 +158,13 @@ func selectAllVMs(node *proxmox.Node) []proxmox.VM {
 	// Return ALL VMs (running and stopped) sorted by resource usage (largest first for better distribution)
 	// Filter out VMs that cannot be migrated
 	vms := filterMigratableVMs(node.VMs)
+	sortForEvacuation(vms)
+	return vms
+}
 
-	// Sort by combined resource score (descending - distribute largest VMs first)
-	// Running VMs get priority, then by size
+// sortForEvacuation orders VMs for GenerateSuggestionsBalanced: running VMs
+// first, then by combined resource score (largest first for better distribution)
+func sortForEvacuation(vms []proxmox.VM) {
 	sort.Slice(vms, func(i, j int) bool {
 		// Running VMs first
 		if vms[i].Status != vms[j].Status {
@@ -163,8 +174,6 @@ func selectAllVMs(node *proxmox.Node) []proxmox.VM {
 		scoreJ := float64(vms[j].CPUCores)*10 + vms[j].CPUUsage + float64(vms[j].MaxMem)/(1024*1024*1024)
 		return scoreI > scoreJ
 	})
-
-	return vms
 }
 
 func selectSpecificVMs(node *proxmox.Node, vmids []int) []proxmox.VM {
@@ -216,13 +225,14 @@ type VMPlacementConstraint struct {
 }
 
 // CheckVMPlacementConstraints checks if a VM can be placed on a target node
-// based on its placement constraints (hostcpumodel, withvm, without)
+// based on its placement constraints (hostcpumodel, withvm, without, affinity groups)
 // Parameters:
 //   - vm: the VM to check
 //   - targetNode: the target node to evaluate
 //   - cluster: the full cluster (needed to find where other VMs are located)
+//   - groups: the affinity groups from ResolveAffinityGroups
 //   - plannedMigrations: map of VM names to their planned target nodes (for VMs being migrated in the same batch)
-func CheckVMPlacementConstraints(vm proxmox.VM, targetNode *proxmox.Node, cluster *proxmox.Cluster, plannedMigrations map[string]string) VMPlacementConstraint {
+func CheckVMPlacementConstraints(vm proxmox.VM, targetNode *proxmox.Node, cluster *proxmox.Cluster, groups []AffinityGroup, plannedMigrations map[string]string) VMPlacementConstraint {
 	// Check hostcpumodel constraint
 	// VM can only run on hosts where CPU model contains the required substring
 	if vm.HostCPUModel != "" {
@@ -266,6 +276,12 @@ func CheckVMPlacementConstraints(vm proxmox.VM, targetNode *proxmox.Node, cluste
 		}
 	}
 
+	// Check affinity groups (group=, groupmode=, groupmax= and the config file)
+	// Spread groups limit the members per host, pack groups keep them together
+	if check := checkAffinityGroups(vm, targetNode.Name, cluster, groups, plannedMigrations); check.Violated {
+		return check
+	}
+
 	return VMPlacementConstraint{Violated: false}
 }
 
@@ -290,8 +306,9 @@ func findVMNode(vmName string, cluster *proxmox.Cluster, plannedMigrations map[s
 }
 
 // HasPlacementConstraints returns true if the VM has any placement constraints
-func HasPlacementConstraints(vm proxmox.VM) bool {
-	return vm.HostCPUModel != "" || len(vm.WithVM) > 0 || len(vm.WithoutVM) > 0
+// groups are the resolved affinity groups of the analysis
+func HasPlacementConstraints(vm proxmox.VM, groups []AffinityGroup) bool {
+	return vm.HostCPUModel != "" || len(vm.WithVM) > 0 || len(vm.WithoutVM) > 0 || inAffinityGroup(vm, groups)
 }
 
 // GetPlacementConstraintsSummary returns a human-readable summary of VM placement constraints
@@ -306,6 +323,9 @@ func GetPlacementConstraintsSummary(vm proxmox.VM) string {
 	if len(vm.WithoutVM) > 0 {
 		parts = append(parts, fmt.Sprintf("without=%s", strings.Join(vm.WithoutVM, ",")))
 	}
+	if vm.Group != "" {
+		parts = append(parts, fmt.Sprintf("group=%s", vm.Group))
+	}
 	if len(parts) == 0 {
 		return ""
 	}
@@ -381,7 +401,7 @@ func selectByCPUUsageEfficient(node *proxmox.Node, targetUsage float64) []proxmo
 		}
 
 		// Get disk size in GiB - use actual thin provisioning size
-		diskGiB := float64(vm.GetEffectiveDisk()) / (1024 * 1024 * 1024)
+		diskGiB := float64(vm.GetLocalDisk()) / (1024 * 1024 * 1024)
 		if diskGiB < 1 {
 			diskGiB = 1 // Minimum 1 GiB to avoid division issues
 		}
@@ -445,7 +465,7 @@ func SelectByCPUUsageDetailed(node *proxmox.Node, targetUsage float64) ([]proxmo
 		}
 
 		// Use actual thin provisioning size
-		diskGiB := float64(vm.GetEffectiveDisk()) / (1024 * 1024 * 1024)
+		diskGiB := float64(vm.GetLocalDisk()) / (1024 * 1024 * 1024)
 		if diskGiB < 1 {
 			diskGiB = 1
 		}
@@ -545,7 +565,7 @@ func selectByStorage(node *proxmox.Node, targetStorage int64) []proxmox.VM {
 	vms := filterMigratableVMs(node.VMs)
 
 	sort.Slice(vms, func(i, j int) bool {
-		return vms[i].UsedDisk < vms[j].UsedDisk
+		return vms[i].GetLocalDisk() < vms[j].GetLocalDisk()
 	})
 
 	// Select VMs until we reach target storage
@@ -557,7 +577,7 @@ func selectByStorage(node *proxmox.Node, targetStorage int64) []proxmox.VM {
 			break
 		}
 		selected = append(selected, vm)
-		totalStorage += vm.UsedDisk
+		totalStorage += vm.GetLocalDisk()
 	}
 
 	return selected
@@ -692,7 +712,7 @@ func selectForBalanceCluster(sourceNode *proxmox.Node, cluster *proxmox.Cluster,
 		ramContribGiB := float64(vm.MaxMem) / (1024 * 1024 * 1024)
 
 		// Storage in GiB - use actual thin provisioning size
-		storageGiB := float64(vm.GetEffectiveDisk()) / (1024 * 1024 * 1024)
+		storageGiB := float64(vm.GetLocalDisk()) / (1024 * 1024 * 1024)
 		if storageGiB < 1 {
 			storageGiB = 1
 		}
@@ -780,7 +800,8 @@ func selectForBalanceCluster(sourceNode *proxmox.Node, cluster *proxmox.Cluster,
 }
 
 // GenerateSuggestions creates migration suggestions by finding best targets
-func GenerateSuggestions(vms []proxmox.VM, targets []proxmox.Node, cluster *proxmox.Cluster, sourceNode *proxmox.Node, constraints MigrationConstraints) []MigrationSuggestion {
+// groups are the affinity groups from ResolveAffinityGroups
+func GenerateSuggestions(vms []proxmox.VM, targets []proxmox.Node, cluster *proxmox.Cluster, groups []AffinityGroup, sourceNode *proxmox.Node, constraints MigrationConstraints) []MigrationSuggestion {
 	var suggestions []MigrationSuggestion
 
 	// Track target states for capacity checking
@@ -815,7 +836,8 @@ func GenerateSuggestions(vms []proxmox.VM, targets []proxmox.Node, cluster *prox
 
 	// For each VM, find the best target
 	for _, vm := range vms {
-		targetNode, score, reason, details := FindBestTarget(vm, targetStates, vmsPerTarget, constraints, cluster, targetNodesMap, plannedMigrations)
+		targetNode, score, reason, details := FindBestTarget(vm, targetStates, vmsPerTarget, constraints, cluster, groups, targetNodesMap, plannedMigrations)
+		var disks []DiskPlacement
 
 		// Add selection info to details
 		if details != nil {
@@ -831,7 +853,9 @@ func GenerateSuggestions(vms []proxmox.VM, targets []proxmox.Node, cluster *prox
 		} else {
 			// Update target state after accepting this VM
 			state := targetStates[targetNode]
+			disks, _ = PlanDiskPlacement(vm, state.Pools, constraints.GetPolicy().ForNode(targetNodesMap[targetNode]).MaxStoragePercent)
 			state = state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
+			state.Pools = applyDiskPlacement(state.Pools, disks)
 			targetStates[targetNode] = state
 			vmsPerTarget[targetNode]++
 			// Track this migration for constraint checking of subsequent VMs
@@ -846,14 +870,18 @@ func GenerateSuggestions(vms []proxmox.VM, targets []proxmox.Node, cluster *prox
 			Reason:      reason,
 			Score:       score,
 			Status:      vm.Status,
+			Type:        vm.Type,
 			VCPUs:       vm.CPUCores,
 			CPUUsage:    vm.CPUUsage,
 			RAM:         vm.MaxMem,
-			Storage:     vm.GetEffectiveDisk(),
+			Storage:     vm.GetLocalDisk(),
 			UsedDisk:    vm.UsedDisk,
 			MaxDisk:     vm.MaxDisk,
+			Transfer:    EstimateTransferBytes(vm),
+			LiveMemory:  LiveMemoryBytes(vm),
 			SourceCores: sourceCores,
 			TargetCores: targetCoresMap[targetNode],
+			Disks:       disks,
 			Details:     details,
 		}
 
@@ -891,27 +919,25 @@ func getSelectionReason(constraints MigrationConstraints) string {
 }
 
 // FindBestTarget finds the best target node for a VM and returns detailed reasoning
-func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarget map[string]int, constraints MigrationConstraints, cluster *proxmox.Cluster, targetNodesMap map[string]*proxmox.Node, plannedMigrations map[string]string) (string, float64, string, *MigrationDetails) {
+func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarget map[string]int, constraints MigrationConstraints, cluster *proxmox.Cluster, groups []AffinityGroup, targetNodesMap map[string]*proxmox.Node, plannedMigrations map[string]string) (string, float64, string, *MigrationDetails) {
 	type candidate struct {
-		name           string
-		score          float64
-		reason         string
-		stateBefore    NodeState
-		stateAfter     NodeState
-		breakdown      ScoreBreakdown
-		rejected       bool
-		rejectReason   string
-		rawCPUPriority int // Absolute CPU priority (higher = newer, e.g., 500 for 4th gen Scalable)
+		TargetCandidate
+		reason       string
+		rejected     bool
+		rejectReason string
 	}
 
 	var allCandidates []candidate
 	var constraintsApplied []string
+	policy := constraints.GetPolicy()
+	strategy := constraints.GetStrategy()
 
 	// Track which constraints are being checked
 	constraintsApplied = append(constraintsApplied, "RAM capacity check")
 	constraintsApplied = append(constraintsApplied, "Storage capacity check")
-	constraintsApplied = append(constraintsApplied, "Storage headroom: 500 GiB + 15% of largest VM")
-	constraintsApplied = append(constraintsApplied, "CPU priority: prefer newer CPU generations")
+	constraintsApplied = append(constraintsApplied, "Storage pool per disk")
+	constraintsApplied = append(constraintsApplied, policy.AppliedConstraints()...)
+	constraintsApplied = append(constraintsApplied, "Placement strategy: "+strategy.Description())
 	if constraints.MinRAMFree != nil {
 		constraintsApplied = append(constraintsApplied, fmt.Sprintf("Min RAM free: %d GB", *constraints.MinRAMFree/(1024*1024*1024)))
 	}
@@ -931,16 +957,16 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 	if len(vm.WithoutVM) > 0 {
 		constraintsApplied = append(constraintsApplied, fmt.Sprintf("Cannot be with VMs: %s", strings.Join(vm.WithoutVM, ", ")))
 	}
+	for _, group := range vmAffinityGroups(vm, groups) {
+		constraintsApplied = append(constraintsApplied, fmt.Sprintf("Affinity group: %s", group))
+	}
 
 	for name, state := range targetStates {
-		cand := candidate{
-			name:        name,
-			stateBefore: state,
-		}
+		cand := candidate{TargetCandidate: TargetCandidate{Name: name, VM: &vm, Before: state}}
 
 		// Check VM placement constraints (hostcpumodel, withvm, without)
 		if targetNode, ok := targetNodesMap[name]; ok {
-			placementCheck := CheckVMPlacementConstraints(vm, targetNode, cluster, plannedMigrations)
+			placementCheck := CheckVMPlacementConstraints(vm, targetNode, cluster, groups, plannedMigrations)
 			if placementCheck.Violated {
 				cand.rejected = true
 				cand.rejectReason = placementCheck.Reason
@@ -949,8 +975,11 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 			}
 		}
 
+		// Limits of this target, including its ConfigMeta overrides
+		targetPolicy := policy.ForNode(targetNodesMap[name])
+
 		// Check capacity
-		if !state.HasCapacity(vm, constraints) {
+		if !state.HasCapacityWithPolicy(vm, constraints, targetPolicy) {
 			cand.rejected = true
 			// Determine specific reason - use MaxMem to check if VM can be powered on
 			newRAMUsed := state.RAMUsed + vm.MaxMem
@@ -958,9 +987,11 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 				cand.rejectReason = "Insufficient RAM capacity"
 			} else {
 				// Use actual thin provisioning size
-				newStorageUsed := state.StorageUsed + vm.GetEffectiveDisk()
+				newStorageUsed := state.StorageUsed + vm.GetLocalDisk()
 				if newStorageUsed > state.StorageTotal {
 					cand.rejectReason = "Insufficient storage capacity"
+				} else if violation := state.PolicyViolation(vm, targetPolicy); violation != "" {
+					cand.rejectReason = violation
 				} else if constraints.MinRAMFree != nil {
 					cand.rejectReason = "Would violate minimum RAM free constraint"
 				} else if constraints.MinCPUFree != nil {
@@ -971,9 +1002,9 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 			continue
 		}
 
-		// Check storage headroom constraint (500 GiB + 15% of largest VM)
+		// Check storage headroom constraint (500 GiB + 15% of largest VM by default)
 		if targetNode, ok := targetNodesMap[name]; ok {
-			headroomCheck := CheckStorageHeadroom(targetNode, vm, state.StorageUsed, state.StorageTotal)
+			headroomCheck := CheckStorageHeadroomWithPolicy(targetNode, vm, state.StorageUsed, state.StorageTotal, targetPolicy)
 			if !headroomCheck.HasSufficientHeadroom {
 				cand.rejected = true
 				cand.rejectReason = headroomCheck.Reason
@@ -982,6 +1013,14 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 			}
 		}
 
+		// Every disk needs a storage pool on the target with room for it
+		if _, reason := PlanDiskPlacement(vm, state.Pools, targetPolicy.MaxStoragePercent); reason != "" {
+			cand.rejected = true
+			cand.rejectReason = reason
+			allCandidates = append(allCandidates, cand)
+			continue
+		}
+
 		// Check MaxVMsPerHost constraint
 		if constraints.MaxVMsPerHost != nil {
 			if vmsPerTarget[name] >= *constraints.MaxVMsPerHost {
@@ -992,42 +1031,15 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 			}
 		}
 
-		// Calculate score after adding this VM
-		newState := state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
-		cand.stateAfter = newState
-
-		// Calculate detailed score breakdown
-		utilizationScore := 100 - newState.GetUtilizationScore()
-		balanceScore := calculateBalanceScoreDetailed(newState)
-
-		// Get CPU priority - both raw (for sorting) and normalized (for display)
-		cpuPriorityScore := 0.0
-		rawCPUPriority := 100 // Default priority
+		// Score the target with the placement strategy
+		cand.After = state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
+		cand.CPUPriority = 100 // Default priority
 		if targetNode, ok := targetNodesMap[name]; ok {
-			rawCPUPriority = GetCPURawPriority(targetNode.CPUModel)
-			cpuPriorityInfo := GetClusterCPUPriorities(cluster)
-			cpuPriorityScore = GetCPUPriorityScore(targetNode.CPUModel, cpuPriorityInfo)
-		}
-		cand.rawCPUPriority = rawCPUPriority
-
-		// CPU priority is now the PRIMARY factor - we strongly prefer newer CPUs
-		// Use raw priority (200-600 scale) directly for massive differentiation
-		// E.g., 4th gen Scalable (500) vs 1st gen (200) = 300 point difference
-		// utilization and balance are secondary factors (tiebreakers within same CPU tier)
-		totalScore := float64(rawCPUPriority) + utilizationScore*0.1 + balanceScore*0.1
-
-		cand.breakdown = ScoreBreakdown{
-			UtilizationScore:  utilizationScore,
-			BalanceScore:      balanceScore,
-			CPUPriorityScore:  cpuPriorityScore,
-			UtilizationWeight: 0.1,
-			BalanceWeight:     0.1,
-			TotalScore:        totalScore,
+			cand.CPUPriority = GetCPURawPriority(targetNode.CPUModel)
+			cand.CPUScore = GetCPUPriorityScore(targetNode.CPUModel, GetClusterCPUPriorities(cluster))
 		}
-		cand.score = cand.breakdown.TotalScore
-
-		cand.reason = fmt.Sprintf("Good balance (CPU: %.1f%%, RAM: %.1f%%, Storage: %.1f%%)",
-			newState.CPUPercent, newState.RAMPercent, newState.StoragePercent)
+		cand.Score = strategy.ScoreTarget(&cand.TargetCandidate)
+		cand.reason = strategy.Reason(&cand.TargetCandidate)
 
 		allCandidates = append(allCandidates, cand)
 	}
@@ -1051,7 +1063,7 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 		// Add rejected alternatives
 		for _, c := range rejectedCandidates {
 			details.Alternatives = append(details.Alternatives, AlternativeTarget{
-				Name:            c.name,
+				Name:            c.Name,
 				Score:           0,
 				RejectionReason: c.rejectReason,
 			})
@@ -1059,46 +1071,37 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 		return "", 0, "No suitable target found", details
 	}
 
-	// Sort candidates: prefer newer CPUs first (raw CPU priority), then by overall score
+	// Rank candidates with the placement strategy
 	sort.Slice(validCandidates, func(i, j int) bool {
-		// First, strongly prefer newer CPUs (higher raw CPU priority)
-		// Raw priority values: 1st gen ~200, 2nd gen ~300, 3rd gen ~400, 4th gen ~500, 5th gen ~600
-		// Threshold of 50 catches different generations
-		cpuPriorityDiff := validCandidates[i].rawCPUPriority - validCandidates[j].rawCPUPriority
-		if cpuPriorityDiff > 50 || cpuPriorityDiff < -50 {
-			// Significant CPU generation difference - prefer newer
-			return cpuPriorityDiff > 0
-		}
-		// Within same CPU tier, sort by overall score
-		return validCandidates[i].score > validCandidates[j].score
+		return strategy.Prefer(&validCandidates[i].TargetCandidate, &validCandidates[j].TargetCandidate)
 	})
 
 	best := validCandidates[0]
 
 	// Build detailed migration reasoning
 	details := &MigrationDetails{
-		ScoreBreakdown: best.breakdown,
+		ScoreBreakdown: best.Score,
 		TargetBefore: ResourceState{
-			CPUPercent:     best.stateBefore.CPUPercent,
-			RAMPercent:     best.stateBefore.RAMPercent,
-			StoragePercent: best.stateBefore.StoragePercent,
-			VMCount:        best.stateBefore.VMCount,
-			VCPUs:          best.stateBefore.VCPUs,
-			RAMUsed:        best.stateBefore.RAMUsed,
-			RAMTotal:       best.stateBefore.RAMTotal,
-			StorageUsed:    best.stateBefore.StorageUsed,
-			StorageTotal:   best.stateBefore.StorageTotal,
+			CPUPercent:     best.Before.CPUPercent,
+			RAMPercent:     best.Before.RAMPercent,
+			StoragePercent: best.Before.StoragePercent,
+			VMCount:        best.Before.VMCount,
+			VCPUs:          best.Before.VCPUs,
+			RAMUsed:        best.Before.RAMUsed,
+			RAMTotal:       best.Before.RAMTotal,
+			StorageUsed:    best.Before.StorageUsed,
+			StorageTotal:   best.Before.StorageTotal,
 		},
 		TargetAfter: ResourceState{
-			CPUPercent:     best.stateAfter.CPUPercent,
-			RAMPercent:     best.stateAfter.RAMPercent,
-			StoragePercent: best.stateAfter.StoragePercent,
-			VMCount:        best.stateAfter.VMCount,
-			VCPUs:          best.stateAfter.VCPUs,
-			RAMUsed:        best.stateAfter.RAMUsed,
-			RAMTotal:       best.stateAfter.RAMTotal,
-			StorageUsed:    best.stateAfter.StorageUsed,
-			StorageTotal:   best.stateAfter.StorageTotal,
+			CPUPercent:     best.After.CPUPercent,
+			RAMPercent:     best.After.RAMPercent,
+			StoragePercent: best.After.StoragePercent,
+			VMCount:        best.After.VMCount,
+			VCPUs:          best.After.VCPUs,
+			RAMUsed:        best.After.RAMUsed,
+			RAMTotal:       best.After.RAMTotal,
+			StorageUsed:    best.After.StorageUsed,
+			StorageTotal:   best.After.StorageTotal,
 		},
 		ConstraintsApplied: constraintsApplied,
 	}
@@ -1112,12 +1115,12 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 			break // Only show top 3 alternatives
 		}
 		details.Alternatives = append(details.Alternatives, AlternativeTarget{
-			Name:            c.name,
-			Score:           c.score,
-			RejectionReason: fmt.Sprintf("Lower score (%.1f vs %.1f)", c.score, best.score),
-			CPUAfter:        c.stateAfter.CPUPercent,
-			RAMAfter:        c.stateAfter.RAMPercent,
-			StorageAfter:    c.stateAfter.StoragePercent,
+			Name:            c.Name,
+			Score:           c.Score.TotalScore,
+			RejectionReason: fmt.Sprintf("Lower score (%.1f vs %.1f)", c.Score.TotalScore, best.Score.TotalScore),
+			CPUAfter:        c.After.CPUPercent,
+			RAMAfter:        c.After.RAMPercent,
+			StorageAfter:    c.After.StoragePercent,
 		})
 	}
 
@@ -1127,13 +1130,13 @@ func FindBestTarget(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarg
 			break
 		}
 		details.Alternatives = append(details.Alternatives, AlternativeTarget{
-			Name:            c.name,
+			Name:            c.Name,
 			Score:           0,
 			RejectionReason: c.rejectReason,
 		})
 	}
 
-	return best.name, best.score, best.reason, details
+	return best.Name, best.Score.TotalScore, best.reason, details
 }
 
 // calculateBalanceScoreDetailed calculates balance score with more detail
@@ -1148,34 +1151,6 @@ func calculateBalanceScoreDetailed(state NodeState) float64 {
 	return 100 - stdDev
 }
 
-// calculateTargetScore computes a score for target selection
-func calculateTargetScore(before, after NodeState) float64 {
-	// Prefer targets with:
-	// 1. Lower final utilization
-	// 2. Balanced resource usage
-	// 3. More headroom
-
-	// Utilization score (lower utilization = higher score)
-	utilizationScore := 100 - after.GetUtilizationScore()
-
-	// Balance score (more balanced = higher score)
-	// Penalize if one resource is much higher than others
-	balanceScore := 100.0
-	resources := []float64{after.CPUPercent, after.RAMPercent, after.StoragePercent}
-	mean := (resources[0] + resources[1] + resources[2]) / 3
-	variance := 0.0
-	for _, r := range resources {
-		variance += math.Pow(r-mean, 2)
-	}
-	stdDev := math.Sqrt(variance / 3)
-	balanceScore -= stdDev // Penalize high standard deviation
-
-	// Combine scores with weights
-	score := 0.7*utilizationScore + 0.3*balanceScore
-
-	return score
-}
-
 // BuildAnalysisResult creates the final analysis result with before/after states
 func BuildAnalysisResult(sourceNode *proxmox.Node, targets []proxmox.Node, suggestions []MigrationSuggestion, vmsToMigrate []proxmox.VM) *AnalysisResult {
 	result := &AnalysisResult{
@@ -1194,7 +1169,7 @@ func BuildAnalysisResult(sourceNode *proxmox.Node, targets []proxmox.Node, sugge
 	var totalVMStorage int64
 	for _, vm := range sourceNode.VMs {
 		// Use actual thin provisioning size
-		totalVMStorage += vm.GetEffectiveDisk()
+		totalVMStorage += vm.GetLocalDisk()
 	}
 	result.SourceBefore.StorageUsed = totalVMStorage
 	if result.SourceBefore.StorageTotal > 0 {
@@ -1209,7 +1184,7 @@ func BuildAnalysisResult(sourceNode *proxmox.Node, targets []proxmox.Node, sugge
 	for _, vm := range vmsToMigrate {
 		// Storage is always counted regardless of power state
 		// Use actual thin provisioning size
-		vmStorageContribution += vm.GetEffectiveDisk()
+		vmStorageContribution += vm.GetLocalDisk()
 
 		if vm.Status == "running" {
 			// HCPU% = vm.CPUUsage * vm.CPUCores / hostCores
@@ -1273,7 +1248,7 @@ func BuildAnalysisResult(sourceNode *proxmox.Node, targets []proxmox.Node, sugge
 		for _, vm := range addVMs {
 			// Storage is always counted regardless of power state
 			// Use actual thin provisioning size
-			addedStorage += vm.GetEffectiveDisk()
+			addedStorage += vm.GetLocalDisk()
 
 			if vm.Status == "running" {
 				// HCPU% contribution on target = vm.CPUUsage * vm.CPUCores / targetCores
@@ -1300,12 +1275,12 @@ func BuildAnalysisResult(sourceNode *proxmox.Node, targets []proxmox.Node, sugge
 		result.TargetsAfter[target.Name] = afterState
 	}
 
-	// Calculate summary - use actual thin provisioning size for storage
+	// Calculate summary - storage counts local disks only
 	result.TotalVMs = len(suggestions)
 	for _, vm := range vmsToMigrate {
 		result.TotalVCPUs += vm.CPUCores
 		result.TotalRAM += vm.MaxMem // Allocated RAM
-		result.TotalStorage += vm.GetEffectiveDisk()
+		result.TotalStorage += vm.GetLocalDisk()
 	}
 
 	// Generate improvement info
@@ -1357,7 +1332,7 @@ func CalculateTargetAverages(cluster *proxmox.Cluster, sourceNode string, vmsToM
 		totalCPUUsage += vm.CPUUsage / 100 * float64(vm.CPUCores)
 		usedRAM += vm.UsedMem
 		// Use actual thin provisioning size
-		usedStorage += vm.GetEffectiveDisk()
+		usedStorage += vm.GetLocalDisk()
 		// Add vCPUs from migrating VMs
 		totalVCPUs += vm.CPUCores
 	}
@@ -1386,7 +1361,8 @@ func CalculateTargetAverages(cluster *proxmox.Cluster, sourceNode string, vmsToM
 // GenerateSuggestionsBalanced creates migration suggestions that distribute
 // ALL VMs from the source host across target nodes.
 // This is used for "Migrate All" mode - every VM MUST be assigned a target.
-func GenerateSuggestionsBalanced(vms []proxmox.VM, targets []proxmox.Node, cluster *proxmox.Cluster, sourceNode *proxmox.Node, constraints MigrationConstraints) []MigrationSuggestion {
+// groups are the affinity groups from ResolveAffinityGroups.
+func GenerateSuggestionsBalanced(vms []proxmox.VM, targets []proxmox.Node, cluster *proxmox.Cluster, groups []AffinityGroup, sourceNode *proxmox.Node, constraints MigrationConstraints) []MigrationSuggestion {
 	// Calculate target averages for the cluster after migration
 	targetAverages := CalculateTargetAverages(cluster, constraints.SourceNode, vms)
 
@@ -1419,7 +1395,7 @@ func GenerateSuggestionsBalanced(vms []proxmox.VM, targets []proxmox.Node, clust
 	// VMs are already sorted by size (largest first) from selectAllVMs
 	for _, vm := range vms {
 		// Find best target for this VM
-		targetName, score, reason, details := findBestTargetForMigrateAll(vm, targetStates, vmsPerTarget, targetAverages, constraints, cluster, targetNodesMap, plannedMigrations)
+		targetName, score, reason, details := findBestTargetForMigrateAll(vm, targetStates, vmsPerTarget, targetAverages, constraints, cluster, groups, targetNodesMap, plannedMigrations)
 
 		// Add selection info to details
 		if details != nil {
@@ -1428,9 +1404,13 @@ func GenerateSuggestionsBalanced(vms []proxmox.VM, targets []proxmox.Node, clust
 		}
 
 		// Update target state after placement
+		var disks []DiskPlacement
 		if targetName != "" && targetName != "NONE" {
 			state := targetStates[targetName]
-			targetStates[targetName] = state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
+			disks, _ = PlanDiskPlacement(vm, state.Pools, constraints.GetPolicy().ForNode(targetNodesMap[targetName]).MaxStoragePercent)
+			state = state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
+			state.Pools = applyDiskPlacement(state.Pools, disks)
+			targetStates[targetName] = state
 			vmsPerTarget[targetName]++
 			// Track this migration for constraint checking of subsequent VMs
 			plannedMigrations[vm.Name] = targetName
@@ -1445,14 +1425,18 @@ func GenerateSuggestionsBalanced(vms []proxmox.VM, targets []proxmox.Node, clust
 			Reason:      reason,
 			Score:       score,
 			Status:      vm.Status,
+			Type:        vm.Type,
 			VCPUs:       vm.CPUCores,
 			CPUUsage:    vm.CPUUsage,
 			RAM:         vm.MaxMem,
-			Storage:     vm.GetEffectiveDisk(),
+			Storage:     vm.GetLocalDisk(),
 			UsedDisk:    vm.UsedDisk,
 			MaxDisk:     vm.MaxDisk,
+			Transfer:    EstimateTransferBytes(vm),
+			LiveMemory:  LiveMemoryBytes(vm),
 			SourceCores: sourceCores,
 			TargetCores: targetCoresMap[targetName],
+			Disks:       disks,
 			Details:     details,
 		}
 		suggestions = append(suggestions, suggestion)
@@ -1463,28 +1447,25 @@ func GenerateSuggestionsBalanced(vms []proxmox.VM, targets []proxmox.Node, clust
 
 // findBestTargetForMigrateAll finds the best target for a VM in "Migrate All" mode.
 // Unlike regular mode, this ALWAYS returns a valid target (never "NONE").
-func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarget map[string]int, averages ClusterAverages, constraints MigrationConstraints, cluster *proxmox.Cluster, targetNodesMap map[string]*proxmox.Node, plannedMigrations map[string]string) (string, float64, string, *MigrationDetails) {
+func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeState, vmsPerTarget map[string]int, averages ClusterAverages, constraints MigrationConstraints, cluster *proxmox.Cluster, groups []AffinityGroup, targetNodesMap map[string]*proxmox.Node, plannedMigrations map[string]string) (string, float64, string, *MigrationDetails) {
 	type candidate struct {
-		name           string
-		score          float64
-		reason         string
-		belowAverage   bool
-		stateBefore    NodeState
-		stateAfter     NodeState
-		breakdown      ScoreBreakdown
-		rejected       bool
-		rejectReason   string
-		rawCPUPriority int // Absolute CPU priority (higher = newer, e.g., 500 for 4th gen Scalable)
+		TargetCandidate
+		reason       string
+		rejected     bool
+		rejectReason string
 	}
 
 	var allCandidates []candidate
 	var constraintsApplied []string
+	policy := constraints.GetPolicy()
+	strategy := constraints.GetStrategy()
 
 	constraintsApplied = append(constraintsApplied, "RAM capacity check")
 	constraintsApplied = append(constraintsApplied, "Storage capacity check")
-	constraintsApplied = append(constraintsApplied, "Storage headroom: 500 GiB + 15% of largest VM")
+	constraintsApplied = append(constraintsApplied, "Storage pool per disk")
+	constraintsApplied = append(constraintsApplied, policy.AppliedConstraints()...)
 	constraintsApplied = append(constraintsApplied, fmt.Sprintf("Cluster balance target (CPU: %.1f%%, RAM: %.1f%%, vCPU: %.1f%%)", averages.CPUPercent, averages.RAMPercent, averages.VCPUPercent))
-	constraintsApplied = append(constraintsApplied, "CPU priority: prefer newer CPU generations")
+	constraintsApplied = append(constraintsApplied, "Placement strategy: "+strategy.Description())
 	if constraints.MaxVMsPerHost != nil {
 		constraintsApplied = append(constraintsApplied, fmt.Sprintf("Max VMs per host: %d", *constraints.MaxVMsPerHost))
 	}
@@ -1499,17 +1480,17 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 	if len(vm.WithoutVM) > 0 {
 		constraintsApplied = append(constraintsApplied, fmt.Sprintf("Cannot be with VMs: %s", strings.Join(vm.WithoutVM, ", ")))
 	}
+	for _, group := range vmAffinityGroups(vm, groups) {
+		constraintsApplied = append(constraintsApplied, fmt.Sprintf("Affinity group: %s", group))
+	}
 
 	// Evaluate all targets
 	for name, state := range targetStates {
-		cand := candidate{
-			name:        name,
-			stateBefore: state,
-		}
+		cand := candidate{TargetCandidate: TargetCandidate{Name: name, VM: &vm, Before: state, Averages: &averages}}
 
 		// Check VM placement constraints (hostcpumodel, withvm, without)
 		if targetNode, ok := targetNodesMap[name]; ok {
-			placementCheck := CheckVMPlacementConstraints(vm, targetNode, cluster, plannedMigrations)
+			placementCheck := CheckVMPlacementConstraints(vm, targetNode, cluster, groups, plannedMigrations)
 			if placementCheck.Violated {
 				cand.rejected = true
 				cand.rejectReason = placementCheck.Reason
@@ -1529,7 +1510,7 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 		}
 
 		// Use actual thin provisioning size
-		newStorageUsed := state.StorageUsed + vm.GetEffectiveDisk()
+		newStorageUsed := state.StorageUsed + vm.GetLocalDisk()
 		if newStorageUsed > state.StorageTotal {
 			cand.rejected = true
 			cand.rejectReason = "Insufficient storage capacity"
@@ -1537,9 +1518,18 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 			continue
 		}
 
-		// Check storage headroom constraint (500 GiB + 15% of largest VM)
+		// Check hard limits of the capacity policy (per-node overrides apply)
+		targetPolicy := policy.ForNode(targetNodesMap[name])
+		if violation := state.PolicyViolation(vm, targetPolicy); violation != "" {
+			cand.rejected = true
+			cand.rejectReason = violation
+			allCandidates = append(allCandidates, cand)
+			continue
+		}
+
+		// Check storage headroom constraint (500 GiB + 15% of largest VM by default)
 		if targetNode, ok := targetNodesMap[name]; ok {
-			headroomCheck := CheckStorageHeadroom(targetNode, vm, state.StorageUsed, state.StorageTotal)
+			headroomCheck := CheckStorageHeadroomWithPolicy(targetNode, vm, state.StorageUsed, state.StorageTotal, targetPolicy)
 			if !headroomCheck.HasSufficientHeadroom {
 				cand.rejected = true
 				cand.rejectReason = headroomCheck.Reason
@@ -1548,6 +1538,14 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 			}
 		}
 
+		// Every disk needs a storage pool on the target with room for it
+		if _, reason := PlanDiskPlacement(vm, state.Pools, targetPolicy.MaxStoragePercent); reason != "" {
+			cand.rejected = true
+			cand.rejectReason = reason
+			allCandidates = append(allCandidates, cand)
+			continue
+		}
+
 		// Check MaxVMsPerHost constraint if set
 		if constraints.MaxVMsPerHost != nil && vmsPerTarget[name] >= *constraints.MaxVMsPerHost {
 			cand.rejected = true
@@ -1557,61 +1555,28 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 		}
 
 		// Calculate state after adding this VM
-		newState := state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
-		cand.stateAfter = newState
+		cand.After = state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
 
 		// Calculate vCPU% after adding this VM
 		// vCPU% = (current vCPUs + VM vCPUs) / host threads * 100
-		newVCPUPercent := 0.0
 		if state.CPUCores > 0 {
-			newVCPUPercent = float64(state.VCPUs+vm.CPUCores) / float64(state.CPUCores) * 100
+			cand.VCPUPercent = float64(state.VCPUs+vm.CPUCores) / float64(state.CPUCores) * 100
 		}
 
 		// Check if this target stays below cluster average (CPU%, RAM%, and vCPU%)
-		margin := 5.0 // 5% margin
-		belowAverage := newState.CPUPercent <= averages.CPUPercent+margin &&
-			newState.RAMPercent <= averages.RAMPercent+margin &&
-			newVCPUPercent <= averages.VCPUPercent+margin
-		cand.belowAverage = belowAverage
-
-		// Calculate detailed score breakdown (include vCPU% headroom and CPU priority)
-		cpuHeadroom := averages.CPUPercent - newState.CPUPercent
-		ramHeadroom := averages.RAMPercent - newState.RAMPercent
-		vcpuHeadroom := averages.VCPUPercent - newVCPUPercent
-		headroomScore := cpuHeadroom*0.3 + ramHeadroom*0.4 + vcpuHeadroom*0.1
-		balanceScore := calculateBalanceScoreDetailed(newState)
-
-		// Get CPU priority - both raw (for sorting) and normalized (for display)
-		cpuPriorityScore := 0.0
-		rawCPUPriority := 100 // Default priority
+		margin := targetPolicy.SoftMarginPercent // 5% margin by default
+		cand.BelowAverage = cand.After.CPUPercent <= averages.CPUPercent+margin &&
+			cand.After.RAMPercent <= averages.RAMPercent+margin &&
+			cand.VCPUPercent <= averages.VCPUPercent+margin
+
+		// Score the target with the placement strategy
+		cand.CPUPriority = 100 // Default priority
 		if targetNode, ok := targetNodesMap[name]; ok {
-			rawCPUPriority = GetCPURawPriority(targetNode.CPUModel)
-			cpuPriorityInfo := GetClusterCPUPriorities(cluster)
-			cpuPriorityScore = GetCPUPriorityScore(targetNode.CPUModel, cpuPriorityInfo)
-		}
-		cand.rawCPUPriority = rawCPUPriority
-
-		// CPU priority is now the PRIMARY factor - we strongly prefer newer CPUs
-		// Use raw priority (200-600 scale) directly for massive differentiation
-		// E.g., 4th gen Scalable (500) vs 1st gen (200) = 300 point difference
-		// headroom and balance are secondary factors (tiebreakers within same CPU tier)
-		totalScore := float64(rawCPUPriority) + headroomScore*0.1 + balanceScore*0.1
-
-		cand.breakdown = ScoreBreakdown{
-			HeadroomScore:     headroomScore,
-			BalanceScore:      balanceScore,
-			CPUPriorityScore:  cpuPriorityScore,
-			UtilizationWeight: 0.0, // Not used in MigrateAll mode
-			BalanceWeight:     0.2,
-			TotalScore:        totalScore,
-		}
-		cand.score = cand.breakdown.TotalScore
-
-		if belowAverage {
-			cand.reason = fmt.Sprintf("Balanced (CPU: %.1f%%, RAM: %.1f%%, vCPU: %.0f%%)", newState.CPUPercent, newState.RAMPercent, newVCPUPercent)
-		} else {
-			cand.reason = fmt.Sprintf("Best available (CPU: %.1f%%, RAM: %.1f%%, vCPU: %.0f%%)", newState.CPUPercent, newState.RAMPercent, newVCPUPercent)
+			cand.CPUPriority = GetCPURawPriority(targetNode.CPUModel)
+			cand.CPUScore = GetCPUPriorityScore(targetNode.CPUModel, GetClusterCPUPriorities(cluster))
 		}
+		cand.Score = strategy.ScoreTarget(&cand.TargetCandidate)
+		cand.reason = strategy.Reason(&cand.TargetCandidate)
 
 		allCandidates = append(allCandidates, cand)
 	}
@@ -1635,7 +1600,7 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 		}
 		for _, c := range rejectedCandidates {
 			details.Alternatives = append(details.Alternatives, AlternativeTarget{
-				Name:            c.name,
+				Name:            c.Name,
 				Score:           0,
 				RejectionReason: c.rejectReason,
 			})
@@ -1643,55 +1608,41 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 		return "NONE", 0, "No target has capacity for this VM", details
 	}
 
-	// Sort candidates: prefer newer CPUs first (raw CPU priority), then balance
-	// We use CPU priority as the primary sort key to ensure newer hardware is filled first
+	// Rank candidates with the placement strategy
 	sort.Slice(validCandidates, func(i, j int) bool {
-		// First, strongly prefer newer CPUs (higher raw CPU priority)
-		// Raw priority values: 1st gen ~200, 2nd gen ~300, 3rd gen ~400, 4th gen ~500, 5th gen ~600
-		// Threshold of 50 catches different generations
-		cpuPriorityDiff := validCandidates[i].rawCPUPriority - validCandidates[j].rawCPUPriority
-		if cpuPriorityDiff > 50 || cpuPriorityDiff < -50 {
-			// Significant CPU generation difference - prefer newer
-			return cpuPriorityDiff > 0
-		}
-		// Within same CPU tier (within 50 priority points), prefer below-average hosts
-		if validCandidates[i].belowAverage != validCandidates[j].belowAverage {
-			return validCandidates[i].belowAverage // below-average first
-		}
-		// Then by overall score (which includes headroom and balance)
-		return validCandidates[i].score > validCandidates[j].score
+		return strategy.Prefer(&validCandidates[i].TargetCandidate, &validCandidates[j].TargetCandidate)
 	})
 
 	best := validCandidates[0]
 
 	// Build detailed migration reasoning
 	details := &MigrationDetails{
-		ScoreBreakdown: best.breakdown,
+		ScoreBreakdown: best.Score,
 		TargetBefore: ResourceState{
-			CPUPercent:     best.stateBefore.CPUPercent,
-			RAMPercent:     best.stateBefore.RAMPercent,
-			StoragePercent: best.stateBefore.StoragePercent,
-			VMCount:        best.stateBefore.VMCount,
-			VCPUs:          best.stateBefore.VCPUs,
-			RAMUsed:        best.stateBefore.RAMUsed,
-			RAMTotal:       best.stateBefore.RAMTotal,
-			StorageUsed:    best.stateBefore.StorageUsed,
-			StorageTotal:   best.stateBefore.StorageTotal,
+			CPUPercent:     best.Before.CPUPercent,
+			RAMPercent:     best.Before.RAMPercent,
+			StoragePercent: best.Before.StoragePercent,
+			VMCount:        best.Before.VMCount,
+			VCPUs:          best.Before.VCPUs,
+			RAMUsed:        best.Before.RAMUsed,
+			RAMTotal:       best.Before.RAMTotal,
+			StorageUsed:    best.Before.StorageUsed,
+			StorageTotal:   best.Before.StorageTotal,
 		},
 		TargetAfter: ResourceState{
-			CPUPercent:     best.stateAfter.CPUPercent,
-			RAMPercent:     best.stateAfter.RAMPercent,
-			StoragePercent: best.stateAfter.StoragePercent,
-			VMCount:        best.stateAfter.VMCount,
-			VCPUs:          best.stateAfter.VCPUs,
-			RAMUsed:        best.stateAfter.RAMUsed,
-			RAMTotal:       best.stateAfter.RAMTotal,
-			StorageUsed:    best.stateAfter.StorageUsed,
-			StorageTotal:   best.stateAfter.StorageTotal,
+			CPUPercent:     best.After.CPUPercent,
+			RAMPercent:     best.After.RAMPercent,
+			StoragePercent: best.After.StoragePercent,
+			VMCount:        best.After.VMCount,
+			VCPUs:          best.After.VCPUs,
+			RAMUsed:        best.After.RAMUsed,
+			RAMTotal:       best.After.RAMTotal,
+			StorageUsed:    best.After.StorageUsed,
+			StorageTotal:   best.After.StorageTotal,
 		},
 		ClusterAvgCPU:      averages.CPUPercent,
 		ClusterAvgRAM:      averages.RAMPercent,
-		BelowAverage:       best.belowAverage,
+		BelowAverage:       best.BelowAverage,
 		ConstraintsApplied: constraintsApplied,
 	}
 
@@ -1704,18 +1655,18 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 			break
 		}
 		var rejectReason string
-		if c.belowAverage != best.belowAverage {
+		if best.BelowAverage && !c.BelowAverage {
 			rejectReason = "Would exceed cluster average"
 		} else {
-			rejectReason = fmt.Sprintf("Lower score (%.1f vs %.1f)", c.score, best.score)
+			rejectReason = fmt.Sprintf("Lower score (%.1f vs %.1f)", c.Score.TotalScore, best.Score.TotalScore)
 		}
 		details.Alternatives = append(details.Alternatives, AlternativeTarget{
-			Name:            c.name,
-			Score:           c.score,
+			Name:            c.Name,
+			Score:           c.Score.TotalScore,
 			RejectionReason: rejectReason,
-			CPUAfter:        c.stateAfter.CPUPercent,
-			RAMAfter:        c.stateAfter.RAMPercent,
-			StorageAfter:    c.stateAfter.StoragePercent,
+			CPUAfter:        c.After.CPUPercent,
+			RAMAfter:        c.After.RAMPercent,
+			StorageAfter:    c.After.StoragePercent,
 		})
 	}
 
@@ -1725,33 +1676,11 @@ func findBestTargetForMigrateAll(vm proxmox.VM, targetStates map[string]NodeStat
 			break
 		}
 		details.Alternatives = append(details.Alternatives, AlternativeTarget{
-			Name:            c.name,
+			Name:            c.Name,
 			Score:           0,
 			RejectionReason: c.rejectReason,
 		})
 	}
 
-	return best.name, best.score, best.reason, details
-}
-
-// calculateBalancedScore computes a score that prefers targets further below average
-func calculateBalancedScore(state NodeState, averages ClusterAverages) float64 {
-	// Calculate how far below average this target is
-	cpuHeadroom := averages.CPUPercent - state.CPUPercent
-	ramHeadroom := averages.RAMPercent - state.RAMPercent
-
-	// Prefer more headroom (positive values mean below average)
-	headroomScore := cpuHeadroom*0.4 + ramHeadroom*0.4
-
-	// Also consider balance between resources
-	resources := []float64{state.CPUPercent, state.RAMPercent, state.StoragePercent}
-	mean := (resources[0] + resources[1] + resources[2]) / 3
-	variance := 0.0
-	for _, r := range resources {
-		variance += math.Pow(r-mean, 2)
-	}
-	balanceScore := 100 - math.Sqrt(variance/3)
-
-	// Combine scores
-	return headroomScore + balanceScore*0.2
+	return best.Name, best.Score.TotalScore, best.reason, details
 }
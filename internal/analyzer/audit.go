@@ -0,0 +1,536 @@
+package analyzer
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// Placement rules checked by the constraint audit
+const (
+	RuleHostCPUModel = "hostcpumodel"
+	RuleWithVM       = "withvm"
+	RuleWithout      = "without"
+	RuleGroup        = "group"
+	RuleNoMigrate    = "nomigrate" // A rule is broken but every VM that could fix it has nomigrate=true
+)
+
+// ConstraintViolation is a placement rule broken in the cluster
+type ConstraintViolation struct {
+	Rule     string
+	VMID     int      // VM whose rule is broken (0 for affinity groups)
+	VMName   string   // VM whose rule is broken, or the group name
+	Other    string   // Partner VM (withvm, without) or affinity group
+	Nodes    []string // Hosts involved
+	Message  string
+	Repaired bool   // The repair plan resolves it
+	Blocked  string // Why the repair plan doesn't resolve it
+
+	excess int // Migrations needed at least (affinity groups can need several)
+}
+
+// key identifies a violation across simulated placements
+func (v ConstraintViolation) key() string {
+	key := v.Rule + "|" + v.VMName + "|" + v.Other
+	if v.VMID == 0 && len(v.Nodes) == 1 {
+		key += "|" + v.Nodes[0] // Spread group over its limit on one host
+	}
+	return key
+}
+
+// ConstraintAudit is the outcome of a constraint audit
+type ConstraintAudit struct {
+	Violations []ConstraintViolation // Found in the cluster as it is, in rule order
+}
+
+// Repaired returns the number of violations the repair plan resolves
+func (a *ConstraintAudit) Repaired() int {
+	n := 0
+	for _, v := range a.Violations {
+		if v.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary describes the audit, e.g. "5 violations, 4 repaired"
+func (a *ConstraintAudit) Summary() string {
+	return fmt.Sprintf("%s, %d repaired", plural(len(a.Violations), "violation", "violations"), a.Repaired())
+}
+
+// AuditConstraints lists the placement rules (hostcpumodel, withvm, without and
+// affinity groups) broken once the planned migrations (VM name -> target node)
+// are applied; nil audits the cluster as it is. groups are the affinity groups
+// from ResolveAffinityGroups. Rules that only nomigrate VMs could repair are
+// reported as nomigrate violations.
+func AuditConstraints(cluster *proxmox.Cluster, groups []AffinityGroup, plannedMigrations map[string]string) []ConstraintViolation {
+	if cluster == nil {
+		return nil
+	}
+
+	nodes := make(map[string]*proxmox.Node, len(cluster.Nodes))
+	location := make(map[string]string)
+	vmsByName := make(map[string]proxmox.VM)
+	var vms []proxmox.VM
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		nodes[node.Name] = node
+		for _, vm := range node.VMs {
+			location[vm.Name] = node.Name
+			vmsByName[vm.Name] = vm
+			vms = append(vms, vm)
+		}
+	}
+	for name, target := range plannedMigrations {
+		if _, ok := location[name]; ok {
+			location[name] = target
+		}
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].VMID < vms[j].VMID })
+
+	var violations []ConstraintViolation
+	add := func(v ConstraintViolation, movers ...proxmox.VM) {
+		pinned := true
+		for _, vm := range movers {
+			if !vm.NoMigrate {
+				pinned = false
+			}
+		}
+		if pinned {
+			v.Rule = RuleNoMigrate
+		}
+		violations = append(violations, v)
+	}
+
+	for _, vm := range vms {
+		host := location[vm.Name]
+		if vm.HostCPUModel != "" {
+			if node := nodes[host]; node != nil && !strings.Contains(node.CPUModel, vm.HostCPUModel) {
+				add(ConstraintViolation{
+					Rule:    RuleHostCPUModel,
+					VMID:    vm.VMID,
+					VMName:  vm.Name,
+					Nodes:   []string{host},
+					Message: fmt.Sprintf("%s requires '%s' in the CPU model, %s has '%s'", vm.Name, vm.HostCPUModel, host, node.CPUModel),
+				}, vm)
+			}
+		}
+		for _, name := range vm.WithVM {
+			partner, ok := vmsByName[name]
+			if !ok || location[name] == host {
+				continue
+			}
+			add(ConstraintViolation{
+				Rule:    RuleWithVM,
+				VMID:    vm.VMID,
+				VMName:  vm.Name,
+				Other:   name,
+				Nodes:   []string{host, location[name]},
+				Message: fmt.Sprintf("%s on %s must be with %s on %s", vm.Name, host, name, location[name]),
+			}, vm, partner)
+		}
+		for _, name := range vm.WithoutVM {
+			partner, ok := vmsByName[name]
+			if !ok || location[name] != host {
+				continue
+			}
+			add(ConstraintViolation{
+				Rule:    RuleWithout,
+				VMID:    vm.VMID,
+				VMName:  vm.Name,
+				Other:   name,
+				Nodes:   []string{host},
+				Message: fmt.Sprintf("%s cannot be with %s, both on %s", vm.Name, name, host),
+			}, vm, partner)
+		}
+	}
+
+	for _, g := range FindAffinityViolations(cluster, groups, plannedMigrations) {
+		var movers []proxmox.VM
+		for _, name := range g.Members {
+			movers = append(movers, vmsByName[name])
+		}
+		add(ConstraintViolation{
+			Rule:    RuleGroup,
+			VMName:  g.Group,
+			Other:   g.Group,
+			Nodes:   g.Nodes,
+			Message: g.Message,
+			excess:  g.Excess,
+		}, movers...)
+	}
+	return violations
+}
+
+// auditScore weighs violations by the migrations needed to resolve them
+func auditScore(violations []ConstraintViolation) int {
+	score := 0
+	for _, v := range violations {
+		if v.excess > 1 {
+			score += v.excess
+		} else {
+			score++
+		}
+	}
+	return score
+}
+
+// repairPlanner searches migrations that resolve constraint violations
+type repairPlanner struct {
+	cluster  *proxmox.Cluster
+	policy   CapacityPolicy
+	groups   []AffinityGroup // Resolved affinity groups
+	nodes    map[string]*proxmox.Node
+	hosts    []proxmox.Node // Hosts that may receive VMs
+	states   map[string]NodeState
+	vms      map[string]proxmox.VM // VM name -> VM
+	origin   map[string]string     // VM name -> node before the plan
+	planned  map[string]string     // VM name -> planned node
+	moves    map[string]*MigrationSuggestion
+	order    []string // VM names in the order they were first moved
+	attempts int
+}
+
+func newRepairPlanner(cluster *proxmox.Cluster, policy CapacityPolicy) *repairPlanner {
+	r := &repairPlanner{
+		cluster: cluster,
+		policy:  policy,
+		groups:  ResolveAffinityGroups(cluster, policy.AffinityGroups),
+		nodes:   make(map[string]*proxmox.Node),
+		hosts:   proxmox.GetAvailableTargets(cluster, "", nil),
+		states:  make(map[string]NodeState),
+		vms:     make(map[string]proxmox.VM),
+		origin:  make(map[string]string),
+		planned: make(map[string]string),
+		moves:   make(map[string]*MigrationSuggestion),
+	}
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		r.nodes[node.Name] = node
+		r.states[node.Name] = NewNodeState(node)
+		for _, vm := range node.VMs {
+			r.vms[vm.Name] = vm
+			r.origin[vm.Name] = node.Name
+		}
+	}
+	return r
+}
+
+// location returns the node a VM is on once the planned moves have run
+func (r *repairPlanner) location(name string) string {
+	if node, ok := r.planned[name]; ok {
+		return node
+	}
+	return r.origin[name]
+}
+
+// movers returns the VMs that could resolve a violation by moving, VMs
+// involved in the most current violations first, then the smallest
+func (r *repairPlanner) movers(v ConstraintViolation, current []ConstraintViolation) []proxmox.VM {
+	var names []string
+	switch v.Rule {
+	case RuleHostCPUModel:
+		names = []string{v.VMName}
+	case RuleWithVM, RuleWithout:
+		names = []string{v.VMName, v.Other}
+	case RuleGroup:
+		names = r.groupMovers(v)
+	}
+
+	involved := make(map[string]int)
+	for _, c := range current {
+		involved[c.VMName]++
+		involved[c.Other]++
+	}
+
+	var vms []proxmox.VM
+	for _, name := range names {
+		vm, ok := r.vms[name]
+		if !ok || vm.NoMigrate {
+			continue
+		}
+		// Migrations can't leave a host with hoststate 0 or 3
+		if node := r.nodes[r.location(name)]; node == nil || node.IsMigrationBlocked() {
+			continue
+		}
+		vms = append(vms, vm)
+	}
+	sort.SliceStable(vms, func(i, j int) bool {
+		if involved[vms[i].Name] != involved[vms[j].Name] {
+			return involved[vms[i].Name] > involved[vms[j].Name]
+		}
+		return vms[i].MaxMem < vms[j].MaxMem
+	})
+	return vms
+}
+
+// groupMovers returns the members of a violated affinity group that have to
+// move: a spread group's members on the crowded host, or a pack group's
+// members away from the host holding most of them
+func (r *repairPlanner) groupMovers(v ConstraintViolation) []string {
+	var group *AffinityGroup
+	for i := range r.groups {
+		if r.groups[i].Name == v.Other {
+			group = &r.groups[i]
+		}
+	}
+	if group == nil {
+		return nil
+	}
+
+	membersByNode := make(map[string][]string)
+	for _, node := range r.cluster.Nodes {
+		for _, vm := range node.VMs {
+			if group.Contains(vm) {
+				host := r.location(vm.Name)
+				membersByNode[host] = append(membersByNode[host], vm.Name)
+			}
+		}
+	}
+	if !group.IsPack() {
+		return membersByNode[v.Nodes[0]]
+	}
+
+	busiest := ""
+	for node, members := range membersByNode {
+		if busiest == "" || len(members) > len(membersByNode[busiest]) ||
+			(len(members) == len(membersByNode[busiest]) && node < busiest) {
+			busiest = node
+		}
+	}
+	var names []string
+	for node, members := range membersByNode {
+		if node != busiest {
+			names = append(names, members...)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// repair tries to make progress on one violation with a single migration
+// A move is accepted if it leaves less to repair overall and resolves the
+// violation; affinity groups may need several moves, each one only has to
+// bring the group closer to its rule. Returns why no move was found.
+func (r *repairPlanner) repair(v ConstraintViolation, current []ConstraintViolation) string {
+	movers := r.movers(v, current)
+	if len(movers) == 0 {
+		return "the VMs involved can't be migrated (nomigrate or blocked host)"
+	}
+
+	for _, vm := range movers {
+		from := r.location(vm.Name)
+		hosts := append([]proxmox.Node(nil), r.hosts...)
+		sort.SliceStable(hosts, func(i, j int) bool {
+			return r.states[hosts[i].Name].GetUtilizationScore() < r.states[hosts[j].Name].GetUtilizationScore()
+		})
+		for i := range hosts {
+			host := &hosts[i]
+			if host.Name == from {
+				continue
+			}
+			r.attempts++
+			state := r.states[host.Name]
+			hostPolicy := r.policy.ForNode(r.nodes[host.Name])
+			if state.PolicyViolation(vm, hostPolicy) != "" {
+				continue
+			}
+			if _, reason := PlanDiskPlacement(vm, state.Pools, hostPolicy.MaxStoragePercent); reason != "" {
+				continue
+			}
+			if CheckVMPlacementConstraints(vm, r.nodes[host.Name], r.cluster, r.groups, r.planned).Violated {
+				continue
+			}
+
+			trial := make(map[string]string, len(r.planned)+1)
+			for name, node := range r.planned {
+				trial[name] = node
+			}
+			trial[vm.Name] = host.Name
+			after := AuditConstraints(r.cluster, r.groups, trial)
+			if auditScore(after) >= auditScore(current) {
+				continue
+			}
+			if v.Rule != RuleGroup && containsViolation(after, v.key()) {
+				continue
+			}
+
+			r.move(vm, host.Name, v)
+			return ""
+		}
+	}
+
+	names := make([]string, len(movers))
+	for i, vm := range movers {
+		names[i] = vm.Name
+	}
+	return fmt.Sprintf("no host can take %s within the capacity limits and other rules", strings.Join(names, " or "))
+}
+
+// move records a migration in the plan and the simulated node states
+func (r *repairPlanner) move(vm proxmox.VM, target string, v ConstraintViolation) {
+	from := r.location(vm.Name)
+	placed := vm
+	if prev, ok := r.moves[vm.Name]; ok {
+		placed = movedVM(vm, prev)
+	}
+	if state, ok := r.states[from]; ok {
+		r.states[from] = state.CalculateAfterMigration(nil, []proxmox.VM{placed})
+	}
+
+	state := r.states[target]
+	disks, _ := PlanDiskPlacement(vm, state.Pools, r.policy.ForNode(r.nodes[target]).MaxStoragePercent)
+	state = state.CalculateAfterMigration([]proxmox.VM{vm}, nil)
+	state.Pools = applyDiskPlacement(state.Pools, disks)
+	r.states[target] = state
+
+	// Back where it started: drop the migration
+	if target == r.origin[vm.Name] {
+		delete(r.moves, vm.Name)
+		delete(r.planned, vm.Name)
+		return
+	}
+
+	source := r.nodes[r.origin[vm.Name]]
+	if _, ok := r.moves[vm.Name]; !ok {
+		r.order = append(r.order, vm.Name)
+	}
+	r.planned[vm.Name] = target
+	r.moves[vm.Name] = &MigrationSuggestion{
+		VMID:        vm.VMID,
+		VMName:      vm.Name,
+		SourceNode:  source.Name,
+		TargetNode:  target,
+		Reason:      fmt.Sprintf("Repair %s: %s", v.Rule, v.Message),
+		Status:      vm.Status,
+		Type:        vm.Type,
+		VCPUs:       vm.CPUCores,
+		CPUUsage:    vm.CPUUsage,
+		RAM:         vm.MaxMem,
+		Storage:     vm.GetLocalDisk(),
+		UsedDisk:    vm.UsedDisk,
+		MaxDisk:     vm.MaxDisk,
+		Transfer:    EstimateTransferBytes(vm),
+		LiveMemory:  LiveMemoryBytes(vm),
+		SourceCores: source.CPUCores,
+		TargetCores: r.nodes[target].CPUCores,
+		Disks:       disks,
+		Details: &MigrationDetails{
+			SelectionMode:   "constraint_audit",
+			SelectionReason: fmt.Sprintf("Resolves %s violation", v.Rule),
+		},
+	}
+}
+
+// suggestions returns the planned migrations in the order they were found
+func (r *repairPlanner) suggestions() []MigrationSuggestion {
+	var result []MigrationSuggestion
+	for _, name := range r.order {
+		if sug, ok := r.moves[name]; ok {
+			result = append(result, *sug)
+		}
+	}
+	return result
+}
+
+// containsViolation returns true if a violation with the key is in the list
+func containsViolation(violations []ConstraintViolation, key string) bool {
+	for _, v := range violations {
+		if v.key() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeConstraintAudit scans the whole cluster for broken placement rules
+// (hostcpumodel, withvm, without, affinity groups and rules only nomigrate VMs
+// could fix) and plans the migrations that repair them. Violations are repaired
+// one at a time with a single migration each, choosing the VM involved in the
+// most violations and the least utilized host that keeps every other rule and
+// the capacity policy, so the plan stays small. A clean cluster gives an
+// empty plan.
+func AnalyzeConstraintAudit(cluster *proxmox.Cluster, policy CapacityPolicy) (*AnalysisResult, error) {
+	if cluster == nil || len(cluster.Nodes) == 0 {
+		return nil, fmt.Errorf("no nodes in cluster")
+	}
+
+	r := newRepairPlanner(cluster, policy)
+	violations := AuditConstraints(cluster, r.groups, nil)
+	log.Printf("ConstraintAudit: %d violations found", len(violations))
+
+	blocked := make(map[string]string)
+	for _, v := range violations {
+		current := AuditConstraints(cluster, r.groups, r.planned)
+		if !containsViolation(current, v.key()) {
+			continue // Resolved by an earlier repair
+		}
+		if v.Rule == RuleNoMigrate {
+			blocked[v.key()] = "every VM involved has nomigrate=true, can't be repaired by migration"
+			continue
+		}
+		// Affinity groups may need one migration per member out of place
+		for containsViolation(current, v.key()) {
+			if reason := r.repair(v, current); reason != "" {
+				blocked[v.key()] = reason
+				break
+			}
+			current = AuditConstraints(cluster, r.groups, r.planned)
+		}
+	}
+
+	remaining := AuditConstraints(cluster, r.groups, r.planned)
+	for i := range violations {
+		key := violations[i].key()
+		if !containsViolation(remaining, key) {
+			violations[i].Repaired = true
+			continue
+		}
+		violations[i].Blocked = blocked[key]
+		if violations[i].Blocked == "" {
+			violations[i].Blocked = "broken again by another repair"
+		}
+	}
+
+	suggestions := r.suggestions()
+	audit := &ConstraintAudit{Violations: violations}
+
+	result := &AnalysisResult{
+		Suggestions:      suggestions,
+		TargetsBefore:    make(map[string]NodeState),
+		TargetsAfter:     make(map[string]NodeState),
+		IsBalanceCluster: true, // Cluster-wide like a balance, no single source
+
+		Constraints:        MigrationConstraints{ConstraintAudit: true, Policy: &policy},
+		ClusterCollectedAt: cluster.CollectedAt,
+		MovementsTried:     r.attempts,
+		Audit:              audit,
+		ImprovementInfo: fmt.Sprintf("Constraint audit: %s with %s",
+			audit.Summary(), plural(len(suggestions), "migration", "migrations")),
+	}
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		if node.Status != "online" {
+			continue
+		}
+		result.TargetsBefore[node.Name] = NewNodeState(node)
+		result.TargetsAfter[node.Name] = r.states[node.Name]
+	}
+	for _, s := range suggestions {
+		result.TotalVMs++
+		result.TotalVCPUs += s.VCPUs
+		result.TotalRAM += s.RAM
+		result.TotalStorage += s.Storage
+	}
+
+	result.Staged = BuildStagedPlan(cluster, suggestions, policy)
+	result.AffinityViolations = FindAffinityViolations(cluster, r.groups, PlannedLocations(suggestions))
+
+	log.Printf("ConstraintAudit: %d of %d violations repaired with %d migrations",
+		audit.Repaired(), len(violations), len(suggestions))
+	return result, nil
+}
@@ -22,14 +22,43 @@ type BalanceProgressCallback func(stage string, current, total, movementsTried i
 // This analyzes ALL nodes in the cluster and generates migrations to balance
 // VM count, vCPUs, RAM, and storage utilization across all hosts
 func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	return AnalyzeClusterWideBalanceWithPolicy(cluster, DefaultCapacityPolicy, progress)
+}
+
+// AnalyzeClusterWideBalanceWithPolicy is AnalyzeClusterWideBalance with custom capacity limits
+// Per-node ConfigMeta overrides are applied on top of the policy
+func AnalyzeClusterWideBalanceWithPolicy(cluster *proxmox.Cluster, policy CapacityPolicy, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	return AnalyzeClusterWideBalanceWithStrategy(cluster, policy, DefaultStrategy, progress)
+}
+
+// AnalyzeClusterWideBalanceWithStrategy is AnalyzeClusterWideBalanceWithPolicy
+// with the placement strategy scoring each move (nil = default)
+func AnalyzeClusterWideBalanceWithStrategy(cluster *proxmox.Cluster, policy CapacityPolicy, strategy PlacementStrategy, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	return AnalyzeClusterWideBalanceWithConstraints(cluster, MigrationConstraints{Policy: &policy, Strategy: strategy}, progress)
+}
+
+// AnalyzeClusterWideBalanceWithConstraints is AnalyzeClusterWideBalance with
+// the capacity policy, placement strategy and budget of the constraints.
+// While the budget lasts it keeps picking the best move that still fits;
+// excluded VMs and VMs above the live RAM limit stay where they are, and
+// excluded nodes receive no VMs.
+func AnalyzeClusterWideBalanceWithConstraints(cluster *proxmox.Cluster, constraints MigrationConstraints, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	if err := constraints.ValidateBudget(); err != nil {
+		return nil, err
+	}
+	policy := constraints.GetPolicy()
+	strategy := constraints.GetStrategy()
+	limits := newBalanceLimits(constraints)
+
 	if cluster == nil || len(cluster.Nodes) == 0 {
 		return nil, fmt.Errorf("no nodes in cluster")
 	}
+	groups := ResolveAffinityGroups(cluster, policy.AffinityGroups)
 
-	// Get only online nodes that are not migration-blocked (hoststate=3)
+	// Get only online nodes that are not migration-blocked (hoststate=3) or removed by a what-if
 	var onlineNodes []proxmox.Node
 	for _, node := range cluster.Nodes {
-		if node.Status == "online" && !node.IsMigrationBlocked() {
+		if node.Status == "online" && !node.IsMigrationBlocked() && !node.Removed {
 			onlineNodes = append(onlineNodes, node)
 		}
 	}
@@ -55,7 +84,14 @@ func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgres
 	}
 
 	// Identify overloaded (donors) and underloaded (receivers) nodes
-	donors, receivers := categorizeNodes(onlineNodes, metrics)
+	// Excluded nodes count towards the averages and may donate, but don't receive
+	donors, candidates := categorizeNodes(onlineNodes, metrics)
+	var receivers []nodeBalance
+	for _, r := range candidates {
+		if limits.receives(r.node.Name) {
+			receivers = append(receivers, r)
+		}
+	}
 
 	log.Printf("ClusterBalance: %d donor nodes, %d receiver nodes", len(donors), len(receivers))
 
@@ -69,9 +105,12 @@ func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgres
 	}
 
 	// Generate optimal migrations using greedy algorithm with optimization
-	suggestions, nodeStates, movementsTried := generateBalancedMigrations(donors, receivers, metrics, cluster, progress)
+	suggestions, nodeStates, movementsTried := generateBalancedMigrations(donors, receivers, metrics, cluster, groups, policy, strategy, limits, progress)
 
 	if len(suggestions) == 0 {
+		if limits.exhausted != "" {
+			return nil, fmt.Errorf("no beneficial migrations fit the %s budget", limits.exhausted)
+		}
 		return nil, fmt.Errorf("no beneficial migrations found")
 	}
 
@@ -86,43 +125,15 @@ func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgres
 	// Also capture BEFORE state for all nodes (for the impact table)
 	beforeStates := make(map[string]NodeState)
 	for _, node := range onlineNodes {
-		state := newSimulatedNodeState(&node)
+		state := newSimulatedNodeState(&node, policy)
 		swapStates[node.Name] = state
 		// Capture before state (before any migrations)
 		beforeStates[node.Name] = state.toNodeState()
 	}
 
 	// Apply the initial migrations to the simulated states
-	migratedVMs := make(map[int]bool)
-	for _, sug := range suggestions {
-		migratedVMs[sug.VMID] = true
-		// Remove VM from source
-		if srcState := swapStates[sug.SourceNode]; srcState != nil {
-			if vm, ok := srcState.vms[sug.VMID]; ok {
-				srcState.vcpus -= vm.CPUCores
-				srcState.ramUsed -= vm.MaxMem
-				srcState.storageUsed -= vm.GetEffectiveDisk()
-				srcState.vmCount--
-				delete(srcState.vms, sug.VMID)
-			}
-		}
-		// Add VM to target
-		if tgtState := swapStates[sug.TargetNode]; tgtState != nil {
-			vm := proxmox.VM{
-				VMID:     sug.VMID,
-				Name:     sug.VMName,
-				CPUCores: sug.VCPUs,
-				MaxMem:   sug.RAM,
-				UsedDisk: sug.UsedDisk,
-				MaxDisk:  sug.MaxDisk,
-				Status:   sug.Status,
-			}
-			tgtState.vms[sug.VMID] = vm
-			tgtState.vcpus += vm.CPUCores
-			tgtState.ramUsed += vm.MaxMem
-			tgtState.storageUsed += vm.GetEffectiveDisk()
-			tgtState.vmCount++
-		}
+	for i := range suggestions {
+		updateSimulatedStates(swapStates, &suggestions[i])
 	}
 
 	// Find vCPU swap opportunities (VMs with similar RAM but different vCPUs)
@@ -135,10 +146,13 @@ func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgres
 		maxSwaps = 20
 	}
 
-	swapSuggestions := findVCPUSwapOpportunities(swapStates, metrics, maxSwaps)
+	swapSuggestions := findVCPUSwapOpportunities(swapStates, metrics, groups, maxSwaps, limits)
 	if len(swapSuggestions) > 0 {
 		log.Printf("ClusterBalance: Found %d vCPU swap migrations", len(swapSuggestions))
 		suggestions = append(suggestions, swapSuggestions...)
+		for i := range swapSuggestions {
+			updateSimulatedStates(swapStates, &swapSuggestions[i])
+		}
 	}
 
 	// Build result
@@ -147,7 +161,12 @@ func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgres
 		TargetsBefore:    make(map[string]NodeState),
 		TargetsAfter:     make(map[string]NodeState),
 		IsBalanceCluster: true, // This is a cluster-wide balance, no single source
+
+		Constraints:        constraints,
+		ClusterCollectedAt: cluster.CollectedAt,
 	}
+	result.Constraints.BalanceCluster = true
+	result.Constraints.Policy = &policy
 
 	// For Balance Cluster, don't set a source (all nodes are being balanced)
 	// SourceBefore/After remain zero-valued
@@ -163,20 +182,27 @@ func AnalyzeClusterWideBalance(cluster *proxmox.Cluster, progress BalanceProgres
 		}
 	}
 
-	// Calculate totals
-	for _, s := range suggestions {
-		result.TotalVMs++
-		result.TotalVCPUs += s.VCPUs
-		result.TotalRAM += s.RAM
-		result.TotalStorage += s.Storage
-	}
-
 	// Set movements tried counter
 	result.MovementsTried = movementsTried
 
 	// Calculate improvement info
 	result.ImprovementInfo = calculateImprovementInfo(nodeStates, metrics)
 
+	// Balance score against the budget spent
+	result.Budget = limits.report(onlineNodes)
+	var before, after []*simulatedNodeState
+	for _, node := range onlineNodes {
+		before = append(before, newSimulatedNodeState(&node, policy))
+		after = append(after, swapStates[node.Name])
+	}
+	result.Budget.ScoreBefore = clusterImbalance(before)
+	result.Budget.ScoreAfter = clusterImbalance(after)
+
+	// Order the migrations so every intermediate state stays within the limits
+	result.Staged = BuildStagedPlan(cluster, suggestions, policy)
+	result.AffinityViolations = FindAffinityViolations(cluster, groups, PlannedLocations(suggestions))
+	result.addPlannedTotals()
+
 	log.Printf("ClusterBalance: Analyzed %d potential movements, generated %d migrations", movementsTried, len(suggestions))
 
 	return result, nil
@@ -214,7 +240,7 @@ func calculateClusterMetrics(nodes []proxmox.Node) clusterMetrics {
 			m.totalVCPUs += vm.CPUCores
 			m.totalRAM += vm.MaxMem
 			// Use actual thin provisioning size (UsedDisk) when available
-			m.totalStorage += vm.GetEffectiveDisk()
+			m.totalStorage += vm.GetLocalDisk()
 		}
 	}
 
@@ -303,8 +329,9 @@ type nodeStatesPair struct {
 }
 
 // generateBalancedMigrations generates optimal migrations to balance the cluster
+// groups are the resolved affinity groups
 // Returns suggestions, node states, and total movements tried
-func generateBalancedMigrations(donors, receivers []nodeBalance, metrics clusterMetrics, cluster *proxmox.Cluster, progress BalanceProgressCallback) ([]MigrationSuggestion, map[string]nodeStatesPair, int) {
+func generateBalancedMigrations(donors, receivers []nodeBalance, metrics clusterMetrics, cluster *proxmox.Cluster, groups []AffinityGroup, policy CapacityPolicy, strategy PlacementStrategy, limits *balanceLimits, progress BalanceProgressCallback) ([]MigrationSuggestion, map[string]nodeStatesPair, int) {
 	var suggestions []MigrationSuggestion
 	nodeStates := make(map[string]nodeStatesPair)
 	var movementsTried int32
@@ -323,16 +350,17 @@ func generateBalancedMigrations(donors, receivers []nodeBalance, metrics cluster
 		}
 	}
 
-	// Track which VMs have been migrated
+	// Track which VMs have been migrated, and where to (for placement constraints)
 	migratedVMs := make(map[int]bool)
+	planned := make(map[string]string)
 
 	// Track current node states (for incremental updates)
 	currentStates := make(map[string]*simulatedNodeState)
 	for _, d := range donors {
-		currentStates[d.node.Name] = newSimulatedNodeState(&d.node)
+		currentStates[d.node.Name] = newSimulatedNodeState(&d.node, policy)
 	}
 	for _, r := range receivers {
-		currentStates[r.node.Name] = newSimulatedNodeState(&r.node)
+		currentStates[r.node.Name] = newSimulatedNodeState(&r.node, policy)
 	}
 
 	// Greedy algorithm: repeatedly find the best migration until balanced
@@ -347,16 +375,27 @@ func generateBalancedMigrations(donors, receivers []nodeBalance, metrics cluster
 			progress("Optimizing migrations", currentProgress, totalProgress, int(movementsTried))
 		}
 
-		bestMigration, tried := findBestMigrationParallel(donors, receivers, currentStates, migratedVMs, metrics, cluster)
+		// Stop when the migration budget is spent
+		if budget := limits.fits(1, 0); budget != "" {
+			limits.exhaust(budget)
+			break
+		}
+
+		bestMigration, tried := findBestMigrationParallel(donors, receivers, currentStates, migratedVMs, planned, metrics, cluster, groups, strategy, limits)
 		atomic.AddInt32(&movementsTried, int32(tried))
 
 		if bestMigration == nil {
+			if limits.blocked {
+				limits.exhaust(BudgetTransfer)
+			}
 			break // No more beneficial migrations
 		}
 
 		// Apply the migration
+		limits.take(currentStates[bestMigration.SourceNode].vms[bestMigration.VMID])
 		suggestions = append(suggestions, *bestMigration)
 		migratedVMs[bestMigration.VMID] = true
+		planned[bestMigration.VMName] = bestMigration.TargetNode
 
 		// Update simulated states
 		updateSimulatedStates(currentStates, bestMigration)
@@ -395,17 +434,21 @@ type simulatedNodeState struct {
 	storageUsed  int64
 	storageTotal int64
 	vmCount      int
-	vms          map[int]proxmox.VM // Current VMs on this node
+	vms          map[int]proxmox.VM    // Current VMs on this node
+	policy       CapacityPolicy        // Capacity limits for this node (ConfigMeta overrides applied)
+	pools        []proxmox.StoragePool // Per-pool storage usage for disk placement
 }
 
-func newSimulatedNodeState(node *proxmox.Node) *simulatedNodeState {
+func newSimulatedNodeState(node *proxmox.Node, policy CapacityPolicy) *simulatedNodeState {
 	s := &simulatedNodeState{
 		name:         node.Name,
+		policy:       policy.ForNode(node),
 		cpuCores:     node.CPUCores,
 		hostCPUUsage: node.CPUUsage, // Actual host CPU usage (0-100 scale, from API it's 0-1)
 		ramTotal:     node.MaxMem,
 		storageTotal: node.MaxDisk,
 		vms:          make(map[int]proxmox.VM),
+		pools:        node.Storages,
 	}
 
 	// Calculate sum of VM CPU usages for estimation
@@ -413,7 +456,7 @@ func newSimulatedNodeState(node *proxmox.Node) *simulatedNodeState {
 		s.vms[vm.VMID] = vm
 		s.vcpus += vm.CPUCores
 		s.ramUsed += vm.MaxMem
-		s.storageUsed += vm.GetEffectiveDisk()
+		s.storageUsed += vm.GetLocalDisk()
 		s.vmCount++
 		// Sum up VM CPU usage (scaled by vCPUs as a proxy for CPU contribution)
 		s.vmCPUSum += vm.CPUUsage * float64(vm.CPUCores)
@@ -422,6 +465,13 @@ func newSimulatedNodeState(node *proxmox.Node) *simulatedNodeState {
 	return s
 }
 
+// placeDisks plans the disks of an incoming VM on this node's storage pools
+// Returns nil if the pools are unknown or a disk doesn't fit
+func (s *simulatedNodeState) placeDisks(vm proxmox.VM) []DiskPlacement {
+	disks, _ := PlanDiskPlacement(vm, s.pools, s.policy.MaxStoragePercent)
+	return disks
+}
+
 // getEstimatedHostCPU estimates host CPU usage based on VM CPU contributions
 // This helps predict CPU usage after migrations
 func (s *simulatedNodeState) getEstimatedHostCPU() float64 {
@@ -466,8 +516,8 @@ func (s *simulatedNodeState) toNodeState() NodeState {
 }
 
 // findBestMigration finds the single best VM migration to improve balance
-func findBestMigration(donors, receivers []nodeBalance, states map[string]*simulatedNodeState, migratedVMs map[int]bool, metrics clusterMetrics, cluster *proxmox.Cluster) *MigrationSuggestion {
-	result, _ := findBestMigrationParallel(donors, receivers, states, migratedVMs, metrics, cluster)
+func findBestMigration(donors, receivers []nodeBalance, states map[string]*simulatedNodeState, migratedVMs map[int]bool, planned map[string]string, metrics clusterMetrics, cluster *proxmox.Cluster, groups []AffinityGroup, strategy PlacementStrategy, limits *balanceLimits) *MigrationSuggestion {
+	result, _ := findBestMigrationParallel(donors, receivers, states, migratedVMs, planned, metrics, cluster, groups, strategy, limits)
 	return result
 }
 
@@ -478,9 +528,12 @@ type migrationCandidate struct {
 }
 
 // findBestMigrationParallel finds the single best VM migration using parallel evaluation
+// planned maps the VMs migrated so far to their targets (read-only here)
+// Only VMs the budget allows are considered; limits.blocked tells whether the transfer budget ruled any out.
 // Returns the best migration and the number of candidates evaluated
-func findBestMigrationParallel(donors, receivers []nodeBalance, states map[string]*simulatedNodeState, migratedVMs map[int]bool, metrics clusterMetrics, cluster *proxmox.Cluster) (*MigrationSuggestion, int) {
+func findBestMigrationParallel(donors, receivers []nodeBalance, states map[string]*simulatedNodeState, migratedVMs map[int]bool, planned map[string]string, metrics clusterMetrics, cluster *proxmox.Cluster, groups []AffinityGroup, strategy PlacementStrategy, limits *balanceLimits) (*MigrationSuggestion, int) {
 	var candidateCount int32
+	limits.blocked = false
 
 	// Collect all VM-receiver pairs to evaluate in parallel
 	type evalJob struct {
@@ -508,7 +561,12 @@ func findBestMigrationParallel(donors, receivers []nodeBalance, states map[strin
 		// Consider each VM on this donor
 		for vmid, vm := range donorState.vms {
 			// Skip if already migrated, not migratable, or not running
-			if migratedVMs[vmid] || vm.NoMigrate || vm.Status != "running" {
+			if migratedVMs[vmid] || vm.NoMigrate || vm.Status != "running" || !limits.movable(vm) {
+				continue
+			}
+			// Skip if the migration doesn't fit the remaining transfer budget
+			if limits.fits(1, EstimateTransferBytes(vm)) != "" {
+				limits.blocked = true
 				continue
 			}
 
@@ -556,13 +614,22 @@ func findBestMigrationParallel(donors, receivers []nodeBalance, states map[strin
 			for job := range jobsChan {
 				atomic.AddInt32(&candidateCount, 1)
 
+				// Check placement constraints (hostcpumodel, withvm, without, affinity groups)
+				if HasPlacementConstraints(job.vm, groups) &&
+					CheckVMPlacementConstraints(job.vm, &job.receiver.node, cluster, groups, planned).Violated {
+					continue
+				}
+
 				// Check if receiver can accept this VM
 				if !canAcceptVM(job.receiverState, &job.vm, metrics) {
 					continue
 				}
 
-				// Calculate improvement score
-				score := calculateMigrationScore(job.donorState, job.receiverState, &job.vm, metrics)
+				// Score the move with the placement strategy
+				move := newBalanceMove(job.donorState, job.receiverState, &job.vm, metrics)
+				move.ReceiverCPUPriority = GetCPURawPriority(job.receiver.node.CPUModel)
+				breakdown := strategy.ScoreMove(move)
+				score := breakdown.TotalScore
 				if score > 0 {
 					results <- migrationCandidate{
 						score: score,
@@ -574,17 +641,22 @@ func findBestMigrationParallel(donors, receivers []nodeBalance, states map[strin
 							Reason:      "Balance cluster",
 							Score:       score,
 							Status:      job.vm.Status,
+							Type:        job.vm.Type,
 							VCPUs:       job.vm.CPUCores,
 							CPUUsage:    job.vm.CPUUsage,
 							RAM:         job.vm.MaxMem,
-							Storage:     job.vm.GetEffectiveDisk(),
+							Storage:     job.vm.GetLocalDisk(),
 							UsedDisk:    job.vm.UsedDisk,
 							MaxDisk:     job.vm.MaxDisk,
+							Transfer:    EstimateTransferBytes(job.vm),
+							LiveMemory:  LiveMemoryBytes(job.vm),
 							SourceCores: job.donorState.cpuCores,
 							TargetCores: job.receiverState.cpuCores,
+							Disks:       job.receiverState.placeDisks(job.vm),
 							Details: &MigrationDetails{
 								SelectionMode:   "balance_cluster",
 								SelectionReason: "Cluster-wide balancing",
+								ScoreBreakdown:  breakdown,
 								ClusterAvgCPU:   metrics.avgVCPUPercent,
 								ClusterAvgRAM:   metrics.avgRAMPercent,
 							},
@@ -616,40 +688,18 @@ func findBestMigrationParallel(donors, receivers []nodeBalance, states map[strin
 
 // canAcceptVM checks if a receiver can accept a VM without becoming overloaded
 func canAcceptVM(receiver *simulatedNodeState, vm *proxmox.VM, metrics clusterMetrics) bool {
-	// Calculate projected utilization after adding VM
-	newRAMPercent := float64(receiver.ramUsed+vm.MaxMem) / float64(receiver.ramTotal) * 100
-	newVCPUPercent := float64(receiver.vcpus+vm.CPUCores) / float64(receiver.cpuCores) * 100
-	newStoragePercent := float64(receiver.storageUsed+vm.GetEffectiveDisk()) / float64(receiver.storageTotal) * 100
-
-	// Calculate estimated host CPU usage after adding VM
-	// VM contribution = VM's CPU usage * VM's vCPUs
-	vmCPUContrib := vm.CPUUsage * float64(vm.CPUCores)
-	newEstimatedHostCPU := (receiver.vmCPUSum + vmCPUContrib) / float64(receiver.cpuCores)
-
-	// HARD LIMITS:
-	// - Host CPU Usage: Never exceed 95% (actual physical CPU utilization)
-	// - RAM: Never exceed 90% (cannot be oversubscribed)
-	// - Storage: Never exceed 85% (need headroom for snapshots etc.)
-	// - vCPU: NO hard cap (oversubscription is normal, 200-600% is common)
-	const hardCapHostCPUPercent = 95.0
-	const hardCapRAMPercent = 90.0
-	const hardCapStoragePercent = 85.0
-
-	// Check host CPU usage (actual physical utilization, not vCPU allocation)
-	if newEstimatedHostCPU > hardCapHostCPUPercent {
+	if receiver.hardLimitViolation(vm) != "" {
 		return false
 	}
 
-	if newRAMPercent > hardCapRAMPercent {
-		return false
-	}
-	if newStoragePercent > hardCapStoragePercent {
-		return false
-	}
+	// Calculate projected utilization after adding VM
+	newRAMPercent := float64(receiver.ramUsed+vm.MaxMem) / float64(receiver.ramTotal) * 100
+	newVCPUPercent := float64(receiver.vcpus+vm.CPUCores) / float64(receiver.cpuCores) * 100
+	newStoragePercent := float64(receiver.storageUsed+vm.GetLocalDisk()) / float64(receiver.storageTotal) * 100
 
-	// SOFT LIMITS: Don't overshoot the cluster average by more than 5%
+	// SOFT LIMITS: Don't overshoot the cluster average by more than the margin (5%)
 	// This ensures balanced distribution across all available hosts
-	const softMargin = 5.0
+	softMargin := receiver.policy.SoftMarginPercent
 	currentRAMPercent := receiver.getRAMPercent()
 	currentVCPUPercent := receiver.getVCPUPercent()
 	currentStoragePercent := float64(receiver.storageUsed) / float64(receiver.storageTotal) * 100
@@ -669,43 +719,82 @@ func canAcceptVM(receiver *simulatedNodeState, vm *proxmox.VM, metrics clusterMe
 		return false
 	}
 
+	return true
+}
+
+// hardLimitViolation returns why the node can't take the VM without breaking
+// a hard limit of its capacity policy, or "" if it fits
+func (s *simulatedNodeState) hardLimitViolation(vm *proxmox.VM) string {
+	// Calculate projected utilization after adding VM
+	newRAMPercent := float64(s.ramUsed+vm.MaxMem) / float64(s.ramTotal) * 100
+	newStoragePercent := float64(s.storageUsed+vm.GetLocalDisk()) / float64(s.storageTotal) * 100
+
+	// Calculate estimated host CPU usage after adding VM
+	// VM contribution = VM's CPU usage * VM's vCPUs
+	vmCPUContrib := vm.CPUUsage * float64(vm.CPUCores)
+	newEstimatedHostCPU := (s.vmCPUSum + vmCPUContrib) / float64(s.cpuCores)
+
+	// HARD LIMITS (receiver's capacity policy, defaults in parentheses):
+	// - Host CPU Usage: Never exceed 95% (actual physical CPU utilization)
+	// - RAM: Never exceed 90% (cannot be oversubscribed)
+	// - Storage: Never exceed 85% (need headroom for snapshots etc.)
+	// - vCPU: NO hard cap (oversubscription is normal, 200-600% is common)
+	policy := s.policy
+
+	// Check host CPU usage (actual physical utilization, not vCPU allocation)
+	if newEstimatedHostCPU > policy.MaxHostCPUPercent {
+		return fmt.Sprintf("Host CPU would reach %.1f%% (limit %.0f%%)", newEstimatedHostCPU, policy.MaxHostCPUPercent)
+	}
+
+	if newRAMPercent > policy.MaxRAMPercent {
+		return fmt.Sprintf("RAM would reach %.1f%% (limit %.0f%%)", newRAMPercent, policy.MaxRAMPercent)
+	}
+	if newStoragePercent > policy.MaxStoragePercent {
+		return fmt.Sprintf("Storage would reach %.1f%% (limit %.0f%%)", newStoragePercent, policy.MaxStoragePercent)
+	}
+
 	// Check storage constraints - use actual thin provisioning size
-	incomingVMStorage := vm.GetEffectiveDisk()
+	incomingVMStorage := vm.GetLocalDisk()
 
 	// Calculate storage used after migration
-	storageUsedAfter := receiver.storageUsed + incomingVMStorage
+	storageUsedAfter := s.storageUsed + incomingVMStorage
 
 	// Basic check - never exceed storage capacity (prevent >100%)
-	if receiver.storageTotal > 0 && storageUsedAfter > receiver.storageTotal {
-		return false
+	if s.storageTotal > 0 && storageUsedAfter > s.storageTotal {
+		return "Storage would exceed its capacity"
 	}
 
-	// Check storage headroom constraint (500 GiB + 15% of largest VM)
+	// Check storage headroom constraint (500 GiB + 15% of largest VM by default)
 	// Find the largest VM on the receiver (including the incoming VM)
 	largestVMStorage := incomingVMStorage
-	for _, existingVM := range receiver.vms {
-		vmStorage := existingVM.GetEffectiveDisk()
+	for _, existingVM := range s.vms {
+		vmStorage := existingVM.GetLocalDisk()
 		if vmStorage > largestVMStorage {
 			largestVMStorage = vmStorage
 		}
 	}
 
-	// Calculate required headroom: 500 GiB + 15% of largest VM's storage
-	minHeadroomBytes := int64(MinStorageHeadroomGiB) * 1024 * 1024 * 1024
-	largestVMHeadroom := int64(float64(largestVMStorage) * LargestVMStorageHeadroomPercent)
-	requiredFreeStorage := minHeadroomBytes + largestVMHeadroom
+	// Calculate required headroom from the policy
+	largestVMHeadroom := int64(float64(largestVMStorage) * policy.LargestVMHeadroomPercent / 100)
+	requiredFreeStorage := policy.MinStorageHeadroomBytes() + largestVMHeadroom
 
 	// Check if we have enough headroom
-	actualFreeStorage := receiver.storageTotal - storageUsedAfter
+	actualFreeStorage := s.storageTotal - storageUsedAfter
 	if actualFreeStorage < requiredFreeStorage {
-		return false
+		return fmt.Sprintf("Storage headroom would drop below %s", proxmox.FormatBytes(requiredFreeStorage))
 	}
 
-	return true
+	// Every disk needs a storage pool with room for it
+	if _, reason := PlanDiskPlacement(*vm, s.pools, policy.MaxStoragePercent); reason != "" {
+		return reason
+	}
+
+	return ""
 }
 
-// calculateMigrationScore calculates how much a migration improves cluster balance
-func calculateMigrationScore(donor, receiver *simulatedNodeState, vm *proxmox.VM, metrics clusterMetrics) float64 {
+// newBalanceMove measures how much a migration improves cluster balance; the
+// placement strategy turns it into a score (see PlacementStrategy.ScoreMove)
+func newBalanceMove(donor, receiver *simulatedNodeState, vm *proxmox.VM, metrics clusterMetrics) BalanceMove {
 	// Calculate current deviations
 	donorRAMDev := math.Abs(donor.getRAMPercent() - metrics.avgRAMPercent)
 	receiverRAMDev := math.Abs(receiver.getRAMPercent() - metrics.avgRAMPercent)
@@ -718,18 +807,18 @@ func calculateMigrationScore(donor, receiver *simulatedNodeState, vm *proxmox.VM
 	newReceiverRAMDev := math.Abs(newReceiverRAM - metrics.avgRAMPercent)
 	newTotalDev := newDonorRAMDev + newReceiverRAMDev
 
-	// Improvement in deviation (higher = better)
-	improvement := currentTotalDev - newTotalDev
-
-	// Bonus for smaller VMs (prefer moving smaller VMs)
-	// Use actual thin provisioning size
-	storageGiB := float64(vm.GetEffectiveDisk()) / (1024 * 1024 * 1024)
-	if storageGiB < 1 {
-		storageGiB = 1
+	move := BalanceMove{
+		VM:                 vm,
+		Donor:              donor.name,
+		Receiver:           receiver.name,
+		Improvement:        currentTotalDev - newTotalDev, // Higher = better
+		ReceiverRAMPercent: newReceiverRAM,
+		ReceiverVMs:        receiver.vmCount + 1,
 	}
-	sizeBonus := 100.0 / storageGiB // Smaller = higher bonus
-
-	return improvement*10 + sizeBonus
+	if receiver.cpuCores > 0 {
+		move.ReceiverVCPUPercent = float64(receiver.vcpus+vm.CPUCores) / float64(receiver.cpuCores) * 100
+	}
+	return move
 }
 
 // updateSimulatedStates updates states after a migration
@@ -741,7 +830,7 @@ func updateSimulatedStates(states map[string]*simulatedNodeState, migration *Mig
 		vm, exists := source.vms[migration.VMID]
 		if exists {
 			// Use actual thin provisioning size
-			storage := vm.GetEffectiveDisk()
+			storage := vm.GetLocalDisk()
 			// VM's CPU contribution (for host CPU estimation)
 			vmCPUContrib := vm.CPUUsage * float64(vm.CPUCores)
 
@@ -752,8 +841,11 @@ func updateSimulatedStates(states map[string]*simulatedNodeState, migration *Mig
 			source.storageUsed -= storage
 			source.vmCount--
 			source.vmCPUSum -= vmCPUContrib
+			source.pools = releaseDisks(source.pools, vm)
 
 			// Add to target
+			vm = movedVM(vm, migration)
+			target.pools = applyDiskPlacement(target.pools, migration.Disks)
 			target.vms[migration.VMID] = vm
 			target.vcpus += vm.CPUCores
 			target.ramUsed += vm.MaxMem
@@ -790,7 +882,7 @@ func buildNodeState(node *proxmox.Node) NodeState {
 		vcpus += vm.CPUCores
 		ramUsed += vm.MaxMem
 		// Use actual thin provisioning size (UsedDisk) when available
-		storageUsed += vm.GetEffectiveDisk()
+		storageUsed += vm.GetLocalDisk()
 	}
 
 	return NodeState{
@@ -842,12 +934,17 @@ type vmSwapCandidate struct {
 
 // findVCPUSwapOpportunities finds VMs that can be swapped to balance vCPUs
 // without significantly affecting RAM balance. Returns pairs of migrations.
-func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics clusterMetrics, maxSwaps int) []MigrationSuggestion {
+// VMs with placement constraints are left out, swaps don't check them.
+func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics clusterMetrics, groups []AffinityGroup, maxSwaps int, limits *balanceLimits) []MigrationSuggestion {
 	var suggestions []MigrationSuggestion
 
 	// Find nodes above and below average vCPU
+	// A swap moves VMs onto both nodes, so excluded nodes take no part
 	var highVCPUNodes, lowVCPUNodes []*simulatedNodeState
 	for _, state := range states {
+		if !limits.receives(state.name) {
+			continue
+		}
 		deviation := state.getVCPUPercent() - metrics.avgVCPUPercent
 		if deviation > 5 { // 5% above average
 			highVCPUNodes = append(highVCPUNodes, state)
@@ -868,8 +965,12 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 		return lowVCPUNodes[i].getVCPUPercent() < lowVCPUNodes[j].getVCPUPercent()
 	})
 
+	// VMs already in the plan are not swapped: every VM migrates at most once
 	swapCount := 0
 	usedVMs := make(map[int]bool)
+	for vmid := range limits.charged {
+		usedVMs[vmid] = true
+	}
 
 	// For each high-vCPU node, try to find swap opportunities
 	for _, highNode := range highVCPUNodes {
@@ -880,7 +981,7 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 		// Get high-vCPU VMs from this node
 		var highVCPUVMs []vmSwapCandidate
 		for _, vm := range highNode.vms {
-			if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] {
+			if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] || HasPlacementConstraints(vm, groups) || !limits.movable(vm) {
 				continue
 			}
 			if vm.CPUCores >= 2 { // Only consider VMs with 2+ vCPUs
@@ -912,12 +1013,12 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 
 			// Search low-vCPU nodes for a swap candidate
 			for _, lowNode := range lowVCPUNodes {
-				if swapCount >= maxSwaps {
+				if swapCount >= maxSwaps || usedVMs[highVM.vm.VMID] {
 					break
 				}
 
 				for _, vm := range lowNode.vms {
-					if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] {
+					if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] || HasPlacementConstraints(vm, groups) || !limits.movable(vm) {
 						continue
 					}
 
@@ -936,6 +1037,11 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 						continue // Low-vCPU VM should have fewer vCPUs
 					}
 
+					// Both migrations have to fit the budget
+					if !limits.take(highVM.vm, vm) {
+						continue
+					}
+
 					// This is a valid swap! Create two migration suggestions
 					log.Printf("vCPU Swap: VM %d (%s, %d vCPU, %d GB RAM) on %s <-> VM %d (%s, %d vCPU, %d GB RAM) on %s",
 						highVM.vm.VMID, highVM.vm.Name, highVM.vcpus, highVM.ram/(1024*1024*1024), highNode.name,
@@ -950,14 +1056,18 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 						Reason:      fmt.Sprintf("vCPU balance swap (-%d vCPU)", vcpuDiff),
 						Score:       float64(vcpuDiff) * 10,
 						Status:      highVM.vm.Status,
+						Type:        highVM.vm.Type,
 						VCPUs:       highVM.vcpus,
 						CPUUsage:    highVM.vm.CPUUsage,
 						RAM:         highVM.ram,
-						Storage:     highVM.vm.GetEffectiveDisk(),
+						Storage:     highVM.vm.GetLocalDisk(),
 						UsedDisk:    highVM.vm.UsedDisk,
 						MaxDisk:     highVM.vm.MaxDisk,
+						Transfer:    EstimateTransferBytes(highVM.vm),
+						LiveMemory:  LiveMemoryBytes(highVM.vm),
 						SourceCores: highNode.cpuCores,
 						TargetCores: lowNode.cpuCores,
+						Disks:       lowNode.placeDisks(highVM.vm),
 						Details: &MigrationDetails{
 							SelectionMode:   "vcpu_swap",
 							SelectionReason: "vCPU balancing via swap",
@@ -973,14 +1083,18 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 						Reason:      fmt.Sprintf("vCPU balance swap (+%d vCPU)", vcpuDiff),
 						Score:       float64(vcpuDiff) * 10,
 						Status:      vm.Status,
+						Type:        vm.Type,
 						VCPUs:       vm.CPUCores,
 						CPUUsage:    vm.CPUUsage,
 						RAM:         vm.MaxMem,
-						Storage:     vm.GetEffectiveDisk(),
+						Storage:     vm.GetLocalDisk(),
 						UsedDisk:    vm.UsedDisk,
 						MaxDisk:     vm.MaxDisk,
+						Transfer:    EstimateTransferBytes(vm),
+						LiveMemory:  LiveMemoryBytes(vm),
 						SourceCores: lowNode.cpuCores,
 						TargetCores: highNode.cpuCores,
+						Disks:       highNode.placeDisks(vm),
 						Details: &MigrationDetails{
 							SelectionMode:   "vcpu_swap",
 							SelectionReason: "vCPU balancing via swap",
@@ -999,7 +1113,7 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 
 	// Try multi-VM swaps (2-for-1 or 3-for-1) if 1-for-1 swaps didn't fully balance
 	if swapCount < maxSwaps {
-		multiSwapSuggestions := findMultiVMSwapOpportunities(states, metrics, maxSwaps-swapCount, usedVMs)
+		multiSwapSuggestions := findMultiVMSwapOpportunities(states, metrics, groups, maxSwaps-swapCount, usedVMs, limits)
 		suggestions = append(suggestions, multiSwapSuggestions...)
 	}
 
@@ -1008,7 +1122,7 @@ func findVCPUSwapOpportunities(states map[string]*simulatedNodeState, metrics cl
 
 // findMultiVMSwapOpportunities finds 2-for-1 or 3-for-1 VM swaps to balance vCPUs/VM count
 // For example: swap 1 large VM for 2 smaller VMs with similar total RAM but fewer vCPUs
-func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics clusterMetrics, maxSwaps int, usedVMs map[int]bool) []MigrationSuggestion {
+func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics clusterMetrics, groups []AffinityGroup, maxSwaps int, usedVMs map[int]bool, limits *balanceLimits) []MigrationSuggestion {
 	var suggestions []MigrationSuggestion
 
 	// Find nodes that are imbalanced in VM count or vCPUs
@@ -1022,6 +1136,9 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 	var imbalanced []nodeImbalance
 
 	for _, state := range states {
+		if !limits.receives(state.name) {
+			continue // Swaps move VMs onto both nodes
+		}
 		vmDev := float64(state.vmCount) - avgVMCount
 		vcpuDev := state.getVCPUPercent() - metrics.avgVCPUPercent
 		if math.Abs(vmDev) > 2 || math.Abs(vcpuDev) > 5 {
@@ -1051,7 +1168,7 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 		// Get large VMs from this node (sorted by RAM descending)
 		var largeVMs []proxmox.VM
 		for _, vm := range highVMNode.vms {
-			if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] {
+			if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] || HasPlacementConstraints(vm, groups) || !limits.movable(vm) {
 				continue
 			}
 			if vm.MaxMem >= 8*1024*1024*1024 { // Only consider VMs with 8+ GB RAM
@@ -1069,7 +1186,7 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 			// Get small VMs from this node
 			var smallVMs []proxmox.VM
 			for _, vm := range lowVMNode.vms {
-				if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] {
+				if vm.NoMigrate || vm.Status != "running" || usedVMs[vm.VMID] || HasPlacementConstraints(vm, groups) || !limits.movable(vm) {
 					continue
 				}
 				smallVMs = append(smallVMs, vm)
@@ -1086,7 +1203,7 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 
 				// Try 2-for-1 swap
 				match2 := findMatchingSmallVMs(largeVM, smallVMs, 2, usedVMs)
-				if match2 != nil {
+				if match2 != nil && limits.take(append([]proxmox.VM{largeVM}, match2...)...) {
 					log.Printf("Multi-swap 2-for-1: VM %d (%s, %d vCPU, %d GB) on %s <-> VMs %v on %s",
 						largeVM.VMID, largeVM.Name, largeVM.CPUCores, largeVM.MaxMem/(1024*1024*1024), highVMNode.name,
 						getVMIDs(match2), lowVMNode.name)
@@ -1100,14 +1217,18 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 						Reason:      fmt.Sprintf("Multi-swap 2-for-1 (balance VM count: %d→%d)", highVMNode.vmCount, highVMNode.vmCount-1+len(match2)),
 						Score:       100,
 						Status:      largeVM.Status,
+						Type:        largeVM.Type,
 						VCPUs:       largeVM.CPUCores,
 						CPUUsage:    largeVM.CPUUsage,
 						RAM:         largeVM.MaxMem,
-						Storage:     largeVM.GetEffectiveDisk(),
+						Storage:     largeVM.GetLocalDisk(),
 						UsedDisk:    largeVM.UsedDisk,
 						MaxDisk:     largeVM.MaxDisk,
+						Transfer:    EstimateTransferBytes(largeVM),
+						LiveMemory:  LiveMemoryBytes(largeVM),
 						SourceCores: highVMNode.cpuCores,
 						TargetCores: lowVMNode.cpuCores,
+						Disks:       lowVMNode.placeDisks(largeVM),
 						Details: &MigrationDetails{
 							SelectionMode:   "multi_swap",
 							SelectionReason: "VM count/vCPU balancing via 2-for-1 swap",
@@ -1124,14 +1245,18 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 							Reason:      "Multi-swap 2-for-1 (part of swap group)",
 							Score:       100,
 							Status:      smallVM.Status,
+							Type:        smallVM.Type,
 							VCPUs:       smallVM.CPUCores,
 							CPUUsage:    smallVM.CPUUsage,
 							RAM:         smallVM.MaxMem,
-							Storage:     smallVM.GetEffectiveDisk(),
+							Storage:     smallVM.GetLocalDisk(),
 							UsedDisk:    smallVM.UsedDisk,
 							MaxDisk:     smallVM.MaxDisk,
+							Transfer:    EstimateTransferBytes(smallVM),
+							LiveMemory:  LiveMemoryBytes(smallVM),
 							SourceCores: lowVMNode.cpuCores,
 							TargetCores: highVMNode.cpuCores,
+							Disks:       highVMNode.placeDisks(smallVM),
 							Details: &MigrationDetails{
 								SelectionMode:   "multi_swap",
 								SelectionReason: "VM count/vCPU balancing via 2-for-1 swap",
@@ -1146,7 +1271,7 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 
 				// Try 3-for-1 swap
 				match3 := findMatchingSmallVMs(largeVM, smallVMs, 3, usedVMs)
-				if match3 != nil {
+				if match3 != nil && limits.take(append([]proxmox.VM{largeVM}, match3...)...) {
 					log.Printf("Multi-swap 3-for-1: VM %d (%s, %d vCPU, %d GB) on %s <-> VMs %v on %s",
 						largeVM.VMID, largeVM.Name, largeVM.CPUCores, largeVM.MaxMem/(1024*1024*1024), highVMNode.name,
 						getVMIDs(match3), lowVMNode.name)
@@ -1160,14 +1285,18 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 						Reason:      fmt.Sprintf("Multi-swap 3-for-1 (balance VM count: %d→%d)", highVMNode.vmCount, highVMNode.vmCount-1+len(match3)),
 						Score:       100,
 						Status:      largeVM.Status,
+						Type:        largeVM.Type,
 						VCPUs:       largeVM.CPUCores,
 						CPUUsage:    largeVM.CPUUsage,
 						RAM:         largeVM.MaxMem,
-						Storage:     largeVM.GetEffectiveDisk(),
+						Storage:     largeVM.GetLocalDisk(),
 						UsedDisk:    largeVM.UsedDisk,
 						MaxDisk:     largeVM.MaxDisk,
+						Transfer:    EstimateTransferBytes(largeVM),
+						LiveMemory:  LiveMemoryBytes(largeVM),
 						SourceCores: highVMNode.cpuCores,
 						TargetCores: lowVMNode.cpuCores,
+						Disks:       lowVMNode.placeDisks(largeVM),
 						Details: &MigrationDetails{
 							SelectionMode:   "multi_swap",
 							SelectionReason: "VM count/vCPU balancing via 3-for-1 swap",
@@ -1184,14 +1313,18 @@ func findMultiVMSwapOpportunities(states map[string]*simulatedNodeState, metrics
 							Reason:      "Multi-swap 3-for-1 (part of swap group)",
 							Score:       100,
 							Status:      smallVM.Status,
+							Type:        smallVM.Type,
 							VCPUs:       smallVM.CPUCores,
 							CPUUsage:    smallVM.CPUUsage,
 							RAM:         smallVM.MaxMem,
-							Storage:     smallVM.GetEffectiveDisk(),
+							Storage:     smallVM.GetLocalDisk(),
 							UsedDisk:    smallVM.UsedDisk,
 							MaxDisk:     smallVM.MaxDisk,
+							Transfer:    EstimateTransferBytes(smallVM),
+							LiveMemory:  LiveMemoryBytes(smallVM),
 							SourceCores: lowVMNode.cpuCores,
 							TargetCores: highVMNode.cpuCores,
+							Disks:       highVMNode.placeDisks(smallVM),
 							Details: &MigrationDetails{
 								SelectionMode:   "multi_swap",
 								SelectionReason: "VM count/vCPU balancing via 3-for-1 swap",
@@ -0,0 +1,224 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// Budgets that can stop a cluster-wide balance before it is balanced
+const (
+	BudgetMigrations = "migrations"
+	BudgetTransfer   = "transfer"
+)
+
+// BalanceBudget reports the balance a cluster-wide balance reached against
+// the budget it spent. Scores are the imbalance of OptimizationReport: the
+// weighted RMS deviation of the nodes from the cluster average, in points.
+type BalanceBudget struct {
+	ScoreBefore float64
+	ScoreAfter  float64
+
+	Migrations    int
+	MaxMigrations int // 0 = unlimited
+	Bytes         int64
+	MaxBytes      int64 // 0 = unlimited
+	MaxLiveRAM    int64 // 0 = unlimited
+
+	ExcludedVMs int    // Running VMs left in place by the exclude list
+	TooLargeVMs int    // Running VMs with more RAM than MaxLiveRAM (containers don't count)
+	Exhausted   string // Budget* that ruled out further migrations, "" if none did
+}
+
+// Summary describes the score against the budget, e.g.
+// "Imbalance 21.4 → 8.2 pts with 10 of 10 migrations, 1.2 TB transferred (migration budget reached)"
+func (b *BalanceBudget) Summary() string {
+	migrations := fmt.Sprintf("%d migrations", b.Migrations)
+	if b.MaxMigrations > 0 {
+		migrations = fmt.Sprintf("%d of %d migrations", b.Migrations, b.MaxMigrations)
+	}
+	transfer := proxmox.FormatBytes(b.Bytes) + " transferred"
+	if b.MaxBytes > 0 {
+		transfer = fmt.Sprintf("%s of %s transferred", proxmox.FormatBytes(b.Bytes), proxmox.FormatBytes(b.MaxBytes))
+	}
+	s := fmt.Sprintf("Imbalance %.1f → %.1f pts with %s, %s", b.ScoreBefore, b.ScoreAfter, migrations, transfer)
+	switch b.Exhausted {
+	case BudgetMigrations:
+		s += " (migration budget reached)"
+	case BudgetTransfer:
+		s += " (transfer budget reached)"
+	}
+	return s
+}
+
+// Restrictions describes the VMs the budget kept in place, e.g.
+// "2 VMs excluded, 3 VMs above the 64.0 GB live migration limit"; "" if none
+func (b *BalanceBudget) Restrictions() string {
+	var parts []string
+	if b.ExcludedVMs > 0 {
+		parts = append(parts, fmt.Sprintf("%d VMs excluded", b.ExcludedVMs))
+	}
+	if b.TooLargeVMs > 0 {
+		parts = append(parts, fmt.Sprintf("%d VMs above the %s live migration limit", b.TooLargeVMs, proxmox.FormatBytes(b.MaxLiveRAM)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BudgetSummary describes the cluster-wide balance budget of the constraints, e.g.
+// "at most 10 migrations, 2.0 TB transfer, live RAM up to 64.0 GB, 3 VMs excluded"; "" if unlimited
+func (c *MigrationConstraints) BudgetSummary() string {
+	var parts []string
+	if c.MaxMigrations != nil {
+		parts = append(parts, fmt.Sprintf("at most %d migrations", *c.MaxMigrations))
+	}
+	if c.MaxTransfer != nil {
+		parts = append(parts, proxmox.FormatBytes(*c.MaxTransfer)+" transfer")
+	}
+	if c.MaxLiveRAM != nil {
+		parts = append(parts, "live RAM up to "+proxmox.FormatBytes(*c.MaxLiveRAM))
+	}
+	if len(c.ExcludeVMs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d VMs excluded", len(c.ExcludeVMs)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// remainingBudget returns the constraints with the migrations and transfer of
+// the planned suggestions taken off their budget, and the budget they use up
+// ("" if some of every budget is left)
+func remainingBudget(c MigrationConstraints, planned []MigrationSuggestion) (MigrationConstraints, string) {
+	var bytes int64
+	for _, sug := range planned {
+		bytes += sug.Transfer
+	}
+	if c.MaxMigrations != nil {
+		left := *c.MaxMigrations - len(planned)
+		if left <= 0 {
+			return c, BudgetMigrations
+		}
+		c.MaxMigrations = &left
+	}
+	if c.MaxTransfer != nil {
+		left := *c.MaxTransfer - bytes
+		if left <= 0 {
+			return c, BudgetTransfer
+		}
+		c.MaxTransfer = &left
+	}
+	return c, ""
+}
+
+// balanceLimits tracks the budget of MigrationConstraints while a balance picks migrations
+type balanceLimits struct {
+	maxMigrations int
+	maxBytes      int64
+	maxLiveRAM    int64
+	excluded      map[int]bool
+	excludedNodes map[string]bool // Nodes that must not receive VMs
+
+	charged    map[int]bool // VMs whose migration the budget already paid for
+	migrations int
+	bytes      int64
+	exhausted  string
+	blocked    bool // The last search skipped candidates over the transfer budget
+}
+
+// newBalanceLimits reads the budget from the constraints
+func newBalanceLimits(c MigrationConstraints) *balanceLimits {
+	l := &balanceLimits{excluded: make(map[int]bool), excludedNodes: make(map[string]bool), charged: make(map[int]bool)}
+	if c.MaxMigrations != nil {
+		l.maxMigrations = *c.MaxMigrations
+	}
+	if c.MaxTransfer != nil {
+		l.maxBytes = *c.MaxTransfer
+	}
+	if c.MaxLiveRAM != nil {
+		l.maxLiveRAM = *c.MaxLiveRAM
+	}
+	for _, vmid := range c.ExcludeVMs {
+		l.excluded[vmid] = true
+	}
+	for _, name := range c.ExcludeNodes {
+		l.excludedNodes[name] = true
+	}
+	return l
+}
+
+// receives returns false for nodes excluded as migration targets; they can still give VMs away
+func (l *balanceLimits) receives(node string) bool {
+	return !l.excludedNodes[node]
+}
+
+// movable returns false for excluded VMs and for live migrations of VMs with
+// more RAM than the limit; containers restart instead and are not limited
+func (l *balanceLimits) movable(vm proxmox.VM) bool {
+	if l.excluded[vm.VMID] {
+		return false
+	}
+	return l.maxLiveRAM <= 0 || LiveMemoryBytes(vm) == 0 || vm.MaxMem <= l.maxLiveRAM
+}
+
+// fits returns the budget n more migrations copying bytes would exceed, "" if they fit
+func (l *balanceLimits) fits(n int, bytes int64) string {
+	if l.maxMigrations > 0 && l.migrations+n > l.maxMigrations {
+		return BudgetMigrations
+	}
+	if l.maxBytes > 0 && l.bytes+bytes > l.maxBytes {
+		return BudgetTransfer
+	}
+	return ""
+}
+
+// take spends the budget of migrating the VMs if it fits, and records the
+// budget that ran out otherwise. VMs already paid for cost nothing again.
+func (l *balanceLimits) take(vms ...proxmox.VM) bool {
+	n, bytes := 0, int64(0)
+	for _, vm := range vms {
+		if !l.charged[vm.VMID] {
+			n++
+			bytes += EstimateTransferBytes(vm)
+		}
+	}
+	if budget := l.fits(n, bytes); budget != "" {
+		l.exhaust(budget)
+		return false
+	}
+	for _, vm := range vms {
+		l.charged[vm.VMID] = true
+	}
+	l.migrations += n
+	l.bytes += bytes
+	return true
+}
+
+// exhaust records a budget that ruled out a migration; the migration count wins
+func (l *balanceLimits) exhaust(budget string) {
+	if l.exhausted != BudgetMigrations {
+		l.exhausted = budget
+	}
+}
+
+// report fills in the budget part of a BalanceBudget for the given nodes
+func (l *balanceLimits) report(nodes []proxmox.Node) *BalanceBudget {
+	b := &BalanceBudget{
+		Migrations:    l.migrations,
+		MaxMigrations: l.maxMigrations,
+		Bytes:         l.bytes,
+		MaxBytes:      l.maxBytes,
+		MaxLiveRAM:    l.maxLiveRAM,
+		Exhausted:     l.exhausted,
+	}
+	for _, node := range nodes {
+		for _, vm := range node.VMs {
+			switch {
+			case vm.NoMigrate || vm.Status != "running":
+			case l.excluded[vm.VMID]:
+				b.ExcludedVMs++
+			case !l.movable(vm):
+				b.TooLargeVMs++
+			}
+		}
+	}
+	return b
+}
@@ -0,0 +1,236 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+const testGiB = int64(1024 * 1024 * 1024)
+
+// budgetTestVM is a running VM that copies disk + ram GiB when it migrates
+func budgetTestVM(vmid int, vmType string, disk, ram int64) proxmox.VM {
+	return proxmox.VM{VMID: vmid, Type: vmType, Status: "running", MaxMem: ram * testGiB, UsedDisk: disk * testGiB}
+}
+
+func TestBalanceLimitsMovable(t *testing.T) {
+	maxLiveRAM := 16 * testGiB
+	limits := newBalanceLimits(MigrationConstraints{MaxLiveRAM: &maxLiveRAM, ExcludeVMs: []int{100}})
+
+	stopped := budgetTestVM(104, "qemu", 10, 64)
+	stopped.Status = "stopped"
+	tests := []struct {
+		name string
+		vm   proxmox.VM
+		want bool
+	}{
+		{"excluded", budgetTestVM(100, "qemu", 10, 4), false},
+		{"small VM", budgetTestVM(101, "qemu", 10, 4), true},
+		{"at the live RAM limit", budgetTestVM(102, "qemu", 10, 16), true},
+		{"above the live RAM limit", budgetTestVM(103, "qemu", 10, 64), false},
+		{"stopped above the limit", stopped, true},
+		{"container above the limit", budgetTestVM(105, "lxc", 10, 64), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limits.movable(tt.vm); got != tt.want {
+				t.Errorf("movable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if unlimited := newBalanceLimits(MigrationConstraints{}); !unlimited.movable(budgetTestVM(103, "qemu", 10, 64)) {
+		t.Error("a VM is not movable without a live RAM limit")
+	}
+}
+
+func TestBalanceLimitsTake(t *testing.T) {
+	a := budgetTestVM(1, "qemu", 10, 2)  // 12 GiB
+	b := budgetTestVM(2, "qemu", 20, 4)  // 24 GiB
+	c := budgetTestVM(3, "lxc", 30, 8)   // 30 GiB, containers copy no RAM
+	d := budgetTestVM(4, "qemu", 100, 4) // 104 GiB
+
+	tests := []struct {
+		name          string
+		maxMigrations int
+		maxTransfer   int64 // GiB
+		takes         [][]proxmox.VM
+		want          []bool
+		migrations    int
+		bytes         int64 // GiB
+		exhausted     string
+	}{
+		{"unlimited", 0, 0, [][]proxmox.VM{{a}, {b, c}, {d}}, []bool{true, true, true}, 4, 170, ""},
+		{"migration budget", 2, 0, [][]proxmox.VM{{a}, {b, c}, {b}}, []bool{true, false, true}, 2, 36, BudgetMigrations},
+		{"transfer budget", 0, 50, [][]proxmox.VM{{a}, {d}, {b}}, []bool{true, false, true}, 2, 36, BudgetTransfer},
+		{"both budgets, migrations win", 1, 20, [][]proxmox.VM{{a}, {d}, {b}}, []bool{true, false, false}, 1, 12, BudgetMigrations},
+		{"a VM is paid for once", 2, 0, [][]proxmox.VM{{a, b}, {a, b}, {a}}, []bool{true, true, true}, 2, 36, ""},
+		{"a swap pays for its new VM only", 2, 0, [][]proxmox.VM{{a}, {a, b}, {a, c}}, []bool{true, true, false}, 2, 36, BudgetMigrations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := MigrationConstraints{}
+			if tt.maxMigrations > 0 {
+				constraints.MaxMigrations = &tt.maxMigrations
+			}
+			if tt.maxTransfer > 0 {
+				bytes := tt.maxTransfer * testGiB
+				constraints.MaxTransfer = &bytes
+			}
+			limits := newBalanceLimits(constraints)
+			for i, vms := range tt.takes {
+				if got := limits.take(vms...); got != tt.want[i] {
+					t.Errorf("take #%d = %v, want %v", i+1, got, tt.want[i])
+				}
+			}
+			if limits.migrations != tt.migrations || limits.bytes != tt.bytes*testGiB {
+				t.Errorf("spent %d migrations and %d GiB, want %d and %d GiB", limits.migrations, limits.bytes/testGiB, tt.migrations, tt.bytes)
+			}
+			if limits.exhausted != tt.exhausted {
+				t.Errorf("exhausted %q, want %q", limits.exhausted, tt.exhausted)
+			}
+		})
+	}
+}
+
+func TestBalanceLimitsReport(t *testing.T) {
+	maxLiveRAM := 16 * testGiB
+	limits := newBalanceLimits(MigrationConstraints{MaxLiveRAM: &maxLiveRAM, ExcludeVMs: []int{1, 2}})
+
+	pinned := budgetTestVM(5, "qemu", 10, 64)
+	pinned.NoMigrate = true
+	stopped := budgetTestVM(2, "qemu", 10, 4)
+	stopped.Status = "stopped"
+	nodes := []proxmox.Node{
+		{Name: "pve1", VMs: []proxmox.VM{budgetTestVM(1, "qemu", 10, 4), stopped, budgetTestVM(3, "qemu", 10, 64)}},
+		{Name: "pve2", VMs: []proxmox.VM{budgetTestVM(4, "lxc", 10, 64), pinned, budgetTestVM(6, "qemu", 10, 32)}},
+	}
+
+	b := limits.report(nodes)
+	// Stopped, nomigrate and container VMs aren't held back by the budget
+	if b.ExcludedVMs != 1 || b.TooLargeVMs != 2 {
+		t.Errorf("%d excluded and %d too large VMs, want 1 and 2", b.ExcludedVMs, b.TooLargeVMs)
+	}
+	if want := "1 VMs excluded, 2 VMs above the 16.0 GB live migration limit"; b.Restrictions() != want {
+		t.Errorf("Restrictions() = %q, want %q", b.Restrictions(), want)
+	}
+}
+
+func TestAnalyzeClusterWideBalanceFixtures(t *testing.T) {
+	for _, tc := range fixtureCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := collectFixture(t, tc.opts)
+			result, err := AnalyzeClusterWideBalance(cluster, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeClusterWideBalance: %v", err)
+			}
+			checkSuggestions(t, cluster, result)
+
+			placed := 0
+			for _, sug := range result.Suggestions {
+				if sug.TargetNode != "NONE" {
+					placed++
+				}
+			}
+			if result.Budget == nil {
+				t.Fatal("no balance budget reported")
+			}
+			if result.Budget.Migrations != placed {
+				t.Errorf("budget counts %d migrations, plan has %d", result.Budget.Migrations, placed)
+			}
+			if result.Budget.ScoreAfter > result.Budget.ScoreBefore {
+				t.Errorf("imbalance rose from %.1f to %.1f", result.Budget.ScoreBefore, result.Budget.ScoreAfter)
+			}
+		})
+	}
+}
+
+func TestAnalyzeClusterWideBalanceBudget(t *testing.T) {
+	cluster := collectFixture(t, proxmox.FixtureOptions{Nodes: 5, VMs: 120, Skew: 0.5, Seed: 2})
+	tests := []struct {
+		name          string
+		maxMigrations int
+		maxTransfer   int64 // GiB
+		excludeVMs    []int
+	}{
+		{"one migration", 1, 0, nil},
+		{"five migrations", 5, 0, nil},
+		{"transfer budget", 0, 500, nil},
+		{"excluded VMs", 0, 0, []int{cluster.Nodes[0].VMs[0].VMID, cluster.Nodes[0].VMs[1].VMID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := MigrationConstraints{BalanceCluster: true, ExcludeVMs: tt.excludeVMs}
+			if tt.maxMigrations > 0 {
+				constraints.MaxMigrations = &tt.maxMigrations
+			}
+			if tt.maxTransfer > 0 {
+				bytes := tt.maxTransfer * testGiB
+				constraints.MaxTransfer = &bytes
+			}
+			result, err := AnalyzeClusterWideBalanceWithConstraints(cluster, constraints, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeClusterWideBalanceWithConstraints: %v", err)
+			}
+			checkSuggestions(t, cluster, result)
+			if tt.maxMigrations > 0 && len(result.Suggestions) > tt.maxMigrations {
+				t.Errorf("%d migrations over a budget of %d", len(result.Suggestions), tt.maxMigrations)
+			}
+			var bytes int64
+			for _, sug := range result.Suggestions {
+				bytes += sug.Transfer
+				for _, vmid := range tt.excludeVMs {
+					if sug.VMID == vmid {
+						t.Errorf("excluded VM %d is migrated", vmid)
+					}
+				}
+			}
+			if tt.maxTransfer > 0 && bytes > tt.maxTransfer*testGiB {
+				t.Errorf("%d GiB transferred over a budget of %d GiB", bytes/testGiB, tt.maxTransfer)
+			}
+			if result.Budget.Bytes != bytes {
+				t.Errorf("budget counts %d bytes, plan copies %d", result.Budget.Bytes, bytes)
+			}
+		})
+	}
+}
+
+func TestAnalyzeClusterWideBalanceExcludeNodes(t *testing.T) {
+	cluster := collectFixture(t, proxmox.FixtureOptions{Nodes: 5, VMs: 120, Skew: 0.5, Seed: 2})
+	// The emptiest nodes would receive most VMs
+	excluded := []string{cluster.Nodes[2].Name, cluster.Nodes[4].Name}
+	constraints := MigrationConstraints{BalanceCluster: true, ExcludeNodes: excluded}
+	settings := OptimizerSettings{Enabled: true, TimeBudgetSeconds: 0.5}
+
+	tests := []struct {
+		name    string
+		analyze func() (*AnalysisResult, error)
+	}{
+		{"greedy", func() (*AnalysisResult, error) {
+			return AnalyzeClusterWideBalanceWithConstraints(cluster, constraints, nil)
+		}},
+		{"optimizer", func() (*AnalysisResult, error) {
+			return AnalyzeClusterWideOptimizedWithConstraints(cluster, constraints, settings, nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.analyze()
+			if err != nil {
+				t.Fatalf("analysis failed: %v", err)
+			}
+			checkSuggestions(t, cluster, result)
+			for _, sug := range result.Suggestions {
+				for _, name := range excluded {
+					if sug.TargetNode == name {
+						t.Errorf("VM %d moves to excluded node %s", sug.VMID, name)
+					}
+				}
+			}
+		})
+	}
+}
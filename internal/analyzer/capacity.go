@@ -0,0 +1,301 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// maxCapacityVMsPerNode stops counting on hosts with (nearly) unlimited room
+const maxCapacityVMsPerNode = 10000
+
+// VMTemplate is the shape of the VMs to plan capacity for
+type VMTemplate struct {
+	VCPUs    int
+	RAM      int64   // Bytes
+	Disk     int64   // Bytes of local storage
+	CPUUsage float64 // Expected CPU usage in % of the VM's vCPUs (0 = average of the running VMs)
+}
+
+// Validate checks that the template has a size
+func (t VMTemplate) Validate() error {
+	if t.VCPUs < 1 {
+		return &ValidationError{Field: "vcpus", Message: "must be at least 1"}
+	}
+	if t.RAM <= 0 {
+		return &ValidationError{Field: "ram", Message: "must be greater than 0"}
+	}
+	if t.Disk < 0 {
+		return &ValidationError{Field: "disk", Message: "must not be negative"}
+	}
+	if t.CPUUsage < 0 || t.CPUUsage > 100 {
+		return &ValidationError{Field: "cpu", Message: "must be between 0 and 100"}
+	}
+	return nil
+}
+
+// String describes the template, e.g. "4 vCPUs at 20% CPU, 8.0 GB RAM, 100.0 GB disk"
+func (t VMTemplate) String() string {
+	return fmt.Sprintf("%s at %.0f%% CPU, %s RAM, %s disk",
+		plural(t.VCPUs, "vCPU", "vCPUs"), t.CPUUsage, proxmox.FormatBytes(t.RAM), proxmox.FormatBytes(t.Disk))
+}
+
+// vm returns a running VM of the template's shape
+func (t VMTemplate) vm(vmid int) proxmox.VM {
+	return proxmox.VM{
+		VMID:     vmid,
+		Name:     fmt.Sprintf("new-%d", -vmid),
+		Status:   "running",
+		Type:     "qemu",
+		CPUCores: t.VCPUs,
+		CPUUsage: t.CPUUsage,
+		MaxMem:   t.RAM,
+		MaxDisk:  t.Disk,
+	}
+}
+
+// NodeCapacity is how many template VMs one node can still take
+type NodeCapacity struct {
+	Node     string
+	Eligible bool   // Node may receive new VMs at all
+	Fits     int    // Template VMs that fit within the hard limits
+	Limit    string // What stops the next VM, or why the node isn't eligible
+	Before   NodeState
+	After    NodeState // Once Fits VMs are added
+}
+
+// CapacityPlan is the remaining room for VMs of one template
+type CapacityPlan struct {
+	Template VMTemplate // CPUUsage resolved to the value used
+	Nodes    []NodeCapacity
+	Total    int
+
+	// True if only provisioning hosts (P flag) are counted; false when the cluster has none
+	ProvisioningOnly bool
+}
+
+// AnalyzeCapacity counts how many VMs of the template fit on each node within
+// the hard limits of the capacity policy (host CPU, RAM, storage, storage
+// headroom and pools). New VMs go to provisioning hosts; clusters without any
+// count every online host. Hosts whose hoststate blocks migrations take none.
+func AnalyzeCapacity(cluster *proxmox.Cluster, policy CapacityPolicy, template VMTemplate) (*CapacityPlan, error) {
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	if template.CPUUsage == 0 {
+		template.CPUUsage = averageVMCPUUsage(cluster)
+	}
+
+	plan := &CapacityPlan{Template: template}
+	for _, node := range cluster.Nodes {
+		if node.AllowProvisioning {
+			plan.ProvisioningOnly = true
+			break
+		}
+	}
+
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		state := newSimulatedNodeState(node, policy)
+		nc := NodeCapacity{Node: node.Name, Before: state.toNodeState()}
+		switch {
+		case node.Status != "online":
+			nc.Limit = "Offline"
+		case node.IsMigrationBlocked():
+			nc.Limit = fmt.Sprintf("hoststate=%d", node.HostState)
+		case plan.ProvisioningOnly && !node.AllowProvisioning:
+			nc.Limit = "Not a provisioning host (no P flag)"
+		default:
+			nc.Eligible = true
+			for nc.Fits < maxCapacityVMsPerNode {
+				vm := template.vm(-(nc.Fits + 1))
+				if reason := state.hardLimitViolation(&vm); reason != "" {
+					nc.Limit = reason
+					break
+				}
+				state.addVM(vm)
+				nc.Fits++
+			}
+		}
+		nc.After = state.toNodeState()
+		plan.Nodes = append(plan.Nodes, nc)
+		plan.Total += nc.Fits
+	}
+
+	sort.Slice(plan.Nodes, func(i, j int) bool { return plan.Nodes[i].Node < plan.Nodes[j].Node })
+	return plan, nil
+}
+
+// addVM adds a new VM to the simulated node, its disks on the best pools
+func (s *simulatedNodeState) addVM(vm proxmox.VM) {
+	s.pools = applyDiskPlacement(s.pools, s.placeDisks(vm))
+	s.vms[vm.VMID] = vm
+	s.vcpus += vm.CPUCores
+	s.ramUsed += vm.MaxMem
+	s.storageUsed += vm.GetLocalDisk()
+	s.vmCount++
+	s.vmCPUSum += vm.CPUUsage * float64(vm.CPUCores)
+}
+
+// averageVMCPUUsage returns the mean CPU usage of the running VMs
+func averageVMCPUUsage(cluster *proxmox.Cluster) float64 {
+	var sum float64
+	var count int
+	for _, node := range cluster.Nodes {
+		for _, vm := range node.VMs {
+			if vm.Status == "running" {
+				sum += vm.CPUUsage
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// HostSpec is the hardware of a host that could be added to the cluster
+type HostSpec struct {
+	CPUCores int
+	RAM      int64 // Bytes
+	Storage  int64 // Bytes
+}
+
+// Validate checks that the host has cores and RAM
+func (h HostSpec) Validate() error {
+	if h.CPUCores < 1 {
+		return &ValidationError{Field: "host_cores", Message: "must be at least 1"}
+	}
+	if h.RAM <= 0 {
+		return &ValidationError{Field: "host_ram", Message: "must be greater than 0"}
+	}
+	if h.Storage < 0 {
+		return &ValidationError{Field: "host_storage", Message: "must not be negative"}
+	}
+	return nil
+}
+
+// ClusterUsage is the cluster-wide average utilization (used / total over the online hosts)
+type ClusterUsage struct {
+	HostCPUPercent float64 // Estimated from the VMs' CPU usage
+	RAMPercent     float64 // Allocated VM RAM
+	StoragePercent float64 // Local VM disks
+}
+
+// Max returns the highest of the three averages
+func (u ClusterUsage) Max() float64 {
+	return math.Max(u.HostCPUPercent, math.Max(u.RAMPercent, u.StoragePercent))
+}
+
+// HostsNeeded answers how many hosts of a spec bring the cluster average down to a target
+type HostsNeeded struct {
+	Spec          HostSpec
+	TargetPercent float64
+	AddedVMs      int // Template VMs assumed on top of today's load
+	Hosts         int
+	Limit         string // Resource that needs the most hosts ("" if the target is already met)
+	Before        ClusterUsage
+	After         ClusterUsage // With the added VMs and hosts
+}
+
+// clusterLoad sums the VM load and capacity of the online hosts, like calculateClusterMetrics
+type clusterLoad struct {
+	cpuBusy      float64 // Sum of VM CPU usage × vCPUs (percent of one core)
+	cores        int
+	ramUsed      int64
+	ramTotal     int64
+	storageUsed  int64
+	storageTotal int64
+}
+
+func (l clusterLoad) usage() ClusterUsage {
+	var u ClusterUsage
+	if l.cores > 0 {
+		u.HostCPUPercent = l.cpuBusy / float64(l.cores)
+	}
+	if l.ramTotal > 0 {
+		u.RAMPercent = float64(l.ramUsed) / float64(l.ramTotal) * 100
+	}
+	if l.storageTotal > 0 {
+		u.StoragePercent = float64(l.storageUsed) / float64(l.storageTotal) * 100
+	}
+	return u
+}
+
+// HostsForTarget returns how many hosts of the spec must be added so that the
+// average host CPU, RAM and storage utilization over the online hosts is at
+// most targetPercent, after adding addVMs VMs of the template (0 = none).
+func HostsForTarget(cluster *proxmox.Cluster, template VMTemplate, addVMs int, spec HostSpec, targetPercent float64) (*HostsNeeded, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if targetPercent <= 0 || targetPercent > 100 {
+		return nil, &ValidationError{Field: "target", Message: "must be between 0 and 100"}
+	}
+	if addVMs < 0 {
+		return nil, &ValidationError{Field: "add_vms", Message: "must not be negative"}
+	}
+	if addVMs > 0 {
+		if err := template.Validate(); err != nil {
+			return nil, err
+		}
+		if template.CPUUsage == 0 {
+			template.CPUUsage = averageVMCPUUsage(cluster)
+		}
+	}
+
+	var load clusterLoad
+	for _, node := range cluster.Nodes {
+		if node.Status != "online" {
+			continue
+		}
+		load.cores += node.CPUCores
+		load.ramTotal += node.MaxMem
+		load.storageTotal += node.MaxDisk
+		for _, vm := range node.VMs {
+			load.cpuBusy += vm.CPUUsage * float64(vm.CPUCores)
+			load.ramUsed += vm.MaxMem
+			load.storageUsed += vm.GetLocalDisk()
+		}
+	}
+	result := &HostsNeeded{Spec: spec, TargetPercent: targetPercent, AddedVMs: addVMs, Before: load.usage()}
+
+	load.cpuBusy += float64(addVMs) * template.CPUUsage * float64(template.VCPUs)
+	load.ramUsed += int64(addVMs) * template.RAM
+	load.storageUsed += int64(addVMs) * template.Disk
+
+	// Capacity needed for used/total <= target, minus what the cluster has, in hosts
+	resources := []struct {
+		name      string
+		used      float64
+		have      float64
+		perHost   float64
+		unlimited bool
+	}{
+		{"Host CPU", load.cpuBusy / 100, float64(load.cores), float64(spec.CPUCores), false},
+		{"RAM", float64(load.ramUsed), float64(load.ramTotal), float64(spec.RAM), false},
+		{"Storage", float64(load.storageUsed), float64(load.storageTotal), float64(spec.Storage), spec.Storage == 0},
+	}
+	for _, r := range resources {
+		missing := r.used*100/targetPercent - r.have
+		if missing <= 0 {
+			continue
+		}
+		if r.unlimited {
+			return nil, fmt.Errorf("storage average stays above %.0f%% with hosts without storage", targetPercent)
+		}
+		if hosts := int(math.Ceil(missing/r.perHost - 1e-9)); hosts > result.Hosts {
+			result.Hosts = hosts
+			result.Limit = r.name
+		}
+	}
+
+	load.cores += result.Hosts * spec.CPUCores
+	load.ramTotal += int64(result.Hosts) * spec.RAM
+	load.storageTotal += int64(result.Hosts) * spec.Storage
+	result.After = load.usage()
+	return result, nil
+}
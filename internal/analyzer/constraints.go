@@ -5,21 +5,34 @@ type MigrationConstraints struct {
 	SourceNode string
 
 	// Migration criteria (one or more can be set)
-	VMCount        *int     // migrate N VMs
-	VCPUCount      *int     // migrate VMs totaling N vCPUs
-	CPUUsage       *float64 // migrate VMs using N% CPU (uses efficiency algorithm)
-	RAMAmount      *int64   // migrate VMs using N GB RAM (in bytes)
-	StorageAmount  *int64   // migrate VMs using N GB storage (in bytes)
-	SpecificVMs    []int    // migrate these specific VMIDs
-	MigrateAll     bool     // migrate all VMs from host, spread across cluster
-	CreationAge    *int     // migrate VMs older than N days (based on ctime)
-	BalanceCluster bool     // migrate VMs to bring host CPU/RAM to cluster average with minimum moves
+	VMCount         *int     // migrate N VMs
+	VCPUCount       *int     // migrate VMs totaling N vCPUs
+	CPUUsage        *float64 // migrate VMs using N% CPU (uses efficiency algorithm)
+	RAMAmount       *int64   // migrate VMs using N GB RAM (in bytes)
+	StorageAmount   *int64   // migrate VMs using N GB storage (in bytes)
+	SpecificVMs     []int    // migrate these specific VMIDs
+	MigrateAll      bool     // migrate all VMs from host, spread across cluster
+	CreationAge     *int     // migrate VMs older than N days (based on ctime)
+	BalanceCluster  bool     // migrate VMs to bring host CPU/RAM to cluster average with minimum moves
+	ConstraintAudit bool     // repair placement constraint violations anywhere in the cluster (no source)
 
 	// Additional constraints
 	ExcludeNodes  []string // don't migrate to these nodes
 	MaxVMsPerHost *int     // limit VMs per target host
 	MinCPUFree    *float64 // require at least N% CPU free on target
 	MinRAMFree    *int64   // require at least N bytes RAM free on target
+
+	// Budget for cluster-wide balance (nil/empty = unlimited)
+	MaxMigrations *int   // at most N migrations
+	MaxTransfer   *int64 // at most N bytes copied by all migrations (local disks + live RAM)
+	MaxLiveRAM    *int64 // don't live-migrate VMs with more than N bytes RAM (containers restart and aren't limited)
+	ExcludeVMs    []int  // never migrate these VMIDs
+
+	// Capacity limits for targets (nil = DefaultCapacityPolicy); node ConfigMeta can override them per host
+	Policy *CapacityPolicy
+
+	// Ranks the targets that pass the hard checks (nil = DefaultStrategy)
+	Strategy PlacementStrategy
 }
 
 // MigrationMode represents the type of migration strategy
@@ -32,9 +45,10 @@ const (
 	ModeRAM
 	ModeStorage
 	ModeSpecific
-	ModeAll            // Migrate all VMs from host, spread across cluster below average
-	ModeCreationDate   // Migrate VMs older than N days based on creation time
-	ModeBalanceCluster // Balance cluster: migrate VMs until host reaches cluster average with minimum moves
+	ModeAll             // Migrate all VMs from host, spread across cluster below average
+	ModeCreationDate    // Migrate VMs older than N days based on creation time
+	ModeBalanceCluster  // Balance cluster: migrate VMs until host reaches cluster average with minimum moves
+	ModeConstraintAudit // Constraint audit: find broken placement rules cluster-wide and repair them
 )
 
 // String returns the string representation of a MigrationMode
@@ -58,11 +72,23 @@ func (m MigrationMode) String() string {
 		return "creation_date"
 	case ModeBalanceCluster:
 		return "balance_cluster"
+	case ModeConstraintAudit:
+		return "constraint_audit"
 	default:
 		return "unknown"
 	}
 }
 
+// ParseMigrationMode converts a mode name (as returned by String) to a MigrationMode
+func ParseMigrationMode(name string) (MigrationMode, error) {
+	for m := ModeVMCount; m <= ModeConstraintAudit; m++ {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return ModeVMCount, &ValidationError{Field: "mode", Message: "unknown migration mode " + name}
+}
+
 // GetMode returns the migration mode based on what's set in constraints
 func (c *MigrationConstraints) GetMode() MigrationMode {
 	if c.MigrateAll {
@@ -71,6 +97,9 @@ func (c *MigrationConstraints) GetMode() MigrationMode {
 	if c.BalanceCluster {
 		return ModeBalanceCluster
 	}
+	if c.ConstraintAudit {
+		return ModeConstraintAudit
+	}
 	if len(c.SpecificVMs) > 0 {
 		return ModeSpecific
 	}
@@ -95,6 +124,22 @@ func (c *MigrationConstraints) GetMode() MigrationMode {
 	return ModeVMCount // default
 }
 
+// GetPolicy returns the capacity policy, falling back to DefaultCapacityPolicy
+func (c *MigrationConstraints) GetPolicy() CapacityPolicy {
+	if c.Policy != nil {
+		return *c.Policy
+	}
+	return DefaultCapacityPolicy
+}
+
+// GetStrategy returns the placement strategy, or the default one if none is set
+func (c *MigrationConstraints) GetStrategy() PlacementStrategy {
+	if c.Strategy != nil {
+		return c.Strategy
+	}
+	return DefaultStrategy
+}
+
 // Validate checks if the constraints are valid
 func (c *MigrationConstraints) Validate() error {
 	if c.SourceNode == "" {
@@ -139,6 +184,20 @@ func (c *MigrationConstraints) Validate() error {
 		return &ValidationError{Field: "CreationAge", Message: "must be greater than 0"}
 	}
 
+	return c.ValidateBudget()
+}
+
+// ValidateBudget checks the cluster-wide balance budget
+func (c *MigrationConstraints) ValidateBudget() error {
+	if c.MaxMigrations != nil && *c.MaxMigrations <= 0 {
+		return &ValidationError{Field: "MaxMigrations", Message: "must be greater than 0"}
+	}
+	if c.MaxTransfer != nil && *c.MaxTransfer <= 0 {
+		return &ValidationError{Field: "MaxTransfer", Message: "must be greater than 0"}
+	}
+	if c.MaxLiveRAM != nil && *c.MaxLiveRAM <= 0 {
+		return &ValidationError{Field: "MaxLiveRAM", Message: "must be greater than 0"}
+	}
 	return nil
 }
 
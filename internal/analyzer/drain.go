@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// DrainInfo marks a result as one half of a host drain
+type DrainInfo struct {
+	Node      string
+	Return    bool // Moves the VMs back to Node after maintenance
+	HostState int  // Node's hoststate when the drain was planned (-1 = not set)
+}
+
+// DrainPlan empties a host for maintenance and records how to move its VMs back
+type DrainPlan struct {
+	Node     string
+	Evacuate *AnalysisResult // Migrate-all plan off the host
+	Return   *AnalysisResult // Every evacuated VM back to the host, on its original storages
+	Pinned   []proxmox.VM    // nomigrate VMs that stay on the host
+}
+
+// Complete returns true if every VM except the pinned ones leaves the host
+func (d *DrainPlan) Complete() bool {
+	return len(d.Evacuate.UnmigrateableVMs) == len(d.Pinned)
+}
+
+// AnalyzeDrain plans the evacuation of constraints.SourceNode like ModeAll and
+// the return plan that restores the original placement afterwards. nomigrate
+// VMs stay on the host and are listed in Pinned.
+func AnalyzeDrain(cluster *proxmox.Cluster, constraints MigrationConstraints) (*DrainPlan, error) {
+	node := proxmox.GetNodeByName(cluster, constraints.SourceNode)
+	if node == nil {
+		return nil, fmt.Errorf("source node %s not found", constraints.SourceNode)
+	}
+	constraints.MigrateAll = true
+
+	evacuate, err := Analyze(cluster, constraints)
+	if err != nil {
+		return nil, err
+	}
+	evacuate.Drain = &DrainInfo{Node: node.Name, HostState: node.HostState}
+
+	plan := &DrainPlan{Node: node.Name, Evacuate: evacuate}
+	for _, vm := range node.VMs {
+		if vm.NoMigrate {
+			plan.Pinned = append(plan.Pinned, vm)
+		}
+	}
+	plan.Return = buildReturnPlan(cluster, node, evacuate)
+	return plan, nil
+}
+
+// buildReturnPlan reverses the evacuation's migrations. Its waves and node
+// states start from the cluster as it is once the evacuation has run.
+func buildReturnPlan(cluster *proxmox.Cluster, node *proxmox.Node, evacuate *AnalysisResult) *AnalysisResult {
+	var moved []MigrationSuggestion
+	for _, sug := range evacuate.Suggestions {
+		if sug.TargetNode != "" && sug.TargetNode != "NONE" {
+			moved = append(moved, sug)
+		}
+	}
+	drained := clusterAfterMigrations(cluster, moved)
+
+	result := &AnalysisResult{
+		TargetsBefore:    make(map[string]NodeState),
+		TargetsAfter:     make(map[string]NodeState),
+		IsBalanceCluster: true, // VMs come from several hosts, no single source
+		ImprovementInfo:  fmt.Sprintf("Return %s to %s after maintenance", plural(len(moved), "VM", "VMs"), node.Name),
+
+		Constraints:        MigrationConstraints{Policy: evacuate.Constraints.Policy},
+		ClusterCollectedAt: cluster.CollectedAt,
+		Drain:              &DrainInfo{Node: node.Name, Return: true, HostState: node.HostState},
+	}
+
+	for _, sug := range moved {
+		back := sug
+		back.SourceNode, back.TargetNode = sug.TargetNode, sug.SourceNode
+		back.SourceCores, back.TargetCores = sug.TargetCores, sug.SourceCores
+		back.Reason = fmt.Sprintf("Return from %s after maintenance of %s", sug.TargetNode, node.Name)
+		back.Details = &MigrationDetails{
+			SelectionMode:   "drain_return",
+			SelectionReason: "Restores the placement before the drain",
+		}
+		if len(sug.Disks) > 0 {
+			back.Disks = make([]DiskPlacement, len(sug.Disks))
+			for i, d := range sug.Disks {
+				d.SourceStorage, d.TargetStorage = d.TargetStorage, d.SourceStorage
+				back.Disks[i] = d
+			}
+		}
+		result.Suggestions = append(result.Suggestions, back)
+
+		result.TotalVMs++
+		result.TotalVCPUs += sug.VCPUs
+		result.TotalRAM += sug.RAM
+		result.TotalStorage += sug.Storage
+	}
+
+	// Node impact is the evacuation's, the other way round
+	if evacuate.SourceBefore.Name != "" {
+		result.TargetsBefore[node.Name] = evacuate.SourceAfter
+		result.TargetsAfter[node.Name] = evacuate.SourceBefore
+	}
+	for name, after := range evacuate.TargetsAfter {
+		result.TargetsBefore[name] = after
+		result.TargetsAfter[name] = evacuate.TargetsBefore[name]
+	}
+
+	policy := evacuate.Constraints.GetPolicy()
+	result.Staged = BuildStagedPlan(drained, result.Suggestions, policy)
+	result.AffinityViolations = FindAffinityViolations(drained, ResolveAffinityGroups(drained, policy.AffinityGroups), PlannedLocations(result.Suggestions))
+	return result
+}
+
+// clusterAfterMigrations returns a copy of the cluster with the migrations
+// applied: VMs on their targets and node usage and storage pools updated
+func clusterAfterMigrations(cluster *proxmox.Cluster, suggestions []MigrationSuggestion) *proxmox.Cluster {
+	after := *cluster
+	after.Nodes = make([]proxmox.Node, len(cluster.Nodes))
+	index := make(map[string]int, len(cluster.Nodes))
+	for i, node := range cluster.Nodes {
+		node.VMs = append([]proxmox.VM(nil), node.VMs...)
+		after.Nodes[i] = node
+		index[node.Name] = i
+	}
+
+	for i := range suggestions {
+		sug := &suggestions[i]
+		si, ok := index[sug.SourceNode]
+		ti, ok2 := index[sug.TargetNode]
+		if !ok || !ok2 {
+			continue
+		}
+		source, target := &after.Nodes[si], &after.Nodes[ti]
+		for j, vm := range source.VMs {
+			if vm.VMID != sug.VMID {
+				continue
+			}
+			source.VMs = append(source.VMs[:j], source.VMs[j+1:]...)
+			adjustNodeUsage(source, vm, -1)
+			source.Storages = releaseDisks(source.Storages, vm)
+
+			vm = movedVM(vm, sug)
+			target.VMs = append(target.VMs, vm)
+			adjustNodeUsage(target, vm, 1)
+			target.Storages = applyDiskPlacement(target.Storages, sug.Disks)
+			break
+		}
+	}
+	return &after
+}
+
+// adjustNodeUsage adds (sign 1) or removes (sign -1) a VM's resources from the node's usage
+func adjustNodeUsage(node *proxmox.Node, vm proxmox.VM, sign int) {
+	node.UsedDisk = max(node.UsedDisk+int64(sign)*vm.GetLocalDisk(), 0)
+	if vm.Status != "running" {
+		return
+	}
+	node.UsedMem = max(node.UsedMem+int64(sign)*vm.MaxMem, 0)
+	if node.CPUCores > 0 {
+		// Node CPU usage is a fraction of all cores
+		node.CPUUsage = max(node.CPUUsage+float64(sign)*vm.CPUUsage*float64(vm.CPUCores)/100/float64(node.CPUCores), 0)
+	}
+}
@@ -0,0 +1,281 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"time"
+)
+
+// EstimateSettings describes the cluster's migration throughput for time estimates
+type EstimateSettings struct {
+	BandwidthMbps    float64         `json:"bandwidth_mbps" yaml:"bandwidth_mbps"`         // Effective migration bandwidth between nodes (Mbit/s)
+	StorageMBps      float64         `json:"storage_mbps" yaml:"storage_mbps"`             // Local storage read/write throughput (MB/s)
+	DirtyRatePercent float64         `json:"dirty_rate_percent" yaml:"dirty_rate_percent"` // Guest RAM rewritten per second at 100% CPU
+	OverheadSeconds  float64         `json:"overhead_seconds" yaml:"overhead_seconds"`     // Fixed setup and switch-over time per migration
+	Links            []LinkBandwidth `json:"links,omitempty" yaml:"links,omitempty"`       // Bandwidth overrides per node pair, first match wins
+}
+
+// LinkBandwidth overrides the migration bandwidth between two groups of nodes
+// Source and Target are filepath.Match globs on node names; a link matches in both directions
+type LinkBandwidth struct {
+	Source        string  `json:"source" yaml:"source"`
+	Target        string  `json:"target" yaml:"target"`
+	BandwidthMbps float64 `json:"bandwidth_mbps" yaml:"bandwidth_mbps"`
+}
+
+// DefaultEstimateSettings assumes a 10 GbE migration network and SSD-backed local storage
+var DefaultEstimateSettings = EstimateSettings{
+	BandwidthMbps:    10000,
+	StorageMBps:      500,
+	DirtyRatePercent: 2,
+	OverheadSeconds:  15,
+}
+
+// maxPrecopyFactor caps the RAM copied by a live migration at this multiple of the guest's
+// memory. Guests dirtying memory faster than the link can copy it are throttled by QEMU's
+// auto-converge instead of being copied forever.
+const maxPrecopyFactor = 5.0
+
+// Validate checks that the throughput settings are usable
+func (s EstimateSettings) Validate() error {
+	if s.BandwidthMbps <= 0 {
+		return &ValidationError{Field: "bandwidth_mbps", Message: "must be positive"}
+	}
+	if s.StorageMBps <= 0 {
+		return &ValidationError{Field: "storage_mbps", Message: "must be positive"}
+	}
+	if s.DirtyRatePercent < 0 || s.DirtyRatePercent > 100 {
+		return &ValidationError{Field: "dirty_rate_percent", Message: "must be between 0 and 100"}
+	}
+	if s.OverheadSeconds < 0 {
+		return &ValidationError{Field: "overhead_seconds", Message: "must not be negative"}
+	}
+	for i, link := range s.Links {
+		if _, err := filepath.Match(link.Source, ""); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("links[%d].source", i), Message: "bad pattern"}
+		}
+		if _, err := filepath.Match(link.Target, ""); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("links[%d].target", i), Message: "bad pattern"}
+		}
+		if link.BandwidthMbps <= 0 {
+			return &ValidationError{Field: fmt.Sprintf("links[%d].bandwidth_mbps", i), Message: "must be positive"}
+		}
+	}
+	return nil
+}
+
+// Bandwidth returns the migration bandwidth between two nodes in bytes per second
+func (s EstimateSettings) Bandwidth(source, target string) float64 {
+	mbps := s.BandwidthMbps
+	for _, link := range s.Links {
+		if linkMatches(link, source, target) || linkMatches(link, target, source) {
+			mbps = link.BandwidthMbps
+			break
+		}
+	}
+	return mbps * 1e6 / 8
+}
+
+// linkMatches returns true if the link's patterns match the node pair in this direction
+func linkMatches(link LinkBandwidth, source, target string) bool {
+	src, _ := filepath.Match(link.Source, source)
+	dst, _ := filepath.Match(link.Target, target)
+	return src && dst
+}
+
+// MigrationEstimate is the predicted cost of one migration
+type MigrationEstimate struct {
+	VMID        int
+	SourceNode  string
+	TargetNode  string
+	Live        bool          // Running VM migrated online; RAM is copied while it runs
+	DiskBytes   int64         // Local disk data copied
+	MemoryBytes int64         // RAM copied including pre-copy rounds for dirtied pages
+	Converges   bool          // False if the guest dirties RAM faster than the link copies it
+	Duration    time.Duration // Predicted time for this migration alone
+	Start       time.Duration // Predicted start, relative to the start of the plan
+}
+
+// PlanEstimate is the predicted wall-clock time of a whole plan
+type PlanEstimate struct {
+	Migrations []MigrationEstimate // In plan order
+	PerSource  int                 // Concurrency limits the schedule was simulated with (0 = unlimited)
+	PerTarget  int
+	Waves      int           // Waves of a staged plan (0 = flat list)
+	Bytes      int64         // Total data copied
+	Sequential time.Duration // Sum of all migration times (one at a time)
+	WallClock  time.Duration // Predicted time until the last migration finishes
+}
+
+// EstimateMigration predicts how long a single suggested migration takes
+// Local disks are copied at the lower of link and storage throughput. For live
+// migrations the RAM is copied in pre-copy rounds: each round re-sends the pages
+// the guest dirtied meanwhile, estimated from its CPU usage and DirtyRatePercent.
+func (s EstimateSettings) EstimateMigration(sug MigrationSuggestion) MigrationEstimate {
+	est := MigrationEstimate{
+		VMID:       sug.VMID,
+		SourceNode: sug.SourceNode,
+		TargetNode: sug.TargetNode,
+		Live:       sug.LiveMemory > 0,
+		DiskBytes:  sug.Transfer - sug.LiveMemory,
+		Converges:  true,
+	}
+
+	bandwidth := s.Bandwidth(sug.SourceNode, sug.TargetNode)
+	diskRate := math.Min(bandwidth, s.StorageMBps*1e6)
+	seconds := s.OverheadSeconds + float64(est.DiskBytes)/diskRate
+
+	if est.Live {
+		dirtyRate := float64(sug.LiveMemory) * s.DirtyRatePercent / 100 * sug.CPUUsage / 100
+		ratio := dirtyRate / bandwidth
+		factor := maxPrecopyFactor
+		if ratio < 1 {
+			factor = math.Min(1/(1-ratio), maxPrecopyFactor)
+		} else {
+			est.Converges = false
+		}
+		est.MemoryBytes = int64(float64(sug.LiveMemory) * factor)
+		seconds += float64(est.MemoryBytes) / bandwidth
+	}
+
+	est.Duration = time.Duration(seconds * float64(time.Second))
+	return est
+}
+
+// EstimatePlan predicts the wall-clock time of a plan executed with the given
+// concurrency limits (0 = unlimited). The schedule mirrors the executor: every
+// pending migration whose source and target are below their limits starts as
+// soon as a slot frees up, in plan order. Concurrent migrations are assumed not
+// to slow each other down; configure per-link bandwidth for shared uplinks.
+func (s EstimateSettings) EstimatePlan(suggestions []MigrationSuggestion, perSource, perTarget int) *PlanEstimate {
+	plan := &PlanEstimate{PerSource: perSource, PerTarget: perTarget}
+	for _, sug := range suggestions {
+		if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			continue
+		}
+		plan.add(s.EstimateMigration(sug))
+	}
+	plan.WallClock = scheduleMigrations(plan.Migrations, 0, perSource, perTarget)
+	return plan
+}
+
+// EstimateStagedPlan is EstimatePlan for a staged plan: each wave starts once
+// the previous wave has finished
+func (s EstimateSettings) EstimateStagedPlan(staged *StagedPlan, perSource, perTarget int) *PlanEstimate {
+	plan := &PlanEstimate{PerSource: perSource, PerTarget: perTarget, Waves: len(staged.Waves)}
+	for _, wave := range staged.Waves {
+		first := len(plan.Migrations)
+		for _, m := range wave.Migrations {
+			plan.add(s.EstimateMigration(m.MigrationSuggestion))
+		}
+		plan.WallClock = scheduleMigrations(plan.Migrations[first:], plan.WallClock, perSource, perTarget)
+	}
+	return plan
+}
+
+// EstimateResult estimates a result's staged plan, or its flat suggestion list when it has none
+func (s EstimateSettings) EstimateResult(result *AnalysisResult, perSource, perTarget int) *PlanEstimate {
+	if result.Staged != nil {
+		return s.EstimateStagedPlan(result.Staged, perSource, perTarget)
+	}
+	return s.EstimatePlan(result.Suggestions, perSource, perTarget)
+}
+
+// add appends a migration estimate to the plan totals
+func (p *PlanEstimate) add(est MigrationEstimate) {
+	p.Migrations = append(p.Migrations, est)
+	p.Bytes += est.DiskBytes + est.MemoryBytes
+	p.Sequential += est.Duration
+}
+
+// scheduleMigrations sets the start of each migration, beginning at start, and
+// returns when the last one finishes
+func scheduleMigrations(migrations []MigrationEstimate, start time.Duration, perSource, perTarget int) time.Duration {
+	started := make([]bool, len(migrations))
+	ends := make([]time.Duration, len(migrations))
+	now := start
+	remaining := len(migrations)
+	for remaining > 0 {
+		// Count migrations still running at this point in time
+		bySource := make(map[string]int)
+		byTarget := make(map[string]int)
+		for i, est := range migrations {
+			if started[i] && ends[i] > now {
+				bySource[est.SourceNode]++
+				byTarget[est.TargetNode]++
+			}
+		}
+
+		for i := range migrations {
+			est := &migrations[i]
+			if started[i] {
+				continue
+			}
+			if (perSource > 0 && bySource[est.SourceNode] >= perSource) ||
+				(perTarget > 0 && byTarget[est.TargetNode] >= perTarget) {
+				continue
+			}
+			started[i] = true
+			est.Start = now
+			ends[i] = now + est.Duration
+			bySource[est.SourceNode]++
+			byTarget[est.TargetNode]++
+			remaining--
+		}
+
+		// Advance to the next migration that finishes
+		next := time.Duration(math.MaxInt64)
+		for i := range migrations {
+			if started[i] && ends[i] > now && ends[i] < next {
+				next = ends[i]
+			}
+		}
+		if next == time.Duration(math.MaxInt64) {
+			break
+		}
+		now = next
+	}
+
+	end := start
+	for _, e := range ends {
+		if e > end {
+			end = e
+		}
+	}
+	return end
+}
+
+// NonConverging returns the number of live migrations predicted not to converge
+func (p *PlanEstimate) NonConverging() int {
+	n := 0
+	for _, est := range p.Migrations {
+		if !est.Converges {
+			n++
+		}
+	}
+	return n
+}
+
+// VMDuration returns the predicted time of all migrations of a VM in the plan
+func (p *PlanEstimate) VMDuration(vmid int) time.Duration {
+	var d time.Duration
+	for _, est := range p.Migrations {
+		if est.VMID == vmid {
+			d += est.Duration
+		}
+	}
+	return d
+}
+
+// FormatDuration formats an estimated duration compactly, e.g. "45s", "12m05s", "2h05m"
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
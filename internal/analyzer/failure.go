@@ -0,0 +1,238 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// maxFailureScenarios caps the node combinations simulated for K > 1
+const maxFailureScenarios = 500
+
+// FailureScenario is the simulated loss of one or more nodes: their VMs are
+// redistributed like a Migrate All evacuation, within the hard limits
+type FailureScenario struct {
+	Failed      []string              // Nodes lost, sorted by name
+	Suggestions []MigrationSuggestion // Where the lost nodes' VMs restart (TargetNode "NONE" = nowhere)
+	Stranded    []UnmigrateableVM     // VMs with nowhere to go
+	After       map[string]NodeState  // Remaining nodes once the VMs are redistributed
+}
+
+// Survivable returns true if every VM of the failed nodes finds a new host
+func (s FailureScenario) Survivable() bool {
+	return len(s.Stranded) == 0
+}
+
+// Name describes the failed nodes, e.g. "pve1 + pve3"
+func (s FailureScenario) Name() string {
+	return strings.Join(s.Failed, " + ")
+}
+
+// HostWorstCase is the highest utilization a remaining node reaches over all scenarios
+type HostWorstCase struct {
+	Node   string
+	Before NodeState
+	After  NodeState // Highest utilization score after a failure
+	Failed []string  // Scenario that causes it (nil = no failure adds load)
+}
+
+// FailureAnalysis is the result of simulating every combination of K node failures
+type FailureAnalysis struct {
+	K         int
+	Scenarios []FailureScenario // In combination order (nodes by name)
+	Truncated bool              // More than maxFailureScenarios combinations; only the first were simulated
+	WorstCase []HostWorstCase   // Per remaining node, sorted by name
+}
+
+// NonSurvivable returns the scenarios in which some VMs have nowhere to go
+func (a *FailureAnalysis) NonSurvivable() []FailureScenario {
+	var result []FailureScenario
+	for _, s := range a.Scenarios {
+		if !s.Survivable() {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Summary describes the analysis, e.g. "3 of 4 single-node failures survivable"
+func (a *FailureAnalysis) Summary() string {
+	kind := "single-node failures"
+	if a.K > 1 {
+		kind = fmt.Sprintf("%d-node failures", a.K)
+	}
+	summary := fmt.Sprintf("%d of %d %s survivable", len(a.Scenarios)-len(a.NonSurvivable()), len(a.Scenarios), kind)
+	if a.Truncated {
+		summary += fmt.Sprintf(" (first %d combinations only)", maxFailureScenarios)
+	}
+	return summary
+}
+
+// AnalyzeFailures simulates the loss of every combination of k online nodes.
+// The VMs of the failed nodes, nomigrate ones included, are placed with the
+// Migrate All logic (GenerateSuggestionsBalanced) on the remaining targets, one
+// failed node after the other, so later nodes see the VMs already placed.
+func AnalyzeFailures(cluster *proxmox.Cluster, policy CapacityPolicy, k int) (*FailureAnalysis, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("number of failed nodes must be at least 1")
+	}
+	var online []string
+	for _, node := range cluster.Nodes {
+		if node.Status == "online" {
+			online = append(online, node.Name)
+		}
+	}
+	sort.Strings(online)
+	if k >= len(online) {
+		return nil, fmt.Errorf("cannot simulate %d failures with %d online nodes", k, len(online))
+	}
+
+	analysis := &FailureAnalysis{K: k}
+	worst := make(map[string]*HostWorstCase)
+	forEachCombination(len(online), k, func(idx []int) bool {
+		if len(analysis.Scenarios) == maxFailureScenarios {
+			analysis.Truncated = true
+			return false
+		}
+		failed := make([]string, len(idx))
+		for i, j := range idx {
+			failed[i] = online[j]
+		}
+		scenario := simulateFailure(cluster, policy, failed)
+		analysis.Scenarios = append(analysis.Scenarios, scenario)
+
+		for name, after := range scenario.After {
+			w, ok := worst[name]
+			if !ok {
+				before := NewNodeState(proxmox.GetNodeByName(cluster, name))
+				w = &HostWorstCase{Node: name, Before: before, After: before}
+				worst[name] = w
+			}
+			if after.GetUtilizationScore() > w.After.GetUtilizationScore() {
+				w.After = after
+				w.Failed = failed
+			}
+		}
+		return true
+	})
+
+	for _, w := range worst {
+		analysis.WorstCase = append(analysis.WorstCase, *w)
+	}
+	sort.Slice(analysis.WorstCase, func(i, j int) bool { return analysis.WorstCase[i].Node < analysis.WorstCase[j].Node })
+	return analysis, nil
+}
+
+// simulateFailure redistributes the VMs of the failed nodes
+func simulateFailure(cluster *proxmox.Cluster, policy CapacityPolicy, failed []string) FailureScenario {
+	scenario := FailureScenario{Failed: failed, After: make(map[string]NodeState)}
+	var current *proxmox.Cluster
+	scenario.Suggestions, scenario.Stranded, current = evacuateNodes(cluster, policy, nil, failed, nil, "lost with")
+
+	for i := range current.Nodes {
+		node := &current.Nodes[i]
+		if node.Status != "online" || containsString(failed, node.Name) {
+			continue
+		}
+		scenario.After[node.Name] = NewNodeState(node)
+	}
+	return scenario
+}
+
+// evacuateNodes places every VM of the nodes, nomigrate ones included, on the
+// other targets with the Migrate All logic (GenerateSuggestionsBalanced), one
+// node after the other, so later nodes see the VMs already placed. Returns the
+// suggestions, the VMs with nowhere to go (their reason ends in "(<why> <node>)")
+// and the cluster once the placed VMs have moved. A nil strategy is the default
+// one; the exclude nodes receive no VMs.
+func evacuateNodes(cluster *proxmox.Cluster, policy CapacityPolicy, strategy PlacementStrategy, names, exclude []string, why string) ([]MigrationSuggestion, []UnmigrateableVM, *proxmox.Cluster) {
+	var all []MigrationSuggestion
+	var stranded []UnmigrateableVM
+	groups := ResolveAffinityGroups(cluster, policy.AffinityGroups)
+	current := cluster
+	for i, name := range names {
+		node := proxmox.GetNodeByName(current, name)
+		if node == nil || len(node.VMs) == 0 {
+			continue
+		}
+		others := make([]string, 0, len(names)-1+len(exclude))
+		others = append(others, names[:i]...)
+		others = append(others, names[i+1:]...)
+		others = append(others, exclude...)
+		targets := proxmox.GetAvailableTargets(current, name, others)
+
+		vms := append([]proxmox.VM(nil), node.VMs...)
+		for j := range vms {
+			vms[j].Node = name
+		}
+		sortForEvacuation(vms)
+
+		reason := "No remaining host within the capacity limits and placement rules"
+		var suggestions []MigrationSuggestion
+		if len(targets) > 0 {
+			constraints := MigrationConstraints{SourceNode: name, MigrateAll: true, Policy: &policy, Strategy: strategy}
+			suggestions = GenerateSuggestionsBalanced(vms, targets, current, groups, node, constraints)
+		} else {
+			reason = "No remaining host can receive VMs"
+			for _, vm := range vms {
+				suggestions = append(suggestions, MigrationSuggestion{
+					VMID:       vm.VMID,
+					VMName:     vm.Name,
+					SourceNode: name,
+					TargetNode: "NONE",
+					Status:     vm.Status,
+					VCPUs:      vm.CPUCores,
+					RAM:        vm.MaxMem,
+					Storage:    vm.GetLocalDisk(),
+				})
+			}
+		}
+
+		var placed []MigrationSuggestion
+		for _, sug := range suggestions {
+			if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+				stranded = append(stranded, UnmigrateableVM{
+					VMID:    sug.VMID,
+					VMName:  sug.VMName,
+					Status:  sug.Status,
+					VCPUs:   sug.VCPUs,
+					RAM:     sug.RAM,
+					Storage: sug.Storage,
+					Reason:  fmt.Sprintf("%s (%s %s)", reason, why, name),
+				})
+				continue
+			}
+			placed = append(placed, sug)
+		}
+		all = append(all, suggestions...)
+		current = clusterAfterMigrations(current, placed)
+	}
+	return all, stranded, current
+}
+
+// forEachCombination calls fn with every k-subset of 0..n-1 in lexicographic
+// order until fn returns false
+func forEachCombination(n, k int, fn func([]int) bool) {
+	idx := make([]int, k)
+	for i := range idx {
+		idx[i] = i
+	}
+	for {
+		if !fn(append([]int(nil), idx...)) {
+			return
+		}
+		i := k - 1
+		for i >= 0 && idx[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		idx[i]++
+		for j := i + 1; j < k; j++ {
+			idx[j] = idx[j-1] + 1
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// TestMain runs the tests in a temporary directory: collection writes migsug.log to the working directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "migsug-analyzer-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.Chdir(wd)
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fixtureCases are the generated clusters the analyzer tests run on
+var fixtureCases = []struct {
+	name string
+	opts proxmox.FixtureOptions
+}{
+	{"even", proxmox.FixtureOptions{Nodes: 4, VMs: 40, Skew: 0, Seed: 1}},
+	{"skewed", proxmox.FixtureOptions{Nodes: 5, VMs: 120, Skew: 0.5, Seed: 2}},
+	{"overloaded first node", proxmox.FixtureOptions{Nodes: 3, VMs: 60, Skew: 0.9, Seed: 3}},
+	{"many small nodes", proxmox.FixtureOptions{Nodes: 8, VMs: 80, Skew: 0.3, Seed: 4}},
+}
+
+// collectFixture generates a fixture cluster and collects it through FixtureClient
+func collectFixture(t *testing.T, opts proxmox.FixtureOptions) *proxmox.Cluster {
+	t.Helper()
+	dir := t.TempDir()
+	if err := proxmox.GenerateFixtures(dir, opts); err != nil {
+		t.Fatalf("GenerateFixtures: %v", err)
+	}
+	cluster, err := proxmox.CollectClusterData(proxmox.NewFixtureClient(dir))
+	if err != nil {
+		t.Fatalf("CollectClusterData: %v", err)
+	}
+	if len(cluster.Nodes) != opts.Nodes {
+		t.Fatalf("collected %d nodes, want %d", len(cluster.Nodes), opts.Nodes)
+	}
+	vms := 0
+	for _, node := range cluster.Nodes {
+		vms += len(node.VMs)
+	}
+	if vms != opts.VMs {
+		t.Fatalf("collected %d VMs, want %d", vms, opts.VMs)
+	}
+	return cluster
+}
+
+// checkSuggestions verifies that every VM is suggested at most once and only
+// moves between two different nodes of the cluster
+func checkSuggestions(t *testing.T, cluster *proxmox.Cluster, result *AnalysisResult) {
+	t.Helper()
+	seen := make(map[int]bool)
+	for _, sug := range result.Suggestions {
+		if seen[sug.VMID] {
+			t.Errorf("VM %d is suggested more than once", sug.VMID)
+		}
+		seen[sug.VMID] = true
+		source := proxmox.GetNodeByName(cluster, sug.SourceNode)
+		if source == nil {
+			t.Errorf("VM %d: unknown source node %q", sug.VMID, sug.SourceNode)
+			continue
+		}
+		// Storage is what lands on the target, as selection and capacity count it
+		for _, vm := range source.VMs {
+			if vm.VMID == sug.VMID && sug.Storage != vm.GetLocalDisk() {
+				t.Errorf("VM %d: storage %d, local disk %d", sug.VMID, sug.Storage, vm.GetLocalDisk())
+			}
+		}
+		if sug.TargetNode == "NONE" {
+			continue
+		}
+		if proxmox.GetNodeByName(cluster, sug.TargetNode) == nil {
+			t.Errorf("VM %d: unknown target node %q", sug.VMID, sug.TargetNode)
+		}
+		if sug.TargetNode == sug.SourceNode {
+			t.Errorf("VM %d: target %s is its source", sug.VMID, sug.TargetNode)
+		}
+	}
+	if n := len(result.PlannedSuggestions()); n != len(result.Suggestions) {
+		t.Errorf("staged plan lists %d moves for %d suggestions", n, len(result.Suggestions))
+	}
+}
+
+func TestAnalyzeFixtures(t *testing.T) {
+	three := 3
+	modes := []struct {
+		name        string
+		constraints MigrationConstraints
+	}{
+		{"vm count", MigrationConstraints{VMCount: &three}},
+		{"migrate all", MigrationConstraints{MigrateAll: true}},
+	}
+
+	for _, tc := range fixtureCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := collectFixture(t, tc.opts)
+			source := cluster.Nodes[0]
+			for _, mode := range modes {
+				t.Run(mode.name, func(t *testing.T) {
+					constraints := mode.constraints
+					constraints.SourceNode = source.Name
+					result, err := Analyze(cluster, constraints)
+					if err != nil {
+						t.Fatalf("Analyze: %v", err)
+					}
+					checkSuggestions(t, cluster, result)
+					for _, sug := range result.Suggestions {
+						if sug.SourceNode != source.Name {
+							t.Errorf("VM %d: source %s, want %s", sug.VMID, sug.SourceNode, source.Name)
+						}
+					}
+					if mode.constraints.VMCount != nil && len(result.Suggestions) > *mode.constraints.VMCount {
+						t.Errorf("%d suggestions for a count of %d", len(result.Suggestions), *mode.constraints.VMCount)
+					}
+					if mode.constraints.MigrateAll {
+						// VMs without a target are both suggested (to NONE) and unmigrateable
+						covered := make(map[int]bool)
+						for _, sug := range result.Suggestions {
+							covered[sug.VMID] = true
+						}
+						for _, vm := range result.UnmigrateableVMs {
+							covered[vm.VMID] = true
+						}
+						if len(covered) != len(source.VMs) {
+							t.Errorf("%d of %d VMs on %s are planned", len(covered), len(source.VMs), source.Name)
+						}
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,856 @@
+package analyzer
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// OptimizerSettings controls the global balance optimizer
+// The migration and transfer budgets are the balance budget of MigrationConstraints.
+type OptimizerSettings struct {
+	Enabled           bool    `json:"enabled" yaml:"enabled"`                         // Balance the cluster with the optimizer instead of the greedy passes
+	TimeBudgetSeconds float64 `json:"time_budget_seconds" yaml:"time_budget_seconds"` // Search time
+}
+
+// DefaultOptimizerSettings searches for up to 10 seconds
+var DefaultOptimizerSettings = OptimizerSettings{
+	TimeBudgetSeconds: 10,
+}
+
+// Validate checks that the time budget is usable
+func (s OptimizerSettings) Validate() error {
+	if s.TimeBudgetSeconds <= 0 {
+		return &ValidationError{Field: "time_budget_seconds", Message: "must be positive"}
+	}
+	return nil
+}
+
+// TimeBudget returns the search time
+func (s OptimizerSettings) TimeBudget() time.Duration {
+	return time.Duration(s.TimeBudgetSeconds * float64(time.Second))
+}
+
+// OptimizationReport describes how the optimizer's plan compares to the
+// current placement and to the best placement possible. Imbalance is the
+// weighted RMS deviation of each node's RAM, vCPU, host CPU and storage
+// utilization from the cluster average, in percentage points.
+type OptimizationReport struct {
+	Before     float64 // Imbalance of the current placement
+	After      float64 // Imbalance once the plan has run
+	LowerBound float64 // No placement gets below this (VM sizes, limits and budgets ignored)
+
+	Migrations    int   // VMs that end up on another node
+	Bytes         int64 // Data the migrations copy
+	MaxMigrations int   // Budgets the search ran with (0 = unlimited)
+	MaxBytes      int64
+
+	Iterations int           // Moves and swaps evaluated
+	Elapsed    time.Duration // Search time
+	TimedOut   bool          // Stopped by the time budget before the search cooled down
+}
+
+// Gap returns how far the plan is from the lower bound, in percentage points
+func (r *OptimizationReport) Gap() float64 {
+	return math.Max(r.After-r.LowerBound, 0)
+}
+
+// Achieved returns the share of the possible improvement the plan reaches (0-100)
+func (r *OptimizationReport) Achieved() float64 {
+	possible := r.Before - r.LowerBound
+	if possible <= 0 {
+		return 100
+	}
+	return math.Min(math.Max((r.Before-r.After)/possible*100, 0), 100)
+}
+
+// Summary describes the result, e.g. "Imbalance 12.4 → 3.1 pts, lower bound 2.7 (gap 0.4, 96% of the possible improvement)"
+func (r *OptimizationReport) Summary() string {
+	return fmt.Sprintf("Imbalance %.1f → %.1f pts, lower bound %.1f (gap %.1f, %.0f%% of the possible improvement)",
+		r.Before, r.After, r.LowerBound, r.Gap(), r.Achieved())
+}
+
+// BudgetSummary describes the budgets used, e.g. "14 of 20 migrations, 220.0 GB transferred (no limit), 120000 iterations in 1.2s"
+func (r *OptimizationReport) BudgetSummary() string {
+	migrations := fmt.Sprintf("%d migrations", r.Migrations)
+	if r.MaxMigrations > 0 {
+		migrations = fmt.Sprintf("%d of %d migrations", r.Migrations, r.MaxMigrations)
+	}
+	transfer := proxmox.FormatBytes(r.Bytes) + " transferred"
+	if r.MaxBytes > 0 {
+		transfer += " of " + proxmox.FormatBytes(r.MaxBytes)
+	}
+	s := fmt.Sprintf("%s, %s, %d iterations in %s", migrations, transfer, r.Iterations, r.Elapsed.Round(10*time.Millisecond))
+	if r.TimedOut {
+		s += " (time budget reached)"
+	}
+	return s
+}
+
+// Resources the optimizer balances, indexes into the load and capacity arrays
+const (
+	optRAM = iota
+	optVCPU
+	optHostCPU
+	optStorage
+	optResources
+)
+
+// optimizerResources names the resources and weighs them in the imbalance
+// RAM can't be oversubscribed, so it weighs most
+var optimizerResources = [optResources]struct {
+	name   string
+	weight float64
+}{
+	{"RAM", 0.4},
+	{"vCPU", 0.25},
+	{"Host CPU", 0.2},
+	{"Storage", 0.15},
+}
+
+const (
+	// migrationCost is the drop in summed squared deviation (percentage points²)
+	// a migration has to buy, so the search doesn't shuffle VMs for nothing
+	migrationCost = 10.0
+
+	// Search length per movable VM when the time budget allows it
+	optimizerIterationsPerVM = 2000
+	optimizerMinIterations   = 20000
+
+	// Share of the proposals that swap two VMs instead of moving one
+	swapProbability = 0.3
+
+	// Final temperature relative to the initial one
+	coolingRatio = 1e-3
+)
+
+// optimizerVM is a VM the optimizer may move
+type optimizerVM struct {
+	orig     proxmox.VM      // As collected, disks on the origin node's storages
+	vm       proxmox.VM      // As placed now
+	disks    []DiskPlacement // Disk placement on the current node (nil at the origin)
+	origin   int
+	node     int
+	load     [optResources]float64
+	transfer int64
+}
+
+// optimizer holds the placement being searched
+type optimizer struct {
+	cluster *proxmox.Cluster
+	groups  []AffinityGroup // Resolved affinity groups
+	nodes   []*simulatedNodeState
+	hosts   []*proxmox.Node // Same index as nodes, for the placement constraints
+	vms     []*optimizerVM
+	mean    [optResources]float64
+	pinned  [][optResources]float64 // Load of the VMs the optimizer can't move, per node
+
+	planned    map[string]string       // VMs off their original node -> current node
+	dependents map[string][]proxmox.VM // VM name -> VMs whose withvm/without lists name it
+	violated   map[string]bool         // VMs breaking their placement constraints before the plan
+	excluded   map[int]bool            // Nodes that only take back their own VMs
+
+	cost          float64 // Summed weighted squared deviation of all nodes
+	migrations    int
+	bytes         int64
+	maxMigrations int
+	maxBytes      int64
+}
+
+// AnalyzeClusterWideOptimized balances the cluster with a global search
+// instead of the greedy passes of AnalyzeClusterWideBalance. Simulated
+// annealing over single moves and swaps minimizes the imbalance (see
+// OptimizationReport) under the hard limits of the capacity policy and the
+// placement constraints, within the time budget of the settings.
+// Each migration has to buy a minimum improvement, so VMs are only moved
+// when it pays off; the result reports how close it gets to a lower bound.
+func AnalyzeClusterWideOptimized(cluster *proxmox.Cluster, policy CapacityPolicy, settings OptimizerSettings, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	return AnalyzeClusterWideOptimizedWithConstraints(cluster, MigrationConstraints{Policy: &policy}, settings, progress)
+}
+
+// AnalyzeClusterWideOptimizedWithConstraints is AnalyzeClusterWideOptimized
+// with the capacity policy and budget of the constraints. The search stays
+// within their migration and transfer budgets; excluded VMs and VMs above
+// the live RAM limit stay where they are, and excluded nodes receive no VMs.
+func AnalyzeClusterWideOptimizedWithConstraints(cluster *proxmox.Cluster, constraints MigrationConstraints, settings OptimizerSettings, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	if cluster == nil || len(cluster.Nodes) == 0 {
+		return nil, fmt.Errorf("no nodes in cluster")
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if err := constraints.ValidateBudget(); err != nil {
+		return nil, err
+	}
+	policy := constraints.GetPolicy()
+	limits := newBalanceLimits(constraints)
+
+	o := newOptimizer(cluster, policy, limits)
+	if len(o.nodes) < 2 {
+		return nil, fmt.Errorf("need at least 2 online nodes for cluster balancing")
+	}
+	if len(o.vms) == 0 {
+		return nil, fmt.Errorf("no running migratable VMs to balance")
+	}
+
+	if progress != nil {
+		progress("Calculating lower bound", 0, len(o.nodes), 0)
+	}
+	beforeStates := make(map[string]NodeState)
+	for _, s := range o.nodes {
+		beforeStates[s.name] = s.toNodeState()
+	}
+	report := &OptimizationReport{
+		Before:        o.imbalance(),
+		LowerBound:    o.lowerBound(),
+		MaxMigrations: limits.maxMigrations,
+		MaxBytes:      limits.maxBytes,
+	}
+
+	log.Printf("Optimizer: %d nodes, %d movable VMs, imbalance %.2f, lower bound %.2f",
+		len(o.nodes), len(o.vms), report.Before, report.LowerBound)
+
+	start := time.Now()
+	rng := rand.New(rand.NewSource(1))
+	maxIterations := max(optimizerIterationsPerVM*len(o.vms), optimizerMinIterations)
+	report.Iterations, report.TimedOut = o.anneal(rng, settings.TimeBudget(), maxIterations, progress)
+	o.descend()
+	report.Elapsed = time.Since(start)
+
+	// Recompute from scratch to drop the rounding of the incremental updates
+	o.cost = 0
+	for _, s := range o.nodes {
+		o.cost += o.nodeCost(s, [optResources]float64{})
+	}
+	report.After = o.imbalance()
+	report.Migrations = o.migrations
+	report.Bytes = o.bytes
+
+	log.Printf("Optimizer: %d iterations in %s, imbalance %.2f -> %.2f, %d migrations",
+		report.Iterations, report.Elapsed, report.Before, report.After, report.Migrations)
+
+	suggestions := o.suggestions()
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("no beneficial migrations found")
+	}
+
+	result := &AnalysisResult{
+		Suggestions:      suggestions,
+		TargetsBefore:    beforeStates,
+		TargetsAfter:     make(map[string]NodeState),
+		IsBalanceCluster: true,
+		ImprovementInfo:  report.Summary(),
+		MovementsTried:   report.Iterations,
+		Optimization:     report,
+
+		Constraints:        constraints,
+		ClusterCollectedAt: cluster.CollectedAt,
+	}
+	result.Constraints.BalanceCluster = true
+	result.Constraints.Policy = &policy
+	for _, s := range o.nodes {
+		result.TargetsAfter[s.name] = s.toNodeState()
+	}
+	// Order the migrations so every intermediate state stays within the limits
+	result.Staged = BuildStagedPlan(cluster, suggestions, policy)
+	result.AffinityViolations = FindAffinityViolations(cluster, o.groups, PlannedLocations(suggestions))
+	result.addPlannedTotals()
+
+	return result, nil
+}
+
+// newOptimizer builds the search state from the online nodes that are not
+// migration-blocked or removed by a what-if, like the greedy balance
+// VMs the limits don't allow to move count as load that can't move.
+func newOptimizer(cluster *proxmox.Cluster, policy CapacityPolicy, limits *balanceLimits) *optimizer {
+	o := &optimizer{
+		cluster:       cluster,
+		groups:        ResolveAffinityGroups(cluster, policy.AffinityGroups),
+		planned:       make(map[string]string),
+		dependents:    make(map[string][]proxmox.VM),
+		violated:      make(map[string]bool),
+		excluded:      make(map[int]bool),
+		maxMigrations: limits.maxMigrations,
+		maxBytes:      limits.maxBytes,
+	}
+
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		if node.Status != "online" || node.IsMigrationBlocked() || node.Removed {
+			continue
+		}
+		index := len(o.nodes)
+		if !limits.receives(node.Name) {
+			o.excluded[index] = true
+		}
+		state := newSimulatedNodeState(node, policy)
+		o.nodes = append(o.nodes, state)
+		o.hosts = append(o.hosts, node)
+
+		pinned := state.optimizerLoad()
+		for _, vm := range node.VMs {
+			for _, name := range append(append([]string(nil), vm.WithVM...), vm.WithoutVM...) {
+				o.dependents[name] = append(o.dependents[name], vm)
+			}
+			if HasPlacementConstraints(vm, o.groups) && CheckVMPlacementConstraints(vm, node, cluster, o.groups, nil).Violated {
+				o.violated[vm.Name] = true
+			}
+			if vm.NoMigrate || vm.Status != "running" || !limits.movable(vm) {
+				continue
+			}
+			v := &optimizerVM{
+				orig:     vm,
+				vm:       vm,
+				origin:   index,
+				node:     index,
+				load:     vmOptimizerLoad(&vm),
+				transfer: EstimateTransferBytes(vm),
+			}
+			o.vms = append(o.vms, v)
+			for r := range pinned {
+				pinned[r] -= v.load[r]
+			}
+		}
+		o.pinned = append(o.pinned, pinned)
+	}
+
+	// Cluster averages over the nodes that have the resource at all
+	var load, capacity [optResources]float64
+	for _, s := range o.nodes {
+		l, c := s.optimizerLoad(), s.optimizerCapacity()
+		for r := range l {
+			if c[r] > 0 {
+				load[r] += l[r]
+				capacity[r] += c[r]
+			}
+		}
+	}
+	for r := range o.mean {
+		if capacity[r] > 0 {
+			o.mean[r] = load[r] / capacity[r]
+		}
+	}
+
+	for _, s := range o.nodes {
+		o.cost += o.nodeCost(s, [optResources]float64{})
+	}
+	return o
+}
+
+// vmOptimizerLoad returns what a VM adds to a node, in the units of optimizerLoad
+func vmOptimizerLoad(vm *proxmox.VM) [optResources]float64 {
+	return [optResources]float64{
+		optRAM:     float64(vm.MaxMem) * 100,
+		optVCPU:    float64(vm.CPUCores) * 100,
+		optHostCPU: vm.CPUUsage * float64(vm.CPUCores),
+		optStorage: float64(vm.GetLocalDisk()) * 100,
+	}
+}
+
+// optimizerLoad returns the node's load per resource; divided by
+// optimizerCapacity it gives the utilization in percent
+func (s *simulatedNodeState) optimizerLoad() [optResources]float64 {
+	return [optResources]float64{
+		optRAM:     float64(s.ramUsed) * 100,
+		optVCPU:    float64(s.vcpus) * 100,
+		optHostCPU: s.vmCPUSum,
+		optStorage: float64(s.storageUsed) * 100,
+	}
+}
+
+// optimizerCapacity returns the node's capacity per resource
+func (s *simulatedNodeState) optimizerCapacity() [optResources]float64 {
+	return [optResources]float64{
+		optRAM:     float64(s.ramTotal),
+		optVCPU:    float64(s.cpuCores),
+		optHostCPU: float64(s.cpuCores),
+		optStorage: float64(s.storageTotal),
+	}
+}
+
+// nodeDeviation returns the squared deviation of each resource from the
+// cluster average with delta added to the node's load
+func (o *optimizer) nodeDeviation(s *simulatedNodeState, delta [optResources]float64) [optResources]float64 {
+	var dev [optResources]float64
+	load, capacity := s.optimizerLoad(), s.optimizerCapacity()
+	for r := range load {
+		if capacity[r] <= 0 {
+			continue
+		}
+		d := (load[r]+delta[r])/capacity[r] - o.mean[r]
+		dev[r] = d * d
+	}
+	return dev
+}
+
+// nodeCost returns the node's weighted squared deviation with delta added to its load
+func (o *optimizer) nodeCost(s *simulatedNodeState, delta [optResources]float64) float64 {
+	cost := 0.0
+	for r, dev := range o.nodeDeviation(s, delta) {
+		cost += optimizerResources[r].weight * dev
+	}
+	return cost
+}
+
+// imbalance returns the weighted RMS deviation of the current placement
+func (o *optimizer) imbalance() float64 {
+	return math.Sqrt(math.Max(o.cost, 0) / float64(len(o.nodes)))
+}
+
+// clusterImbalance returns the imbalance of OptimizationReport for any set of node states
+func clusterImbalance(states []*simulatedNodeState) float64 {
+	if len(states) == 0 {
+		return 0
+	}
+	var load, capacity [optResources]float64
+	for _, s := range states {
+		l, c := s.optimizerLoad(), s.optimizerCapacity()
+		for r := range l {
+			if c[r] > 0 {
+				load[r] += l[r]
+				capacity[r] += c[r]
+			}
+		}
+	}
+	sum := 0.0
+	for _, s := range states {
+		l, c := s.optimizerLoad(), s.optimizerCapacity()
+		for r := range l {
+			if c[r] <= 0 {
+				continue
+			}
+			d := l[r]/c[r] - load[r]/capacity[r]
+			sum += optimizerResources[r].weight * d * d
+		}
+	}
+	return math.Sqrt(sum / float64(len(states)))
+}
+
+// lowerBound returns an imbalance no placement can beat. Per resource, the
+// movable VMs are treated as a fluid poured onto the nodes on top of the load
+// that can't move (water-filling): node i ends at max(pinned_i, mean + t·cap_i)
+// with t found by bisection so the whole load is placed. VM sizes, hard limits
+// and the budgets only make the real optimum worse.
+func (o *optimizer) lowerBound() float64 {
+	bound := 0.0
+	for r := 0; r < optResources; r++ {
+		var caps, floors []float64
+		for i, s := range o.nodes {
+			c := s.optimizerCapacity()[r]
+			if c <= 0 {
+				continue
+			}
+			caps = append(caps, c)
+			floors = append(floors, o.pinned[i][r]/c)
+		}
+		if len(caps) == 0 {
+			continue
+		}
+
+		mean := o.mean[r]
+		level := func(t float64, i int) float64 {
+			return math.Max(floors[i], mean+t*caps[i])
+		}
+		placed := func(t float64) float64 {
+			sum := 0.0
+			for i := range caps {
+				sum += caps[i] * level(t, i)
+			}
+			return sum
+		}
+		total := 0.0
+		lo := 0.0
+		for i := range caps {
+			total += caps[i] * mean
+			lo = math.Min(lo, (floors[i]-mean)/caps[i])
+		}
+		hi := 0.0
+		for iter := 0; iter < 100; iter++ {
+			mid := (lo + hi) / 2
+			if placed(mid) > total {
+				hi = mid
+			} else {
+				lo = mid
+			}
+		}
+
+		sum := 0.0
+		for i := range caps {
+			d := level(hi, i) - mean
+			sum += d * d
+		}
+		bound += optimizerResources[r].weight * sum
+	}
+	return math.Sqrt(bound / float64(len(o.nodes)))
+}
+
+// proposal is a candidate change: v moves to node to; w, when set, swaps
+// places with it (w is on node to and moves to v's node)
+type proposal struct {
+	v, w       *optimizerVM
+	to         int
+	cost       float64 // Change of the summed squared deviation
+	migrations int
+	bytes      int64
+}
+
+// evaluate computes the change a proposal makes, without checking any limits
+func (o *optimizer) evaluate(v, w *optimizerVM, to int) proposal {
+	p := proposal{v: v, w: w, to: to}
+	from := v.node
+	var dFrom, dTo [optResources]float64
+	for r := range dFrom {
+		dFrom[r] = -v.load[r]
+		dTo[r] = v.load[r]
+		if w != nil {
+			dFrom[r] += w.load[r]
+			dTo[r] -= w.load[r]
+		}
+	}
+	a, b := o.nodes[from], o.nodes[to]
+	p.cost = o.nodeCost(a, dFrom) + o.nodeCost(b, dTo) - o.nodeCost(a, [optResources]float64{}) - o.nodeCost(b, [optResources]float64{})
+
+	p.migrations, p.bytes = o.relocationChange(v, to)
+	if w != nil {
+		m, b := o.relocationChange(w, from)
+		p.migrations += m
+		p.bytes += b
+	}
+	return p
+}
+
+// relocationChange returns how moving v to node to changes the migration count and bytes
+func (o *optimizer) relocationChange(v *optimizerVM, to int) (int, int64) {
+	switch {
+	case v.node == v.origin && to != v.origin:
+		return 1, v.transfer
+	case v.node != v.origin && to == v.origin:
+		return -1, -v.transfer
+	}
+	return 0, 0
+}
+
+// objective returns the change of the annealing objective: the imbalance
+// plus migrationCost per VM off its original node
+func (p proposal) objective() float64 {
+	return p.cost + migrationCost*float64(p.migrations)
+}
+
+// withinBudget returns true if the proposal keeps the plan within the budgets
+// Proposals that shrink the plan are always allowed.
+func (o *optimizer) withinBudget(p proposal) bool {
+	if o.maxMigrations > 0 && p.migrations > 0 && o.migrations+p.migrations > o.maxMigrations {
+		return false
+	}
+	if o.maxBytes > 0 && p.bytes > 0 && o.bytes+p.bytes > o.maxBytes {
+		return false
+	}
+	return true
+}
+
+// anneal runs simulated annealing until the iterations or the time budget
+// run out; the temperature cools geometrically with whichever is further along.
+// Returns the iterations run and whether the time budget stopped the search.
+func (o *optimizer) anneal(rng *rand.Rand, budget time.Duration, maxIterations int, progress BalanceProgressCallback) (int, bool) {
+	start := time.Now()
+
+	// Start hot enough to accept an average uphill proposal 1 in e times
+	uphill, samples := 0.0, 0
+	for i := 0; i < 200; i++ {
+		if p, ok := o.propose(rng); ok {
+			if d := p.objective(); d > 0 {
+				uphill += d
+				samples++
+			}
+		}
+	}
+	t0 := 1.0
+	if samples > 0 {
+		t0 = uphill / float64(samples)
+	}
+
+	temperature := t0
+	for i := 0; i < maxIterations; i++ {
+		if i%1024 == 0 {
+			done := float64(i) / float64(maxIterations)
+			if budget > 0 {
+				elapsed := time.Since(start)
+				if elapsed >= budget {
+					return i, true
+				}
+				done = math.Max(done, float64(elapsed)/float64(budget))
+			}
+			temperature = t0 * math.Pow(coolingRatio, done)
+			if progress != nil && i%(64*1024) == 0 {
+				progress("Optimizing placement", i, maxIterations, i)
+			}
+		}
+
+		p, ok := o.propose(rng)
+		if !ok || !o.withinBudget(p) {
+			continue
+		}
+		if d := p.objective(); d > 0 && rng.Float64() >= math.Exp(-d/temperature) {
+			continue
+		}
+		o.apply(p)
+	}
+	return maxIterations, false
+}
+
+// propose picks a random move or swap
+func (o *optimizer) propose(rng *rand.Rand) (proposal, bool) {
+	v := o.vms[rng.Intn(len(o.vms))]
+	if rng.Float64() < swapProbability {
+		w := o.vms[rng.Intn(len(o.vms))]
+		if w.node == v.node {
+			return proposal{}, false
+		}
+		return o.evaluate(v, w, w.node), true
+	}
+	to := rng.Intn(len(o.nodes) - 1)
+	if to >= v.node {
+		to++
+	}
+	return o.evaluate(v, nil, to), true
+}
+
+// descend finishes the search greedily: it returns VMs to their original node
+// and moves VMs to their best node while that lowers the objective
+func (o *optimizer) descend() {
+	order := make([]*optimizerVM, len(o.vms))
+	copy(order, o.vms)
+	sort.Slice(order, func(i, j int) bool { return order[i].orig.VMID < order[j].orig.VMID })
+
+	for pass := 0; pass < 10; pass++ {
+		improved := false
+		for _, v := range order {
+			if v.node != v.origin {
+				if p := o.evaluate(v, nil, v.origin); p.objective() < 0 && o.apply(p) {
+					improved = true
+					continue
+				}
+			}
+			var best *proposal
+			for to := range o.nodes {
+				if to == v.node {
+					continue
+				}
+				p := o.evaluate(v, nil, to)
+				if p.objective() < 0 && o.withinBudget(p) && (best == nil || p.objective() < best.objective()) {
+					best = &p
+				}
+			}
+			if best != nil && o.apply(*best) {
+				improved = true
+			}
+		}
+		if !improved {
+			return
+		}
+	}
+}
+
+// apply carries out a proposal if every hard limit and placement constraint
+// allows it. Returns false and leaves the state unchanged otherwise.
+func (o *optimizer) apply(p proposal) bool {
+	v, w := p.v, p.w
+	from := v.node
+	a, b := o.nodes[from], o.nodes[p.to]
+	savedA, savedB, savedV := *a, *b, *v
+	var savedW optimizerVM
+	if w != nil {
+		savedW = *w
+	}
+
+	o.detach(v)
+	o.setPlanned(v, p.to)
+	if w != nil {
+		o.detach(w)
+		o.setPlanned(w, from)
+	}
+	ok := o.canPlace(v, p.to)
+	if ok {
+		o.attach(v, p.to)
+		if w != nil {
+			if ok = o.canPlace(w, from); ok {
+				o.attach(w, from)
+			}
+		}
+	}
+	ok = ok && o.dependentsAllow(v) && (w == nil || o.dependentsAllow(w))
+
+	if !ok {
+		// Roll back: the node structs hold copies of their pools, only the VM maps are shared
+		delete(b.vms, v.vm.VMID)
+		*a, *b, *v = savedA, savedB, savedV
+		a.vms[v.vm.VMID] = v.vm
+		o.setPlanned(v, v.node)
+		if w != nil {
+			delete(a.vms, w.vm.VMID)
+			*w = savedW
+			b.vms[w.vm.VMID] = w.vm
+			o.setPlanned(w, w.node)
+		}
+		return false
+	}
+
+	o.cost += p.cost
+	o.migrations += p.migrations
+	o.bytes += p.bytes
+	return true
+}
+
+// detach removes a VM from its current node
+func (o *optimizer) detach(v *optimizerVM) {
+	s := o.nodes[v.node]
+	delete(s.vms, v.vm.VMID)
+	s.vcpus -= v.vm.CPUCores
+	s.ramUsed -= v.vm.MaxMem
+	s.storageUsed -= v.vm.GetLocalDisk()
+	s.vmCount--
+	s.vmCPUSum -= v.vm.CPUUsage * float64(v.vm.CPUCores)
+	s.pools = releaseDisks(s.pools, v.vm)
+}
+
+// attach places a VM on a node; back on its original node its disks return to their storages
+func (o *optimizer) attach(v *optimizerVM, to int) {
+	s := o.nodes[to]
+	v.node = to
+	if to == v.origin {
+		v.vm, v.disks = v.orig, nil
+		s.pools = applyDiskPlacement(s.pools, currentPlacement(v.orig))
+	} else {
+		v.disks = s.placeDisks(v.orig)
+		v.vm = movedVM(v.orig, &MigrationSuggestion{TargetNode: s.name, Disks: v.disks})
+		s.pools = applyDiskPlacement(s.pools, v.disks)
+	}
+	s.vms[v.vm.VMID] = v.vm
+	s.vcpus += v.vm.CPUCores
+	s.ramUsed += v.vm.MaxMem
+	s.storageUsed += v.vm.GetLocalDisk()
+	s.vmCount++
+	s.vmCPUSum += v.vm.CPUUsage * float64(v.vm.CPUCores)
+}
+
+// currentPlacement returns the placement of a VM's local disks on the storages they use now
+func currentPlacement(vm proxmox.VM) []DiskPlacement {
+	var placements []DiskPlacement
+	for _, disk := range vm.Disks {
+		if disk.Shared {
+			continue
+		}
+		placements = append(placements, DiskPlacement{
+			Disk: disk.Key, SourceStorage: disk.Storage, TargetStorage: disk.Storage, Size: vm.GetDiskBytes(disk),
+		})
+	}
+	return placements
+}
+
+// setPlanned records where a VM is in the plan for the placement constraint checks
+func (o *optimizer) setPlanned(v *optimizerVM, node int) {
+	if node == v.origin {
+		delete(o.planned, v.orig.Name)
+	} else {
+		o.planned[v.orig.Name] = o.nodes[node].name
+	}
+}
+
+// canPlace checks the exclude list, the hard limits and the VM's own placement constraints on a node
+func (o *optimizer) canPlace(v *optimizerVM, to int) bool {
+	if o.excluded[to] && to != v.origin {
+		return false
+	}
+	if o.nodes[to].hardLimitViolation(&v.orig) != "" {
+		return false
+	}
+	return !HasPlacementConstraints(v.orig, o.groups) || !CheckVMPlacementConstraints(v.orig, o.hosts[to], o.cluster, o.groups, o.planned).Violated
+}
+
+// dependentsAllow checks that the VMs naming v in their withvm/without lists
+// still satisfy them after v moved, unless they were violated to begin with
+func (o *optimizer) dependentsAllow(v *optimizerVM) bool {
+	for _, d := range o.dependents[v.orig.Name] {
+		if o.violated[d.Name] {
+			continue
+		}
+		node := findVMNode(d.Name, o.cluster, o.planned)
+		for i, host := range o.hosts {
+			if o.nodes[i].name == node && CheckVMPlacementConstraints(d, host, o.cluster, o.groups, o.planned).Violated {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// suggestions returns a migration from the original to the final node of
+// every VM that moved, scored by how much the imbalance would grow without it
+func (o *optimizer) suggestions() []MigrationSuggestion {
+	var result []MigrationSuggestion
+	for _, v := range o.vms {
+		if v.node == v.origin {
+			continue
+		}
+		source, target := o.nodes[v.origin], o.nodes[v.node]
+
+		// Contribution of this migration alone: undo it and compare
+		var back [optResources]float64
+		for r := range back {
+			back[r] = -v.load[r]
+		}
+		targetNow, targetWithout := o.nodeDeviation(target, [optResources]float64{}), o.nodeDeviation(target, back)
+		sourceNow, sourceWith := o.nodeDeviation(source, [optResources]float64{}), o.nodeDeviation(source, v.load)
+		var components []ScoreComponent
+		total := 0.0
+		for r, res := range optimizerResources {
+			gain := targetWithout[r] + sourceWith[r] - targetNow[r] - sourceNow[r]
+			components = append(components, ScoreComponent{Name: res.name, Value: gain, Weight: res.weight})
+			total += gain * res.weight
+		}
+
+		vm := v.orig
+		result = append(result, MigrationSuggestion{
+			VMID:        vm.VMID,
+			VMName:      vm.Name,
+			SourceNode:  source.name,
+			TargetNode:  target.name,
+			Reason:      "Balance cluster (optimizer)",
+			Score:       total,
+			Status:      vm.Status,
+			Type:        vm.Type,
+			VCPUs:       vm.CPUCores,
+			CPUUsage:    vm.CPUUsage,
+			RAM:         vm.MaxMem,
+			Storage:     vm.GetLocalDisk(),
+			UsedDisk:    vm.UsedDisk,
+			MaxDisk:     vm.MaxDisk,
+			Transfer:    v.transfer,
+			LiveMemory:  LiveMemoryBytes(vm),
+			SourceCores: source.cpuCores,
+			TargetCores: target.cpuCores,
+			Disks:       v.disks,
+			Details: &MigrationDetails{
+				SelectionMode:   "balance_cluster",
+				SelectionReason: "Global optimization of cluster balance",
+				ScoreBreakdown:  ScoreBreakdown{TotalScore: total, Components: components},
+				ClusterAvgCPU:   o.mean[optVCPU],
+				ClusterAvgRAM:   o.mean[optRAM],
+			},
+		})
+	}
+
+	// Biggest contribution first
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].VMID < result[j].VMID
+	})
+	return result
+}
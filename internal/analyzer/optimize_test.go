@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// testOptimizerSettings keeps the optimizer tests short
+var testOptimizerSettings = OptimizerSettings{Enabled: true, TimeBudgetSeconds: 0.5}
+
+func TestOptimizerSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings OptimizerSettings
+		valid    bool
+	}{
+		{"defaults", DefaultOptimizerSettings, true},
+		{"short search", OptimizerSettings{TimeBudgetSeconds: 0.1}, true},
+		{"no time budget", OptimizerSettings{Enabled: true}, false},
+		{"negative time budget", OptimizerSettings{TimeBudgetSeconds: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.settings.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestAnalyzeClusterWideOptimizedFixtures(t *testing.T) {
+	for _, tc := range fixtureCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := collectFixture(t, tc.opts)
+			result, err := AnalyzeClusterWideOptimized(cluster, DefaultCapacityPolicy, testOptimizerSettings, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeClusterWideOptimized: %v", err)
+			}
+			checkSuggestions(t, cluster, result)
+
+			report := result.Optimization
+			if report == nil {
+				t.Fatal("no optimization report")
+			}
+			if report.Migrations != len(result.Suggestions) {
+				t.Errorf("report counts %d migrations, plan has %d", report.Migrations, len(result.Suggestions))
+			}
+			if report.After > report.Before {
+				t.Errorf("imbalance rose from %.2f to %.2f", report.Before, report.After)
+			}
+			if report.LowerBound > report.After+1e-9 {
+				t.Errorf("lower bound %.2f above the plan's imbalance %.2f", report.LowerBound, report.After)
+			}
+		})
+	}
+}
+
+func TestAnalyzeClusterWideOptimizedBudget(t *testing.T) {
+	cluster := collectFixture(t, proxmox.FixtureOptions{Nodes: 5, VMs: 120, Skew: 0.5, Seed: 2})
+	tests := []struct {
+		name          string
+		maxMigrations int
+		maxTransfer   int64 // GiB
+	}{
+		{"three migrations", 3, 0},
+		{"transfer budget", 0, 400},
+		{"both", 10, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := MigrationConstraints{BalanceCluster: true}
+			if tt.maxMigrations > 0 {
+				constraints.MaxMigrations = &tt.maxMigrations
+			}
+			if tt.maxTransfer > 0 {
+				bytes := tt.maxTransfer * testGiB
+				constraints.MaxTransfer = &bytes
+			}
+			result, err := AnalyzeClusterWideOptimizedWithConstraints(cluster, constraints, testOptimizerSettings, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeClusterWideOptimizedWithConstraints: %v", err)
+			}
+			checkSuggestions(t, cluster, result)
+
+			// The constraints are the only budget the search runs with
+			report := result.Optimization
+			if report.MaxMigrations != tt.maxMigrations || report.MaxBytes != tt.maxTransfer*testGiB {
+				t.Errorf("searched with %d migrations and %d bytes, want %d and %d GiB", report.MaxMigrations, report.MaxBytes, tt.maxMigrations, tt.maxTransfer)
+			}
+			var bytes int64
+			for _, sug := range result.Suggestions {
+				bytes += sug.Transfer
+			}
+			if tt.maxMigrations > 0 && len(result.Suggestions) > tt.maxMigrations {
+				t.Errorf("%d migrations over a budget of %d", len(result.Suggestions), tt.maxMigrations)
+			}
+			if tt.maxTransfer > 0 && bytes > tt.maxTransfer*testGiB {
+				t.Errorf("%d GiB transferred over a budget of %d GiB", bytes/testGiB, tt.maxTransfer)
+			}
+		})
+	}
+}
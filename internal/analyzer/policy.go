@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// CapacityPolicy holds the limits a target host must stay within after receiving a VM
+// Hard limits apply to every mode; the soft margin is a limit for cluster balancing and a
+// preference for the other modes, which favor targets that stay within it
+type CapacityPolicy struct {
+	// Hard limits (projected utilization after the migration)
+	MaxHostCPUPercent float64 `json:"max_host_cpu_percent" yaml:"max_host_cpu_percent"` // Actual host CPU usage
+	MaxRAMPercent     float64 `json:"max_ram_percent" yaml:"max_ram_percent"`           // RAM cannot be oversubscribed
+	MaxStoragePercent float64 `json:"max_storage_percent" yaml:"max_storage_percent"`   // Headroom for snapshots etc.
+
+	// Soft limit: don't overshoot the cluster average by more than this. Cluster balance rejects
+	// receivers beyond it; the other modes only prefer targets within it
+	SoftMarginPercent float64 `json:"soft_margin_percent" yaml:"soft_margin_percent"`
+
+	// Storage headroom: MinStorageHeadroomGiB + LargestVMHeadroomPercent of the largest VM must stay free
+	MinStorageHeadroomGiB    float64 `json:"min_storage_headroom_gib" yaml:"min_storage_headroom_gib"`
+	LargestVMHeadroomPercent float64 `json:"largest_vm_headroom_percent" yaml:"largest_vm_headroom_percent"`
+
+	// Affinity groups of the config file (affinity_groups), on top of the group= tags
+	// Not part of the policy file; set when the config is loaded
+	AffinityGroups []AffinityGroup `json:"-" yaml:"-"`
+}
+
+// DefaultCapacityPolicy holds the limits used when no policy is configured
+var DefaultCapacityPolicy = CapacityPolicy{
+	MaxHostCPUPercent:        95,
+	MaxRAMPercent:            90,
+	MaxStoragePercent:        85,
+	SoftMarginPercent:        5,
+	MinStorageHeadroomGiB:    MinStorageHeadroomGiB,
+	LargestVMHeadroomPercent: LargestVMStorageHeadroomPercent * 100,
+}
+
+// Node config comment keys that override the policy for a single host
+// Example node config line: #hostprovision=true,maxstorage=92
+const (
+	PolicyKeyMaxHostCPU         = "maxhostcpu"
+	PolicyKeyMaxRAM             = "maxram"
+	PolicyKeyMaxStorage         = "maxstorage"
+	PolicyKeySoftMargin         = "softmargin"
+	PolicyKeyMinStorageHeadroom = "storageheadroom"
+	PolicyKeyLargestVMHeadroom  = "largestvmheadroom"
+)
+
+// LoadCapacityPolicy reads a policy from a YAML (or JSON) file
+// Fields missing from the file keep their DefaultCapacityPolicy values
+func LoadCapacityPolicy(path string) (CapacityPolicy, error) {
+	policy := DefaultCapacityPolicy
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read capacity policy: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse capacity policy %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf("invalid capacity policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Validate checks that all limits are within sensible ranges
+func (p CapacityPolicy) Validate() error {
+	percents := []struct {
+		name  string
+		value float64
+	}{
+		{"max_host_cpu_percent", p.MaxHostCPUPercent},
+		{"max_ram_percent", p.MaxRAMPercent},
+		{"max_storage_percent", p.MaxStoragePercent},
+	}
+	for _, pct := range percents {
+		if pct.value <= 0 || pct.value > 100 {
+			return &ValidationError{Field: pct.name, Message: "must be between 0 and 100"}
+		}
+	}
+	if p.SoftMarginPercent < 0 {
+		return &ValidationError{Field: "soft_margin_percent", Message: "must not be negative"}
+	}
+	if p.MinStorageHeadroomGiB < 0 {
+		return &ValidationError{Field: "min_storage_headroom_gib", Message: "must not be negative"}
+	}
+	if p.LargestVMHeadroomPercent < 0 {
+		return &ValidationError{Field: "largest_vm_headroom_percent", Message: "must not be negative"}
+	}
+	return nil
+}
+
+// ForNode returns the policy with the node's ConfigMeta overrides applied
+// Invalid override values are ignored
+func (p CapacityPolicy) ForNode(node *proxmox.Node) CapacityPolicy {
+	if node == nil || len(node.ConfigMeta) == 0 {
+		return p
+	}
+
+	overrides := []struct {
+		key   string
+		field *float64
+		max   float64 // 0 = no upper bound
+	}{
+		{PolicyKeyMaxHostCPU, &p.MaxHostCPUPercent, 100},
+		{PolicyKeyMaxRAM, &p.MaxRAMPercent, 100},
+		{PolicyKeyMaxStorage, &p.MaxStoragePercent, 100},
+		{PolicyKeySoftMargin, &p.SoftMarginPercent, 0},
+		{PolicyKeyMinStorageHeadroom, &p.MinStorageHeadroomGiB, 0},
+		{PolicyKeyLargestVMHeadroom, &p.LargestVMHeadroomPercent, 0},
+	}
+	for _, o := range overrides {
+		raw, ok := node.ConfigMeta[o.key]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(raw), "%"), 64)
+		if err != nil || value < 0 || (o.max > 0 && (value == 0 || value > o.max)) {
+			continue
+		}
+		*o.field = value
+	}
+	return p
+}
+
+// HasNodeOverrides returns true if the node's config overrides any policy limit
+func HasNodeOverrides(node *proxmox.Node) bool {
+	for _, key := range []string{PolicyKeyMaxHostCPU, PolicyKeyMaxRAM, PolicyKeyMaxStorage,
+		PolicyKeySoftMargin, PolicyKeyMinStorageHeadroom, PolicyKeyLargestVMHeadroom} {
+		if _, ok := node.ConfigMeta[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MinStorageHeadroomBytes returns the fixed part of the storage headroom in bytes
+func (p CapacityPolicy) MinStorageHeadroomBytes() int64 {
+	return int64(p.MinStorageHeadroomGiB * 1024 * 1024 * 1024)
+}
+
+// AppliedConstraints describes the limits for the "constraints applied" list of a migration
+func (p CapacityPolicy) AppliedConstraints() []string {
+	return []string{
+		fmt.Sprintf("Capacity limits: host CPU ≤%.0f%%, RAM ≤%.0f%%, storage ≤%.0f%% (node overrides apply)",
+			p.MaxHostCPUPercent, p.MaxRAMPercent, p.MaxStoragePercent),
+		fmt.Sprintf("Storage headroom: %.0f GiB + %.0f%% of largest VM", p.MinStorageHeadroomGiB, p.LargestVMHeadroomPercent),
+	}
+}
+
+// Summary returns a one-line description of the limits, e.g. for the TUI
+func (p CapacityPolicy) Summary() string {
+	return fmt.Sprintf("CPU ≤%.0f%%  RAM ≤%.0f%%  Storage ≤%.0f%%  Headroom %.0f GiB + %.0f%% largest VM  Balance margin %.0f%%",
+		p.MaxHostCPUPercent, p.MaxRAMPercent, p.MaxStoragePercent,
+		p.MinStorageHeadroomGiB, p.LargestVMHeadroomPercent, p.SoftMarginPercent)
+}
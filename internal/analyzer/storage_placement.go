@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// DiskPlacement maps one VM disk to the storage it will use on the target node
+type DiskPlacement struct {
+	Disk          string // Config key, e.g. scsi0
+	SourceStorage string
+	TargetStorage string
+	Size          int64 // Bytes the disk takes on the target storage (0 for shared storage)
+	Shared        bool  // Volume stays on shared storage, nothing is copied
+}
+
+// PlanDiskPlacement picks a target storage pool for each disk of a VM
+// Disks on shared storage stay in place. Local disks go to the pool with the
+// same ID if it has room, otherwise to the compatible pool (same content type,
+// preferably same storage type) with the most free space. A pool may not be
+// filled beyond maxPercent. Returns nil placements when the VM's disks or the
+// target's pools are unknown; returns a reason when a disk does not fit anywhere.
+func PlanDiskPlacement(vm proxmox.VM, pools []proxmox.StoragePool, maxPercent float64) ([]DiskPlacement, string) {
+	if len(vm.Disks) == 0 || len(pools) == 0 {
+		return nil, ""
+	}
+
+	content := "images"
+	if proxmox.GuestType(vm.Type) == "lxc" {
+		content = "rootdir"
+	}
+
+	// Work on a copy so several disks of one VM see each other's usage
+	used := make(map[string]int64, len(pools))
+	for _, pool := range pools {
+		used[pool.Name] = pool.Used
+	}
+	fits := func(pool proxmox.StoragePool, size int64) bool {
+		return float64(used[pool.Name]+size) <= float64(pool.Total)*maxPercent/100
+	}
+
+	// Proxmox maps storages, not disks: all disks of one source storage share a target
+	mapped := make(map[string]string)
+
+	var placements []DiskPlacement
+	for _, disk := range vm.Disks {
+		if disk.Shared {
+			placements = append(placements, DiskPlacement{
+				Disk: disk.Key, SourceStorage: disk.Storage, TargetStorage: disk.Storage, Shared: true,
+			})
+			continue
+		}
+
+		size := vm.GetDiskBytes(disk)
+
+		var best *proxmox.StoragePool
+		for i := range pools {
+			pool := &pools[i]
+			if pool.Shared || !pool.HasContent(content) || !fits(*pool, size) {
+				continue
+			}
+			if target, ok := mapped[disk.Storage]; ok {
+				if pool.Name == target {
+					best = pool
+					break
+				}
+				continue
+			}
+			if pool.Name == disk.Storage {
+				best = pool
+				break
+			}
+			if best == nil || betterPool(*pool, *best, disk.StorageType, used) {
+				best = pool
+			}
+		}
+		if best == nil {
+			return nil, fmt.Sprintf("No storage with room for disk %s (%s)", disk.Key, proxmox.FormatBytes(size))
+		}
+
+		used[best.Name] += size
+		mapped[disk.Storage] = best.Name
+		placements = append(placements, DiskPlacement{
+			Disk: disk.Key, SourceStorage: disk.Storage, TargetStorage: best.Name, Size: size,
+		})
+	}
+	return placements, ""
+}
+
+// betterPool prefers the source's storage type, then the most free space
+func betterPool(a, b proxmox.StoragePool, sourceType string, used map[string]int64) bool {
+	if (a.Type == sourceType) != (b.Type == sourceType) {
+		return a.Type == sourceType
+	}
+	return a.Total-used[a.Name] > b.Total-used[b.Name]
+}
+
+// applyDiskPlacement returns a copy of pools with the placed disks added
+func applyDiskPlacement(pools []proxmox.StoragePool, placements []DiskPlacement) []proxmox.StoragePool {
+	if len(placements) == 0 {
+		return pools
+	}
+	result := append([]proxmox.StoragePool(nil), pools...)
+	for _, p := range placements {
+		for i := range result {
+			if result[i].Name == p.TargetStorage {
+				result[i].Used += p.Size
+			}
+		}
+	}
+	return result
+}
+
+// releaseDisks returns a copy of pools with the VM's local disks removed
+func releaseDisks(pools []proxmox.StoragePool, vm proxmox.VM) []proxmox.StoragePool {
+	if len(vm.Disks) == 0 {
+		return pools
+	}
+	result := append([]proxmox.StoragePool(nil), pools...)
+	for _, disk := range vm.Disks {
+		if disk.Shared {
+			continue
+		}
+		for i := range result {
+			if result[i].Name == disk.Storage {
+				result[i].Used -= vm.GetDiskBytes(disk)
+			}
+		}
+	}
+	return result
+}
+
+// movedVM returns the VM as it will be after the migration: on the target
+// node, with its local disks on the planned target storages
+func movedVM(vm proxmox.VM, migration *MigrationSuggestion) proxmox.VM {
+	vm.Node = migration.TargetNode
+	if len(migration.Disks) == 0 {
+		return vm
+	}
+	disks := make([]proxmox.VMDisk, len(vm.Disks))
+	copy(disks, vm.Disks)
+	for i := range disks {
+		for _, p := range migration.Disks {
+			if p.Disk == disks[i].Key {
+				disks[i].Storage = p.TargetStorage
+			}
+		}
+	}
+	vm.Disks = disks
+	return vm
+}
+
+// TargetStorage returns the --targetstorage mapping for the suggestion's disks
+// e.g. "kv0001storage:kv0002storage". Empty when every local disk keeps its storage ID.
+func (s MigrationSuggestion) TargetStorage() string {
+	moved := false
+	seen := make(map[string]bool)
+	var pairs []string
+	for _, d := range s.Disks {
+		if d.Shared {
+			continue
+		}
+		if d.SourceStorage != d.TargetStorage {
+			moved = true
+		}
+		pair := d.SourceStorage + ":" + d.TargetStorage
+		if !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	if !moved {
+		return ""
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
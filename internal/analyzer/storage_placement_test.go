@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// placementTestPool is a storage pool of total GiB with used GiB taken
+func placementTestPool(name, storageType, content string, total, used int64) proxmox.StoragePool {
+	return proxmox.StoragePool{Name: name, Type: storageType, Content: content, Total: total * testGiB, Used: used * testGiB}
+}
+
+// placementTestDisk is a fully allocated local disk of size GiB
+func placementTestDisk(key, storage, storageType string, size int64) proxmox.VMDisk {
+	return proxmox.VMDisk{Key: key, Storage: storage, StorageType: storageType, Size: size * testGiB}
+}
+
+func TestPlanDiskPlacement(t *testing.T) {
+	shared := proxmox.VMDisk{Key: "scsi1", Storage: "ceph", StorageType: "rbd", Size: 100 * testGiB, Shared: true}
+	cephPool := placementTestPool("ceph", "rbd", "images", 10000, 0)
+	cephPool.Shared = true
+
+	tests := []struct {
+		name       string
+		vmType     string
+		disks      []proxmox.VMDisk
+		pools      []proxmox.StoragePool
+		maxPercent float64
+		want       []DiskPlacement
+		unplaced   bool
+	}{
+		{
+			"disks unknown", "qemu", nil,
+			[]proxmox.StoragePool{placementTestPool("local-lvm", "lvmthin", "images", 1000, 0)}, 100,
+			nil, false,
+		},
+		{
+			"pools unknown", "qemu", []proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 10)},
+			nil, 100,
+			nil, false,
+		},
+		{
+			"same storage ID", "qemu", []proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 10)},
+			[]proxmox.StoragePool{placementTestPool("big", "lvmthin", "images", 1000, 0), placementTestPool("local-lvm", "lvmthin", "images", 100, 0)}, 100,
+			[]DiskPlacement{{Disk: "scsi0", SourceStorage: "local-lvm", TargetStorage: "local-lvm", Size: 10 * testGiB}}, false,
+		},
+		{
+			"shared disk stays", "qemu", []proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 10), shared},
+			[]proxmox.StoragePool{placementTestPool("local-lvm", "lvmthin", "images", 100, 0), cephPool}, 100,
+			[]DiskPlacement{
+				{Disk: "scsi0", SourceStorage: "local-lvm", TargetStorage: "local-lvm", Size: 10 * testGiB},
+				{Disk: "scsi1", SourceStorage: "ceph", TargetStorage: "ceph", Shared: true},
+			}, false,
+		},
+		{
+			"same storage ID above the limit", "qemu", []proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 40)},
+			[]proxmox.StoragePool{placementTestPool("local-lvm", "lvmthin", "images", 100, 50), placementTestPool("data", "lvmthin", "images", 500, 0)}, 80,
+			[]DiskPlacement{{Disk: "scsi0", SourceStorage: "local-lvm", TargetStorage: "data", Size: 40 * testGiB}}, false,
+		},
+		{
+			"same storage type first", "qemu", []proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 10)},
+			[]proxmox.StoragePool{placementTestPool("dir", "dir", "images", 1000, 0), placementTestPool("thin", "lvmthin", "images", 200, 0)}, 100,
+			[]DiskPlacement{{Disk: "scsi0", SourceStorage: "local-lvm", TargetStorage: "thin", Size: 10 * testGiB}}, false,
+		},
+		{
+			"most free space", "qemu", []proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 10)},
+			[]proxmox.StoragePool{placementTestPool("thin1", "lvmthin", "images", 1000, 900), placementTestPool("thin2", "lvmthin", "images", 500, 0)}, 100,
+			[]DiskPlacement{{Disk: "scsi0", SourceStorage: "local-lvm", TargetStorage: "thin2", Size: 10 * testGiB}}, false,
+		},
+		{
+			"shared pools and other content skipped", "lxc", []proxmox.VMDisk{placementTestDisk("rootfs", "local", "dir", 10)},
+			[]proxmox.StoragePool{cephPool, placementTestPool("images", "dir", "images", 1000, 0), placementTestPool("ct", "dir", "rootdir", 100, 0)}, 100,
+			[]DiskPlacement{{Disk: "rootfs", SourceStorage: "local", TargetStorage: "ct", Size: 10 * testGiB}}, false,
+		},
+		{
+			"disks of one storage share a target", "qemu",
+			[]proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 60), placementTestDisk("scsi1", "local-lvm", "lvmthin", 10)},
+			[]proxmox.StoragePool{placementTestPool("thin1", "lvmthin", "images", 100, 0), placementTestPool("thin2", "lvmthin", "images", 80, 0)}, 100,
+			[]DiskPlacement{
+				{Disk: "scsi0", SourceStorage: "local-lvm", TargetStorage: "thin1", Size: 60 * testGiB},
+				{Disk: "scsi1", SourceStorage: "local-lvm", TargetStorage: "thin1", Size: 10 * testGiB},
+			}, false,
+		},
+		{
+			"second disk without room", "qemu",
+			[]proxmox.VMDisk{placementTestDisk("scsi0", "local-lvm", "lvmthin", 60), placementTestDisk("scsi1", "local-lvm", "lvmthin", 50)},
+			[]proxmox.StoragePool{placementTestPool("local-lvm", "lvmthin", "images", 100, 0)}, 100,
+			nil, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := proxmox.VM{VMID: 100, Type: tt.vmType, Disks: tt.disks}
+			got, reason := PlanDiskPlacement(vm, tt.pools, tt.maxPercent)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("placements %+v, want %+v", got, tt.want)
+			}
+			if (reason != "") != tt.unplaced {
+				t.Errorf("reason %q, want one: %v", reason, tt.unplaced)
+			}
+		})
+	}
+}
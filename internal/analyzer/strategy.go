@@ -0,0 +1,315 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// PlacementStrategy ranks the targets a VM can go to. Hard checks (capacity
+// policy, storage pools, placement rules) are done before a strategy sees a
+// target; the strategy only decides which of the remaining ones is best.
+type PlacementStrategy interface {
+	Name() string        // Name used by --strategy and the config file, e.g. "binpack"
+	Description() string // One line for the criteria view and usage text
+
+	// ScoreTarget scores placing c.VM on the candidate target (higher = better)
+	ScoreTarget(c *TargetCandidate) ScoreBreakdown
+	// Prefer reports whether scored candidate a ranks before b
+	Prefer(a, b *TargetCandidate) bool
+	// Reason describes why the chosen target won
+	Reason(c *TargetCandidate) string
+
+	// ScoreMove scores one donor to receiver move of cluster-wide balancing
+	// (higher = better, moves scoring 0 or less are skipped)
+	ScoreMove(m BalanceMove) ScoreBreakdown
+}
+
+// TargetCandidate is a target that passed every hard check for a VM
+type TargetCandidate struct {
+	Name        string
+	VM          *proxmox.VM
+	Before      NodeState
+	After       NodeState // Projected state with the VM added
+	VCPUPercent float64   // vCPU allocation after the move, % of the host's threads
+	CPUPriority int       // Raw CPU generation priority (GetCPURawPriority), 100 without node data
+	CPUScore    float64   // CPU priority normalized within the cluster (0-100)
+
+	// Migrate All only: the cluster averages targets should stay below
+	Averages     *ClusterAverages
+	BelowAverage bool // After stays within the averages plus the soft margin
+
+	Score ScoreBreakdown // Set from ScoreTarget
+}
+
+// BalanceMove is one VM move evaluated by cluster-wide balancing
+type BalanceMove struct {
+	VM          *proxmox.VM
+	Donor       string
+	Receiver    string
+	Improvement float64 // Drop of the donor and receiver RAM deviation from the cluster average (points)
+
+	// Receiver after the move
+	ReceiverRAMPercent  float64
+	ReceiverVCPUPercent float64
+	ReceiverVMs         int
+	ReceiverCPUPriority int // Raw CPU generation priority
+}
+
+// sizeBonus prefers moving small VMs: 100 divided by local disk GiB (VMs under 1 GiB score 100)
+func (m BalanceMove) sizeBonus() float64 {
+	storageGiB := float64(m.VM.GetLocalDisk()) / (1024 * 1024 * 1024)
+	if storageGiB < 1 {
+		storageGiB = 1
+	}
+	return 100.0 / storageGiB
+}
+
+// ScoreComponent is one weighted term of a strategy's score
+type ScoreComponent struct {
+	Name   string
+	Value  float64
+	Weight float64
+}
+
+// newScore adds up the components of a strategy's score
+func newScore(strategy string, components ...ScoreComponent) ScoreBreakdown {
+	score := ScoreBreakdown{Strategy: strategy, Components: components}
+	for _, c := range components {
+		score.TotalScore += c.Value * c.Weight
+	}
+	return score
+}
+
+// Placement strategies, selectable with --strategy, defaults.strategy and 'g' in the criteria view
+var (
+	DefaultStrategy       PlacementStrategy = defaultStrategy{}
+	BinPackStrategy       PlacementStrategy = binPackStrategy{}
+	SpreadStrategy        PlacementStrategy = spreadStrategy{}
+	CPUGenerationStrategy PlacementStrategy = cpuGenerationStrategy{}
+)
+
+// PlacementStrategies lists the strategies in the order the criteria view cycles through them
+var PlacementStrategies = []PlacementStrategy{DefaultStrategy, BinPackStrategy, SpreadStrategy, CPUGenerationStrategy}
+
+// ParsePlacementStrategy returns the strategy with the given name ("" = default)
+func ParsePlacementStrategy(name string) (PlacementStrategy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultStrategy, nil
+	}
+	var names []string
+	for _, s := range PlacementStrategies {
+		if s.Name() == name {
+			return s, nil
+		}
+		names = append(names, s.Name())
+	}
+	return DefaultStrategy, &ValidationError{Field: "strategy", Message: fmt.Sprintf("unknown placement strategy %s (%s)", name, strings.Join(names, ", "))}
+}
+
+// NextPlacementStrategy returns the strategy after s in PlacementStrategies, wrapping around
+func NextPlacementStrategy(s PlacementStrategy) PlacementStrategy {
+	for i, c := range PlacementStrategies {
+		if s != nil && c.Name() == s.Name() {
+			return PlacementStrategies[(i+1)%len(PlacementStrategies)]
+		}
+	}
+	return PlacementStrategies[0]
+}
+
+// cpuTierDiff is the raw CPU priority difference treated as another CPU generation
+// Raw priority values: 1st gen ~200, 2nd gen ~300, 3rd gen ~400, 4th gen ~500, 5th gen ~600
+const cpuTierDiff = 50
+
+// defaultStrategy is the original behavior: newer CPU generations first, then
+// the least utilized and most balanced target (below the cluster average in Migrate All)
+type defaultStrategy struct{}
+
+func (defaultStrategy) Name() string { return "default" }
+
+func (defaultStrategy) Description() string {
+	return "Newest CPU generation first, then the least utilized and most balanced host"
+}
+
+func (defaultStrategy) ScoreTarget(c *TargetCandidate) ScoreBreakdown {
+	balanceScore := calculateBalanceScoreDetailed(c.After)
+
+	// Raw CPU priority (200-600 scale) dominates: 4th gen Scalable (500) vs 1st gen (200)
+	// is a 300 point difference; the other terms break ties within a CPU tier
+	if c.Averages == nil {
+		utilizationScore := 100 - c.After.GetUtilizationScore()
+		score := newScore("default",
+			ScoreComponent{Name: "CPU generation", Value: float64(c.CPUPriority), Weight: 1},
+			ScoreComponent{Name: "Utilization", Value: utilizationScore, Weight: 0.1},
+			ScoreComponent{Name: "Balance", Value: balanceScore, Weight: 0.1},
+		)
+		score.UtilizationScore = utilizationScore
+		score.BalanceScore = balanceScore
+		score.CPUPriorityScore = c.CPUScore
+		score.UtilizationWeight = 0.1
+		score.BalanceWeight = 0.1
+		return score
+	}
+
+	// Migrate All: headroom below the cluster average (CPU%, RAM% and vCPU%)
+	cpuHeadroom := c.Averages.CPUPercent - c.After.CPUPercent
+	ramHeadroom := c.Averages.RAMPercent - c.After.RAMPercent
+	vcpuHeadroom := c.Averages.VCPUPercent - c.VCPUPercent
+	headroomScore := cpuHeadroom*0.3 + ramHeadroom*0.4 + vcpuHeadroom*0.1
+	score := newScore("default",
+		ScoreComponent{Name: "CPU generation", Value: float64(c.CPUPriority), Weight: 1},
+		ScoreComponent{Name: "Headroom", Value: headroomScore, Weight: 0.1},
+		ScoreComponent{Name: "Balance", Value: balanceScore, Weight: 0.1},
+	)
+	score.HeadroomScore = headroomScore
+	score.BalanceScore = balanceScore
+	score.CPUPriorityScore = c.CPUScore
+	score.BalanceWeight = 0.2
+	return score
+}
+
+func (defaultStrategy) Prefer(a, b *TargetCandidate) bool {
+	// Significant CPU generation difference - prefer newer
+	if diff := a.CPUPriority - b.CPUPriority; diff > cpuTierDiff || diff < -cpuTierDiff {
+		return diff > 0
+	}
+	// Within the same CPU tier, prefer below-average hosts (Migrate All)
+	if a.BelowAverage != b.BelowAverage {
+		return a.BelowAverage
+	}
+	return a.Score.TotalScore > b.Score.TotalScore
+}
+
+func (defaultStrategy) Reason(c *TargetCandidate) string {
+	switch {
+	case c.Averages == nil:
+		return fmt.Sprintf("Good balance (CPU: %.1f%%, RAM: %.1f%%, Storage: %.1f%%)", c.After.CPUPercent, c.After.RAMPercent, c.After.StoragePercent)
+	case c.BelowAverage:
+		return fmt.Sprintf("Balanced (CPU: %.1f%%, RAM: %.1f%%, vCPU: %.0f%%)", c.After.CPUPercent, c.After.RAMPercent, c.VCPUPercent)
+	}
+	return fmt.Sprintf("Best available (CPU: %.1f%%, RAM: %.1f%%, vCPU: %.0f%%)", c.After.CPUPercent, c.After.RAMPercent, c.VCPUPercent)
+}
+
+func (defaultStrategy) ScoreMove(m BalanceMove) ScoreBreakdown {
+	return newScore("default",
+		ScoreComponent{Name: "Balance improvement", Value: m.Improvement, Weight: 10},
+		ScoreComponent{Name: "Small VM", Value: m.sizeBonus(), Weight: 1},
+	)
+}
+
+// binPackStrategy fills the fullest hosts that still fit first, so the
+// least used hosts drain and can be powered down
+type binPackStrategy struct{}
+
+func (binPackStrategy) Name() string { return "binpack" }
+
+func (binPackStrategy) Description() string {
+	return "Bin-packing: fill the fullest hosts first so emptied hosts can be powered down"
+}
+
+func (binPackStrategy) ScoreTarget(c *TargetCandidate) ScoreBreakdown {
+	// Balance keeps one resource from filling up while the others stay empty
+	return newScore("binpack",
+		ScoreComponent{Name: "Fill", Value: c.After.GetUtilizationScore(), Weight: 1},
+		ScoreComponent{Name: "Balance", Value: calculateBalanceScoreDetailed(c.After), Weight: 0.1},
+	)
+}
+
+func (binPackStrategy) Prefer(a, b *TargetCandidate) bool {
+	return a.Score.TotalScore > b.Score.TotalScore
+}
+
+func (binPackStrategy) Reason(c *TargetCandidate) string {
+	return fmt.Sprintf("Fullest host that fits (CPU: %.1f%%, RAM: %.1f%%, Storage: %.1f%%)", c.After.CPUPercent, c.After.RAMPercent, c.After.StoragePercent)
+}
+
+func (binPackStrategy) ScoreMove(m BalanceMove) ScoreBreakdown {
+	return newScore("binpack",
+		ScoreComponent{Name: "Balance improvement", Value: m.Improvement, Weight: 10},
+		ScoreComponent{Name: "Small VM", Value: m.sizeBonus(), Weight: 1},
+		ScoreComponent{Name: "Receiver fill", Value: m.ReceiverRAMPercent, Weight: 0.5},
+	)
+}
+
+// spreadStrategy puts each VM on the host with the most headroom and the
+// fewest VMs, so losing a host affects as few VMs as possible
+type spreadStrategy struct{}
+
+func (spreadStrategy) Name() string { return "spread" }
+
+func (spreadStrategy) Description() string {
+	return "Spread: most headroom and fewest VMs first, so a host failure affects fewer VMs"
+}
+
+// spreadVMScore is 100 for a single VM and shrinks as VMs pile up on a host
+func spreadVMScore(vms int) float64 {
+	return 100 / float64(max(vms, 1))
+}
+
+func (spreadStrategy) ScoreTarget(c *TargetCandidate) ScoreBreakdown {
+	peak := math.Max(c.After.CPUPercent, math.Max(c.After.RAMPercent, c.After.StoragePercent))
+	return newScore("spread",
+		ScoreComponent{Name: "Headroom", Value: 100 - peak, Weight: 1},
+		ScoreComponent{Name: "VM density", Value: spreadVMScore(c.After.VMCount), Weight: 0.5},
+	)
+}
+
+func (spreadStrategy) Prefer(a, b *TargetCandidate) bool {
+	// Staying below the cluster average is spreading too (Migrate All)
+	if a.BelowAverage != b.BelowAverage {
+		return a.BelowAverage
+	}
+	return a.Score.TotalScore > b.Score.TotalScore
+}
+
+func (spreadStrategy) Reason(c *TargetCandidate) string {
+	return fmt.Sprintf("Most headroom (CPU: %.1f%%, RAM: %.1f%%, Storage: %.1f%%, %d VMs)", c.After.CPUPercent, c.After.RAMPercent, c.After.StoragePercent, c.After.VMCount)
+}
+
+func (spreadStrategy) ScoreMove(m BalanceMove) ScoreBreakdown {
+	return newScore("spread",
+		ScoreComponent{Name: "Balance improvement", Value: m.Improvement, Weight: 10},
+		ScoreComponent{Name: "Small VM", Value: m.sizeBonus(), Weight: 1},
+		ScoreComponent{Name: "VM density", Value: spreadVMScore(m.ReceiverVMs), Weight: 0.5},
+	)
+}
+
+// cpuGenerationStrategy always picks the newest CPU generation that fits;
+// unlike the default strategy, every priority difference counts, not only
+// a different tier
+type cpuGenerationStrategy struct{}
+
+func (cpuGenerationStrategy) Name() string { return "cpu_generation" }
+
+func (cpuGenerationStrategy) Description() string {
+	return "CPU generation first: always the newest CPU that fits, then the least utilized host"
+}
+
+func (cpuGenerationStrategy) ScoreTarget(c *TargetCandidate) ScoreBreakdown {
+	return newScore("cpu_generation",
+		ScoreComponent{Name: "CPU generation", Value: float64(c.CPUPriority), Weight: 1},
+		ScoreComponent{Name: "Utilization", Value: 100 - c.After.GetUtilizationScore(), Weight: 0.1},
+	)
+}
+
+func (cpuGenerationStrategy) Prefer(a, b *TargetCandidate) bool {
+	if a.CPUPriority != b.CPUPriority {
+		return a.CPUPriority > b.CPUPriority
+	}
+	return a.Score.TotalScore > b.Score.TotalScore
+}
+
+func (cpuGenerationStrategy) Reason(c *TargetCandidate) string {
+	return fmt.Sprintf("Newest CPU that fits (priority %d, CPU: %.1f%%, RAM: %.1f%%)", c.CPUPriority, c.After.CPUPercent, c.After.RAMPercent)
+}
+
+func (cpuGenerationStrategy) ScoreMove(m BalanceMove) ScoreBreakdown {
+	return newScore("cpu_generation",
+		ScoreComponent{Name: "Balance improvement", Value: m.Improvement, Weight: 10},
+		ScoreComponent{Name: "Small VM", Value: m.sizeBonus(), Weight: 1},
+		ScoreComponent{Name: "CPU generation", Value: float64(m.ReceiverCPUPriority), Weight: 0.1},
+	)
+}
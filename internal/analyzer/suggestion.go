@@ -2,6 +2,7 @@ package analyzer
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/yourusername/migsug/internal/proxmox"
 )
@@ -15,23 +16,82 @@ type MigrationSuggestion struct {
 	Reason     string
 	Score      float64 // Target selection score
 	Status     string  // VM status: "running" or "stopped"
+	Type       string  // qemu or lxc
 
 	// VM resources
 	VCPUs    int
 	CPUUsage float64
 	RAM      int64
-	Storage  int64 // Effective disk (UsedDisk if available, else MaxDisk)
+	Storage  int64 // Local disk, see proxmox.VM.GetLocalDisk (shared disks stay on their storage)
 	UsedDisk int64 // Actual disk usage (thin provisioning)
 	MaxDisk  int64 // Allocated/provisioned disk size
 
+	Transfer   int64 // Estimated bytes copied by the migration (see EstimateTransferBytes)
+	LiveMemory int64 // Part of Transfer that is guest RAM copied by a live migration (0 when offline)
+
 	// Host info for CPU% calculations
 	SourceCores int // Source host's CPU cores/threads
 	TargetCores int // Target host's CPU cores/threads
 
+	// Target storage per disk (nil when storages are unknown, disks keep their storage IDs)
+	Disks []DiskPlacement
+
 	// Detailed migration reasoning
 	Details *MigrationDetails
 }
 
+// MigrateCommand returns the pvesh command that performs this migration
+// The command works from any cluster node; running VMs are migrated online,
+// running containers in restart mode. Disks moving to another storage ID get
+// a target storage mapping.
+func (s MigrationSuggestion) MigrateCommand() string {
+	guestType := proxmox.GuestType(s.Type)
+	cmd := fmt.Sprintf("pvesh create /nodes/%s/%s/%d/migrate --target %s", s.SourceNode, guestType, s.VMID, s.TargetNode)
+	if s.Status == "running" {
+		if guestType == "lxc" {
+			cmd += " --restart 1"
+		} else {
+			cmd += " --online 1"
+		}
+	}
+	if targetStorage := s.TargetStorage(); targetStorage != "" {
+		if guestType == "lxc" {
+			cmd += " --target-storage " + targetStorage
+		} else {
+			cmd += " --targetstorage " + targetStorage
+			if s.Status == "running" {
+				cmd += " --with-local-disks 1"
+			}
+		}
+	}
+	return cmd
+}
+
+// EstimateTransferBytes estimates the data a migration copies over the network:
+// the disks on local storage, plus the guest's memory when a running VM is live-migrated.
+// Disks on shared storage stay in place.
+func EstimateTransferBytes(vm proxmox.VM) int64 {
+	return vm.GetLocalDisk() + LiveMemoryBytes(vm)
+}
+
+// LiveMemoryBytes returns the guest memory a live migration copies
+// Zero for stopped VMs and containers, which are not migrated live
+func LiveMemoryBytes(vm proxmox.VM) int64 {
+	if vm.Status != "running" || proxmox.GuestType(vm.Type) == "lxc" {
+		return 0
+	}
+	if vm.UsedMem > 0 {
+		return vm.UsedMem
+	}
+	return vm.MaxMem
+}
+
+// NeedsRestart returns true if the migration stops and restarts the guest
+// Running LXC containers can't be live-migrated and incur downtime
+func (s MigrationSuggestion) NeedsRestart() bool {
+	return s.Status == "running" && proxmox.GuestType(s.Type) == "lxc"
+}
+
 // MigrationDetails contains comprehensive reasoning for why a VM was migrated to a specific host
 type MigrationDetails struct {
 	// VM Selection Info
@@ -68,6 +128,10 @@ type ScoreBreakdown struct {
 	// Weights used
 	UtilizationWeight float64
 	BalanceWeight     float64
+
+	// Placement strategy that ranked the target and its terms (TotalScore = sum of Value × Weight)
+	Strategy   string
+	Components []ScoreComponent
 }
 
 // ResourceState captures resource utilization at a point in time
@@ -100,7 +164,7 @@ type UnmigrateableVM struct {
 	Status  string // running or stopped
 	VCPUs   int
 	RAM     int64
-	Storage int64
+	Storage int64  // Local disk
 	Reason  string // Why the VM cannot be migrated
 }
 
@@ -120,9 +184,40 @@ type AnalysisResult struct {
 	TotalStorage    int64
 	ImprovementInfo string
 
+	// Execution order in waves (nil = run the suggestions as a flat list)
+	Staged *StagedPlan
+
+	// Affinity groups still violated once the plan has run
+	AffinityViolations []AffinityViolation
+
+	// Placement rule violations found by a constraint audit (nil for other modes)
+	Audit *ConstraintAudit
+
+	// Host drain this result belongs to (nil for other modes)
+	Drain *DrainInfo
+
+	// Hypothetical hardware the result was simulated on (nil = real cluster)
+	WhatIf *WhatIfInfo
+
+	// CPU statistic the VM and host CPU usage came from, e.g. "day p95" ("" = current usage)
+	CPUMetric string
+
+	// Predicted migration time (nil until estimated, see EstimateSettings.EstimateResult)
+	Estimate *PlanEstimate
+
+	// Global optimizer statistics (nil for the greedy balance and other modes)
+	Optimization *OptimizationReport
+
+	// Greedy cluster-wide balance: score against the budget spent (nil for other modes)
+	Budget *BalanceBudget
+
 	// Balance cluster analysis statistics
 	MovementsTried   int  // Number of migration attempts evaluated during analysis
 	IsBalanceCluster bool // True if this is a cluster-wide balance result (no single source)
+
+	// Analysis inputs (recorded for export)
+	Constraints        MigrationConstraints // Constraints the analysis was run with
+	ClusterCollectedAt time.Time            // When the analyzed cluster data was collected
 }
 
 // NodeState represents the state of a node before or after migration
@@ -131,7 +226,7 @@ type NodeState struct {
 	VMCount        int
 	VCPUs          int     // Total vCPUs allocated to VMs
 	CPUCores       int     // Physical CPU cores/threads
-	CPUUsageTotal  float64 // Total CPU usage value
+	CPUUsageTotal  float64 // Busy cores: CPU usage × cores, summed the way CalculateAfterMigration adds VMs
 	CPUPercent     float64 // vCPU allocation as percentage of cores (can exceed 100% due to oversubscription)
 	HostCPUPercent float64 // Actual host CPU usage percentage (0-100)
 	RAMUsed        int64
@@ -140,6 +235,8 @@ type NodeState struct {
 	StorageUsed    int64
 	StorageTotal   int64
 	StoragePercent float64
+
+	Pools []proxmox.StoragePool // Per-pool usage for disk placement (nil = unknown)
 }
 
 // NewNodeState creates a NodeState from a proxmox.Node
@@ -157,7 +254,7 @@ func NewNodeState(node *proxmox.Node) NodeState {
 		VMCount:        len(node.VMs),
 		VCPUs:          totalVCPUs,
 		CPUCores:       node.CPUCores,
-		CPUUsageTotal:  node.CPUUsage,
+		CPUUsageTotal:  node.CPUUsage * float64(node.CPUCores), // node.CPUUsage is a 0-1 fraction
 		CPUPercent:     node.GetCPUPercent(),
 		RAMUsed:        node.UsedMem,
 		RAMTotal:       node.MaxMem,
@@ -165,6 +262,7 @@ func NewNodeState(node *proxmox.Node) NodeState {
 		StorageUsed:    node.UsedDisk,
 		StorageTotal:   node.MaxDisk,
 		StoragePercent: node.GetDiskPercent(),
+		Pools:          node.Storages,
 	}
 }
 
@@ -185,7 +283,7 @@ func NewNodeStateFromVMs(node *proxmox.Node) NodeState {
 		}
 		// Storage is always counted (disk space is used regardless of power state)
 		// Use actual thin provisioning size (UsedDisk) when available
-		totalStorage += vm.GetEffectiveDisk()
+		totalStorage += vm.GetLocalDisk()
 	}
 
 	// Calculate percentages based on node capacity
@@ -215,6 +313,7 @@ func NewNodeStateFromVMs(node *proxmox.Node) NodeState {
 		StorageUsed:    totalStorage,
 		StorageTotal:   node.MaxDisk,
 		StoragePercent: storagePercent,
+		Pools:          node.Storages,
 	}
 }
 
@@ -235,10 +334,11 @@ func (ns NodeState) CalculateAfterMigration(addVMs []proxmox.VM, removeVMs []pro
 		}
 		// Storage is always counted (disk space is used regardless of power state)
 		// Use actual thin provisioning size (UsedDisk) when available
-		newState.StorageUsed -= vm.GetEffectiveDisk()
+		newState.StorageUsed -= vm.GetLocalDisk()
+		newState.Pools = releaseDisks(newState.Pools, vm)
 	}
 
-	// Add VMs
+	// Add VMs (pool usage is added by the caller, see PlanDiskPlacement)
 	for _, vm := range addVMs {
 		newState.VMCount++
 		// Only add vCPUs/CPU/RAM for running VMs (stopped VMs don't contribute to usage)
@@ -250,7 +350,7 @@ func (ns NodeState) CalculateAfterMigration(addVMs []proxmox.VM, removeVMs []pro
 		}
 		// Storage is always counted (disk space is used regardless of power state)
 		// Use actual thin provisioning size (UsedDisk) when available
-		newState.StorageUsed += vm.GetEffectiveDisk()
+		newState.StorageUsed += vm.GetLocalDisk()
 	}
 
 	// Ensure values don't go negative
@@ -306,6 +406,12 @@ func (ns NodeState) CalculateAfterMigration(addVMs []proxmox.VM, removeVMs []pro
 // HasCapacity checks if the node has capacity for a VM
 // Uses MaxMem for RAM check to ensure there's room to power on the VM
 func (ns NodeState) HasCapacity(vm proxmox.VM, constraints MigrationConstraints) bool {
+	return ns.HasCapacityWithPolicy(vm, constraints, constraints.GetPolicy())
+}
+
+// HasCapacityWithPolicy is HasCapacity with explicit capacity limits
+// Callers pass the target's policy (CapacityPolicy.ForNode) so per-node overrides apply
+func (ns NodeState) HasCapacityWithPolicy(vm proxmox.VM, constraints MigrationConstraints, policy CapacityPolicy) bool {
 	// Check RAM capacity - use MaxMem to ensure there's room to power on the VM
 	// Even if VM is currently stopped, we need capacity for when it's powered on
 	newRAMUsed := ns.RAMUsed + vm.MaxMem
@@ -314,11 +420,16 @@ func (ns NodeState) HasCapacity(vm proxmox.VM, constraints MigrationConstraints)
 	}
 
 	// Check storage capacity - use actual thin provisioning size
-	newStorageUsed := ns.StorageUsed + vm.GetEffectiveDisk()
+	newStorageUsed := ns.StorageUsed + vm.GetLocalDisk()
 	if newStorageUsed > ns.StorageTotal {
 		return false
 	}
 
+	// Check hard limits of the capacity policy
+	if ns.PolicyViolation(vm, policy) != "" {
+		return false
+	}
+
 	// Check minimum free requirements
 	if constraints.MinRAMFree != nil {
 		ramFree := ns.RAMTotal - newRAMUsed
@@ -328,7 +439,7 @@ func (ns NodeState) HasCapacity(vm proxmox.VM, constraints MigrationConstraints)
 	}
 
 	if constraints.MinCPUFree != nil {
-		newCPUUsage := ns.CPUUsageTotal + (vm.CPUUsage / 100)
+		newCPUUsage := ns.CPUUsageTotal + vm.CPUUsage*float64(vm.CPUCores)/100
 		if ns.CPUCores > 0 {
 			cpuPercent := (newCPUUsage / float64(ns.CPUCores)) * 100
 			cpuFree := 100 - cpuPercent
@@ -341,6 +452,34 @@ func (ns NodeState) HasCapacity(vm proxmox.VM, constraints MigrationConstraints)
 	return true
 }
 
+// PolicyViolation returns why adding a VM would exceed a hard limit of the policy
+// Returns an empty string if the VM fits
+func (ns NodeState) PolicyViolation(vm proxmox.VM, policy CapacityPolicy) string {
+	if ns.RAMTotal > 0 {
+		ramPercent := float64(ns.RAMUsed+vm.MaxMem) / float64(ns.RAMTotal) * 100
+		if ramPercent > policy.MaxRAMPercent {
+			return fmt.Sprintf("RAM would reach %.1f%% (limit %.0f%%)", ramPercent, policy.MaxRAMPercent)
+		}
+	}
+
+	if ns.StorageTotal > 0 {
+		storagePercent := float64(ns.StorageUsed+vm.GetLocalDisk()) / float64(ns.StorageTotal) * 100
+		if storagePercent > policy.MaxStoragePercent {
+			return fmt.Sprintf("Storage would reach %.1f%% (limit %.0f%%)", storagePercent, policy.MaxStoragePercent)
+		}
+	}
+
+	// HCPU% contribution on target = vm.CPUUsage * vm.CPUCores / targetCores
+	if ns.CPUCores > 0 && vm.Status == "running" {
+		hostCPUPercent := ns.CPUPercent + vm.CPUUsage*float64(vm.CPUCores)/float64(ns.CPUCores)
+		if hostCPUPercent > policy.MaxHostCPUPercent {
+			return fmt.Sprintf("Host CPU would reach %.1f%% (limit %.0f%%)", hostCPUPercent, policy.MaxHostCPUPercent)
+		}
+	}
+
+	return ""
+}
+
 // GetUtilizationScore returns a score representing overall utilization (lower is better for targets)
 func (ns NodeState) GetUtilizationScore() float64 {
 	// Weighted score based on resource utilization
@@ -376,15 +515,20 @@ type StorageHeadroomCheck struct {
 // CheckStorageHeadroom verifies if a target node has sufficient storage headroom after receiving a VM
 // The constraint is: host must have at least 500GiB + 15% of largest VM's storage free after migration
 func CheckStorageHeadroom(targetNode *proxmox.Node, incomingVM proxmox.VM, currentStorageUsed, storageTotal int64) StorageHeadroomCheck {
+	return CheckStorageHeadroomWithPolicy(targetNode, incomingVM, currentStorageUsed, storageTotal, DefaultCapacityPolicy)
+}
+
+// CheckStorageHeadroomWithPolicy is CheckStorageHeadroom using the headroom of a capacity policy
+func CheckStorageHeadroomWithPolicy(targetNode *proxmox.Node, incomingVM proxmox.VM, currentStorageUsed, storageTotal int64, policy CapacityPolicy) StorageHeadroomCheck {
 	result := StorageHeadroomCheck{}
 
 	// Get storage of the incoming VM - use actual thin provisioning size
-	incomingVMStorage := incomingVM.GetEffectiveDisk()
+	incomingVMStorage := incomingVM.GetLocalDisk()
 
 	// Find the largest VM's storage on the target (including the incoming VM)
 	largestVMStorage := incomingVMStorage
 	for _, vm := range targetNode.VMs {
-		vmStorage := vm.GetEffectiveDisk()
+		vmStorage := vm.GetLocalDisk()
 		if vmStorage > largestVMStorage {
 			largestVMStorage = vmStorage
 		}
@@ -394,10 +538,9 @@ func CheckStorageHeadroom(targetNode *proxmox.Node, incomingVM proxmox.VM, curre
 	// Calculate storage used after migration
 	storageUsedAfter := currentStorageUsed + incomingVMStorage
 
-	// Calculate required headroom: 500 GiB + 15% of largest VM's storage
-	minHeadroomBytes := int64(MinStorageHeadroomGiB) * 1024 * 1024 * 1024
-	largestVMHeadroom := int64(float64(largestVMStorage) * LargestVMStorageHeadroomPercent)
-	result.RequiredFreeStorage = minHeadroomBytes + largestVMHeadroom
+	// Calculate required headroom: 500 GiB + 15% of largest VM's storage (by default)
+	largestVMHeadroom := int64(float64(largestVMStorage) * policy.LargestVMHeadroomPercent / 100)
+	result.RequiredFreeStorage = policy.MinStorageHeadroomBytes() + largestVMHeadroom
 
 	// Calculate actual free storage after migration
 	result.ActualFreeStorage = storageTotal - storageUsedAfter
@@ -409,8 +552,8 @@ func CheckStorageHeadroom(targetNode *proxmox.Node, incomingVM proxmox.VM, curre
 		result.HasSufficientHeadroom = false
 		requiredGiB := float64(result.RequiredFreeStorage) / (1024 * 1024 * 1024)
 		actualGiB := float64(result.ActualFreeStorage) / (1024 * 1024 * 1024)
-		result.Reason = fmt.Sprintf("Insufficient storage headroom: need %.0f GiB free (500 + 15%% of %.0f GiB largest VM), only %.0f GiB available",
-			requiredGiB, float64(largestVMStorage)/(1024*1024*1024), actualGiB)
+		result.Reason = fmt.Sprintf("Insufficient storage headroom: need %.0f GiB free (%.0f + %.0f%% of %.0f GiB largest VM), only %.0f GiB available",
+			requiredGiB, policy.MinStorageHeadroomGiB, policy.LargestVMHeadroomPercent, float64(largestVMStorage)/(1024*1024*1024), actualGiB)
 	}
 
 	return result
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// cpuTestNode is a 64-thread host at 50% CPU (32 busy cores) with plenty of RAM and disk
+func cpuTestNode() *proxmox.Node {
+	return &proxmox.Node{
+		Name:     "pve1",
+		Status:   "online",
+		CPUCores: 64,
+		CPUUsage: 0.5,
+		MaxMem:   1 << 40,
+		MaxDisk:  1 << 42,
+		VMs: []proxmox.VM{
+			{VMID: 100, Name: "busy", Status: "running", CPUCores: 16, CPUUsage: 100, MaxMem: 1 << 30},
+			{VMID: 101, Name: "idle", Status: "running", CPUCores: 4, CPUUsage: 0, MaxMem: 1 << 30},
+		},
+	}
+}
+
+func TestNodeStateCPUBusyCores(t *testing.T) {
+	// A VM with 8 vCPUs at 100% keeps 8 cores busy, one at 50% keeps 4 busy
+	full := proxmox.VM{VMID: 1, Status: "running", CPUCores: 8, CPUUsage: 100}
+	half := proxmox.VM{VMID: 2, Status: "running", CPUCores: 8, CPUUsage: 50}
+	stopped := proxmox.VM{VMID: 3, Status: "stopped", CPUCores: 8, CPUUsage: 100}
+	busy := cpuTestNode().VMs[0]
+
+	tests := []struct {
+		name        string
+		add, remove []proxmox.VM
+		busyCores   float64
+		cpuPercent  float64
+	}{
+		{"unchanged", nil, nil, 32, 50},
+		{"add a full VM", []proxmox.VM{full}, nil, 40, 62.5},
+		{"add two VMs", []proxmox.VM{full, half}, nil, 44, 68.75},
+		{"add a stopped VM", []proxmox.VM{stopped}, nil, 32, 50},
+		{"remove a VM", nil, []proxmox.VM{busy}, 16, 25},
+		{"swap", []proxmox.VM{half}, []proxmox.VM{busy}, 20, 31.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := NewNodeState(cpuTestNode())
+			if before.CPUUsageTotal != 32 || before.CPUPercent != 50 {
+				t.Fatalf("before: %.2f busy cores, %.2f%%; want 32, 50%%", before.CPUUsageTotal, before.CPUPercent)
+			}
+			after := before
+			if tt.add != nil || tt.remove != nil {
+				after = before.CalculateAfterMigration(tt.add, tt.remove)
+			}
+			if math.Abs(after.CPUUsageTotal-tt.busyCores) > 1e-9 {
+				t.Errorf("after: %.2f busy cores, want %.2f", after.CPUUsageTotal, tt.busyCores)
+			}
+			if math.Abs(after.CPUPercent-tt.cpuPercent) > 1e-9 {
+				t.Errorf("after: %.2f%% CPU, want %.2f%%", after.CPUPercent, tt.cpuPercent)
+			}
+		})
+	}
+}
+
+func TestNodeStateCPULimitsAfterMigration(t *testing.T) {
+	// 8 full vCPUs add 12.5 points to the 64-thread host
+	vm := proxmox.VM{VMID: 1, Status: "running", CPUCores: 8, CPUUsage: 100, MaxMem: 1 << 30}
+
+	tests := []struct {
+		name   string
+		placed int // VMs already placed on the host in this analysis
+		minCPU float64
+		fits   bool
+	}{
+		{"first VM", 0, 20, true},                          // 50% -> 62.5%, 37.5% free
+		{"second VM", 1, 20, true},                         // 62.5% -> 75%, 25% free
+		{"third VM below MinCPUFree", 2, 20, false},        // 75% -> 87.5%, 12.5% free
+		{"fourth VM over the host CPU limit", 3, 0, false}, // 87.5% -> 100% > 95%
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewNodeState(cpuTestNode())
+			for i := 0; i < tt.placed; i++ {
+				placed := vm
+				placed.VMID = 10 + i
+				state = state.CalculateAfterMigration([]proxmox.VM{placed}, nil)
+			}
+			constraints := MigrationConstraints{}
+			if tt.minCPU > 0 {
+				constraints.MinCPUFree = &tt.minCPU
+			}
+			if got := state.HasCapacityWithPolicy(vm, constraints, DefaultCapacityPolicy); got != tt.fits {
+				t.Errorf("HasCapacityWithPolicy at %.1f%% CPU = %v, want %v", state.CPUPercent, got, tt.fits)
+			}
+		})
+	}
+}
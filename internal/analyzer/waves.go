@@ -0,0 +1,601 @@
+package analyzer
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// StagedMigration is one step of a staged plan
+type StagedMigration struct {
+	MigrationSuggestion
+	Temporary bool // Parks the VM on an intermediate host to break a cycle
+}
+
+// MigrationWave is a group of migrations that can run at the same time
+// Each migration only relies on capacity freed by earlier waves
+type MigrationWave struct {
+	Migrations []StagedMigration
+	Unsafe     bool // Remaining migrations that could not be ordered within the hard limits
+}
+
+// StagedPlan orders a plan's migrations into waves
+// Waves run one after another; the migrations of a wave run in parallel
+type StagedPlan struct {
+	Waves  []MigrationWave
+	Merged int // Moves saved by migrating VMs moved several times straight to their final host
+	Cycles int // Cycles broken by parking a VM on a temporary host
+}
+
+// Migrations returns the plan's migrations in execution order
+func (p *StagedPlan) Migrations() []StagedMigration {
+	var all []StagedMigration
+	for _, wave := range p.Waves {
+		all = append(all, wave.Migrations...)
+	}
+	return all
+}
+
+// Count returns the number of migrations in the plan
+func (p *StagedPlan) Count() int {
+	n := 0
+	for _, wave := range p.Waves {
+		n += len(wave.Migrations)
+	}
+	return n
+}
+
+// PlannedSuggestions returns the moves the plan makes, one per VM: a VM the
+// staged plan merged is listed once with its final move from where it is now,
+// a VM moved back to where it started not at all, and VMs without a target as
+// suggested. Without a staged plan these are the suggestions themselves.
+func (r *AnalysisResult) PlannedSuggestions() []MigrationSuggestion {
+	if r.Staged == nil {
+		return r.Suggestions
+	}
+	final := make(map[int]MigrationSuggestion)
+	for _, m := range r.Staged.Migrations() {
+		if !m.Temporary {
+			final[m.VMID] = m.MigrationSuggestion
+		}
+	}
+	var planned []MigrationSuggestion
+	seen := make(map[int]bool)
+	for _, sug := range r.Suggestions {
+		if seen[sug.VMID] {
+			continue
+		}
+		if m, ok := final[sug.VMID]; ok {
+			seen[sug.VMID] = true
+			planned = append(planned, m)
+		} else if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			seen[sug.VMID] = true
+			planned = append(planned, sug)
+		}
+	}
+	return planned
+}
+
+// addPlannedTotals adds the VMs of PlannedSuggestions to the result's totals
+func (r *AnalysisResult) addPlannedTotals() {
+	for _, s := range r.PlannedSuggestions() {
+		r.TotalVMs++
+		r.TotalVCPUs += s.VCPUs
+		r.TotalRAM += s.RAM
+		r.TotalStorage += s.Storage
+	}
+}
+
+// HasUnsafe returns true if some migrations could not be ordered within the hard limits
+func (p *StagedPlan) HasUnsafe() bool {
+	if p == nil {
+		return false
+	}
+	for _, wave := range p.Waves {
+		if wave.Unsafe {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes the plan, e.g. "3 waves, 2 repeated moves merged, 1 cycle broken"
+func (p *StagedPlan) Summary() string {
+	parts := []string{plural(len(p.Waves), "wave", "waves")}
+	if p.Merged > 0 {
+		parts = append(parts, plural(p.Merged, "repeated move merged", "repeated moves merged"))
+	}
+	if p.Cycles > 0 {
+		parts = append(parts, plural(p.Cycles, "cycle broken", "cycles broken"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Title describes the wave at index (0-based), e.g. "Wave 2: 4 migrations in parallel"
+func (w MigrationWave) Title(index int) string {
+	title := fmt.Sprintf("Wave %d: %s", index+1, plural(len(w.Migrations), "migration", "migrations in parallel"))
+	if w.Unsafe {
+		title += " (no order stays within the capacity limits)"
+	}
+	return title
+}
+
+// plural formats a count with the singular or plural noun
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, pluralForm)
+}
+
+// stagedMove is a pending migration of one VM while the plan is built
+type stagedMove struct {
+	sug       MigrationSuggestion
+	vm        proxmox.VM // The VM as it is before this move (current node and storages)
+	temporary bool
+}
+
+// BuildStagedPlan orders suggestions into waves of parallel migrations
+// A VM the balancer moved several times is migrated once, straight to its final
+// host. A wave only contains migrations whose targets stay within the hard limits
+// of the policy and the VMs' anti-affinity rules while the VMs leaving those
+// targets in the same wave are still there. withvm partners moving to the same
+// host share a wave. When nothing fits, e.g. for a swap A→B while B→A, one VM of
+// the cycle is parked on a temporary host first.
+func BuildStagedPlan(cluster *proxmox.Cluster, suggestions []MigrationSuggestion, policy CapacityPolicy) *StagedPlan {
+	plan := &StagedPlan{}
+	pending, merged := consolidateMoves(cluster, suggestions)
+	plan.Merged = merged
+	if len(pending) == 0 {
+		return plan
+	}
+
+	sim := newWaveSimulation(cluster, policy)
+	parked := 0
+	for len(pending) > 0 {
+		wave, rest := sim.nextWave(pending)
+		if len(wave) > 0 {
+			plan.Waves = append(plan.Waves, MigrationWave{Migrations: sim.apply(wave)})
+			pending = rest
+			continue
+		}
+
+		// Nothing fits: park one VM of a cycle on a temporary host
+		if parked < len(suggestions) {
+			if temp, idx, cycle := sim.breakCycle(pending); temp != nil {
+				parked++
+				plan.Cycles++
+				log.Printf("StagedPlan: parking VM %d on %s (cycle: %s)", temp.sug.VMID, temp.sug.TargetNode, cycle)
+				plan.Waves = append(plan.Waves, MigrationWave{Migrations: sim.apply([]*stagedMove{temp})})
+				pending[idx] = withSource(pending[idx].sug, movedVM(temp.vm, &temp.sug))
+				continue
+			}
+		}
+
+		// No safe order exists for the rest; run them last
+		log.Printf("StagedPlan: %d migrations could not be ordered within the capacity limits", len(pending))
+		plan.Waves = append(plan.Waves, MigrationWave{Migrations: sim.apply(pending), Unsafe: true})
+		break
+	}
+	return plan
+}
+
+// consolidateMoves turns suggestions into one move per VM, from its current
+// node to its last suggested target. Returns the moves in suggestion order and
+// the number of suggestions saved.
+func consolidateMoves(cluster *proxmox.Cluster, suggestions []MigrationSuggestion) ([]*stagedMove, int) {
+	vms := make(map[int]proxmox.VM)
+	for _, node := range cluster.Nodes {
+		for _, vm := range node.VMs {
+			vm.Node = node.Name
+			vms[vm.VMID] = vm
+		}
+	}
+
+	var order []int
+	last := make(map[int]MigrationSuggestion)
+	count := make(map[int]int)
+	for _, sug := range suggestions {
+		if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			continue
+		}
+		if count[sug.VMID] == 0 {
+			order = append(order, sug.VMID)
+		}
+		count[sug.VMID]++
+		last[sug.VMID] = sug
+	}
+
+	var moves []*stagedMove
+	merged := 0
+	for _, vmid := range order {
+		sug := last[vmid]
+		vm, ok := vms[vmid]
+		if !ok {
+			// Not in the cluster data: trust the suggestion
+			moves = append(moves, &stagedMove{sug: sug, vm: suggestionVM(sug)})
+			continue
+		}
+		if vm.Node == sug.TargetNode {
+			// Moved back to where it started
+			merged += count[vmid]
+			continue
+		}
+		merged += count[vmid] - 1
+		moves = append(moves, withSource(sug, vm))
+	}
+	return moves, merged
+}
+
+// withSource returns a move of vm to the suggestion's target, starting from the VM's current node and storages
+func withSource(sug MigrationSuggestion, vm proxmox.VM) *stagedMove {
+	sug.SourceNode = vm.Node
+	if len(sug.Disks) > 0 {
+		disks := make([]DiskPlacement, len(sug.Disks))
+		copy(disks, sug.Disks)
+		for i := range disks {
+			for _, disk := range vm.Disks {
+				if disk.Key == disks[i].Disk {
+					disks[i].SourceStorage = disk.Storage
+				}
+			}
+		}
+		sug.Disks = disks
+	}
+	return &stagedMove{sug: sug, vm: vm}
+}
+
+// suggestionVM rebuilds the VM resources a suggestion carries
+func suggestionVM(sug MigrationSuggestion) proxmox.VM {
+	return proxmox.VM{
+		VMID:     sug.VMID,
+		Name:     sug.VMName,
+		Node:     sug.SourceNode,
+		Status:   sug.Status,
+		Type:     sug.Type,
+		CPUCores: sug.VCPUs,
+		CPUUsage: sug.CPUUsage,
+		MaxMem:   sug.RAM,
+		UsedDisk: sug.UsedDisk,
+		MaxDisk:  sug.MaxDisk,
+	}
+}
+
+// waveSimulation tracks node usage and VM locations while waves are applied
+type waveSimulation struct {
+	cluster   *proxmox.Cluster
+	policy    CapacityPolicy
+	nodes     map[string]*proxmox.Node
+	states    map[string]NodeState
+	location  map[string]string // VM name -> node
+	vmsByName map[string]proxmox.VM
+	groups    []AffinityGroup
+}
+
+func newWaveSimulation(cluster *proxmox.Cluster, policy CapacityPolicy) *waveSimulation {
+	sim := &waveSimulation{
+		cluster:   cluster,
+		policy:    policy,
+		nodes:     make(map[string]*proxmox.Node),
+		states:    make(map[string]NodeState),
+		location:  make(map[string]string),
+		vmsByName: make(map[string]proxmox.VM),
+		groups:    ResolveAffinityGroups(cluster, policy.AffinityGroups),
+	}
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		sim.nodes[node.Name] = node
+		sim.states[node.Name] = NewNodeState(node)
+		for _, vm := range node.VMs {
+			sim.location[vm.Name] = node.Name
+			sim.vmsByName[vm.Name] = vm
+		}
+	}
+	return sim
+}
+
+// nextWave picks the pending moves that can run now, keeping withvm groups together
+func (sim *waveSimulation) nextWave(pending []*stagedMove) (wave, rest []*stagedMove) {
+	states := make(map[string]NodeState, len(sim.states))
+	for name, state := range sim.states {
+		states[name] = state
+	}
+	arrivals := make(map[string][]string) // node -> VM names arriving in this wave
+
+	for _, group := range withVMGroups(pending, sim.groups) {
+		groupStates := make(map[string]NodeState)
+		groupArrivals := make(map[string][]string)
+		ok := true
+		for _, move := range group {
+			target := move.sug.TargetNode
+			state, seen := groupStates[target]
+			if !seen {
+				state = states[target]
+			}
+			if reason := sim.blocked(move.vm, target, state, append(arrivals[target], groupArrivals[target]...)); reason != "" {
+				ok = false
+				break
+			}
+			groupStates[target] = state.CalculateAfterMigration([]proxmox.VM{move.vm}, nil)
+			groupArrivals[target] = append(groupArrivals[target], move.vm.Name)
+		}
+		if !ok {
+			rest = append(rest, group...)
+			continue
+		}
+		for name, state := range groupStates {
+			states[name] = state
+		}
+		for name, vms := range groupArrivals {
+			arrivals[name] = append(arrivals[name], vms...)
+		}
+		wave = append(wave, group...)
+	}
+
+	// Keep the original order for the next round
+	if len(rest) > 0 {
+		inRest := make(map[*stagedMove]bool, len(rest))
+		for _, move := range rest {
+			inRest[move] = true
+		}
+		rest = rest[:0]
+		for _, move := range pending {
+			if inRest[move] {
+				rest = append(rest, move)
+			}
+		}
+	}
+	return wave, rest
+}
+
+// blocked returns why the VM can't arrive on the node in the given state
+// arriving lists the VMs already arriving there in the same wave
+func (sim *waveSimulation) blocked(vm proxmox.VM, target string, state NodeState, arriving []string) string {
+	if reason := state.PolicyViolation(vm, sim.policy.ForNode(sim.nodes[target])); reason != "" {
+		return reason
+	}
+	present := arriving
+	for name, node := range sim.location {
+		if node == target {
+			present = append(present, name)
+		}
+	}
+	for _, name := range present {
+		if name == vm.Name {
+			continue
+		}
+		if containsString(vm.WithoutVM, name) || containsString(sim.vmsByName[name].WithoutVM, vm.Name) {
+			return fmt.Sprintf("Cannot be with VM '%s'", name)
+		}
+	}
+	// Spread groups may not exceed their per-host limit, even for a moment
+	for _, g := range vmAffinityGroups(vm, sim.groups) {
+		if g.IsPack() {
+			continue
+		}
+		count := 0
+		for _, name := range present {
+			if name != vm.Name && g.Contains(sim.vmsByName[name]) {
+				count++
+			}
+		}
+		if count >= g.Limit() {
+			return fmt.Sprintf("Group '%s' (spread): %d members already on %s", g.Name, count, target)
+		}
+	}
+	return ""
+}
+
+// apply records a wave's migrations in the simulated cluster
+func (sim *waveSimulation) apply(moves []*stagedMove) []StagedMigration {
+	var migrations []StagedMigration
+	for _, move := range moves {
+		source, target := move.sug.SourceNode, move.sug.TargetNode
+		if state, ok := sim.states[source]; ok {
+			sim.states[source] = state.CalculateAfterMigration(nil, []proxmox.VM{move.vm})
+		}
+		if state, ok := sim.states[target]; ok {
+			sim.states[target] = state.CalculateAfterMigration([]proxmox.VM{move.vm}, nil)
+		}
+		sim.location[move.vm.Name] = target
+		migrations = append(migrations, StagedMigration{
+			MigrationSuggestion: move.sug,
+			Temporary:           move.temporary,
+		})
+	}
+	return migrations
+}
+
+// breakCycle finds a pending move on a cycle and a temporary host that can take
+// its VM now. Returns the parking move, the index of the pending move it
+// belongs to and the cycle, or nil if no VM can be parked.
+func (sim *waveSimulation) breakCycle(pending []*stagedMove) (*stagedMove, int, string) {
+	cycle := findNodeCycle(pending)
+
+	// Prefer VMs on the cycle, smallest RAM first (quickest to move twice)
+	var candidates []int
+	for i, move := range pending {
+		if len(cycle) == 0 || cycleHasEdge(cycle, move.sug.SourceNode, move.sug.TargetNode) {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return pending[candidates[a]].vm.MaxMem < pending[candidates[b]].vm.MaxMem
+	})
+
+	hosts := proxmox.GetAvailableTargets(sim.cluster, "", nil)
+	for _, idx := range candidates {
+		move := pending[idx]
+		var best string
+		for _, host := range hosts {
+			if host.Name == move.sug.SourceNode || host.Name == move.sug.TargetNode {
+				continue
+			}
+			state := sim.states[host.Name]
+			if sim.blocked(move.vm, host.Name, state, nil) != "" {
+				continue
+			}
+			if CheckVMPlacementConstraints(move.vm, sim.nodes[host.Name], sim.cluster, sim.groups, sim.location).Violated {
+				continue
+			}
+			if _, reason := PlanDiskPlacement(move.vm, state.Pools, sim.policy.ForNode(sim.nodes[host.Name]).MaxStoragePercent); reason != "" {
+				continue
+			}
+			if best == "" || state.GetUtilizationScore() < sim.states[best].GetUtilizationScore() {
+				best = host.Name
+			}
+		}
+		if best == "" {
+			continue
+		}
+
+		temp := move.sug
+		temp.TargetNode = best
+		temp.TargetCores = sim.nodes[best].CPUCores
+		temp.Disks, _ = PlanDiskPlacement(move.vm, sim.states[best].Pools, sim.policy.ForNode(sim.nodes[best]).MaxStoragePercent)
+		temp.Details = nil
+		cycleText := "none"
+		temp.Reason = fmt.Sprintf("Temporary move to make room, continues to %s", move.sug.TargetNode)
+		if len(cycle) > 0 {
+			cycleText = strings.Join(append(cycle, cycle[0]), "→")
+			temp.Reason = fmt.Sprintf("Temporary move to break cycle %s, continues to %s", cycleText, move.sug.TargetNode)
+		}
+		return &stagedMove{sug: temp, vm: move.vm, temporary: true}, idx, cycleText
+	}
+	return nil, 0, ""
+}
+
+// withVMGroups groups pending moves whose VMs must share a host (withvm or a
+// pack affinity group) and move to the same target, so they land in the same
+// wave. Groups keep the order of their first move.
+func withVMGroups(pending []*stagedMove, affinity []AffinityGroup) [][]*stagedMove {
+	byName := make(map[string]int, len(pending))
+	for i, move := range pending {
+		byName[move.vm.Name] = i
+	}
+
+	parent := make([]int, len(pending))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i, move := range pending {
+		for _, name := range move.vm.WithVM {
+			j, ok := byName[name]
+			if ok && pending[j].sug.TargetNode == move.sug.TargetNode {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+	for _, g := range affinity {
+		if !g.IsPack() {
+			continue
+		}
+		first := make(map[string]int) // target -> first pending member moving there
+		for i, move := range pending {
+			if !g.Contains(move.vm) {
+				continue
+			}
+			if j, ok := first[move.sug.TargetNode]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				first[move.sug.TargetNode] = i
+			}
+		}
+	}
+
+	var groups [][]*stagedMove
+	index := make(map[int]int)
+	for i, move := range pending {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], move)
+	}
+	return groups
+}
+
+// findNodeCycle returns the nodes of a cycle in the graph of pending moves
+// (source -> target), e.g. [A B] for A→B while B→A, or nil if there is none
+func findNodeCycle(pending []*stagedMove) []string {
+	edges := make(map[string][]string)
+	for _, move := range pending {
+		edges[move.sug.SourceNode] = append(edges[move.sug.SourceNode], move.sug.TargetNode)
+	}
+	starts := make([]string, 0, len(edges))
+	for node := range edges {
+		starts = append(starts, node)
+	}
+	sort.Strings(starts)
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(string) []string
+	visit = func(node string) []string {
+		state[node] = onPath
+		path = append(path, node)
+		for _, next := range edges[node] {
+			switch state[next] {
+			case onPath:
+				for i, n := range path {
+					if n == next {
+						return append([]string(nil), path[i:]...)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = done
+		return nil
+	}
+	for _, node := range starts {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// cycleHasEdge returns true if source -> target is an edge of the cycle
+func cycleHasEdge(cycle []string, source, target string) bool {
+	for i, node := range cycle {
+		if node == source && cycle[(i+1)%len(cycle)] == target {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns true if list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
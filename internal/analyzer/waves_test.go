@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// wavesTestVM is a running VM with ram GiB of memory and no disks
+func wavesTestVM(vmid int, name string, ram int64) proxmox.VM {
+	return proxmox.VM{VMID: vmid, Name: name, Status: "running", Type: "qemu", CPUCores: 1, MaxMem: ram * testGiB}
+}
+
+// wavesTestNode is an online 100 GiB host without a hoststate, running the VMs
+func wavesTestNode(name string, vms ...proxmox.VM) proxmox.Node {
+	node := proxmox.Node{Name: name, Status: "online", HostState: -1, CPUCores: 64, MaxMem: 100 * testGiB, MaxDisk: 10000 * testGiB, VMs: vms}
+	for _, vm := range vms {
+		node.UsedMem += vm.MaxMem
+	}
+	return node
+}
+
+// wavesTestMove suggests moving the VM from source to target
+func wavesTestMove(vm proxmox.VM, source, target string) MigrationSuggestion {
+	return MigrationSuggestion{VMID: vm.VMID, VMName: vm.Name, SourceNode: source, TargetNode: target, Status: vm.Status, Type: vm.Type, VCPUs: vm.CPUCores, RAM: vm.MaxMem}
+}
+
+func TestBuildStagedPlan(t *testing.T) {
+	a := wavesTestVM(100, "a", 60)
+	b := wavesTestVM(101, "b", 60)
+	c := wavesTestVM(102, "c", 20)
+	small := wavesTestVM(103, "small", 10)
+
+	// Each wave is listed as "VM source->target", parked VMs with a trailing "*"
+	type want struct {
+		waves          [][]string
+		merged, cycles int
+		unsafe         bool
+	}
+	tests := []struct {
+		name        string
+		nodes       []proxmox.Node
+		suggestions []MigrationSuggestion
+		want        want
+	}{
+		{
+			"independent moves share a wave",
+			[]proxmox.Node{wavesTestNode("pve1", small), wavesTestNode("pve2", c), wavesTestNode("pve3")},
+			[]MigrationSuggestion{wavesTestMove(small, "pve1", "pve3"), wavesTestMove(c, "pve2", "pve3")},
+			want{waves: [][]string{{"small pve1->pve3", "c pve2->pve3"}}},
+		},
+		{
+			"room is freed first",
+			[]proxmox.Node{wavesTestNode("pve1", a), wavesTestNode("pve2", b, c), wavesTestNode("pve3")},
+			[]MigrationSuggestion{wavesTestMove(a, "pve1", "pve2"), wavesTestMove(b, "pve2", "pve3")},
+			want{waves: [][]string{{"b pve2->pve3"}, {"a pve1->pve2"}}},
+		},
+		{
+			"repeated moves merged",
+			[]proxmox.Node{wavesTestNode("pve1", small), wavesTestNode("pve2"), wavesTestNode("pve3")},
+			[]MigrationSuggestion{wavesTestMove(small, "pve1", "pve2"), wavesTestMove(small, "pve2", "pve3")},
+			want{waves: [][]string{{"small pve1->pve3"}}, merged: 1},
+		},
+		{
+			"moved back to where it started",
+			[]proxmox.Node{wavesTestNode("pve1", small), wavesTestNode("pve2")},
+			[]MigrationSuggestion{wavesTestMove(small, "pve1", "pve2"), wavesTestMove(small, "pve2", "pve1")},
+			want{merged: 2},
+		},
+		{
+			"swap cycle broken on a third host",
+			[]proxmox.Node{wavesTestNode("pve1", a), wavesTestNode("pve2", b), wavesTestNode("pve3")},
+			[]MigrationSuggestion{wavesTestMove(a, "pve1", "pve2"), wavesTestMove(b, "pve2", "pve1")},
+			want{waves: [][]string{{"a pve1->pve3*"}, {"b pve2->pve1"}, {"a pve3->pve2"}}, cycles: 1},
+		},
+		{
+			"swap without a third host is unsafe",
+			[]proxmox.Node{wavesTestNode("pve1", a), wavesTestNode("pve2", b)},
+			[]MigrationSuggestion{wavesTestMove(a, "pve1", "pve2"), wavesTestMove(b, "pve2", "pve1")},
+			want{waves: [][]string{{"a pve1->pve2", "b pve2->pve1"}}, unsafe: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := BuildStagedPlan(&proxmox.Cluster{Nodes: tt.nodes}, tt.suggestions, DefaultCapacityPolicy)
+
+			var got [][]string
+			for _, wave := range plan.Waves {
+				var moves []string
+				for _, m := range wave.Migrations {
+					move := m.VMName + " " + m.SourceNode + "->" + m.TargetNode
+					if m.Temporary {
+						move += "*"
+					}
+					moves = append(moves, move)
+				}
+				got = append(got, moves)
+			}
+			if !equalWaves(got, tt.want.waves) {
+				t.Errorf("waves %v, want %v", got, tt.want.waves)
+			}
+			if plan.Merged != tt.want.merged || plan.Cycles != tt.want.cycles {
+				t.Errorf("%d merged and %d cycles, want %d and %d", plan.Merged, plan.Cycles, tt.want.merged, tt.want.cycles)
+			}
+			if plan.HasUnsafe() != tt.want.unsafe {
+				t.Errorf("HasUnsafe() = %v, want %v", plan.HasUnsafe(), tt.want.unsafe)
+			}
+			if tt.want.unsafe && !plan.Waves[len(plan.Waves)-1].Unsafe {
+				t.Error("the unsafe wave is not the last one")
+			}
+		})
+	}
+}
+
+// equalWaves compares waves in order, the migrations of a wave in any order
+func equalWaves(got, want [][]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if len(got[i]) != len(want[i]) {
+			return false
+		}
+		for _, move := range want[i] {
+			if !containsString(got[i], move) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// HypotheticalNode describes hosts to add in a what-if simulation
+type HypotheticalNode struct {
+	Name      string  `json:"name" yaml:"name"`
+	CPUModel  string  `json:"cpu_model,omitempty" yaml:"cpu_model,omitempty"` // Used for CPU priority scoring
+	CPUCores  int     `json:"cores" yaml:"cores"`                             // Logical CPUs
+	RAMGB     float64 `json:"ram_gb" yaml:"ram_gb"`
+	StorageGB float64 `json:"storage_gb" yaml:"storage_gb"`
+	Count     int     `json:"count,omitempty" yaml:"count,omitempty"` // Hosts of this spec (default 1), named <name>-1..N when > 1
+}
+
+// Names returns the names of the hosts the entry adds
+func (h HypotheticalNode) Names() []string {
+	if h.Count <= 1 {
+		return []string{h.Name}
+	}
+	names := make([]string, h.Count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", h.Name, i+1)
+	}
+	return names
+}
+
+// WhatIf adds hypothetical hosts to a cluster and marks real ones as removed,
+// to see the migrations and utilization before hardware is racked or retired
+type WhatIf struct {
+	Add    []HypotheticalNode `json:"add,omitempty" yaml:"add,omitempty"`
+	Remove []string           `json:"remove,omitempty" yaml:"remove,omitempty"` // Names of real nodes
+}
+
+// WhatIfInfo marks a result as simulated on hypothetical hardware
+type WhatIfInfo struct {
+	Added   []string // Hypothetical nodes
+	Removed []string // Real nodes assumed gone
+}
+
+// LoadWhatIf reads a what-if simulation from a YAML (or JSON) file
+func LoadWhatIf(path string) (*WhatIf, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read what-if file: %w", err)
+	}
+	var w WhatIf
+	if err := yaml.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse what-if file %s: %w", path, err)
+	}
+	if err := w.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid what-if file %s: %w", path, err)
+	}
+	return &w, nil
+}
+
+// Validate checks the hypothetical hosts' specs
+func (w WhatIf) Validate() error {
+	if len(w.Add) == 0 && len(w.Remove) == 0 {
+		return &ValidationError{Field: "add", Message: "add or remove at least one node"}
+	}
+	for i, h := range w.Add {
+		field := fmt.Sprintf("add[%d]", i)
+		switch {
+		case h.Name == "":
+			return &ValidationError{Field: field + ".name", Message: "must not be empty"}
+		case h.CPUCores < 1:
+			return &ValidationError{Field: field + ".cores", Message: "must be at least 1"}
+		case h.RAMGB <= 0:
+			return &ValidationError{Field: field + ".ram_gb", Message: "must be greater than 0"}
+		case h.StorageGB < 0:
+			return &ValidationError{Field: field + ".storage_gb", Message: "must not be negative"}
+		case h.Count < 0:
+			return &ValidationError{Field: field + ".count", Message: "must not be negative"}
+		}
+	}
+	return nil
+}
+
+// Check verifies the simulation against a cluster: removed nodes must exist
+// and added ones must not clash with existing names
+func (w WhatIf) Check(cluster *proxmox.Cluster) error {
+	seen := make(map[string]bool)
+	for _, node := range cluster.Nodes {
+		seen[node.Name] = true
+	}
+	for _, name := range w.Remove {
+		if !seen[name] {
+			return fmt.Errorf("node %s to remove not found", name)
+		}
+	}
+	for _, h := range w.Add {
+		for _, name := range h.Names() {
+			if seen[name] {
+				return fmt.Errorf("node %s to add already exists", name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// Summary describes the simulation, e.g. "+2 simulated (new-1, new-2), -1 removed (pve1)"
+func (i *WhatIfInfo) Summary() string {
+	var parts []string
+	if len(i.Added) > 0 {
+		parts = append(parts, fmt.Sprintf("+%d simulated (%s)", len(i.Added), strings.Join(i.Added, ", ")))
+	}
+	if len(i.Removed) > 0 {
+		parts = append(parts, fmt.Sprintf("-%d removed (%s)", len(i.Removed), strings.Join(i.Removed, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Label returns "simulated" or "removed" for the nodes of the simulation, else ""
+func (i *WhatIfInfo) Label(node string) string {
+	switch {
+	case i == nil:
+		return ""
+	case containsString(i.Added, node):
+		return "simulated"
+	case containsString(i.Removed, node):
+		return "removed"
+	}
+	return ""
+}
+
+// Apply returns a copy of the cluster with the hypothetical nodes added
+// (Simulated, online, empty) and the removed nodes marked Removed. Unknown
+// removed nodes and clashing names are skipped; see Check.
+func (w WhatIf) Apply(cluster *proxmox.Cluster) *proxmox.Cluster {
+	sim := *cluster
+	sim.Nodes = make([]proxmox.Node, 0, len(cluster.Nodes))
+	existing := make(map[string]bool)
+	for _, node := range cluster.Nodes {
+		node.VMs = append([]proxmox.VM(nil), node.VMs...)
+		node.Removed = containsString(w.Remove, node.Name)
+		sim.Nodes = append(sim.Nodes, node)
+		existing[node.Name] = true
+	}
+	for _, h := range w.Add {
+		for _, name := range h.Names() {
+			if existing[name] {
+				continue
+			}
+			existing[name] = true
+			sim.Nodes = append(sim.Nodes, proxmox.Node{
+				Name:       name,
+				Status:     "online",
+				CPUCores:   h.CPUCores,
+				CPUSockets: 1,
+				CPUModel:   h.CPUModel,
+				MaxMem:     int64(h.RAMGB * 1024 * 1024 * 1024),
+				MaxDisk:    int64(h.StorageGB * 1024 * 1024 * 1024),
+				HostState:  -1,
+				Simulated:  true,
+			})
+		}
+	}
+	return &sim
+}
+
+// AnalyzeWhatIf balances a cluster returned by WhatIf.Apply. The VMs of the
+// removed nodes, nomigrate ones included, are moved off first like a Migrate
+// All (the hypothetical nodes count as targets, their CPU model included);
+// then the remaining and hypothetical nodes are balanced like Balance Cluster,
+// with the optimizer if the settings enable it.
+// The node impact covers every node, removed and simulated ones included.
+// The constraints supply the policy, the placement strategy, the excluded
+// nodes and the balance budget. The evacuation is always planned in full and
+// charged to the budget first; the balancing spends what is left.
+func AnalyzeWhatIf(cluster *proxmox.Cluster, constraints MigrationConstraints, settings OptimizerSettings, progress BalanceProgressCallback) (*AnalysisResult, error) {
+	info := ClusterWhatIf(cluster)
+	if info == nil {
+		return nil, fmt.Errorf("no simulated or removed nodes in cluster")
+	}
+	if err := constraints.ValidateBudget(); err != nil {
+		return nil, err
+	}
+	policy := constraints.GetPolicy()
+	strategy := constraints.GetStrategy()
+
+	evacuation, stranded, drained := evacuateNodes(cluster, policy, strategy, info.Removed, constraints.ExcludeNodes, "on removed node")
+	var placed []MigrationSuggestion
+	for i := range evacuation {
+		sug := &evacuation[i]
+		if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			continue
+		}
+		sug.Reason = fmt.Sprintf("Removed node %s: %s", sug.SourceNode, sug.Reason)
+		placed = append(placed, *sug)
+	}
+
+	// Balancing leaves out the removed nodes; a stranded VM stays where it is
+	rest, spent := remainingBudget(constraints, placed)
+	var balance *AnalysisResult
+	var err error
+	switch {
+	case spent != "":
+		err = fmt.Errorf("the evacuation uses up the %s budget", spent)
+	case settings.Enabled:
+		balance, err = AnalyzeClusterWideOptimizedWithConstraints(drained, rest, settings, progress)
+	default:
+		balance, err = AnalyzeClusterWideBalanceWithConstraints(drained, rest, progress)
+	}
+	if err != nil && len(placed) == 0 && len(stranded) == 0 {
+		return nil, err
+	}
+
+	result := &AnalysisResult{
+		TargetsBefore:    make(map[string]NodeState),
+		TargetsAfter:     make(map[string]NodeState),
+		IsBalanceCluster: true,
+		UnmigrateableVMs: stranded,
+
+		Constraints:        constraints,
+		ClusterCollectedAt: cluster.CollectedAt,
+		WhatIf:             info,
+	}
+	result.Constraints.BalanceCluster = true
+	result.Constraints.Policy = &policy
+	result.Suggestions = append(result.Suggestions, placed...)
+	improvement := fmt.Sprintf("What-if %s: %s off removed nodes", info.Summary(), plural(len(placed), "migration", "migrations"))
+	if balance != nil {
+		result.Suggestions = append(result.Suggestions, balance.Suggestions...)
+		result.MovementsTried = balance.MovementsTried
+		result.Optimization = balance.Optimization
+		if balance.Budget != nil {
+			// The budget covers the evacuation as well
+			budget := *balance.Budget
+			budget.MaxMigrations, budget.MaxBytes = 0, 0
+			if constraints.MaxMigrations != nil {
+				budget.MaxMigrations = *constraints.MaxMigrations
+			}
+			if constraints.MaxTransfer != nil {
+				budget.MaxBytes = *constraints.MaxTransfer
+			}
+			for _, sug := range placed {
+				budget.Migrations++
+				budget.Bytes += sug.Transfer
+			}
+			result.Budget = &budget
+		}
+		improvement += ", then " + balance.ImprovementInfo
+	} else {
+		improvement += fmt.Sprintf(", no balancing: %v", err)
+	}
+	result.ImprovementInfo = improvement
+
+	after := clusterAfterMigrations(cluster, result.Suggestions)
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		if node.Status != "online" || node.IsMigrationBlocked() {
+			continue
+		}
+		result.TargetsBefore[node.Name] = newSimulatedNodeState(node, policy).toNodeState()
+		result.TargetsAfter[node.Name] = newSimulatedNodeState(proxmox.GetNodeByName(after, node.Name), policy).toNodeState()
+	}
+
+	result.Staged = BuildStagedPlan(cluster, result.Suggestions, policy)
+	result.AffinityViolations = FindAffinityViolations(cluster, ResolveAffinityGroups(cluster, policy.AffinityGroups), PlannedLocations(result.Suggestions))
+	result.addPlannedTotals()
+	return result, nil
+}
+
+// ClusterWhatIf returns the simulated and removed nodes of a cluster returned
+// by WhatIf.Apply, or nil for a real cluster
+func ClusterWhatIf(cluster *proxmox.Cluster) *WhatIfInfo {
+	info := &WhatIfInfo{}
+	for _, node := range cluster.Nodes {
+		if node.Simulated {
+			info.Added = append(info.Added, node.Name)
+		} else if node.Removed {
+			info.Removed = append(info.Removed, node.Name)
+		}
+	}
+	if len(info.Added) == 0 && len(info.Removed) == 0 {
+		return nil
+	}
+	sort.Strings(info.Added)
+	sort.Strings(info.Removed)
+	return info
+}
@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+func TestAnalyzeWhatIfConstraints(t *testing.T) {
+	base := collectFixture(t, proxmox.FixtureOptions{Nodes: 5, VMs: 120, Skew: 0.3, Seed: 2})
+	removed := base.Nodes[4].Name
+	whatIf := WhatIf{
+		Add:    []HypotheticalNode{{Name: "new", CPUModel: "AMD EPYC 7763 64-Core Processor", CPUCores: 64, RAMGB: 512, StorageGB: 8192}},
+		Remove: []string{removed},
+	}
+	cluster := whatIf.Apply(base)
+
+	// Every running or stopped VM on the removed node has to move
+	var evacuation int
+	if unlimited, err := AnalyzeWhatIf(cluster, MigrationConstraints{}, OptimizerSettings{}, nil); err != nil {
+		t.Fatalf("AnalyzeWhatIf: %v", err)
+	} else {
+		for _, sug := range unlimited.Suggestions {
+			if sug.SourceNode == removed && sug.TargetNode != "NONE" {
+				evacuation++
+			}
+		}
+	}
+	if evacuation == 0 {
+		t.Fatalf("no VMs to evacuate from %s", removed)
+	}
+
+	tests := []struct {
+		name          string
+		maxMigrations int
+		exclude       []string
+		optimize      bool
+		balanced      bool // Balancing migrations follow the evacuation
+	}{
+		{"unlimited", 0, nil, false, true},
+		{"budget left after the evacuation", evacuation + 3, nil, false, true},
+		{"budget used up by the evacuation", evacuation, nil, false, false},
+		{"budget below the evacuation", 1, nil, false, false},
+		{"optimizer", evacuation + 3, nil, true, true},
+		{"excluded nodes", 0, []string{base.Nodes[2].Name, base.Nodes[3].Name}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := MigrationConstraints{BalanceCluster: true, ExcludeNodes: tt.exclude}
+			if tt.maxMigrations > 0 {
+				constraints.MaxMigrations = &tt.maxMigrations
+			}
+			settings := OptimizerSettings{Enabled: tt.optimize, TimeBudgetSeconds: 0.5}
+			result, err := AnalyzeWhatIf(cluster, constraints, settings, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeWhatIf: %v", err)
+			}
+
+			evacuated, balanced := 0, 0
+			for _, sug := range result.Suggestions {
+				switch {
+				case sug.TargetNode == "NONE":
+				case sug.SourceNode == removed:
+					evacuated++
+				default:
+					balanced++
+				}
+				for _, name := range tt.exclude {
+					if sug.TargetNode == name {
+						t.Errorf("VM %d moves to excluded node %s", sug.VMID, name)
+					}
+				}
+			}
+			if tt.exclude == nil && evacuated != evacuation {
+				t.Errorf("%d VMs evacuated, want %d", evacuated, evacuation)
+			}
+			if (balanced > 0) != tt.balanced {
+				t.Errorf("%d balancing migrations, want some: %v (%s)", balanced, tt.balanced, result.ImprovementInfo)
+			}
+			if tt.maxMigrations > evacuation && evacuated+balanced > tt.maxMigrations {
+				t.Errorf("%d migrations over a budget of %d", evacuated+balanced, tt.maxMigrations)
+			}
+			if !tt.balanced && !strings.Contains(result.ImprovementInfo, "uses up the migrations budget") {
+				t.Errorf("improvement info %q doesn't say the budget is used up", result.ImprovementInfo)
+			}
+			if tt.optimize != (result.Optimization != nil) {
+				t.Errorf("optimization report %v with the optimizer %v", result.Optimization != nil, tt.optimize)
+			}
+			if result.Budget != nil && result.Budget.Migrations != evacuated+balanced {
+				t.Errorf("budget counts %d migrations, plan has %d", result.Budget.Migrations, evacuated+balanced)
+			}
+		})
+	}
+}
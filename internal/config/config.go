@@ -0,0 +1,224 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// SystemPath is the system-wide config file, read before the user's file
+const SystemPath = "/etc/migsug/config.yaml"
+
+// Config holds the settings read from the migsug config files
+// Command line flags and environment variables take precedence over it
+type Config struct {
+	DefaultProfile string             `yaml:"default_profile"` // Profile used when --profile is not given
+	Profiles       map[string]Profile `yaml:"profiles"`        // Named clusters
+
+	Defaults Defaults `yaml:"defaults"` // Default mode and constraints
+
+	StorageRules        []proxmox.StorageRule `yaml:"storage_rules"`         // Storage pools counted as node storage, first match wins
+	RecentlyCreatedDays int                   `yaml:"recently_created_days"` // VMs younger than this don't set the C flag
+	RefreshInterval     int                   `yaml:"refresh_interval"`      // Dashboard auto-refresh in seconds (0 = off)
+	Theme               string                `yaml:"theme"`                 // TUI color theme (default, mono)
+	FailureNodes        int                   `yaml:"failure_nodes"`         // Nodes lost at once in the dashboard failure simulation (0 = off)
+
+	HistoryFile          string `yaml:"history_file"`           // History database ("" = migsug_history.db next to the executable, "off" = disabled)
+	HistoryRetentionDays int    `yaml:"history_retention_days"` // Collections older than this are pruned (0 = keep forever)
+
+	CapacityPolicy    analyzer.CapacityPolicy    `yaml:"capacity_policy"`
+	MigrationEstimate analyzer.EstimateSettings  `yaml:"migration_estimate"` // Network and storage throughput for time estimates
+	AffinityGroups    []analyzer.AffinityGroup   `yaml:"affinity_groups"`    // Named VM groups placed together or apart
+	Optimizer         analyzer.OptimizerSettings `yaml:"optimizer"`          // Global balance optimizer and its time budget
+
+	// Files the config was read from, in load order
+	Files []string `yaml:"-"`
+}
+
+// Profile holds the connection settings of one cluster
+type Profile struct {
+	APIHost  string `yaml:"api_host"`
+	APIToken string `yaml:"api_token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Source   string `yaml:"source"`   // Default source node
+	Fixtures string `yaml:"fixtures"` // Fixture directory instead of a live cluster (testing)
+}
+
+// Defaults holds the analysis settings used when no flag overrides them
+type Defaults struct {
+	Mode          string   `yaml:"mode"` // Migration mode name, e.g. balance_cluster
+	Exclude       []string `yaml:"exclude"`
+	MaxVMsPerHost int      `yaml:"max_vms_per_host"`
+	MinCPUFree    float64  `yaml:"min_cpu_free"`
+	MinRAMFreeGB  float64  `yaml:"min_ram_free_gb"`
+	Strategy      string   `yaml:"strategy"` // Placement strategy name, e.g. binpack
+
+	// Budget for cluster-wide balance (0/empty = unlimited)
+	MaxMigrations int     `yaml:"max_migrations"`
+	MaxTransferGB float64 `yaml:"max_transfer_gb"`
+	MaxLiveRAMGB  float64 `yaml:"max_live_ram_gb"` // Largest VM RAM to live-migrate
+	ExcludeVMs    []int   `yaml:"exclude_vms"`
+}
+
+// Default returns the built-in settings
+func Default() *Config {
+	return &Config{
+		Profiles:             make(map[string]Profile),
+		StorageRules:         proxmox.DefaultStorageRules,
+		RecentlyCreatedDays:  90,
+		RefreshInterval:      180,
+		Theme:                "default",
+		FailureNodes:         1,
+		HistoryRetentionDays: 90,
+		CapacityPolicy:       analyzer.DefaultCapacityPolicy,
+		MigrationEstimate:    analyzer.DefaultEstimateSettings,
+		Optimizer:            analyzer.DefaultOptimizerSettings,
+	}
+}
+
+// UserPath returns the per-user config file (~/.config/migsug/config.yaml)
+func UserPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "migsug", "config.yaml")
+}
+
+// Load reads the config file at path, or the system and user files when path is empty
+// Missing default files are skipped; a missing explicit path is an error.
+// Later files override the fields they set, profiles are merged by name.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.merge(path); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, p := range []string{SystemPath, UserPath()} {
+			if p == "" {
+				continue
+			}
+			if err := cfg.merge(p); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// merge decodes a config file on top of the current settings
+func (c *Config) merge(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	c.Files = append(c.Files, path)
+	return nil
+}
+
+// Validate checks the settings for consistency
+func (c *Config) Validate() error {
+	if c.DefaultProfile != "" {
+		if _, ok := c.Profiles[c.DefaultProfile]; !ok {
+			return fmt.Errorf("default_profile: unknown profile %s", c.DefaultProfile)
+		}
+	}
+	if c.Defaults.Mode != "" {
+		if _, err := analyzer.ParseMigrationMode(c.Defaults.Mode); err != nil {
+			return err
+		}
+	}
+	if _, err := analyzer.ParsePlacementStrategy(c.Defaults.Strategy); err != nil {
+		return err
+	}
+	if c.Defaults.MaxVMsPerHost < 0 || c.Defaults.MinCPUFree < 0 || c.Defaults.MinRAMFreeGB < 0 {
+		return fmt.Errorf("defaults: limits must not be negative")
+	}
+	if c.Defaults.MaxMigrations < 0 || c.Defaults.MaxTransferGB < 0 || c.Defaults.MaxLiveRAMGB < 0 {
+		return fmt.Errorf("defaults: budgets must not be negative")
+	}
+	for i, rule := range c.StorageRules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("storage_rules[%d]: %w", i, err)
+		}
+	}
+	if c.RecentlyCreatedDays <= 0 {
+		return fmt.Errorf("recently_created_days: must be positive")
+	}
+	if c.RefreshInterval < 0 {
+		return fmt.Errorf("refresh_interval: must not be negative")
+	}
+	if c.FailureNodes < 0 {
+		return fmt.Errorf("failure_nodes: must not be negative")
+	}
+	if c.HistoryRetentionDays < 0 {
+		return fmt.Errorf("history_retention_days: must not be negative")
+	}
+	if err := c.CapacityPolicy.Validate(); err != nil {
+		return fmt.Errorf("capacity_policy: %w", err)
+	}
+	if err := c.MigrationEstimate.Validate(); err != nil {
+		return fmt.Errorf("migration_estimate: %w", err)
+	}
+	if err := c.Optimizer.Validate(); err != nil {
+		return fmt.Errorf("optimizer: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i, group := range c.AffinityGroups {
+		if err := group.Validate(); err != nil {
+			return fmt.Errorf("affinity_groups[%d]: %w", i, err)
+		}
+		if seen[group.Name] {
+			return fmt.Errorf("affinity_groups[%d]: duplicate group %s", i, group.Name)
+		}
+		seen[group.Name] = true
+	}
+	return nil
+}
+
+// Profile returns the named profile, or the default profile when name is empty
+// Returns a zero Profile if no name is given and no default is configured
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return p, nil
+}
+
+// ProfileNames returns the profile names in alphabetical order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
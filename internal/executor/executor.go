@@ -0,0 +1,371 @@
+package executor
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// DefaultPollInterval is how often running migration tasks are polled
+const DefaultPollInterval = 3 * time.Second
+
+// maxPollErrors is how many polls of a task may fail in a row before its job
+// is marked failed; a few failures are tolerated as transient API errors
+const maxPollErrors = 10
+
+// JobState is the execution state of a single migration
+type JobState int
+
+const (
+	JobPending JobState = iota
+	JobRunning
+	JobSucceeded
+	JobFailed
+	JobCancelled // Never started, or no longer tracked, because the run was cancelled
+)
+
+// String returns the display name of a JobState
+func (s JobState) String() string {
+	switch s {
+	case JobPending:
+		return "pending"
+	case JobRunning:
+		return "running"
+	case JobSucceeded:
+		return "done"
+	case JobFailed:
+		return "failed"
+	case JobCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Job tracks the execution of one migration suggestion
+type Job struct {
+	Suggestion analyzer.MigrationSuggestion
+	Wave       int // Jobs of a wave start once every earlier wave has finished
+	State      JobState
+	UPID       string // Proxmox task ID once started
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      string // Failure reason (task exit status or request error)
+}
+
+// Elapsed returns how long the job has been running (or ran)
+func (j Job) Elapsed() time.Duration {
+	if j.StartedAt.IsZero() {
+		return 0
+	}
+	if j.FinishedAt.IsZero() {
+		return time.Since(j.StartedAt)
+	}
+	return j.FinishedAt.Sub(j.StartedAt)
+}
+
+// Limits bounds how many migrations run at the same time
+// A value of 0 means no limit for that dimension
+type Limits struct {
+	PerSource int // Concurrent migrations leaving the same node
+	PerTarget int // Concurrent migrations arriving at the same node
+}
+
+// DefaultLimits runs one migration per source and per target node at a time
+var DefaultLimits = Limits{PerSource: 1, PerTarget: 1}
+
+// Executor runs a plan's migrations through a ProxmoxClient and tracks their progress
+// All methods are safe for concurrent use; the TUI polls Snapshot while jobs run
+type Executor struct {
+	client       proxmox.ProxmoxClient
+	limits       Limits
+	pollInterval time.Duration
+
+	mu        sync.Mutex
+	jobs      []Job
+	started   time.Time
+	finished  time.Time
+	cancelled bool
+	wake      chan struct{} // Signals the scheduler that a job finished or the run was cancelled
+	stop      chan struct{} // Closed by Cancel to stop polling running tasks
+}
+
+// New creates an executor for all suggestions that have a target node
+func New(client proxmox.ProxmoxClient, suggestions []analyzer.MigrationSuggestion, limits Limits) *Executor {
+	e := &Executor{
+		client:       client,
+		limits:       limits,
+		pollInterval: DefaultPollInterval,
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+	}
+	for _, sug := range suggestions {
+		if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			continue
+		}
+		e.jobs = append(e.jobs, Job{Suggestion: sug, State: JobPending})
+	}
+	return e
+}
+
+// NewStaged creates an executor that runs a staged plan wave by wave
+// If a migration fails, later waves are not started: their capacity depends on it.
+// The migrations of an unsafe wave are only started with runUnsafe; otherwise
+// they are listed as cancelled.
+func NewStaged(client proxmox.ProxmoxClient, plan *analyzer.StagedPlan, limits Limits, runUnsafe bool) *Executor {
+	e := New(client, nil, limits)
+	for i, wave := range plan.Waves {
+		for _, m := range wave.Migrations {
+			job := Job{Suggestion: m.MigrationSuggestion, Wave: i, State: JobPending}
+			if wave.Unsafe && !runUnsafe {
+				job.State = JobCancelled
+				job.Error = "not started: no order stays within the capacity limits"
+			}
+			e.jobs = append(e.jobs, job)
+		}
+	}
+	return e
+}
+
+// Start begins executing migrations in the background
+func (e *Executor) Start() {
+	e.mu.Lock()
+	e.started = time.Now()
+	e.mu.Unlock()
+
+	go e.run()
+}
+
+// Cancel stops scheduling new migrations and stops tracking running ones
+// Migrations already running on Proxmox are left to finish there; their jobs
+// are marked cancelled with the task ID so they can be followed up in Proxmox
+func (e *Executor) Cancel() {
+	e.mu.Lock()
+	if !e.cancelled {
+		e.cancelled = true
+		close(e.stop)
+	}
+	e.mu.Unlock()
+	e.signal()
+}
+
+// Snapshot returns a copy of the current job states
+func (e *Executor) Snapshot() []Job {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	jobs := make([]Job, len(e.jobs))
+	copy(jobs, e.jobs)
+	return jobs
+}
+
+// Limits returns the concurrency limits of this run
+func (e *Executor) Limits() Limits {
+	return e.limits
+}
+
+// Done returns true once every job has reached a final state
+func (e *Executor) Done() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.finished.IsZero()
+}
+
+// Cancelled returns true if Cancel was called
+func (e *Executor) Cancelled() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cancelled
+}
+
+// Elapsed returns the wall-clock time since Start (frozen once done)
+func (e *Executor) Elapsed() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.started.IsZero() {
+		return 0
+	}
+	if !e.finished.IsZero() {
+		return e.finished.Sub(e.started)
+	}
+	return time.Since(e.started)
+}
+
+// signal wakes the scheduler without blocking
+func (e *Executor) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run is the scheduler loop: start every job the limits allow, then wait for a change
+func (e *Executor) run() {
+	for {
+		e.mu.Lock()
+		running := 0
+		pending := 0
+		perSource := make(map[string]int)
+		perTarget := make(map[string]int)
+		wave := -1 // Lowest wave with unfinished jobs
+		failedWave := -1
+		for _, job := range e.jobs {
+			if job.State == JobRunning {
+				running++
+				perSource[job.Suggestion.SourceNode]++
+				perTarget[job.Suggestion.TargetNode]++
+			}
+			if (job.State == JobPending || job.State == JobRunning) && (wave < 0 || job.Wave < wave) {
+				wave = job.Wave
+			}
+			if job.State == JobFailed && (failedWave < 0 || job.Wave < failedWave) {
+				failedWave = job.Wave
+			}
+		}
+
+		for i := range e.jobs {
+			job := &e.jobs[i]
+			if job.State != JobPending {
+				continue
+			}
+			if e.cancelled {
+				job.State = JobCancelled
+				continue
+			}
+			if failedWave >= 0 && job.Wave > failedWave {
+				job.State = JobCancelled
+				job.Error = fmt.Sprintf("not started: a migration of wave %d failed", failedWave+1)
+				continue
+			}
+			pending++
+			if job.Wave != wave {
+				continue
+			}
+			src, tgt := job.Suggestion.SourceNode, job.Suggestion.TargetNode
+			if e.limits.PerSource > 0 && perSource[src] >= e.limits.PerSource {
+				continue
+			}
+			if e.limits.PerTarget > 0 && perTarget[tgt] >= e.limits.PerTarget {
+				continue
+			}
+
+			job.State = JobRunning
+			job.StartedAt = time.Now()
+			perSource[src]++
+			perTarget[tgt]++
+			running++
+			pending--
+			go e.runJob(i)
+		}
+
+		if running == 0 && pending == 0 {
+			e.finished = time.Now()
+			e.mu.Unlock()
+			log.Printf("Executor: run finished in %v", e.finished.Sub(e.started))
+			return
+		}
+		e.mu.Unlock()
+
+		<-e.wake
+	}
+}
+
+// runJob starts one migration and polls its task until it stops
+func (e *Executor) runJob(idx int) {
+	e.mu.Lock()
+	sug := e.jobs[idx].Suggestion
+	e.mu.Unlock()
+
+	online := sug.Status == "running"
+	targetStorage := sug.TargetStorage()
+	log.Printf("Executor: migrating %s %d (%s) %s -> %s (online=%v, targetstorage=%q)", proxmox.GuestType(sug.Type), sug.VMID, sug.VMName, sug.SourceNode, sug.TargetNode, online, targetStorage)
+
+	upid, err := e.client.MigrateVM(sug.SourceNode, sug.Type, sug.VMID, sug.TargetNode, online, targetStorage)
+	if err != nil {
+		e.finishJob(idx, fmt.Errorf("failed to start migration: %w", err))
+		return
+	}
+
+	e.mu.Lock()
+	e.jobs[idx].UPID = upid
+	e.mu.Unlock()
+
+	// Tasks run on the source node; the UPID names it explicitly
+	taskNode := proxmox.ParseUPIDNode(upid)
+	if taskNode == "" {
+		taskNode = sug.SourceNode
+	}
+
+	pollErrors := 0
+	for {
+		select {
+		case <-e.stop:
+			e.stopJob(idx, fmt.Sprintf("cancelled while running; task %s may still be running on Proxmox", upid))
+			return
+		case <-time.After(e.pollInterval):
+		}
+
+		status, err := e.client.GetTaskStatus(taskNode, upid)
+		if err != nil {
+			// Transient API errors shouldn't fail a migration that is still running
+			pollErrors++
+			log.Printf("Executor: failed to poll task %s (%d/%d): %v", upid, pollErrors, maxPollErrors, err)
+			if pollErrors >= maxPollErrors {
+				e.finishJob(idx, fmt.Errorf("lost track of task %s after %d failed polls: %w", upid, pollErrors, err))
+				return
+			}
+			continue
+		}
+		pollErrors = 0
+		if status.IsRunning() {
+			continue
+		}
+		if status.Succeeded() {
+			e.finishJob(idx, nil)
+		} else {
+			e.finishJob(idx, fmt.Errorf("task failed: %s", status.ExitStatus))
+		}
+		return
+	}
+}
+
+// finishJob records the final state of a job and wakes the scheduler
+func (e *Executor) finishJob(idx int, err error) {
+	e.mu.Lock()
+	job := &e.jobs[idx]
+	job.FinishedAt = time.Now()
+	if err != nil {
+		job.State = JobFailed
+		job.Error = err.Error()
+		log.Printf("Executor: VM %d failed: %v", job.Suggestion.VMID, err)
+	} else {
+		job.State = JobSucceeded
+		log.Printf("Executor: VM %d migrated in %v", job.Suggestion.VMID, job.FinishedAt.Sub(job.StartedAt))
+	}
+	e.mu.Unlock()
+	e.signal()
+}
+
+// stopJob marks a running job cancelled once it is no longer tracked and wakes the scheduler
+func (e *Executor) stopJob(idx int, reason string) {
+	e.mu.Lock()
+	job := &e.jobs[idx]
+	job.State = JobCancelled
+	job.FinishedAt = time.Now()
+	job.Error = reason
+	log.Printf("Executor: VM %d no longer tracked: %s", job.Suggestion.VMID, reason)
+	e.mu.Unlock()
+	e.signal()
+}
+
+// Counts returns how many jobs are in each state
+func Counts(jobs []Job) map[JobState]int {
+	counts := make(map[JobState]int)
+	for _, job := range jobs {
+		counts[job.State]++
+	}
+	return counts
+}
@@ -0,0 +1,680 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// SchemaVersion is the version of the exported plan format
+// Bump it whenever a field is renamed or removed; adding fields is compatible
+const SchemaVersion = 1
+
+// Format is an export serialization format
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat converts a format name (json, yaml, yml) to a Format
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (use json or yaml)", name)
+	}
+}
+
+// Plan is the stable, versioned serialization of an analyzer.AnalysisResult
+// Field names are part of the schema; internal analyzer types may change freely
+type Plan struct {
+	SchemaVersion    int               `json:"schema_version" yaml:"schema_version"`
+	GeneratedAt      time.Time         `json:"generated_at" yaml:"generated_at"`
+	SnapshotTime     time.Time         `json:"snapshot_time" yaml:"snapshot_time"` // When the cluster data was collected
+	MigsugVersion    string            `json:"migsug_version" yaml:"migsug_version"`
+	Mode             string            `json:"mode" yaml:"mode"`
+	ClusterWide      bool              `json:"cluster_wide" yaml:"cluster_wide"`
+	ClusterNodeCount int               `json:"cluster_node_count" yaml:"cluster_node_count"`
+	ClusterVMCount   int               `json:"cluster_vm_count" yaml:"cluster_vm_count"`
+	CPUMetric        string            `json:"cpu_metric,omitempty" yaml:"cpu_metric,omitempty"` // RRD statistic behind the CPU usage, e.g. "day p95" (empty = current)
+	ImprovementInfo  string            `json:"improvement_info,omitempty" yaml:"improvement_info,omitempty"`
+	MovementsTried   int               `json:"movements_tried,omitempty" yaml:"movements_tried,omitempty"`
+	Constraints      Constraints       `json:"constraints" yaml:"constraints"`
+	Summary          Summary           `json:"summary" yaml:"summary"`
+	Suggestions      []Suggestion      `json:"suggestions" yaml:"suggestions"`
+	UnmigrateableVMs []UnmigrateableVM `json:"unmigrateable_vms" yaml:"unmigrateable_vms"`
+	Nodes            []NodeImpact      `json:"nodes" yaml:"nodes"`
+	Waves            []Wave            `json:"waves,omitempty" yaml:"waves,omitempty"` // Execution order, see analyzer.StagedPlan
+
+	AffinityViolations []AffinityViolation `json:"affinity_violations,omitempty" yaml:"affinity_violations,omitempty"` // Left after the plan
+
+	ConstraintViolations []ConstraintViolation `json:"constraint_violations,omitempty" yaml:"constraint_violations,omitempty"` // Found by a constraint audit
+
+	Drain *Drain `json:"drain,omitempty" yaml:"drain,omitempty"` // Host drain the plan belongs to
+
+	WhatIf *WhatIf `json:"what_if,omitempty" yaml:"what_if,omitempty"` // Simulated on hypothetical hardware, not executable
+
+	Optimization *Optimization `json:"optimization,omitempty" yaml:"optimization,omitempty"` // Global optimizer statistics
+
+	Budget *Budget `json:"budget,omitempty" yaml:"budget,omitempty"` // Greedy balance score against the budget spent
+}
+
+// Budget mirrors analyzer.BalanceBudget
+// Imbalances are weighted RMS deviations from the cluster average in percentage points.
+type Budget struct {
+	ImbalanceBefore float64 `json:"imbalance_before" yaml:"imbalance_before"`
+	ImbalanceAfter  float64 `json:"imbalance_after" yaml:"imbalance_after"`
+	Migrations      int     `json:"migrations" yaml:"migrations"`
+	TransferBytes   int64   `json:"transfer_bytes" yaml:"transfer_bytes"`
+	MaxMigrations   int     `json:"max_migrations,omitempty" yaml:"max_migrations,omitempty"`
+	MaxBytes        int64   `json:"max_transfer_bytes,omitempty" yaml:"max_transfer_bytes,omitempty"`
+	MaxLiveRAMBytes int64   `json:"max_live_ram_bytes,omitempty" yaml:"max_live_ram_bytes,omitempty"`
+	ExcludedVMs     int     `json:"excluded_vms" yaml:"excluded_vms"`
+	TooLargeVMs     int     `json:"too_large_vms" yaml:"too_large_vms"`             // Above the live RAM limit
+	Exhausted       string  `json:"exhausted,omitempty" yaml:"exhausted,omitempty"` // migrations or transfer
+}
+
+// Optimization mirrors analyzer.OptimizationReport
+// Imbalances are weighted RMS deviations from the cluster average in percentage points.
+type Optimization struct {
+	ImbalanceBefore float64 `json:"imbalance_before" yaml:"imbalance_before"`
+	ImbalanceAfter  float64 `json:"imbalance_after" yaml:"imbalance_after"`
+	LowerBound      float64 `json:"lower_bound" yaml:"lower_bound"`
+	Gap             float64 `json:"gap" yaml:"gap"`
+	AchievedPercent float64 `json:"achieved_percent" yaml:"achieved_percent"` // Share of the possible improvement reached
+	Migrations      int     `json:"migrations" yaml:"migrations"`
+	TransferBytes   int64   `json:"transfer_bytes" yaml:"transfer_bytes"`
+	MaxMigrations   int     `json:"max_migrations,omitempty" yaml:"max_migrations,omitempty"`
+	MaxBytes        int64   `json:"max_transfer_bytes,omitempty" yaml:"max_transfer_bytes,omitempty"`
+	Iterations      int     `json:"iterations" yaml:"iterations"`
+	Seconds         float64 `json:"seconds" yaml:"seconds"`
+	TimedOut        bool    `json:"timed_out" yaml:"timed_out"`
+}
+
+// WhatIf mirrors analyzer.WhatIfInfo
+type WhatIf struct {
+	SimulatedNodes []string `json:"simulated_nodes" yaml:"simulated_nodes"`
+	RemovedNodes   []string `json:"removed_nodes" yaml:"removed_nodes"`
+}
+
+// Drain mirrors analyzer.DrainInfo
+type Drain struct {
+	Node      string `json:"node" yaml:"node"`
+	Phase     string `json:"phase" yaml:"phase"`           // evacuate or return
+	HostState *int   `json:"host_state" yaml:"host_state"` // Node's hoststate when the drain was planned (null = not set)
+}
+
+// Constraints mirrors analyzer.MigrationConstraints
+type Constraints struct {
+	SourceNode      string   `json:"source_node,omitempty" yaml:"source_node,omitempty"`
+	VMCount         *int     `json:"vm_count,omitempty" yaml:"vm_count,omitempty"`
+	VCPUCount       *int     `json:"vcpu_count,omitempty" yaml:"vcpu_count,omitempty"`
+	CPUUsage        *float64 `json:"cpu_usage,omitempty" yaml:"cpu_usage,omitempty"`
+	RAMBytes        *int64   `json:"ram_bytes,omitempty" yaml:"ram_bytes,omitempty"`
+	StorageBytes    *int64   `json:"storage_bytes,omitempty" yaml:"storage_bytes,omitempty"`
+	SpecificVMs     []int    `json:"specific_vms,omitempty" yaml:"specific_vms,omitempty"`
+	MigrateAll      bool     `json:"migrate_all,omitempty" yaml:"migrate_all,omitempty"`
+	CreationAgeDays *int     `json:"creation_age_days,omitempty" yaml:"creation_age_days,omitempty"`
+	BalanceCluster  bool     `json:"balance_cluster,omitempty" yaml:"balance_cluster,omitempty"`
+	ConstraintAudit bool     `json:"constraint_audit,omitempty" yaml:"constraint_audit,omitempty"`
+	ExcludeNodes    []string `json:"exclude_nodes,omitempty" yaml:"exclude_nodes,omitempty"`
+	MaxVMsPerHost   *int     `json:"max_vms_per_host,omitempty" yaml:"max_vms_per_host,omitempty"`
+	MinCPUFree      *float64 `json:"min_cpu_free,omitempty" yaml:"min_cpu_free,omitempty"`
+	MinRAMFreeBytes *int64   `json:"min_ram_free_bytes,omitempty" yaml:"min_ram_free_bytes,omitempty"`
+
+	MaxMigrations    *int   `json:"max_migrations,omitempty" yaml:"max_migrations,omitempty"`
+	MaxTransferBytes *int64 `json:"max_transfer_bytes,omitempty" yaml:"max_transfer_bytes,omitempty"`
+	MaxLiveRAMBytes  *int64 `json:"max_live_ram_bytes,omitempty" yaml:"max_live_ram_bytes,omitempty"`
+	ExcludeVMs       []int  `json:"exclude_vms,omitempty" yaml:"exclude_vms,omitempty"`
+
+	CapacityPolicy *analyzer.CapacityPolicy `json:"capacity_policy,omitempty" yaml:"capacity_policy,omitempty"`
+	Strategy       string                   `json:"strategy,omitempty" yaml:"strategy,omitempty"` // Placement strategy (empty = default)
+}
+
+// Summary holds the plan totals
+type Summary struct {
+	TotalVMs          int   `json:"total_vms" yaml:"total_vms"`
+	PlacedVMs         int   `json:"placed_vms" yaml:"placed_vms"`
+	UnplacedVMs       int   `json:"unplaced_vms" yaml:"unplaced_vms"`
+	TotalVCPUs        int   `json:"total_vcpus" yaml:"total_vcpus"`
+	TotalRAMBytes     int64 `json:"total_ram_bytes" yaml:"total_ram_bytes"`
+	TotalStorageBytes int64 `json:"total_storage_bytes" yaml:"total_storage_bytes"`
+
+	// Predicted duration, present when the plan was estimated
+	EstimatedSeconds  float64 `json:"estimated_seconds,omitempty" yaml:"estimated_seconds,omitempty"`   // Wall-clock with the concurrency limits
+	SequentialSeconds float64 `json:"sequential_seconds,omitempty" yaml:"sequential_seconds,omitempty"` // One migration at a time
+}
+
+// Suggestion mirrors analyzer.MigrationSuggestion
+type Suggestion struct {
+	VMID          int      `json:"vmid" yaml:"vmid"`
+	VMName        string   `json:"vm_name" yaml:"vm_name"`
+	Status        string   `json:"status" yaml:"status"`
+	Type          string   `json:"type" yaml:"type"` // qemu or lxc
+	SourceNode    string   `json:"source_node" yaml:"source_node"`
+	TargetNode    string   `json:"target_node" yaml:"target_node"` // "NONE" when no target was found
+	Reason        string   `json:"reason" yaml:"reason"`
+	Score         float64  `json:"score" yaml:"score"`
+	VCPUs         int      `json:"vcpus" yaml:"vcpus"`
+	CPUUsage      float64  `json:"cpu_usage" yaml:"cpu_usage"`
+	RAMBytes      int64    `json:"ram_bytes" yaml:"ram_bytes"`
+	StorageBytes  int64    `json:"storage_bytes" yaml:"storage_bytes"` // Local disk
+	UsedDiskBytes int64    `json:"used_disk_bytes" yaml:"used_disk_bytes"`
+	MaxDiskBytes  int64    `json:"max_disk_bytes" yaml:"max_disk_bytes"`
+	TransferBytes int64    `json:"transfer_bytes" yaml:"transfer_bytes"` // Estimated data copied
+	EstimatedSecs float64  `json:"estimated_seconds,omitempty" yaml:"estimated_seconds,omitempty"`
+	Disks         []Disk   `json:"disks,omitempty" yaml:"disks,omitempty"`
+	Command       string   `json:"command,omitempty" yaml:"command,omitempty"`
+	Details       *Details `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// Wave mirrors analyzer.MigrationWave
+type Wave struct {
+	Wave       int             `json:"wave" yaml:"wave"` // 1-based
+	Unsafe     bool            `json:"unsafe,omitempty" yaml:"unsafe,omitempty"`
+	Migrations []WaveMigration `json:"migrations" yaml:"migrations"`
+}
+
+// WaveMigration is one migration of a wave
+type WaveMigration struct {
+	VMID       int    `json:"vmid" yaml:"vmid"`
+	VMName     string `json:"vm_name" yaml:"vm_name"`
+	SourceNode string `json:"source_node" yaml:"source_node"`
+	TargetNode string `json:"target_node" yaml:"target_node"`
+	Temporary  bool   `json:"temporary,omitempty" yaml:"temporary,omitempty"` // Parks the VM to break a cycle
+	Command    string `json:"command" yaml:"command"`
+}
+
+// AffinityViolation mirrors analyzer.AffinityViolation
+type AffinityViolation struct {
+	Group   string   `json:"group" yaml:"group"`
+	Mode    string   `json:"mode" yaml:"mode"`
+	Nodes   []string `json:"nodes" yaml:"nodes"`
+	Members []string `json:"members" yaml:"members"`
+	Message string   `json:"message" yaml:"message"`
+}
+
+// ConstraintViolation mirrors analyzer.ConstraintViolation
+type ConstraintViolation struct {
+	Rule     string   `json:"rule" yaml:"rule"`
+	VMID     int      `json:"vmid,omitempty" yaml:"vmid,omitempty"`
+	VMName   string   `json:"vm_name,omitempty" yaml:"vm_name,omitempty"`
+	Other    string   `json:"other,omitempty" yaml:"other,omitempty"`
+	Nodes    []string `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Message  string   `json:"message" yaml:"message"`
+	Repaired bool     `json:"repaired" yaml:"repaired"`
+	Blocked  string   `json:"blocked,omitempty" yaml:"blocked,omitempty"`
+}
+
+// Disk mirrors analyzer.DiskPlacement
+type Disk struct {
+	Disk          string `json:"disk" yaml:"disk"`
+	SourceStorage string `json:"source_storage" yaml:"source_storage"`
+	TargetStorage string `json:"target_storage" yaml:"target_storage"`
+	SizeBytes     int64  `json:"size_bytes" yaml:"size_bytes"`
+	Shared        bool   `json:"shared,omitempty" yaml:"shared,omitempty"`
+}
+
+// Details mirrors analyzer.MigrationDetails
+type Details struct {
+	SelectionMode      string         `json:"selection_mode" yaml:"selection_mode"`
+	SelectionReason    string         `json:"selection_reason" yaml:"selection_reason"`
+	ScoreBreakdown     ScoreBreakdown `json:"score_breakdown" yaml:"score_breakdown"`
+	TargetBefore       ResourceState  `json:"target_before" yaml:"target_before"`
+	TargetAfter        ResourceState  `json:"target_after" yaml:"target_after"`
+	ClusterAvgCPU      float64        `json:"cluster_avg_cpu" yaml:"cluster_avg_cpu"`
+	ClusterAvgRAM      float64        `json:"cluster_avg_ram" yaml:"cluster_avg_ram"`
+	BelowAverage       bool           `json:"below_average" yaml:"below_average"`
+	Alternatives       []Alternative  `json:"alternatives,omitempty" yaml:"alternatives,omitempty"`
+	ConstraintsApplied []string       `json:"constraints_applied,omitempty" yaml:"constraints_applied,omitempty"`
+}
+
+// ScoreBreakdown mirrors analyzer.ScoreBreakdown
+type ScoreBreakdown struct {
+	TotalScore        float64 `json:"total_score" yaml:"total_score"`
+	UtilizationScore  float64 `json:"utilization_score" yaml:"utilization_score"`
+	BalanceScore      float64 `json:"balance_score" yaml:"balance_score"`
+	HeadroomScore     float64 `json:"headroom_score" yaml:"headroom_score"`
+	CPUPriorityScore  float64 `json:"cpu_priority_score" yaml:"cpu_priority_score"`
+	UtilizationWeight float64 `json:"utilization_weight" yaml:"utilization_weight"`
+	BalanceWeight     float64 `json:"balance_weight" yaml:"balance_weight"`
+
+	Strategy   string           `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Components []ScoreComponent `json:"components,omitempty" yaml:"components,omitempty"` // total_score = Σ value × weight
+}
+
+// ScoreComponent mirrors analyzer.ScoreComponent
+type ScoreComponent struct {
+	Name   string  `json:"name" yaml:"name"`
+	Value  float64 `json:"value" yaml:"value"`
+	Weight float64 `json:"weight" yaml:"weight"`
+}
+
+// ResourceState mirrors analyzer.ResourceState
+type ResourceState struct {
+	CPUPercent        float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	RAMPercent        float64 `json:"ram_percent" yaml:"ram_percent"`
+	StoragePercent    float64 `json:"storage_percent" yaml:"storage_percent"`
+	VMCount           int     `json:"vm_count" yaml:"vm_count"`
+	VCPUs             int     `json:"vcpus" yaml:"vcpus"`
+	RAMUsedBytes      int64   `json:"ram_used_bytes" yaml:"ram_used_bytes"`
+	RAMTotalBytes     int64   `json:"ram_total_bytes" yaml:"ram_total_bytes"`
+	StorageUsedBytes  int64   `json:"storage_used_bytes" yaml:"storage_used_bytes"`
+	StorageTotalBytes int64   `json:"storage_total_bytes" yaml:"storage_total_bytes"`
+}
+
+// Alternative mirrors analyzer.AlternativeTarget
+type Alternative struct {
+	Name            string  `json:"name" yaml:"name"`
+	Score           float64 `json:"score" yaml:"score"`
+	RejectionReason string  `json:"rejection_reason,omitempty" yaml:"rejection_reason,omitempty"`
+	CPUAfter        float64 `json:"cpu_after" yaml:"cpu_after"`
+	RAMAfter        float64 `json:"ram_after" yaml:"ram_after"`
+	StorageAfter    float64 `json:"storage_after" yaml:"storage_after"`
+}
+
+// UnmigrateableVM mirrors analyzer.UnmigrateableVM
+type UnmigrateableVM struct {
+	VMID         int    `json:"vmid" yaml:"vmid"`
+	VMName       string `json:"vm_name" yaml:"vm_name"`
+	Status       string `json:"status" yaml:"status"`
+	VCPUs        int    `json:"vcpus" yaml:"vcpus"`
+	RAMBytes     int64  `json:"ram_bytes" yaml:"ram_bytes"`
+	StorageBytes int64  `json:"storage_bytes" yaml:"storage_bytes"`
+	Reason       string `json:"reason" yaml:"reason"`
+}
+
+// NodeImpact holds the before and after state of one node
+type NodeImpact struct {
+	Name   string    `json:"name" yaml:"name"`
+	Role   string    `json:"role" yaml:"role"` // "source" or "target"
+	Before NodeState `json:"before" yaml:"before"`
+	After  NodeState `json:"after" yaml:"after"`
+}
+
+// NodeState mirrors analyzer.NodeState
+type NodeState struct {
+	VMCount           int     `json:"vm_count" yaml:"vm_count"`
+	VCPUs             int     `json:"vcpus" yaml:"vcpus"`
+	CPUCores          int     `json:"cpu_cores" yaml:"cpu_cores"`
+	CPUPercent        float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	HostCPUPercent    float64 `json:"host_cpu_percent" yaml:"host_cpu_percent"`
+	RAMUsedBytes      int64   `json:"ram_used_bytes" yaml:"ram_used_bytes"`
+	RAMTotalBytes     int64   `json:"ram_total_bytes" yaml:"ram_total_bytes"`
+	RAMPercent        float64 `json:"ram_percent" yaml:"ram_percent"`
+	StorageUsedBytes  int64   `json:"storage_used_bytes" yaml:"storage_used_bytes"`
+	StorageTotalBytes int64   `json:"storage_total_bytes" yaml:"storage_total_bytes"`
+	StoragePercent    float64 `json:"storage_percent" yaml:"storage_percent"`
+}
+
+// NewPlan converts an analysis result into its export representation
+// cluster may be nil; it only contributes the node and VM counts
+func NewPlan(result *analyzer.AnalysisResult, cluster *proxmox.Cluster, version string) *Plan {
+	c := result.Constraints
+	plan := &Plan{
+		SchemaVersion:   SchemaVersion,
+		GeneratedAt:     time.Now().UTC(),
+		SnapshotTime:    result.ClusterCollectedAt.UTC(),
+		MigsugVersion:   version,
+		Mode:            c.GetMode().String(),
+		ClusterWide:     result.IsBalanceCluster,
+		CPUMetric:       result.CPUMetric,
+		ImprovementInfo: result.ImprovementInfo,
+		MovementsTried:  result.MovementsTried,
+		Constraints: Constraints{
+			SourceNode:      c.SourceNode,
+			VMCount:         c.VMCount,
+			VCPUCount:       c.VCPUCount,
+			CPUUsage:        c.CPUUsage,
+			RAMBytes:        c.RAMAmount,
+			StorageBytes:    c.StorageAmount,
+			SpecificVMs:     c.SpecificVMs,
+			MigrateAll:      c.MigrateAll,
+			CreationAgeDays: c.CreationAge,
+			BalanceCluster:  c.BalanceCluster,
+			ConstraintAudit: c.ConstraintAudit,
+			ExcludeNodes:    c.ExcludeNodes,
+			MaxVMsPerHost:   c.MaxVMsPerHost,
+			MinCPUFree:      c.MinCPUFree,
+			MinRAMFreeBytes: c.MinRAMFree,
+
+			MaxMigrations:    c.MaxMigrations,
+			MaxTransferBytes: c.MaxTransfer,
+			MaxLiveRAMBytes:  c.MaxLiveRAM,
+			ExcludeVMs:       c.ExcludeVMs,
+
+			CapacityPolicy: c.Policy,
+		},
+		Summary: Summary{
+			TotalVMs:          result.TotalVMs,
+			TotalVCPUs:        result.TotalVCPUs,
+			TotalRAMBytes:     result.TotalRAM,
+			TotalStorageBytes: result.TotalStorage,
+		},
+		Suggestions:      []Suggestion{},
+		UnmigrateableVMs: []UnmigrateableVM{},
+		Nodes:            []NodeImpact{},
+	}
+	if c.Strategy != nil {
+		plan.Constraints.Strategy = c.Strategy.Name()
+	}
+	if cluster != nil {
+		plan.ClusterNodeCount = len(cluster.Nodes)
+		plan.ClusterVMCount = cluster.TotalVMs
+	}
+	if result.Estimate != nil {
+		plan.Summary.EstimatedSeconds = result.Estimate.WallClock.Seconds()
+		plan.Summary.SequentialSeconds = result.Estimate.Sequential.Seconds()
+	}
+
+	// One suggestion per VM: repeated moves are merged like the waves run them
+	for _, sug := range result.PlannedSuggestions() {
+		s := Suggestion{
+			VMID:          sug.VMID,
+			VMName:        sug.VMName,
+			Status:        sug.Status,
+			Type:          sug.Type,
+			SourceNode:    sug.SourceNode,
+			TargetNode:    sug.TargetNode,
+			Reason:        sug.Reason,
+			Score:         sug.Score,
+			VCPUs:         sug.VCPUs,
+			CPUUsage:      sug.CPUUsage,
+			RAMBytes:      sug.RAM,
+			StorageBytes:  sug.Storage,
+			UsedDiskBytes: sug.UsedDisk,
+			MaxDiskBytes:  sug.MaxDisk,
+			TransferBytes: sug.Transfer,
+			Details:       newDetails(sug.Details),
+		}
+		for _, d := range sug.Disks {
+			s.Disks = append(s.Disks, Disk{
+				Disk:          d.Disk,
+				SourceStorage: d.SourceStorage,
+				TargetStorage: d.TargetStorage,
+				SizeBytes:     d.Size,
+				Shared:        d.Shared,
+			})
+		}
+		if sug.TargetNode != "NONE" {
+			s.Command = sug.MigrateCommand()
+			plan.Summary.PlacedVMs++
+			if result.Estimate != nil {
+				s.EstimatedSecs = result.Estimate.VMDuration(sug.VMID).Seconds()
+			}
+		} else {
+			plan.Summary.UnplacedVMs++
+		}
+		plan.Suggestions = append(plan.Suggestions, s)
+	}
+
+	if result.Staged != nil {
+		for i, wave := range result.Staged.Waves {
+			w := Wave{Wave: i + 1, Unsafe: wave.Unsafe}
+			for _, m := range wave.Migrations {
+				w.Migrations = append(w.Migrations, WaveMigration{
+					VMID:       m.VMID,
+					VMName:     m.VMName,
+					SourceNode: m.SourceNode,
+					TargetNode: m.TargetNode,
+					Temporary:  m.Temporary,
+					Command:    m.MigrateCommand(),
+				})
+			}
+			plan.Waves = append(plan.Waves, w)
+		}
+	}
+
+	if d := result.Drain; d != nil {
+		plan.Drain = &Drain{Node: d.Node, Phase: "evacuate"}
+		if d.Return {
+			plan.Mode = "drain_return"
+			plan.Drain.Phase = "return"
+		}
+		if d.HostState >= 0 {
+			state := d.HostState
+			plan.Drain.HostState = &state
+		}
+	}
+
+	if w := result.WhatIf; w != nil {
+		plan.WhatIf = &WhatIf{SimulatedNodes: w.Added, RemovedNodes: w.Removed}
+	}
+
+	if o := result.Optimization; o != nil {
+		plan.Optimization = &Optimization{
+			ImbalanceBefore: o.Before,
+			ImbalanceAfter:  o.After,
+			LowerBound:      o.LowerBound,
+			Gap:             o.Gap(),
+			AchievedPercent: o.Achieved(),
+			Migrations:      o.Migrations,
+			TransferBytes:   o.Bytes,
+			MaxMigrations:   o.MaxMigrations,
+			MaxBytes:        o.MaxBytes,
+			Iterations:      o.Iterations,
+			Seconds:         o.Elapsed.Seconds(),
+			TimedOut:        o.TimedOut,
+		}
+	}
+
+	if b := result.Budget; b != nil {
+		plan.Budget = &Budget{
+			ImbalanceBefore: b.ScoreBefore,
+			ImbalanceAfter:  b.ScoreAfter,
+			Migrations:      b.Migrations,
+			TransferBytes:   b.Bytes,
+			MaxMigrations:   b.MaxMigrations,
+			MaxBytes:        b.MaxBytes,
+			MaxLiveRAMBytes: b.MaxLiveRAM,
+			ExcludedVMs:     b.ExcludedVMs,
+			TooLargeVMs:     b.TooLargeVMs,
+			Exhausted:       b.Exhausted,
+		}
+	}
+
+	for _, v := range result.AffinityViolations {
+		plan.AffinityViolations = append(plan.AffinityViolations, AffinityViolation{
+			Group:   v.Group,
+			Mode:    v.Mode,
+			Nodes:   v.Nodes,
+			Members: v.Members,
+			Message: v.Message,
+		})
+	}
+
+	if result.Audit != nil {
+		for _, v := range result.Audit.Violations {
+			plan.ConstraintViolations = append(plan.ConstraintViolations, ConstraintViolation{
+				Rule:     v.Rule,
+				VMID:     v.VMID,
+				VMName:   v.VMName,
+				Other:    v.Other,
+				Nodes:    v.Nodes,
+				Message:  v.Message,
+				Repaired: v.Repaired,
+				Blocked:  v.Blocked,
+			})
+		}
+	}
+
+	for _, vm := range result.UnmigrateableVMs {
+		plan.UnmigrateableVMs = append(plan.UnmigrateableVMs, UnmigrateableVM{
+			VMID:         vm.VMID,
+			VMName:       vm.VMName,
+			Status:       vm.Status,
+			VCPUs:        vm.VCPUs,
+			RAMBytes:     vm.RAM,
+			StorageBytes: vm.Storage,
+			Reason:       vm.Reason,
+		})
+	}
+
+	// Source first (single-source modes), then targets in name order
+	if !result.IsBalanceCluster && result.SourceBefore.Name != "" {
+		plan.Nodes = append(plan.Nodes, NodeImpact{
+			Name:   result.SourceBefore.Name,
+			Role:   "source",
+			Before: newNodeState(result.SourceBefore),
+			After:  newNodeState(result.SourceAfter),
+		})
+	}
+	names := make([]string, 0, len(result.TargetsBefore))
+	for name := range result.TargetsBefore {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		plan.Nodes = append(plan.Nodes, NodeImpact{
+			Name:   name,
+			Role:   "target",
+			Before: newNodeState(result.TargetsBefore[name]),
+			After:  newNodeState(result.TargetsAfter[name]),
+		})
+	}
+
+	return plan
+}
+
+// newDetails converts migration details, returning nil when there are none
+func newDetails(d *analyzer.MigrationDetails) *Details {
+	if d == nil {
+		return nil
+	}
+	details := &Details{
+		SelectionMode:   d.SelectionMode,
+		SelectionReason: d.SelectionReason,
+		ScoreBreakdown: ScoreBreakdown{
+			TotalScore:        d.ScoreBreakdown.TotalScore,
+			UtilizationScore:  d.ScoreBreakdown.UtilizationScore,
+			BalanceScore:      d.ScoreBreakdown.BalanceScore,
+			HeadroomScore:     d.ScoreBreakdown.HeadroomScore,
+			CPUPriorityScore:  d.ScoreBreakdown.CPUPriorityScore,
+			UtilizationWeight: d.ScoreBreakdown.UtilizationWeight,
+			BalanceWeight:     d.ScoreBreakdown.BalanceWeight,
+			Strategy:          d.ScoreBreakdown.Strategy,
+		},
+		TargetBefore:       newResourceState(d.TargetBefore),
+		TargetAfter:        newResourceState(d.TargetAfter),
+		ClusterAvgCPU:      d.ClusterAvgCPU,
+		ClusterAvgRAM:      d.ClusterAvgRAM,
+		BelowAverage:       d.BelowAverage,
+		ConstraintsApplied: d.ConstraintsApplied,
+	}
+	for _, c := range d.ScoreBreakdown.Components {
+		details.ScoreBreakdown.Components = append(details.ScoreBreakdown.Components, ScoreComponent{Name: c.Name, Value: c.Value, Weight: c.Weight})
+	}
+	for _, alt := range d.Alternatives {
+		details.Alternatives = append(details.Alternatives, Alternative{
+			Name:            alt.Name,
+			Score:           alt.Score,
+			RejectionReason: alt.RejectionReason,
+			CPUAfter:        alt.CPUAfter,
+			RAMAfter:        alt.RAMAfter,
+			StorageAfter:    alt.StorageAfter,
+		})
+	}
+	return details
+}
+
+// newResourceState converts an analyzer resource state
+func newResourceState(rs analyzer.ResourceState) ResourceState {
+	return ResourceState{
+		CPUPercent:        rs.CPUPercent,
+		RAMPercent:        rs.RAMPercent,
+		StoragePercent:    rs.StoragePercent,
+		VMCount:           rs.VMCount,
+		VCPUs:             rs.VCPUs,
+		RAMUsedBytes:      rs.RAMUsed,
+		RAMTotalBytes:     rs.RAMTotal,
+		StorageUsedBytes:  rs.StorageUsed,
+		StorageTotalBytes: rs.StorageTotal,
+	}
+}
+
+// newNodeState converts an analyzer node state
+func newNodeState(ns analyzer.NodeState) NodeState {
+	return NodeState{
+		VMCount:           ns.VMCount,
+		VCPUs:             ns.VCPUs,
+		CPUCores:          ns.CPUCores,
+		CPUPercent:        ns.CPUPercent,
+		HostCPUPercent:    ns.HostCPUPercent,
+		RAMUsedBytes:      ns.RAMUsed,
+		RAMTotalBytes:     ns.RAMTotal,
+		RAMPercent:        ns.RAMPercent,
+		StorageUsedBytes:  ns.StorageUsed,
+		StorageTotalBytes: ns.StorageTotal,
+		StoragePercent:    ns.StoragePercent,
+	}
+}
+
+// Marshal serializes a plan in the given format
+func Marshal(plan *Plan, format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(plan)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// Write serializes a plan to w
+func Write(w io.Writer, plan *Plan, format Format) error {
+	data, err := Marshal(plan, format)
+	if err != nil {
+		return fmt.Errorf("failed to serialize plan: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteFile serializes a plan to path
+func WriteFile(path string, plan *Plan, format Format) error {
+	data, err := Marshal(plan, format)
+	if err != nil {
+		return fmt.Errorf("failed to serialize plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// DefaultFileName returns a file name like migsug-plan-pve1-20260124-153000.json
+func DefaultFileName(plan *Plan, format Format) string {
+	kind, scope := "plan", plan.Constraints.SourceNode
+	if plan.ClusterWide || scope == "" {
+		scope = "cluster"
+	}
+	if plan.WhatIf != nil {
+		kind = "whatif"
+	}
+	if plan.Drain != nil {
+		kind, scope = "drain", plan.Drain.Node
+		if plan.Drain.Phase == "return" {
+			kind = "return"
+		}
+	}
+	return fmt.Sprintf("migsug-%s-%s-%s.%s", kind, scope, plan.GeneratedAt.Local().Format("20060102-150405"), format)
+}
@@ -0,0 +1,378 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// NodeSample is one node as seen by one collection
+type NodeSample struct {
+	Time         time.Time
+	Node         string
+	Status       string
+	CPUCores     int
+	CPUUsage     float64 // Measured host CPU usage (fraction 0-1, like /cluster/resources)
+	VMCPU        float64 // Sum of VM CPU usage × vCPUs (percent of one core)
+	MemTotal     int64   // bytes
+	MemUsed      int64   // bytes, measured
+	VMRAM        int64   // bytes allocated to the node's VMs
+	StorageTotal int64   // bytes of the counted storage pools
+	StorageUsed  int64   // bytes of local VM disks
+	VMCount      int
+	VCPUs        int
+	ConfigMeta   map[string]string // Node config comment (capacity policy overrides)
+}
+
+// HostCPUPercent returns the host CPU usage estimated from the VMs, like the analyzer
+func (s NodeSample) HostCPUPercent() float64 {
+	if s.CPUCores == 0 {
+		return 0
+	}
+	return s.VMCPU / float64(s.CPUCores)
+}
+
+// RAMPercent returns the VM RAM allocated in % of the node's RAM
+func (s NodeSample) RAMPercent() float64 {
+	if s.MemTotal == 0 {
+		return 0
+	}
+	return float64(s.VMRAM) / float64(s.MemTotal) * 100
+}
+
+// StoragePercent returns the local VM disks in % of the node's storage
+func (s NodeSample) StoragePercent() float64 {
+	if s.StorageTotal == 0 {
+		return 0
+	}
+	return float64(s.StorageUsed) / float64(s.StorageTotal) * 100
+}
+
+// VMSample is one VM as seen by one collection
+type VMSample struct {
+	Time     time.Time
+	VMID     int
+	Name     string
+	Node     string
+	Status   string
+	VCPUs    int
+	CPUUsage float64 // Percentage 0-100 of the VM's vCPUs
+	MemMax   int64   // bytes allocated
+	MemUsed  int64   // bytes, measured
+	Disk     int64   // bytes of local disk (thin provisioned size)
+	DiskMax  int64   // bytes allocated
+}
+
+// Store records every collected cluster in a SQLite database so utilization
+// trends and VM placement can be followed over time
+type Store struct {
+	db   *sql.DB
+	mu   sync.Mutex
+	path string
+}
+
+// DefaultPath returns the history database next to the executable, or in
+// the current directory when running from go run (like the disk cache)
+func DefaultPath() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		exePath = "."
+	}
+	exeDir := filepath.Dir(exePath)
+	if filepath.Base(exeDir) == "exe" || filepath.Base(exePath) == "main" {
+		exeDir = "."
+	}
+	return filepath.Join(exeDir, "migsug_history.db")
+}
+
+// Open opens (or creates) the history database at path
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	// A single connection serializes writers from the TUI and the CLI commands
+	db.SetMaxOpenConns(1)
+
+	store := &Store{db: db, path: path}
+	if err := store.initSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history schema: %w", err)
+	}
+
+	log.Printf("History database opened at %s", path)
+	return store, nil
+}
+
+// Path returns the database file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// initSchema creates the tables if they don't exist
+func (s *Store) initSchema() error {
+	statements := []string{
+		`PRAGMA busy_timeout = 5000`,
+		`CREATE TABLE IF NOT EXISTS collections (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cluster TEXT NOT NULL,
+			collected_at INTEGER NOT NULL,
+			UNIQUE (cluster, collected_at)
+		)`,
+		`CREATE TABLE IF NOT EXISTS node_samples (
+			collection_id INTEGER NOT NULL,
+			node TEXT NOT NULL,
+			status TEXT NOT NULL,
+			cpu_cores INTEGER NOT NULL,
+			cpu_usage REAL NOT NULL,
+			vm_cpu REAL NOT NULL,
+			mem_total INTEGER NOT NULL,
+			mem_used INTEGER NOT NULL,
+			vm_ram INTEGER NOT NULL,
+			storage_total INTEGER NOT NULL,
+			storage_used INTEGER NOT NULL,
+			vm_count INTEGER NOT NULL,
+			vcpus INTEGER NOT NULL,
+			config_meta TEXT NOT NULL,
+			PRIMARY KEY (collection_id, node)
+		)`,
+		`CREATE TABLE IF NOT EXISTS vm_samples (
+			collection_id INTEGER NOT NULL,
+			vmid INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			node TEXT NOT NULL,
+			status TEXT NOT NULL,
+			vcpus INTEGER NOT NULL,
+			cpu_usage REAL NOT NULL,
+			mem_max INTEGER NOT NULL,
+			mem_used INTEGER NOT NULL,
+			disk INTEGER NOT NULL,
+			disk_max INTEGER NOT NULL,
+			PRIMARY KEY (collection_id, vmid)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_vm_samples_vmid ON vm_samples(vmid)`,
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record stores the nodes and VMs of a collected cluster under a cluster name
+// (the config profile). Simulated nodes are skipped; recording the same
+// collection twice is a no-op.
+func (s *Store) Record(clusterName string, cluster *proxmox.Cluster) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collectedAt := cluster.CollectedAt
+	if collectedAt.IsZero() {
+		collectedAt = time.Now()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT OR IGNORE INTO collections (cluster, collected_at) VALUES (?, ?)`,
+		clusterName, collectedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record collection: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to record collection: %w", err)
+	}
+
+	nodeStmt, err := tx.Prepare(`
+		INSERT INTO node_samples (collection_id, node, status, cpu_cores, cpu_usage, vm_cpu,
+			mem_total, mem_used, vm_ram, storage_total, storage_used, vm_count, vcpus, config_meta)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer nodeStmt.Close()
+
+	vmStmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO vm_samples (collection_id, vmid, name, node, status, vcpus,
+			cpu_usage, mem_max, mem_used, disk, disk_max)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer vmStmt.Close()
+
+	for i := range cluster.Nodes {
+		node := &cluster.Nodes[i]
+		if node.Simulated {
+			continue
+		}
+		var vmCPU float64
+		var vmRAM, storageUsed int64
+		var vcpus int
+		for j := range node.VMs {
+			vm := &node.VMs[j]
+			vmCPU += vm.CPUUsage * float64(vm.CPUCores)
+			vmRAM += vm.MaxMem
+			storageUsed += vm.GetLocalDisk()
+			vcpus += vm.CPUCores
+
+			_, err := vmStmt.Exec(id, vm.VMID, vm.Name, node.Name, vm.Status, vm.CPUCores,
+				vm.CPUUsage, vm.MaxMem, vm.UsedMem, vm.GetLocalDisk(), vm.MaxDisk)
+			if err != nil {
+				return fmt.Errorf("failed to record VM %d: %w", vm.VMID, err)
+			}
+		}
+		meta, err := json.Marshal(node.ConfigMeta)
+		if err != nil {
+			return fmt.Errorf("failed to record node %s: %w", node.Name, err)
+		}
+		_, err = nodeStmt.Exec(id, node.Name, node.Status, node.CPUCores, node.CPUUsage, vmCPU,
+			node.MaxMem, node.UsedMem, vmRAM, node.MaxDisk, storageUsed, len(node.VMs), vcpus, string(meta))
+		if err != nil {
+			return fmt.Errorf("failed to record node %s: %w", node.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Prune removes collections older than maxAge; returns how many were removed
+func (s *Store) Prune(maxAge time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cutoff := time.Now().Add(-maxAge).Unix()
+	for _, table := range []string{"node_samples", "vm_samples"} {
+		_, err := tx.Exec(`DELETE FROM `+table+` WHERE collection_id IN
+			(SELECT id FROM collections WHERE collected_at < ?)`, cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune %s: %w", table, err)
+		}
+	}
+	res, err := tx.Exec(`DELETE FROM collections WHERE collected_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune collections: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	affected, _ := res.RowsAffected()
+	if affected > 0 {
+		log.Printf("Pruned %d old history collections", affected)
+	}
+	return int(affected), nil
+}
+
+// Collections returns how many collections of the cluster were recorded since the given time
+func (s *Store) Collections(clusterName string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM collections WHERE cluster = ? AND collected_at >= ?`,
+		clusterName, since.Unix()).Scan(&count)
+	return count, err
+}
+
+// NodeSamples returns the node samples of a cluster since the given time, oldest first
+func (s *Store) NodeSamples(clusterName string, since time.Time) ([]NodeSample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT c.collected_at, n.node, n.status, n.cpu_cores, n.cpu_usage, n.vm_cpu, n.mem_total,
+			n.mem_used, n.vm_ram, n.storage_total, n.storage_used, n.vm_count, n.vcpus, n.config_meta
+		FROM node_samples n JOIN collections c ON c.id = n.collection_id
+		WHERE c.cluster = ? AND c.collected_at >= ?
+		ORDER BY c.collected_at, n.node
+	`, clusterName, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node history: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []NodeSample
+	for rows.Next() {
+		var ns NodeSample
+		var at int64
+		var meta string
+		if err := rows.Scan(&at, &ns.Node, &ns.Status, &ns.CPUCores, &ns.CPUUsage, &ns.VMCPU, &ns.MemTotal,
+			&ns.MemUsed, &ns.VMRAM, &ns.StorageTotal, &ns.StorageUsed, &ns.VMCount, &ns.VCPUs, &meta); err != nil {
+			return nil, fmt.Errorf("failed to read node history: %w", err)
+		}
+		ns.Time = time.Unix(at, 0)
+		if err := json.Unmarshal([]byte(meta), &ns.ConfigMeta); err != nil {
+			log.Printf("History: invalid config of node %s: %v", ns.Node, err)
+		}
+		samples = append(samples, ns)
+	}
+	return samples, rows.Err()
+}
+
+// VMSamples returns the VM samples of a cluster since the given time, oldest first
+func (s *Store) VMSamples(clusterName string, since time.Time) ([]VMSample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT c.collected_at, v.vmid, v.name, v.node, v.status, v.vcpus, v.cpu_usage,
+			v.mem_max, v.mem_used, v.disk, v.disk_max
+		FROM vm_samples v JOIN collections c ON c.id = v.collection_id
+		WHERE c.cluster = ? AND c.collected_at >= ?
+		ORDER BY c.collected_at, v.vmid
+	`, clusterName, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VM history: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []VMSample
+	for rows.Next() {
+		var vs VMSample
+		var at int64
+		if err := rows.Scan(&at, &vs.VMID, &vs.Name, &vs.Node, &vs.Status, &vs.VCPUs, &vs.CPUUsage,
+			&vs.MemMax, &vs.MemUsed, &vs.Disk, &vs.DiskMax); err != nil {
+			return nil, fmt.Errorf("failed to read VM history: %w", err)
+		}
+		vs.Time = time.Unix(at, 0)
+		samples = append(samples, vs)
+	}
+	return samples, rows.Err()
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
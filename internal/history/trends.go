@@ -0,0 +1,328 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/proxmox"
+)
+
+// MinForecastSpan is the history a host needs before its limits are forecast;
+// shorter spans mostly measure noise between refreshes
+const MinForecastSpan = 24 * time.Hour
+
+// Forecast is when a host's utilization crosses one hard limit of the
+// capacity policy (the limits canAcceptVM checks), extrapolated linearly
+type Forecast struct {
+	Resource string  // "Host CPU", "RAM" or "Storage"
+	Percent  float64 // Latest utilization
+	Limit    float64 // Hard limit for the host (node overrides applied)
+	PerDay   float64 // Growth in percentage points per day
+	Days     float64 // Days until the limit is crossed: 0 = already above, -1 = not growing
+	At       time.Time
+}
+
+// Crosses returns true if the limit is (or will be) crossed
+func (f Forecast) Crosses() bool {
+	return f.Days >= 0
+}
+
+// String describes the forecast, e.g. "RAM 72% +0.45/day, reaches 90% in 40 days (2026-11-26)"
+func (f Forecast) String() string {
+	s := fmt.Sprintf("%s %.0f%% %+.2f/day", f.Resource, f.Percent, f.PerDay)
+	switch {
+	case f.Days == 0:
+		return s + fmt.Sprintf(", above the %.0f%% limit", f.Limit)
+	case f.Days < 0:
+		return s + ", not growing"
+	}
+	return s + fmt.Sprintf(", reaches %.0f%% in %s (%s)", f.Limit, FormatDays(f.Days), f.At.Format("2006-01-02"))
+}
+
+// FormatDays formats a forecast horizon, e.g. "12 days" or "3.5 years"
+func FormatDays(days float64) string {
+	switch {
+	case days < 1:
+		return "less than a day"
+	case days < 2:
+		return "1 day"
+	case days < 365:
+		return fmt.Sprintf("%.0f days", math.Floor(days))
+	}
+	return fmt.Sprintf("%.1f years", days/365)
+}
+
+// FormatBytesPerDay formats a growth rate in bytes per day, e.g. "+1.2 GB" or "-300.0 MB"
+func FormatBytesPerDay(bytes float64) string {
+	if bytes < 0 {
+		return "-" + proxmox.FormatBytes(int64(-bytes))
+	}
+	return "+" + proxmox.FormatBytes(int64(bytes))
+}
+
+// HostTrend is the growth of one host over the recorded history
+type HostTrend struct {
+	Node    string
+	Samples int
+	First   NodeSample
+	Last    NodeSample
+
+	// Growth in percentage points per day (least squares over all samples)
+	HostCPUPerDay float64
+	RAMPerDay     float64
+	StoragePerDay float64
+
+	Forecasts []Forecast // Host CPU, RAM, storage; nil with less than MinForecastSpan of history
+}
+
+// Next returns the forecast whose limit is crossed first, or nil if none is
+func (h HostTrend) Next() *Forecast {
+	var next *Forecast
+	for i := range h.Forecasts {
+		f := &h.Forecasts[i]
+		if f.Crosses() && (next == nil || f.Days < next.Days) {
+			next = f
+		}
+	}
+	return next
+}
+
+// LimitSummary describes the first limit the host crosses, e.g. "RAM in 40 days"
+// Returns "-" without enough history for a forecast
+func (h HostTrend) LimitSummary() string {
+	if h.Forecasts == nil {
+		return "-"
+	}
+	next := h.Next()
+	switch {
+	case next == nil:
+		return "None"
+	case next.Days == 0:
+		return next.Resource + " above limit"
+	}
+	return fmt.Sprintf("%s in %s", next.Resource, FormatDays(next.Days))
+}
+
+// VMTrend is the growth of one VM over the recorded history
+type VMTrend struct {
+	VMID    int
+	Name    string
+	Samples int
+	First   VMSample
+	Last    VMSample
+
+	CPUPerDay  float64 // Percentage points of the VM's vCPUs per day
+	MemPerDay  float64 // Bytes of used RAM per day
+	DiskPerDay float64 // Bytes of local disk per day
+
+	// Fastest growth relative to the VM's size: CPU, RAM used of allocated or disk of allocated, per day
+	Growth float64
+}
+
+// Move is a VM seen on a different node than in the previous collection
+type Move struct {
+	VMID  int
+	Name  string
+	From  string
+	To    string
+	After time.Time // Last collection on the old node
+	By    time.Time // First collection on the new node
+}
+
+// Report holds the trends of one cluster over a time range
+type Report struct {
+	Cluster     string
+	Since       time.Time
+	Collections int
+	First       time.Time // Oldest collection in range
+	Last        time.Time // Newest collection in range
+	Hosts       []HostTrend
+	VMs         []VMTrend // Fastest growing first
+	Moves       []Move    // Newest first
+}
+
+// Span returns the time covered by the collections
+func (r *Report) Span() time.Duration {
+	return r.Last.Sub(r.First)
+}
+
+// Report builds the trends of a cluster since the given time. Forecasts use
+// the hard limits of the policy with each node's latest config overrides.
+func (s *Store) Report(clusterName string, since time.Time, policy analyzer.CapacityPolicy) (*Report, error) {
+	nodes, err := s.NodeSamples(clusterName, since)
+	if err != nil {
+		return nil, err
+	}
+	vms, err := s.VMSamples(clusterName, since)
+	if err != nil {
+		return nil, err
+	}
+	return BuildReport(clusterName, since, nodes, vms, policy), nil
+}
+
+// BuildReport computes the host and VM trends and the moves from samples sorted oldest first
+func BuildReport(clusterName string, since time.Time, nodes []NodeSample, vms []VMSample, policy analyzer.CapacityPolicy) *Report {
+	report := &Report{Cluster: clusterName, Since: since}
+
+	times := make(map[int64]bool)
+	byNode := make(map[string][]NodeSample)
+	var nodeNames []string
+	for _, ns := range nodes {
+		times[ns.Time.Unix()] = true
+		if _, ok := byNode[ns.Node]; !ok {
+			nodeNames = append(nodeNames, ns.Node)
+		}
+		byNode[ns.Node] = append(byNode[ns.Node], ns)
+	}
+	report.Collections = len(times)
+	if len(nodes) > 0 {
+		report.First = nodes[0].Time
+		report.Last = nodes[len(nodes)-1].Time
+	}
+
+	sort.Strings(nodeNames)
+	for _, name := range nodeNames {
+		report.Hosts = append(report.Hosts, hostTrend(byNode[name], policy))
+	}
+
+	byVM := make(map[int][]VMSample)
+	var vmids []int
+	for _, vs := range vms {
+		if _, ok := byVM[vs.VMID]; !ok {
+			vmids = append(vmids, vs.VMID)
+		}
+		byVM[vs.VMID] = append(byVM[vs.VMID], vs)
+	}
+	for _, vmid := range vmids {
+		samples := byVM[vmid]
+		report.VMs = append(report.VMs, vmTrend(samples))
+		report.Moves = append(report.Moves, findMoves(samples)...)
+	}
+	sort.SliceStable(report.VMs, func(i, j int) bool {
+		if report.VMs[i].Growth != report.VMs[j].Growth {
+			return report.VMs[i].Growth > report.VMs[j].Growth
+		}
+		return report.VMs[i].VMID < report.VMs[j].VMID
+	})
+	sort.SliceStable(report.Moves, func(i, j int) bool {
+		if !report.Moves[i].By.Equal(report.Moves[j].By) {
+			return report.Moves[i].By.After(report.Moves[j].By)
+		}
+		return report.Moves[i].VMID < report.Moves[j].VMID
+	})
+	return report
+}
+
+// hostTrend fits the growth of one host and forecasts its hard limits
+func hostTrend(samples []NodeSample, policy analyzer.CapacityPolicy) HostTrend {
+	first, last := samples[0], samples[len(samples)-1]
+	t := HostTrend{Node: last.Node, Samples: len(samples), First: first, Last: last}
+
+	at := make([]time.Time, len(samples))
+	cpu := make([]float64, len(samples))
+	ram := make([]float64, len(samples))
+	storage := make([]float64, len(samples))
+	for i, s := range samples {
+		at[i] = s.Time
+		cpu[i] = s.HostCPUPercent()
+		ram[i] = s.RAMPercent()
+		storage[i] = s.StoragePercent()
+	}
+	t.HostCPUPerDay = slopePerDay(at, cpu)
+	t.RAMPerDay = slopePerDay(at, ram)
+	t.StoragePerDay = slopePerDay(at, storage)
+	if last.Time.Sub(first.Time) < MinForecastSpan {
+		return t
+	}
+
+	policy = policy.ForNode(&proxmox.Node{Name: last.Node, ConfigMeta: last.ConfigMeta})
+	t.Forecasts = []Forecast{
+		forecast("Host CPU", last.Time, last.HostCPUPercent(), t.HostCPUPerDay, policy.MaxHostCPUPercent),
+		forecast("RAM", last.Time, last.RAMPercent(), t.RAMPerDay, policy.MaxRAMPercent),
+	}
+	if last.StorageTotal > 0 {
+		t.Forecasts = append(t.Forecasts, forecast("Storage", last.Time, last.StoragePercent(), t.StoragePerDay, policy.MaxStoragePercent))
+	}
+	return t
+}
+
+// forecast extrapolates the latest value linearly to the limit
+func forecast(resource string, at time.Time, percent, perDay, limit float64) Forecast {
+	f := Forecast{Resource: resource, Percent: percent, Limit: limit, PerDay: perDay, Days: -1}
+	switch {
+	case percent > limit:
+		f.Days = 0
+		f.At = at
+	case perDay > 0:
+		f.Days = (limit - percent) / perDay
+		f.At = at.Add(time.Duration(f.Days * float64(24*time.Hour)))
+	}
+	return f
+}
+
+// vmTrend fits the growth of one VM
+func vmTrend(samples []VMSample) VMTrend {
+	first, last := samples[0], samples[len(samples)-1]
+	t := VMTrend{VMID: last.VMID, Name: last.Name, Samples: len(samples), First: first, Last: last}
+
+	at := make([]time.Time, len(samples))
+	cpu := make([]float64, len(samples))
+	mem := make([]float64, len(samples))
+	disk := make([]float64, len(samples))
+	for i, s := range samples {
+		at[i] = s.Time
+		cpu[i] = s.CPUUsage
+		mem[i] = float64(s.MemUsed)
+		disk[i] = float64(s.Disk)
+	}
+	t.CPUPerDay = slopePerDay(at, cpu)
+	t.MemPerDay = slopePerDay(at, mem)
+	t.DiskPerDay = slopePerDay(at, disk)
+
+	t.Growth = t.CPUPerDay / 100
+	if last.MemMax > 0 {
+		t.Growth = math.Max(t.Growth, t.MemPerDay/float64(last.MemMax))
+	}
+	if last.DiskMax > 0 {
+		t.Growth = math.Max(t.Growth, t.DiskPerDay/float64(last.DiskMax))
+	}
+	return t
+}
+
+// findMoves returns the node changes between consecutive samples of one VM
+func findMoves(samples []VMSample) []Move {
+	var moves []Move
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		if prev.Node != cur.Node {
+			moves = append(moves, Move{VMID: cur.VMID, Name: cur.Name, From: prev.Node, To: cur.Node, After: prev.Time, By: cur.Time})
+		}
+	}
+	return moves
+}
+
+// slopePerDay returns the least-squares slope of values over time, per day
+// Returns 0 with fewer than two samples or no time between them
+func slopePerDay(at []time.Time, values []float64) float64 {
+	n := float64(len(values))
+	if len(values) < 2 {
+		return 0
+	}
+	origin := at[0]
+	var sumX, sumY, sumXY, sumXX float64
+	for i, v := range values {
+		x := at[i].Sub(origin).Hours() / 24
+		sumX += x
+		sumY += v
+		sumXY += x * v
+		sumXX += x * x
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
@@ -89,13 +89,22 @@ func (c *Client) Authenticate() error {
 }
 
 // doRequest performs an HTTP request with authentication
-func (c *Client) doRequest(method, path string) (*http.Response, error) {
+// params are sent form-encoded in the request body (used by POST/create calls)
+func (c *Client) doRequest(method, path string, params url.Values) (*http.Response, error) {
 	reqURL := c.BaseURL + path
 
-	req, err := http.NewRequest(method, reqURL, nil)
+	var body io.Reader
+	if params != nil {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequest(method, reqURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if params != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
 
 	// Add authentication
 	if c.ticket != "" {
@@ -139,7 +148,7 @@ func (c *Client) doRequest(method, path string) (*http.Response, error) {
 
 // GetClusterResources retrieves all cluster resources
 func (c *Client) GetClusterResources() ([]ClusterResource, error) {
-	resp, err := c.doRequest("GET", "/api2/json/cluster/resources")
+	resp, err := c.doRequest("GET", "/api2/json/cluster/resources", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +176,7 @@ func (c *Client) GetClusterResources() ([]ClusterResource, error) {
 // GetNodeStatus retrieves detailed status for a specific node
 func (c *Client) GetNodeStatus(node string) (*NodeStatus, error) {
 	path := fmt.Sprintf("/api2/json/nodes/%s/status", node)
-	resp, err := c.doRequest("GET", path)
+	resp, err := c.doRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -246,7 +255,7 @@ func (c *Client) GetNodeStatus(node string) (*NodeStatus, error) {
 // GetVMStatus retrieves detailed status for a specific VM
 func (c *Client) GetVMStatus(node string, vmid int) (*VMStatus, error) {
 	path := fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/status/current", node, vmid)
-	resp, err := c.doRequest("GET", path)
+	resp, err := c.doRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -273,7 +282,7 @@ func (c *Client) GetVMStatus(node string, vmid int) (*VMStatus, error) {
 // GetVMConfig retrieves VM configuration
 func (c *Client) GetVMConfig(node string, vmid int) (map[string]interface{}, error) {
 	path := fmt.Sprintf("/api2/json/nodes/%s/qemu/%d/config", node, vmid)
-	resp, err := c.doRequest("GET", path)
+	resp, err := c.doRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -294,7 +303,7 @@ func (c *Client) GetVMConfig(node string, vmid int) (map[string]interface{}, err
 
 // GetNodes retrieves a list of all nodes in the cluster
 func (c *Client) GetNodes() ([]string, error) {
-	resp, err := c.doRequest("GET", "/api2/json/nodes")
+	resp, err := c.doRequest("GET", "/api2/json/nodes", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -325,9 +334,48 @@ func (c *Client) GetNodes() ([]string, error) {
 	return nodeNames, nil
 }
 
+// GetNodeDescription retrieves the description (config comment) of a node
+func (c *Client) GetNodeDescription(node string) (string, error) {
+	path := fmt.Sprintf("/api2/json/nodes/%s/config", node)
+	resp, err := c.doRequest("GET", path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Description string `json:"description"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Data.Description, nil
+}
+
+// SetNodeDescription replaces the description (config comment) of a node
+func (c *Client) SetNodeDescription(node, description string) error {
+	path := fmt.Sprintf("/api2/json/nodes/%s/config", node)
+	params := url.Values{}
+	if description == "" {
+		params.Set("delete", "description")
+	} else {
+		params.Set("description", description)
+	}
+
+	resp, err := c.doRequest("PUT", path, params)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
 // Ping tests the connection to the Proxmox API
 func (c *Client) Ping() error {
-	resp, err := c.doRequest("GET", "/api2/json/version")
+	resp, err := c.doRequest("GET", "/api2/json/version", nil)
 	if err != nil {
 		return err
 	}
@@ -338,7 +386,7 @@ func (c *Client) Ping() error {
 // GetNodeStorages retrieves list of storages available on a node
 func (c *Client) GetNodeStorages(node string) ([]StorageInfo, error) {
 	path := fmt.Sprintf("/api2/json/nodes/%s/storage", node)
-	resp, err := c.doRequest("GET", path)
+	resp, err := c.doRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -365,7 +413,7 @@ func (c *Client) GetNodeStorages(node string) ([]StorageInfo, error) {
 // GetStorageContent retrieves content (volumes) of a storage with actual disk usage
 func (c *Client) GetStorageContent(node, storage string) ([]StorageContentItem, error) {
 	path := fmt.Sprintf("/api2/json/nodes/%s/storage/%s/content", node, storage)
-	resp, err := c.doRequest("GET", path)
+	resp, err := c.doRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -388,3 +436,96 @@ func (c *Client) GetStorageContent(node, storage string) ([]StorageContentItem,
 
 	return content, nil
 }
+
+// GetNodeRRDData retrieves a node's RRD statistics
+func (c *Client) GetNodeRRDData(node, timeframe, cf string) ([]RRDPoint, error) {
+	return c.getRRDData(fmt.Sprintf("/api2/json/nodes/%s/rrddata", node), timeframe, cf)
+}
+
+// GetVMRRDData retrieves a VM's or container's RRD statistics
+func (c *Client) GetVMRRDData(node, vmType string, vmid int, timeframe, cf string) ([]RRDPoint, error) {
+	return c.getRRDData(fmt.Sprintf("/api2/json/nodes/%s/%s/%d/rrddata", node, GuestType(vmType), vmid), timeframe, cf)
+}
+
+// getRRDData fetches an rrddata endpoint; GET parameters go in the query string
+func (c *Client) getRRDData(path, timeframe, cf string) ([]RRDPoint, error) {
+	query := url.Values{"timeframe": {timeframe}, "cf": {cf}}
+	resp, err := c.doRequest("GET", path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []RRDPoint `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Data, nil
+}
+
+// MigrateVM starts a migration of a VM to the target node and returns the task UPID
+// Running VMs are live-migrated when online is true; containers can't live-migrate
+// and are migrated in restart mode (stopped, moved and started again) instead
+func (c *Client) MigrateVM(node, vmType string, vmid int, target string, online bool, targetStorage string) (string, error) {
+	guestType := GuestType(vmType)
+	path := fmt.Sprintf("/api2/json/nodes/%s/%s/%d/migrate", node, guestType, vmid)
+	params := url.Values{}
+	params.Set("target", target)
+	if online {
+		if guestType == "lxc" {
+			params.Set("restart", "1")
+		} else {
+			params.Set("online", "1")
+		}
+	}
+	if targetStorage != "" {
+		if guestType == "lxc" {
+			params.Set("target-storage", targetStorage)
+		} else {
+			params.Set("targetstorage", targetStorage)
+			if online {
+				params.Set("with-local-disks", "1")
+			}
+		}
+	}
+
+	resp, err := c.doRequest("POST", path, params)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Data == "" {
+		return "", fmt.Errorf("migration request returned no task ID")
+	}
+
+	return result.Data, nil
+}
+
+// GetTaskStatus retrieves the status of a task by UPID
+func (c *Client) GetTaskStatus(node, upid string) (*TaskStatus, error) {
+	path := fmt.Sprintf("/api2/json/nodes/%s/tasks/%s/status", node, url.PathEscape(upid))
+	resp, err := c.doRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data TaskStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result.Data, nil
+}
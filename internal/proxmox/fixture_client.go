@@ -0,0 +1,241 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FixtureClient serves Proxmox data from JSON files in a fixture directory
+// It needs no cluster, so the collection pipeline and the analyzer can run
+// against recorded or generated data. The layout mirrors the API paths:
+//
+//	cluster/resources.json                     []ClusterResource
+//	nodes/{node}/status.json                   NodeStatus
+//	nodes/{node}/storage.json                  []StorageInfo
+//	nodes/{node}/storage/{storage}/content.json []StorageContentItem
+//	nodes/{node}/{qemu|lxc}/{vmid}/status.json VMStatus
+//	nodes/{node}/{qemu|lxc}/{vmid}/config.json VM config (key -> value)
+//	nodes/{node}/rrddata-{timeframe}.json      []RRDPoint (optional)
+//	nodes/{node}/{qemu|lxc}/{vmid}/rrddata-{timeframe}.json []RRDPoint (optional)
+//	etc/pve/...                                Raw config files (comment metadata)
+//
+// Missing optional files (VM status/config, storage content, RRD data) return errors
+// the same way a failing API call would. Migrations are recorded in memory
+// and complete immediately.
+type FixtureClient struct {
+	dir string
+
+	mu         sync.Mutex
+	migrations []FixtureMigration
+}
+
+// FixtureMigration records a MigrateVM call made against a FixtureClient
+type FixtureMigration struct {
+	Node   string
+	Type   string
+	VMID   int
+	Target string
+	Online bool
+	UPID   string
+
+	TargetStorage string
+}
+
+// NewFixtureClient creates a client serving data from dir
+func NewFixtureClient(dir string) *FixtureClient {
+	return &FixtureClient{dir: dir}
+}
+
+// Dir returns the fixture directory
+func (c *FixtureClient) Dir() string {
+	return c.dir
+}
+
+// readJSON decodes a fixture file relative to the fixture directory
+func (c *FixtureClient) readJSON(rel string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(c.dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return fmt.Errorf("fixture %s: %w", rel, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal fixture %s: %w", rel, err)
+	}
+	return nil
+}
+
+// GetClusterResources retrieves all cluster resources
+func (c *FixtureClient) GetClusterResources() ([]ClusterResource, error) {
+	var resources []ClusterResource
+	if err := c.readJSON("cluster/resources.json", &resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// GetNodeStatus retrieves detailed status for a specific node
+func (c *FixtureClient) GetNodeStatus(node string) (*NodeStatus, error) {
+	var status NodeStatus
+	if err := c.readJSON(fmt.Sprintf("nodes/%s/status.json", node), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// findGuestDir returns the fixture path of a VM, trying qemu before lxc
+func (c *FixtureClient) findGuestDir(node string, vmid int) string {
+	for _, guestType := range []string{"qemu", "lxc"} {
+		rel := fmt.Sprintf("nodes/%s/%s/%d", node, guestType, vmid)
+		if _, err := os.Stat(filepath.Join(c.dir, filepath.FromSlash(rel))); err == nil {
+			return rel
+		}
+	}
+	return fmt.Sprintf("nodes/%s/qemu/%d", node, vmid)
+}
+
+// GetVMStatus retrieves detailed status for a specific VM
+func (c *FixtureClient) GetVMStatus(node string, vmid int) (*VMStatus, error) {
+	var status VMStatus
+	if err := c.readJSON(c.findGuestDir(node, vmid)+"/status.json", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetVMConfig retrieves VM configuration
+func (c *FixtureClient) GetVMConfig(node string, vmid int) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := c.readJSON(c.findGuestDir(node, vmid)+"/config.json", &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// GetNodes retrieves a list of all nodes from the cluster resources
+func (c *FixtureClient) GetNodes() ([]string, error) {
+	resources, err := c.GetClusterResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+	for _, res := range resources {
+		if res.Type == "node" {
+			nodes = append(nodes, res.Node)
+		}
+	}
+	return nodes, nil
+}
+
+// GetNodeStorages retrieves list of storages available on a node
+func (c *FixtureClient) GetNodeStorages(node string) ([]StorageInfo, error) {
+	var storages []StorageInfo
+	if err := c.readJSON(fmt.Sprintf("nodes/%s/storage.json", node), &storages); err != nil {
+		return nil, err
+	}
+	return storages, nil
+}
+
+// GetStorageContent retrieves content (volumes) of a storage
+func (c *FixtureClient) GetStorageContent(node, storage string) ([]StorageContentItem, error) {
+	var content []StorageContentItem
+	if err := c.readJSON(fmt.Sprintf("nodes/%s/storage/%s/content.json", node, storage), &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// GetNodeRRDData reads nodes/{node}/rrddata-{timeframe}.json; cf is ignored
+func (c *FixtureClient) GetNodeRRDData(node, timeframe, cf string) ([]RRDPoint, error) {
+	var points []RRDPoint
+	if err := c.readJSON(fmt.Sprintf("nodes/%s/rrddata-%s.json", node, timeframe), &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// GetVMRRDData reads rrddata-{timeframe}.json from the guest directory; cf is ignored
+func (c *FixtureClient) GetVMRRDData(node, vmType string, vmid int, timeframe, cf string) ([]RRDPoint, error) {
+	var points []RRDPoint
+	rel := fmt.Sprintf("nodes/%s/%s/%d/rrddata-%s.json", node, GuestType(vmType), vmid, timeframe)
+	if err := c.readJSON(rel, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// ReadConfigFile serves /etc/pve paths from the etc/pve fixture subdirectory
+func (c *FixtureClient) ReadConfigFile(path string) ([]byte, error) {
+	rel := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	return os.ReadFile(filepath.Join(c.dir, filepath.FromSlash(rel)))
+}
+
+// WriteConfigFile replaces an /etc/pve path in the etc/pve fixture subdirectory
+func (c *FixtureClient) WriteConfigFile(path string, data []byte) error {
+	rel := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	return os.WriteFile(filepath.Join(c.dir, filepath.FromSlash(rel)), data, 0644)
+}
+
+// MigrateVM records the migration and returns a fake task UPID
+// The fixture files are not modified
+func (c *FixtureClient) MigrateVM(node, vmType string, vmid int, target string, online bool, targetStorage string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	upid := fmt.Sprintf("UPID:%s:00000000:00000000:%08X:qmigrate:%d:fixture@pve:", node, time.Now().Unix(), vmid)
+	c.migrations = append(c.migrations, FixtureMigration{
+		Node:   node,
+		Type:   GuestType(vmType),
+		VMID:   vmid,
+		Target: target,
+		Online: online,
+		UPID:   upid,
+
+		TargetStorage: targetStorage,
+	})
+	return upid, nil
+}
+
+// Migrations returns the migrations started so far
+func (c *FixtureClient) Migrations() []FixtureMigration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FixtureMigration(nil), c.migrations...)
+}
+
+// GetTaskStatus reports every recorded migration as finished successfully
+func (c *FixtureClient) GetTaskStatus(node, upid string) (*TaskStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range c.migrations {
+		if m.UPID == upid {
+			return &TaskStatus{
+				UPID:       upid,
+				Node:       node,
+				Type:       "qmigrate",
+				ID:         fmt.Sprintf("%d", m.VMID),
+				Status:     "stopped",
+				ExitStatus: "OK",
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown task %s", upid)
+}
+
+// Ping checks that the fixture directory contains cluster resources
+func (c *FixtureClient) Ping() error {
+	if _, err := os.Stat(filepath.Join(c.dir, "cluster", "resources.json")); err != nil {
+		return fmt.Errorf("not a fixture directory: %w", err)
+	}
+	return nil
+}
+
+// Authenticate is a no-op for fixture client
+func (c *FixtureClient) Authenticate() error {
+	return nil
+}
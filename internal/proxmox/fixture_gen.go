@@ -0,0 +1,373 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FixtureOptions controls the synthetic cluster written by GenerateFixtures
+type FixtureOptions struct {
+	Nodes int     // Number of nodes
+	VMs   int     // Number of VMs across all nodes
+	Skew  float64 // 0 = VMs spread evenly, 1 = all VMs on the first node; each node gets (1-Skew) times the VMs of the previous one
+	Seed  int64   // Random seed; the same options always produce the same files
+}
+
+// Hardware profiles used by generated nodes (alternating, so hostcpumodel constraints have a choice)
+var fixtureCPUModels = []string{
+	"Intel(R) Xeon(R) Gold 6150 CPU @ 2.70GHz",
+	"AMD EPYC 7763 64-Core Processor",
+}
+
+// fixtureRRDTimeframes are the RRD series written for nodes and running VMs,
+// with the seconds between samples Proxmox uses for them
+var fixtureRRDTimeframes = []struct {
+	name string
+	step int64
+}{{"hour", 60}, {"day", 1800}, {"week", 10800}}
+
+// fixtureRRDSamples is the length of every generated RRD series
+const fixtureRRDSamples = 70
+
+// fixtureSharedStorage is the shared Ceph pool every generated node sees
+// Every tenth qemu VM keeps its disk there
+const fixtureSharedStorage = "ceph-vm"
+
+// GenerateFixtures writes a synthetic cluster in FixtureClient layout to dir
+// Nodes and storages follow the kv{NNNN} naming the collection pipeline expects
+func GenerateFixtures(dir string, opts FixtureOptions) error {
+	if opts.Nodes <= 0 {
+		return fmt.Errorf("fixture needs at least one node")
+	}
+	if opts.VMs < 0 {
+		return fmt.Errorf("VM count must not be negative")
+	}
+	if opts.Skew < 0 || opts.Skew > 1 {
+		return fmt.Errorf("skew must be between 0 and 1")
+	}
+
+	const gb = int64(1024 * 1024 * 1024)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	now := time.Now().Unix()
+
+	// Per-node weights for VM placement
+	weights := make([]float64, opts.Nodes)
+	totalWeight := 0.0
+	for i := range weights {
+		weights[i] = math.Pow(1-opts.Skew, float64(i))
+		totalWeight += weights[i]
+	}
+
+	type nodeData struct {
+		name     string
+		storage  string
+		cores    int
+		maxMem   int64
+		maxDisk  int64
+		cpuUsed  float64 // Sum of vCPUs * usage fraction of running VMs
+		memUsed  int64
+		diskUsed int64
+		content  []StorageContentItem
+		rrdBusy  [][]float64 // Per RRD timeframe and sample: sum of vCPUs * usage fraction
+	}
+
+	nodes := make([]*nodeData, opts.Nodes)
+	for i := range nodes {
+		prefix := fmt.Sprintf("kv%04d", i+1)
+		nodes[i] = &nodeData{
+			name:    fmt.Sprintf("%s-10-0-%d-%d", prefix, i/250, i%250+1),
+			storage: prefix + "storage",
+			cores:   64,
+			maxMem:  512 * gb,
+			maxDisk: 8192 * gb,
+			memUsed: 8 * gb, // Host overhead
+			rrdBusy: make([][]float64, len(fixtureRRDTimeframes)),
+		}
+		for t := range nodes[i].rrdBusy {
+			nodes[i].rrdBusy[t] = make([]float64, fixtureRRDSamples)
+		}
+	}
+
+	var resources []ClusterResource
+	var sharedUsed int64
+	vcpuChoices := []int{2, 4, 8, 16}
+	memChoices := []int64{4, 8, 16, 32}
+	diskChoices := []int64{50, 100, 200, 500}
+
+	for i := 0; i < opts.VMs; i++ {
+		// Weighted node choice
+		pick := rng.Float64() * totalWeight
+		idx := 0
+		for idx < len(weights)-1 && pick >= weights[idx] {
+			pick -= weights[idx]
+			idx++
+		}
+		node := nodes[idx]
+
+		vmid := 100 + i
+		vmType := "qemu"
+		if rng.Float64() < 0.1 {
+			vmType = "lxc"
+		}
+		status := "running"
+		if rng.Float64() < 0.1 {
+			status = "stopped"
+		}
+		vcpus := vcpuChoices[rng.Intn(len(vcpuChoices))]
+		maxMem := memChoices[rng.Intn(len(memChoices))] * gb
+		diskGB := diskChoices[rng.Intn(len(diskChoices))]
+		usedDisk := int64(float64(diskGB*gb) * (0.3 + 0.5*rng.Float64()))
+		ctime := now - int64(rng.Intn(400))*24*3600
+
+		var cpu float64
+		var usedMem, uptime int64
+		if status == "running" {
+			cpu = 0.05 + 0.55*rng.Float64()
+			usedMem = int64(float64(maxMem) * (0.4 + 0.5*rng.Float64()))
+			uptime = int64(rng.Intn(90*24*3600)) + 3600
+			node.cpuUsed += cpu * float64(vcpus)
+			node.memUsed += usedMem
+		}
+		shared := vmType == "qemu" && vmid%10 == 7
+		if shared {
+			sharedUsed += usedDisk
+		} else {
+			node.diskUsed += usedDisk
+		}
+
+		name := fmt.Sprintf("vm%05d.fixture.local", vmid)
+		resources = append(resources, ClusterResource{
+			ID:      fmt.Sprintf("%s/%d", vmType, vmid),
+			Type:    vmType,
+			Node:    node.name,
+			Status:  status,
+			Name:    name,
+			VMID:    vmid,
+			MaxCPU:  vcpus,
+			CPU:     cpu,
+			MaxMem:  maxMem,
+			Mem:     usedMem,
+			MaxDisk: diskGB * gb,
+			Uptime:  uptime,
+		})
+
+		// Disk volume, VM config and raw config file
+		diskKey, configDir := "scsi0", "qemu-server"
+		if vmType == "lxc" {
+			diskKey, configDir = "rootfs", "lxc"
+		}
+		volID := fmt.Sprintf("%s:%d/vm-%d-disk-0.qcow2", node.storage, vmid, vmid)
+		if shared {
+			volID = fmt.Sprintf("%s:vm-%d-disk-0", fixtureSharedStorage, vmid)
+		}
+		diskLine := fmt.Sprintf("%s,size=%dG", volID, diskGB)
+		if !shared {
+			node.content = append(node.content, StorageContentItem{
+				Content: "images",
+				Format:  "qcow2",
+				Size:    diskGB * gb,
+				Used:    usedDisk,
+				VMID:    vmid,
+				VolID:   volID,
+			})
+		}
+
+		guestDir := fmt.Sprintf("nodes/%s/%s/%d", node.name, vmType, vmid)
+		config := map[string]interface{}{
+			"name":   name,
+			"cores":  vcpus,
+			"memory": maxMem / (1024 * 1024),
+			diskKey:  diskLine,
+		}
+		if err := writeFixtureJSON(dir, guestDir+"/config.json", config); err != nil {
+			return err
+		}
+		if status == "running" {
+			// Own random source, so the other fixture files don't depend on the history
+			vmRng := rand.New(rand.NewSource(opts.Seed*100003 + int64(vmid)))
+			for t, tf := range fixtureRRDTimeframes {
+				series := fixtureCPUSeries(vmRng, cpu, now, tf.step)
+				for k, p := range series {
+					node.rrdBusy[t][k] += *p.CPU * float64(vcpus)
+					series[k].MaxCPU = float64(vcpus)
+				}
+				if err := writeFixtureJSON(dir, fmt.Sprintf("%s/rrddata-%s.json", guestDir, tf.name), series); err != nil {
+					return err
+				}
+			}
+		}
+		if err := writeFixtureJSON(dir, guestDir+"/status.json", VMStatus{
+			Status: status, VMID: vmid, Name: name, Uptime: uptime, CPUs: vcpus, CPU: cpu,
+			MaxMem: maxMem, Mem: usedMem, MaxDisk: diskGB * gb, Disk: usedDisk,
+		}); err != nil {
+			return err
+		}
+
+		var conf strings.Builder
+		meta := []string{"owner=fixture"}
+		if rng.Float64() < 0.02 {
+			meta = append(meta, "nomigrate=true")
+		}
+		if vmid%20 == 3 {
+			meta = append(meta, "group=fixture-db", "groupmode=spread")
+		}
+		fmt.Fprintf(&conf, "#%s\n", strings.Join(meta, ","))
+		fmt.Fprintf(&conf, "cores: %d\nmemory: %d\nname: %s\n", vcpus, maxMem/(1024*1024), name)
+		fmt.Fprintf(&conf, "meta: creation-qemu=8.1.2,ctime=%d\n", ctime)
+		fmt.Fprintf(&conf, "%s: %s\n", diskKey, diskLine)
+		confPath := fmt.Sprintf("etc/pve/nodes/%s/%s/%d.conf", node.name, configDir, vmid)
+		if err := writeFixtureFile(dir, confPath, []byte(conf.String())); err != nil {
+			return err
+		}
+	}
+
+	for i, node := range nodes {
+		cpuUsage := math.Min(node.cpuUsed/float64(node.cores), 1)
+		if cpuUsage == 0 {
+			cpuUsage = 0.01 // Idle hosts still report some load
+		}
+		model := fixtureCPUModels[i%len(fixtureCPUModels)]
+
+		resources = append(resources,
+			ClusterResource{
+				ID:      "node/" + node.name,
+				Type:    "node",
+				Node:    node.name,
+				Status:  "online",
+				Name:    node.name,
+				MaxCPU:  node.cores,
+				CPU:     cpuUsage,
+				MaxMem:  node.maxMem,
+				Mem:     node.memUsed,
+				MaxDisk: 100 * gb,
+				Disk:    20 * gb,
+				Uptime:  180 * 24 * 3600,
+			},
+			ClusterResource{
+				ID:      fmt.Sprintf("storage/%s/%s", node.name, node.storage),
+				Type:    "storage",
+				Node:    node.name,
+				Status:  "available",
+				Name:    node.storage,
+				Storage: node.storage,
+				MaxDisk: node.maxDisk,
+				Disk:    node.diskUsed,
+
+				PluginType: "dir",
+				Content:    "images,rootdir",
+			},
+			ClusterResource{
+				ID:      fmt.Sprintf("storage/%s/%s", node.name, fixtureSharedStorage),
+				Type:    "storage",
+				Node:    node.name,
+				Status:  "available",
+				Name:    fixtureSharedStorage,
+				Storage: fixtureSharedStorage,
+				MaxDisk: 102400 * gb,
+				Disk:    sharedUsed,
+
+				PluginType: "rbd",
+				Shared:     1,
+				Content:    "images",
+			},
+		)
+
+		nodeDir := "nodes/" + node.name
+		if err := writeFixtureJSON(dir, nodeDir+"/status.json", NodeStatus{
+			Uptime:      180 * 24 * 3600,
+			CPUInfo:     CPUInfo{Cores: node.cores / 2, CPUs: node.cores, Model: model, Sockets: 2, MHz: 2700},
+			Memory:      Memory{Total: node.maxMem, Used: node.memUsed, Free: node.maxMem - node.memUsed},
+			Swap:        Swap{},
+			RootFS:      RootFS{Total: 100 * gb, Used: 20 * gb, Free: 80 * gb, Avail: 80 * gb},
+			LoadAverage: []float64{cpuUsage * float64(node.cores), cpuUsage * float64(node.cores), cpuUsage * float64(node.cores)},
+			PVEVersion:  "pve-manager/8.1.4/fixture",
+		}); err != nil {
+			return err
+		}
+		if err := writeFixtureJSON(dir, nodeDir+"/storage.json", []StorageInfo{{
+			Storage: node.storage, Type: "dir", Content: "images,rootdir",
+			Total: node.maxDisk, Used: node.diskUsed, Avail: node.maxDisk - node.diskUsed,
+			Active: 1, Enabled: 1,
+		}, {
+			Storage: fixtureSharedStorage, Type: "rbd", Content: "images",
+			Total: 102400 * gb, Used: sharedUsed, Avail: 102400*gb - sharedUsed,
+			Active: 1, Enabled: 1, Shared: 1,
+		}}); err != nil {
+			return err
+		}
+		for t, tf := range fixtureRRDTimeframes {
+			series := make([]RRDPoint, fixtureRRDSamples)
+			for k := range series {
+				usage := math.Max(math.Min(node.rrdBusy[t][k]/float64(node.cores), 1), 0.01)
+				series[k] = RRDPoint{Time: fixtureRRDTime(now, tf.step, k), CPU: &usage, MaxCPU: float64(node.cores)}
+			}
+			if err := writeFixtureJSON(dir, fmt.Sprintf("%s/rrddata-%s.json", nodeDir, tf.name), series); err != nil {
+				return err
+			}
+		}
+
+		content := node.content
+		if content == nil {
+			content = []StorageContentItem{}
+		}
+		if err := writeFixtureJSON(dir, fmt.Sprintf("%s/storage/%s/content.json", nodeDir, node.storage), content); err != nil {
+			return err
+		}
+
+		nodeConfig := "#hostprovision=true\n"
+		if i%3 == 2 {
+			nodeConfig = "#hostprovision=false\n"
+		}
+		if err := writeFixtureFile(dir, fmt.Sprintf("etc/pve/nodes/%s/config", node.name), []byte(nodeConfig)); err != nil {
+			return err
+		}
+	}
+
+	return writeFixtureJSON(dir, "cluster/resources.json", resources)
+}
+
+// fixtureCPUSeries returns an RRD series of a VM's CPU usage fraction that
+// follows a daily cycle around cpu, with some noise
+func fixtureCPUSeries(rng *rand.Rand, cpu float64, now, step int64) []RRDPoint {
+	phase := rng.Float64() * 2 * math.Pi
+	series := make([]RRDPoint, fixtureRRDSamples)
+	for k := range series {
+		t := fixtureRRDTime(now, step, k)
+		cycle := 0.5 + 0.5*math.Sin(2*math.Pi*float64(t%86400)/86400+phase)
+		usage := math.Min(cpu*(0.4+1.2*cycle)*(0.85+0.3*rng.Float64()), 1)
+		series[k] = RRDPoint{Time: t, CPU: &usage}
+	}
+	return series
+}
+
+// fixtureRRDTime returns the timestamp of sample k of a series ending at now
+func fixtureRRDTime(now, step int64, k int) int64 {
+	return (now/step - int64(fixtureRRDSamples-1-k)) * step
+}
+
+// writeFixtureJSON writes v as indented JSON to a path relative to dir
+func writeFixtureJSON(dir, rel string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture %s: %w", rel, err)
+	}
+	return writeFixtureFile(dir, rel, data)
+}
+
+// writeFixtureFile writes data to a path relative to dir, creating parent directories
+func writeFixtureFile(dir, rel string, data []byte) error {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fixture %s: %w", rel, err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package proxmox
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the tests in a temporary directory: collection writes migsug.log to the working directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "migsug-proxmox-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.Chdir(wd)
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// generateFixture writes a fixture cluster to a temporary directory and returns its client
+func generateFixture(t *testing.T, opts FixtureOptions) *FixtureClient {
+	t.Helper()
+	dir := t.TempDir()
+	if err := GenerateFixtures(dir, opts); err != nil {
+		t.Fatalf("GenerateFixtures: %v", err)
+	}
+	return NewFixtureClient(dir)
+}
+
+func TestGenerateFixturesOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FixtureOptions
+		err  string
+	}{
+		{"no nodes", FixtureOptions{Nodes: 0, VMs: 10}, "at least one node"},
+		{"negative VMs", FixtureOptions{Nodes: 2, VMs: -1}, "must not be negative"},
+		{"negative skew", FixtureOptions{Nodes: 2, VMs: 10, Skew: -0.1}, "between 0 and 1"},
+		{"skew above 1", FixtureOptions{Nodes: 2, VMs: 10, Skew: 1.5}, "between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := GenerateFixtures(t.TempDir(), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("GenerateFixtures(%+v) = %v, want an error containing %q", tt.opts, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestGenerateFixtures(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FixtureOptions
+		// VMs on the first node: at least minFirst, at most maxFirst
+		minFirst, maxFirst int
+	}{
+		{"even", FixtureOptions{Nodes: 4, VMs: 200, Skew: 0, Seed: 1}, 30, 70},
+		{"skewed", FixtureOptions{Nodes: 4, VMs: 200, Skew: 0.5, Seed: 1}, 80, 130},
+		{"all on the first node", FixtureOptions{Nodes: 3, VMs: 50, Skew: 1, Seed: 2}, 50, 50},
+		{"no VMs", FixtureOptions{Nodes: 2, VMs: 0, Seed: 3}, 0, 0},
+		{"single node", FixtureOptions{Nodes: 1, VMs: 10, Seed: 4}, 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := generateFixture(t, tt.opts)
+			if err := client.Ping(); err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			cluster, err := CollectClusterData(client)
+			if err != nil {
+				t.Fatalf("CollectClusterData: %v", err)
+			}
+			if len(cluster.Nodes) != tt.opts.Nodes {
+				t.Fatalf("collected %d nodes, want %d", len(cluster.Nodes), tt.opts.Nodes)
+			}
+			if cluster.TotalVMs != tt.opts.VMs {
+				t.Errorf("collected %d VMs, want %d", cluster.TotalVMs, tt.opts.VMs)
+			}
+
+			first := GetNodeByName(cluster, "kv0001-10-0-0-1")
+			if first == nil {
+				t.Fatal("first node kv0001-10-0-0-1 not collected")
+			}
+			if n := len(first.VMs); n < tt.minFirst || n > tt.maxFirst {
+				t.Errorf("%d VMs on the first node, want %d-%d", n, tt.minFirst, tt.maxFirst)
+			}
+			for _, node := range cluster.Nodes {
+				if node.Status != "online" || node.CPUCores != 64 {
+					t.Errorf("node %s: status %s with %d cores, want online with 64", node.Name, node.Status, node.CPUCores)
+				}
+				if len(node.Storages) == 0 {
+					t.Errorf("node %s: no storage pools collected", node.Name)
+				}
+				for _, vm := range node.VMs {
+					if vm.Node != node.Name || vm.CPUCores == 0 || vm.MaxMem == 0 || len(vm.Disks) == 0 {
+						t.Errorf("VM %d on %s: incomplete %+v", vm.VMID, node.Name, vm)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateFixturesSeed(t *testing.T) {
+	opts := FixtureOptions{Nodes: 3, VMs: 40, Skew: 0.3, Seed: 7}
+	resources := func(opts FixtureOptions) []ClusterResource {
+		res, err := generateFixture(t, opts).GetClusterResources()
+		if err != nil {
+			t.Fatalf("GetClusterResources: %v", err)
+		}
+		return res
+	}
+
+	first := resources(opts)
+	if again := resources(opts); !reflect.DeepEqual(first, again) {
+		t.Error("the same seed produced different clusters")
+	}
+	opts.Seed++
+	if other := resources(opts); reflect.DeepEqual(first, other) {
+		t.Error("different seeds produced the same cluster")
+	}
+}
+
+func TestFixtureClientMigrations(t *testing.T) {
+	client := generateFixture(t, FixtureOptions{Nodes: 2, VMs: 4, Seed: 1})
+
+	upid, err := client.MigrateVM("kv0001-10-0-0-1", "qemu", 100, "kv0002-10-0-0-2", true, "kv0002storage")
+	if err != nil {
+		t.Fatalf("MigrateVM: %v", err)
+	}
+	if _, err := client.MigrateVM("kv0001-10-0-0-1", "lxc", 101, "kv0002-10-0-0-2", false, ""); err != nil {
+		t.Fatalf("MigrateVM: %v", err)
+	}
+
+	migrations := client.Migrations()
+	if len(migrations) != 2 {
+		t.Fatalf("%d migrations recorded, want 2", len(migrations))
+	}
+	want := FixtureMigration{
+		Node: "kv0001-10-0-0-1", Type: "qemu", VMID: 100, Target: "kv0002-10-0-0-2", Online: true, UPID: upid,
+		TargetStorage: "kv0002storage",
+	}
+	if migrations[0] != want {
+		t.Errorf("recorded %+v, want %+v", migrations[0], want)
+	}
+	if migrations[1].Type != "lxc" || migrations[1].Online {
+		t.Errorf("recorded %+v, want an offline lxc migration", migrations[1])
+	}
+
+	status, err := client.GetTaskStatus("kv0001-10-0-0-1", upid)
+	if err != nil {
+		t.Fatalf("GetTaskStatus: %v", err)
+	}
+	if status.Status != "stopped" || status.ExitStatus != "OK" {
+		t.Errorf("task %s is %s/%s, want stopped/OK", upid, status.Status, status.ExitStatus)
+	}
+	if _, err := client.GetTaskStatus("kv0001-10-0-0-1", "UPID:unknown"); err == nil {
+		t.Error("GetTaskStatus accepted an unknown task")
+	}
+}
+
+func TestFixtureClientConfigFiles(t *testing.T) {
+	client := generateFixture(t, FixtureOptions{Nodes: 3, VMs: 0, Seed: 1})
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/etc/pve/nodes/kv0001-10-0-0-1/config", "#hostprovision=true\n"},
+		{"/etc/pve/nodes/kv0003-10-0-0-3/config", "#hostprovision=false\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			data, err := client.ReadConfigFile(tt.path)
+			if err != nil {
+				t.Fatalf("ReadConfigFile: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("read %q, want %q", data, tt.want)
+			}
+
+			updated := "#hostprovision=true,hoststate=0\n"
+			if err := client.WriteConfigFile(tt.path, []byte(updated)); err != nil {
+				t.Fatalf("WriteConfigFile: %v", err)
+			}
+			if data, _ := client.ReadConfigFile(tt.path); string(data) != updated {
+				t.Errorf("read %q after writing %q", data, updated)
+			}
+		})
+	}
+
+	if _, err := client.ReadConfigFile("/etc/pve/nodes/missing/config"); err == nil {
+		t.Error("ReadConfigFile accepted a missing file")
+	}
+}
+
+func TestFixtureClientPing(t *testing.T) {
+	if err := NewFixtureClient(t.TempDir()).Ping(); err == nil {
+		t.Error("Ping accepted an empty directory")
+	}
+}
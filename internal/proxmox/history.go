@@ -0,0 +1,218 @@
+package proxmox
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CPUMetric selects the CPU usage placement decisions are based on: the
+// current value from /cluster/resources, or a statistic over the RRD history
+type CPUMetric struct {
+	Window string // "" (current), "hour", "day" or "week"
+	Stat   string // "avg" or "p95" (ignored for the current value)
+}
+
+// CPUMetrics lists the choices in the order the criteria screen cycles through them
+var CPUMetrics = []CPUMetric{
+	{},
+	{Window: "hour", Stat: "avg"},
+	{Window: "hour", Stat: "p95"},
+	{Window: "day", Stat: "avg"},
+	{Window: "day", Stat: "p95"},
+	{Window: "week", Stat: "avg"},
+	{Window: "week", Stat: "p95"},
+}
+
+// IsCurrent returns true for the instantaneous CPU usage (no history)
+func (m CPUMetric) IsCurrent() bool {
+	return m.Window == ""
+}
+
+// String describes the metric, e.g. "current" or "day p95"
+func (m CPUMetric) String() string {
+	if m.IsCurrent() {
+		return "current"
+	}
+	return m.Window + " " + m.Stat
+}
+
+// Next returns the metric after m in CPUMetrics, wrapping around
+func (m CPUMetric) Next() CPUMetric {
+	for i, c := range CPUMetrics {
+		if c == m {
+			return CPUMetrics[(i+1)%len(CPUMetrics)]
+		}
+	}
+	return CPUMetrics[0]
+}
+
+// ParseCPUMetric parses "current" or "<hour|day|week>-<avg|p95>", e.g. "day-p95"
+func ParseCPUMetric(s string) (CPUMetric, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "current" {
+		return CPUMetric{}, nil
+	}
+	for _, m := range CPUMetrics[1:] {
+		if s == m.Window+"-"+m.Stat {
+			return m, nil
+		}
+	}
+	return CPUMetric{}, fmt.Errorf("unknown CPU metric %q (current, hour-avg, hour-p95, day-avg, day-p95, week-avg or week-p95)", s)
+}
+
+// statistic reduces the CPU samples of an RRD series; ok is false without samples
+func (m CPUMetric) statistic(points []RRDPoint) (value float64, ok bool) {
+	var values []float64
+	for _, p := range points {
+		if p.CPU != nil && !math.IsNaN(*p.CPU) {
+			values = append(values, *p.CPU)
+		}
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	if m.Stat == "p95" {
+		// Nearest-rank percentile
+		sort.Float64s(values)
+		rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+		return values[max(rank, 0)], true
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values)), true
+}
+
+// rrdJob is one node (vmIdx = -1) or VM whose history is fetched
+type rrdJob struct {
+	nodeIdx int
+	vmIdx   int
+}
+
+type rrdResult struct {
+	rrdJob
+	value float64
+	ok    bool
+	err   error
+}
+
+// CollectCPUHistory returns a copy of the cluster whose node and VM CPUUsage
+// values are replaced by the metric's statistic over the RRD history (the
+// AVERAGE series: 1-minute samples for an hour, 30-minute ones for a day and
+// 3-hour ones for a week). Online nodes and running VMs are fetched in
+// parallel; those without history keep their current value. Fails only if
+// no history could be fetched at all.
+func CollectCPUHistory(client ProxmoxClient, cluster *Cluster, metric CPUMetric, progress ProgressCallback) (*Cluster, error) {
+	if metric.IsCurrent() {
+		return cluster, nil
+	}
+
+	hist := *cluster
+	hist.Nodes = make([]Node, len(cluster.Nodes))
+	var jobs []rrdJob
+	for i, node := range cluster.Nodes {
+		node.VMs = append([]VM(nil), node.VMs...)
+		hist.Nodes[i] = node
+		if node.Status != "online" {
+			continue
+		}
+		jobs = append(jobs, rrdJob{nodeIdx: i, vmIdx: -1})
+		for j, vm := range node.VMs {
+			if vm.Status == "running" {
+				jobs = append(jobs, rrdJob{nodeIdx: i, vmIdx: j})
+			}
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no online nodes to fetch CPU history for")
+	}
+
+	stage := fmt.Sprintf("Fetching %s CPU history", metric.Window)
+	if progress != nil {
+		progress(stage, 0, len(jobs))
+	}
+
+	jobCh := make(chan rrdJob, len(jobs))
+	results := make(chan rrdResult, len(jobs))
+	numWorkers := maxConcurrentFetches
+	if len(jobs) < numWorkers {
+		numWorkers = len(jobs)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				node := &hist.Nodes[job.nodeIdx]
+				var points []RRDPoint
+				var err error
+				if job.vmIdx < 0 {
+					points, err = client.GetNodeRRDData(node.Name, metric.Window, "AVERAGE")
+				} else {
+					vm := node.VMs[job.vmIdx]
+					points, err = client.GetVMRRDData(node.Name, vm.Type, vm.VMID, metric.Window, "AVERAGE")
+				}
+				result := rrdResult{rrdJob: job, err: err}
+				if err == nil {
+					result.value, result.ok = metric.statistic(points)
+				}
+				results <- result
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		jobCh <- job
+	}
+	close(jobCh)
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	completed, fetched := 0, 0
+	var firstErr error
+	for result := range results {
+		completed++
+		if progress != nil {
+			progress(stage, completed, len(jobs))
+		}
+
+		node := &hist.Nodes[result.nodeIdx]
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			if result.vmIdx < 0 {
+				log.Printf("CPU history of node %s unavailable: %v", node.Name, result.err)
+			} else {
+				log.Printf("CPU history of VM %d unavailable: %v", node.VMs[result.vmIdx].VMID, result.err)
+			}
+			continue
+		}
+		fetched++
+		if !result.ok {
+			continue
+		}
+		if result.vmIdx < 0 {
+			node.CPUUsage = result.value // Fraction, like /cluster/resources
+		} else {
+			node.VMs[result.vmIdx].CPUUsage = result.value * 100
+		}
+	}
+
+	if fetched == 0 {
+		return nil, fmt.Errorf("failed to fetch CPU history: %w", firstErr)
+	}
+	hist.CPUMetric = metric.String()
+	return &hist, nil
+}
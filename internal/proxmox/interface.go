@@ -1,7 +1,8 @@
 package proxmox
 
 // ProxmoxClient defines the interface for interacting with Proxmox
-// This interface is implemented by both Client (API-based) and ShellClient (pvesh-based)
+// This interface is implemented by Client (API-based), ShellClient (pvesh-based)
+// and FixtureClient (JSON files on disk, for testing)
 type ProxmoxClient interface {
 	// GetClusterResources retrieves all cluster resources
 	GetClusterResources() ([]ClusterResource, error)
@@ -24,6 +25,21 @@ type ProxmoxClient interface {
 	// GetStorageContent retrieves content (volumes) of a storage with actual disk usage
 	GetStorageContent(node, storage string) ([]StorageContentItem, error)
 
+	// GetNodeRRDData retrieves a node's RRD statistics
+	// timeframe is hour, day, week, month or year; cf is AVERAGE or MAX
+	GetNodeRRDData(node, timeframe, cf string) ([]RRDPoint, error)
+
+	// GetVMRRDData retrieves a VM's or container's RRD statistics (vmType selects qemu or lxc)
+	GetVMRRDData(node, vmType string, vmid int, timeframe, cf string) ([]RRDPoint, error)
+
+	// MigrateVM starts migrating a VM or container to the target node and returns the task UPID
+	// vmType selects the qemu or lxc endpoint; running containers use restart mode instead of online.
+	// targetStorage maps local disks to target storages ("src:dst,..."), empty keeps the storage IDs
+	MigrateVM(node, vmType string, vmid int, target string, online bool, targetStorage string) (string, error)
+
+	// GetTaskStatus retrieves the status of a task (e.g. a migration) by UPID
+	GetTaskStatus(node, upid string) (*TaskStatus, error)
+
 	// Ping tests the connection to Proxmox
 	Ping() error
 
@@ -31,6 +47,37 @@ type ProxmoxClient interface {
 	Authenticate() error
 }
 
-// Ensure both client types implement the interface
+// ConfigFileReader is implemented by clients that serve /etc/pve config files
+// themselves instead of having them read from the local cluster filesystem
+// The collection pipeline uses it for VM and node comment metadata
+type ConfigFileReader interface {
+	// ReadConfigFile returns the content of an absolute /etc/pve path
+	// Missing files return an error satisfying os.IsNotExist
+	ReadConfigFile(path string) ([]byte, error)
+}
+
+// ConfigFileWriter is implemented by clients that store /etc/pve config files
+// themselves instead of having them changed through the API
+type ConfigFileWriter interface {
+	// WriteConfigFile replaces the content of an absolute /etc/pve path
+	WriteConfigFile(path string, data []byte) error
+}
+
+// NodeDescriptionWriter is implemented by clients that update the node
+// description (the comment lines of /etc/pve/nodes/{node}/config) through the API
+type NodeDescriptionWriter interface {
+	// GetNodeDescription returns the node description, "" if not set
+	GetNodeDescription(node string) (string, error)
+
+	// SetNodeDescription replaces the node description; "" removes it
+	SetNodeDescription(node, description string) error
+}
+
+// Ensure all client types implement the interface
 var _ ProxmoxClient = (*Client)(nil)
 var _ ProxmoxClient = (*ShellClient)(nil)
+var _ ProxmoxClient = (*FixtureClient)(nil)
+var _ ConfigFileReader = (*FixtureClient)(nil)
+var _ ConfigFileWriter = (*FixtureClient)(nil)
+var _ NodeDescriptionWriter = (*Client)(nil)
+var _ NodeDescriptionWriter = (*ShellClient)(nil)
@@ -89,11 +89,9 @@ func CollectClusterDataWithProgress(client ProxmoxClient, progress ProgressCallb
 	vmList := []VM{}
 	missingStorageCount := 0 // Track VMs with missing storage
 
-	// Track storage per node (aggregated from storage type resources)
-	nodeStorage := make(map[string]struct {
-		maxDisk  int64
-		usedDisk int64
-	})
+	// Track storage pools per node (selected from storage type resources)
+	nodeStorage := make(map[string][]StoragePool)
+	allStorage := make(map[string]StoragePool) // Every storage by node/storage, selected or not
 
 	// Process resources
 	for _, res := range resources {
@@ -115,15 +113,13 @@ func CollectClusterDataWithProgress(client ProxmoxClient, progress ProgressCallb
 			nodeMap[res.Node] = &node
 
 		case "storage":
-			// Only count storage that matches kv*storage* pattern
-			if !strings.HasPrefix(res.Storage, "kv") || !strings.Contains(res.Storage, "storage") {
+			// Only count pools selected by StorageRules (kv*storage* by default)
+			pool := storagePoolFromResource(res)
+			allStorage[res.Node+"/"+pool.Name] = pool
+			if !SelectStorage(pool) {
 				continue
 			}
-			// Aggregate storage from matching storage resources per node
-			storage := nodeStorage[res.Node]
-			storage.maxDisk += res.MaxDisk
-			storage.usedDisk += res.Disk
-			nodeStorage[res.Node] = storage
+			nodeStorage[res.Node] = append(nodeStorage[res.Node], pool)
 
 		case "qemu", "lxc":
 			// Skip templates
@@ -180,24 +176,42 @@ func CollectClusterDataWithProgress(client ProxmoxClient, progress ProgressCallb
 	}
 
 	// Fetch config metadata for all VMs (for nomigrate flag, etc.)
-	fetchVMConfigMeta(vmList, progress)
+	fetchVMConfigMeta(client, vmList, progress)
 
 	// Filter out VMs with empty config files (invalid VMs)
 	vmList = filterVMsWithValidConfig(vmList)
 
+	// Record the storage type of each disk; volumes on shared storage don't move with the VM
+	for i := range vmList {
+		for j := range vmList[i].Disks {
+			disk := &vmList[i].Disks[j]
+			if pool, ok := allStorage[vmList[i].Node+"/"+disk.Storage]; ok {
+				disk.StorageType = pool.Type
+				disk.Shared = pool.Shared
+			}
+		}
+	}
+
 	// Fetch actual disk usage from storage content API (thin provisioning actual size)
 	fetchVMDiskUsageFromStorage(client, vmList, progress)
 
 	// Fetch config metadata for all nodes (for allowProvisioning flag, OSD detection, etc.)
-	fetchNodeConfigMeta(nodeMap, progress)
+	fetchNodeConfigMeta(client, nodeMap, progress)
 
-	// Update node storage with aggregated values from storage resources
-	for nodeName, storage := range nodeStorage {
+	// Update node storage with the selected pools
+	for nodeName, pools := range nodeStorage {
 		if node, exists := nodeMap[nodeName]; exists {
-			// Use storage resource totals if available (more accurate than rootfs only)
-			if storage.maxDisk > 0 {
-				node.MaxDisk = storage.maxDisk
-				node.UsedDisk = storage.usedDisk
+			sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+			var maxDisk, usedDisk int64
+			for _, pool := range pools {
+				maxDisk += pool.Total
+				usedDisk += pool.Used
+			}
+			// Use storage pool totals if available (more accurate than rootfs only)
+			if maxDisk > 0 {
+				node.Storages = pools
+				node.MaxDisk = maxDisk
+				node.UsedDisk = usedDisk
 			}
 		}
 	}
@@ -279,6 +293,8 @@ func CollectClusterDataWithProgress(client ProxmoxClient, progress ProgressCallb
 		return cluster.Nodes[i].Name < cluster.Nodes[j].Name
 	})
 
+	cluster.CollectedAt = time.Now()
+
 	// Log summary of collection
 	if storageLogger != nil {
 		storageLogger.Printf("=== Collection complete: %d nodes, %d VMs (%d running, %d stopped), %d VMs with missing storage ===",
@@ -411,6 +427,10 @@ func GetAvailableTargets(cluster *Cluster, sourceNode string, excludeNodes []str
 		if node.IsMigrationBlocked() {
 			continue
 		}
+		// Skip hosts a what-if simulation decommissions
+		if node.Removed {
+			continue
+		}
 		targets = append(targets, node)
 	}
 
@@ -733,8 +753,18 @@ var diskSizeRegex = regexp.MustCompile(`size=(\d+)([KMGT]?)`)
 // VMConfigResult holds parsed VM config data including metadata and creation time
 type VMConfigResult struct {
 	Meta          map[string]string
-	CreationTime  int64 // Unix timestamp from meta: ctime=
-	TotalDiskSize int64 // Total disk size in bytes (sum of all disks)
+	CreationTime  int64    // Unix timestamp from meta: ctime=
+	TotalDiskSize int64    // Total disk size in bytes (sum of all disks)
+	Disks         []VMDisk // Disks with their storage IDs
+}
+
+// readPVEConfigFile reads a file below /etc/pve, through client if it serves
+// config files itself, otherwise from the local cluster filesystem
+func readPVEConfigFile(client ProxmoxClient, path string) ([]byte, error) {
+	if reader, ok := client.(ConfigFileReader); ok {
+		return reader.ReadConfigFile(path)
+	}
+	return os.ReadFile(path)
 }
 
 // ParseVMConfigMeta reads the VM config file and parses comment metadata, creation time, and disk sizes
@@ -760,6 +790,12 @@ func GetVMConfigContent(node string, vmid int) string {
 // Also parses meta: line for ctime (e.g., meta: creation-qemu=9.2.0,ctime=1767793774)
 // Also sums up all disk sizes from scsi*, ide*, virtio*, sata*, efidisk*, tpmstate* entries
 func ParseVMConfigMeta(node string, vmid int, vmType string) (*VMConfigResult, error) {
+	return ParseVMConfigMetaWithClient(nil, node, vmid, vmType)
+}
+
+// ParseVMConfigMetaWithClient is ParseVMConfigMeta reading the config file through
+// client when it implements ConfigFileReader (e.g. FixtureClient)
+func ParseVMConfigMetaWithClient(client ProxmoxClient, node string, vmid int, vmType string) (*VMConfigResult, error) {
 	result := &VMConfigResult{
 		Meta:          make(map[string]string),
 		CreationTime:  0,
@@ -775,7 +811,7 @@ func ParseVMConfigMeta(node string, vmid int, vmType string) (*VMConfigResult, e
 	}
 
 	// Read the config file
-	content, err := os.ReadFile(configPath)
+	content, err := readPVEConfigFile(client, configPath)
 	if err != nil {
 		// File might not exist or not readable, return empty result
 		return result, nil
@@ -853,6 +889,13 @@ func ParseVMConfigMeta(node string, vmid int, vmType string) (*VMConfigResult, e
 					continue
 				}
 
+				// Volume ID is storage:volume; bind mounts and pass-through devices are paths
+				disk := VMDisk{Key: strings.TrimSpace(parts[0])}
+				volume := strings.SplitN(diskValue, ",", 2)[0]
+				if idx := strings.Index(volume, ":"); idx > 0 && !strings.HasPrefix(volume, "/") {
+					disk.Storage = volume[:idx]
+				}
+
 				// Extract size from the disk specification using regex
 				matches := diskSizeRegex.FindStringSubmatch(diskValue)
 				if len(matches) >= 2 {
@@ -878,6 +921,10 @@ func ParseVMConfigMeta(node string, vmid int, vmType string) (*VMConfigResult, e
 					}
 
 					result.TotalDiskSize += sizeNum * multiplier
+					disk.Size = sizeNum * multiplier
+				}
+				if disk.Storage != "" {
+					result.Disks = append(result.Disks, disk)
 				}
 				break // Found matching prefix, no need to check others
 			}
@@ -895,7 +942,7 @@ type vmConfigMetaResult struct {
 }
 
 // fetchVMConfigMeta fetches config metadata for all VMs in parallel
-func fetchVMConfigMeta(vmList []VM, progress ProgressCallback) {
+func fetchVMConfigMeta(client ProxmoxClient, vmList []VM, progress ProgressCallback) {
 	if len(vmList) == 0 {
 		return
 	}
@@ -925,7 +972,7 @@ func fetchVMConfigMeta(vmList []VM, progress ProgressCallback) {
 			defer wg.Done()
 			for vmIdx := range jobs {
 				vm := vmList[vmIdx]
-				result, err := ParseVMConfigMeta(vm.Node, vm.VMID, vm.Type)
+				result, err := ParseVMConfigMetaWithClient(client, vm.Node, vm.VMID, vm.Type)
 				results <- vmConfigMetaResult{
 					vmIdx:  vmIdx,
 					result: result,
@@ -956,6 +1003,7 @@ func fetchVMConfigMeta(vmList []VM, progress ProgressCallback) {
 		if result.err == nil && result.result != nil {
 			vmList[result.vmIdx].ConfigMeta = result.result.Meta
 			vmList[result.vmIdx].CreationTime = result.result.CreationTime
+			vmList[result.vmIdx].Disks = result.result.Disks
 			// Set total disk size from config file (more accurate than API)
 			if result.result.TotalDiskSize > 0 {
 				vmList[result.vmIdx].MaxDisk = result.result.TotalDiskSize
@@ -996,6 +1044,19 @@ func fetchVMConfigMeta(vmList []VM, progress ProgressCallback) {
 					}
 				}
 			}
+			// group=db-cluster -> VM is a member of affinity group "db-cluster"
+			// groupmode=spread|pack and groupmax=N set the group's placement rule
+			if group, ok := result.result.Meta["group"]; ok {
+				vmList[result.vmIdx].Group = strings.TrimSpace(group)
+			}
+			if groupMode, ok := result.result.Meta["groupmode"]; ok {
+				vmList[result.vmIdx].GroupMode = strings.ToLower(strings.TrimSpace(groupMode))
+			}
+			if groupMax, ok := result.result.Meta["groupmax"]; ok {
+				if n, err := strconv.Atoi(strings.TrimSpace(groupMax)); err == nil && n > 0 {
+					vmList[result.vmIdx].GroupMax = n
+				}
+			}
 		}
 	}
 }
@@ -1004,29 +1065,27 @@ func fetchVMConfigMeta(vmList []VM, progress ProgressCallback) {
 // The config file path is: /etc/pve/nodes/{nodename}/config
 // Comment format: #key1=value1,key2=value2,allowProvisioning=true,...
 func ParseNodeConfigMeta(nodeName string) (map[string]string, error) {
+	return ParseNodeConfigMetaWithClient(nil, nodeName)
+}
+
+// ParseNodeConfigMetaWithClient is ParseNodeConfigMeta reading the config file
+// through client when it implements ConfigFileReader (e.g. FixtureClient)
+func ParseNodeConfigMetaWithClient(client ProxmoxClient, nodeName string) (map[string]string, error) {
 	meta := make(map[string]string)
 
 	// Node config path
 	configPath := fmt.Sprintf("/etc/pve/nodes/%s/config", nodeName)
 
-	// Check if file exists and get its info
-	fileInfo, statErr := os.Stat(configPath)
-	if statErr != nil {
-		if os.IsNotExist(statErr) {
+	// Read the config file
+	content, err := readPVEConfigFile(client, configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
 			log.Printf("Node config file does not exist: %s", configPath)
 		} else {
-			log.Printf("Cannot stat node config file %s: %v", configPath, statErr)
+			log.Printf("Failed to read node config file %s: %v", configPath, err)
 		}
 		return meta, nil
 	}
-	log.Printf("Node config file %s exists, size=%d bytes", configPath, fileInfo.Size())
-
-	// Read the config file
-	content, err := os.ReadFile(configPath)
-	if err != nil {
-		log.Printf("Failed to read node config file %s: %v", configPath, err)
-		return meta, nil
-	}
 	log.Printf("Read node config file %s: %d bytes content", configPath, len(content))
 
 	// Parse each line looking for comment lines with metadata
@@ -1064,6 +1123,95 @@ func ParseNodeConfigMeta(nodeName string) (map[string]string, error) {
 	return meta, nil
 }
 
+// SetNodeHostState sets hoststate=N in the node config comment, or removes the
+// key for a negative state. Other keys and lines are kept; without a metadata
+// comment line one is added at the top. Returns the previous state (-1 = not set).
+// The comment is the node description, updated through the API; clients that
+// store /etc/pve files themselves (fixtures) have the config file rewritten.
+func SetNodeHostState(client ProxmoxClient, nodeName string, state int) (int, error) {
+	if writer, ok := client.(NodeDescriptionWriter); ok {
+		description, err := writer.GetNodeDescription(nodeName)
+		if err != nil {
+			return -1, fmt.Errorf("failed to read node %s description: %w", nodeName, err)
+		}
+		// The description holds the comment lines of the config file without their '#'
+		var lines []string
+		if description != "" {
+			for _, line := range strings.Split(description, "\n") {
+				lines = append(lines, "#"+line)
+			}
+		}
+		lines, previous := setHostStateLines(lines, state)
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(line, "#")
+		}
+		if err := writer.SetNodeDescription(nodeName, strings.Join(lines, "\n")); err != nil {
+			return previous, fmt.Errorf("failed to update node %s description: %w", nodeName, err)
+		}
+		log.Printf("Node %s: hoststate %d -> %d", nodeName, previous, state)
+		return previous, nil
+	}
+
+	writer, ok := client.(ConfigFileWriter)
+	if !ok {
+		return -1, fmt.Errorf("setting hoststate is not supported by this client")
+	}
+	configPath := fmt.Sprintf("/etc/pve/nodes/%s/config", nodeName)
+	content, err := readPVEConfigFile(client, configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return -1, fmt.Errorf("failed to read node config %s: %w", configPath, err)
+	}
+	lines, previous := setHostStateLines(strings.Split(string(content), "\n"), state)
+	if err := writer.WriteConfigFile(configPath, []byte(strings.Join(lines, "\n"))); err != nil {
+		return previous, fmt.Errorf("failed to write node config %s: %w", configPath, err)
+	}
+	log.Printf("Node %s: hoststate %d -> %d", nodeName, previous, state)
+	return previous, nil
+}
+
+// setHostStateLines sets hoststate in the metadata comment of node config lines
+// and returns the new lines and the previous state (-1 = not set)
+func setHostStateLines(lines []string, state int) ([]string, int) {
+	previous := -1
+	found := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") || !strings.Contains(trimmed, "=") {
+			continue
+		}
+		// Same metadata line as ParseNodeConfigMeta reads
+		var pairs []string
+		for _, pair := range strings.Split(strings.TrimPrefix(trimmed, "#"), ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(strings.ToLower(kv[0])) == "hoststate" {
+				if n, err := strconv.Atoi(strings.TrimSpace(kv[1])); err == nil {
+					previous = n
+				}
+				if state < 0 || found {
+					continue
+				}
+				pair = fmt.Sprintf("hoststate=%d", state)
+				found = true
+			}
+			pairs = append(pairs, pair)
+		}
+		if len(pairs) == 0 {
+			lines = append(lines[:i], lines[i+1:]...)
+		} else {
+			if !found && state >= 0 {
+				pairs = append(pairs, fmt.Sprintf("hoststate=%d", state))
+				found = true
+			}
+			lines[i] = "#" + strings.Join(pairs, ",")
+		}
+		break
+	}
+	if !found && state >= 0 {
+		lines = append([]string{fmt.Sprintf("#hoststate=%d", state)}, lines...)
+	}
+	return lines, previous
+}
+
 // CheckNodeHasOSD checks if a node has any VMs with names starting with "osd" and containing "cloudwm.com"
 // Examples: osd050.vsan001.il.cloudwm.com, osd001.cloudwm.com
 func CheckNodeHasOSD(vms []VM) bool {
@@ -1079,7 +1227,7 @@ func CheckNodeHasOSD(vms []VM) bool {
 // fetchNodeConfigMeta fetches config metadata for all nodes
 // Note: This should be called BEFORE VMs are assigned to nodes
 // The OSD check should be done separately after VMs are assigned
-func fetchNodeConfigMeta(nodeMap map[string]*Node, progress ProgressCallback) {
+func fetchNodeConfigMeta(client ProxmoxClient, nodeMap map[string]*Node, progress ProgressCallback) {
 	if len(nodeMap) == 0 {
 		return
 	}
@@ -1098,7 +1246,7 @@ func fetchNodeConfigMeta(nodeMap map[string]*Node, progress ProgressCallback) {
 		}
 
 		// Parse node config
-		meta, err := ParseNodeConfigMeta(nodeName)
+		meta, err := ParseNodeConfigMetaWithClient(client, nodeName)
 		if err == nil && meta != nil {
 			node.ConfigMeta = meta
 			// Check for hostprovision flag
@@ -1130,16 +1278,17 @@ func updateNodeOSDStatus(nodeMap map[string]*Node) {
 }
 
 // RecentlyCreatedThresholdDays is the number of days to consider a VM as "recently created"
-const RecentlyCreatedThresholdDays = 90
+// Set from the config file before collecting
+var RecentlyCreatedThresholdDays = 90
 
-// updateNodeOldVMsStatus checks if P-flagged nodes have VMs older than 90 days
+// updateNodeOldVMsStatus checks if P-flagged nodes have VMs older than RecentlyCreatedThresholdDays
 // This indicates hosts with old VMs that may need migration
 // This must be called AFTER VMs are assigned to nodes
 func updateNodeOldVMsStatus(nodeMap map[string]*Node) {
-	// Calculate the threshold timestamp (90 days ago)
+	// Calculate the threshold timestamp (90 days ago by default)
 	// VMs created BEFORE this time are considered OLD
 	now := time.Now()
-	thresholdTime := now.Unix() - (RecentlyCreatedThresholdDays * 24 * 60 * 60)
+	thresholdTime := now.Unix() - int64(RecentlyCreatedThresholdDays)*24*60*60
 	thresholdDate := time.Unix(thresholdTime, 0).Format("2006-01-02")
 
 	for nodeName, node := range nodeMap {
@@ -1267,9 +1416,14 @@ func fetchVMDiskUsageFromStorage(client ProxmoxClient, vmList []VM, progress Pro
 	}
 
 	// Initialize cache
-	cache, cacheErr := GetDiskCache()
-	if cacheErr != nil {
-		log.Printf("Warning: disk cache unavailable: %v - will query all storage", cacheErr)
+	// Fixture data must neither be shadowed by nor pollute a real cluster's cache
+	var cache *DiskCache
+	if _, isFixture := client.(*FixtureClient); !isFixture {
+		var cacheErr error
+		cache, cacheErr = GetDiskCache()
+		if cacheErr != nil {
+			log.Printf("Warning: disk cache unavailable: %v - will query all storage", cacheErr)
+		}
 	}
 
 	// Check cache for valid entries
@@ -1387,13 +1541,6 @@ func fetchVMDiskUsageFromStorage(client ProxmoxClient, vmList []VM, progress Pro
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			// Extract node prefix for matching local storages
-			// Node name format: kv0078-63-250-62-88 -> prefix is "kv0078"
-			nodePrefix := node
-			if idx := strings.Index(node, "-"); idx > 0 {
-				nodePrefix = node[:idx]
-			}
-
 			// Get list of storages for this node
 			storages, err := client.GetNodeStorages(node)
 			if err != nil {
@@ -1405,9 +1552,10 @@ func fetchVMDiskUsageFromStorage(client ProxmoxClient, vmList []VM, progress Pro
 
 			// Process each storage that can hold VM images
 			for _, storage := range storages {
-				// Only query LOCAL storages (storage name starts with node prefix)
-				// This avoids querying shared/remote storages on wrong nodes
-				if !strings.HasPrefix(storage.Storage, nodePrefix) {
+				// Only query active LOCAL storages selected by StorageRules
+				// Shared storages list the volumes of every node and would be counted twice
+				pool := storagePoolFromInfo(storage)
+				if pool.Shared || storage.Active == 0 || !SelectStorage(pool) {
 					continue
 				}
 
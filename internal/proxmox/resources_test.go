@@ -0,0 +1,40 @@
+package proxmox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetHostStateLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string // Lines separated by "|"
+		state    int
+		want     string
+		previous int
+	}{
+		{"added to a config without metadata", "# rack 4|cpu: host", 2, "#hoststate=2|# rack 4|cpu: host", -1},
+		{"added to other metadata", "#nomigrate=false|cpu: host", 2, "#nomigrate=false,hoststate=2|cpu: host", -1},
+		{"replaced", "#hoststate=1,owner=ops", 3, "#hoststate=3,owner=ops", 1},
+		{"key is case insensitive", "# HostState = 1", 0, "#hoststate=0", 1},
+		{"duplicates collapsed", "#hoststate=1,hoststate=3", 2, "#hoststate=2", 3},
+		{"unparsable value", "#hoststate=maybe", 1, "#hoststate=1", -1},
+		{"only the first metadata line", "#owner=ops|#hoststate=1", 2, "#owner=ops,hoststate=2|#hoststate=1", -1},
+		{"cleared with other metadata", "#owner=ops,hoststate=1|cpu: host", -1, "#owner=ops|cpu: host", 1},
+		{"cleared line removed", "#hoststate=3|cpu: host", -1, "cpu: host", 3},
+		{"cleared when not set", "#owner=ops", -1, "#owner=ops", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, previous := setHostStateLines(strings.Split(tt.config, "|"), tt.state)
+			if want := strings.Split(tt.want, "|"); !reflect.DeepEqual(got, want) {
+				t.Errorf("lines %q, want %q", got, want)
+			}
+			if previous != tt.previous {
+				t.Errorf("previous state %d, want %d", previous, tt.previous)
+			}
+		})
+	}
+}
@@ -29,8 +29,10 @@ func IsAvailable() bool {
 }
 
 // pvesh executes a pvesh command and returns the JSON output
+// args start with the verb: "get" for reads, "create" for POST calls (e.g. migrate)
 func (c *ShellClient) pvesh(args ...string) ([]byte, error) {
 	// pvesh get /api2/json/path --output-format json
+	// pvesh create /api2/json/path --param value --output-format json
 	fullArgs := append(args, "--output-format", "json")
 	cmd := exec.Command("pvesh", fullArgs...)
 
@@ -191,6 +193,35 @@ func (c *ShellClient) GetVMConfig(node string, vmid int) (map[string]interface{}
 	return config, nil
 }
 
+// GetNodeDescription retrieves the description (config comment) of a node
+func (c *ShellClient) GetNodeDescription(node string) (string, error) {
+	path := fmt.Sprintf("/nodes/%s/config", node)
+	output, err := c.pvesh("get", path)
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(output, &config); err != nil {
+		return "", fmt.Errorf("failed to unmarshal node config: %w", err)
+	}
+
+	return config.Description, nil
+}
+
+// SetNodeDescription replaces the description (config comment) of a node
+func (c *ShellClient) SetNodeDescription(node, description string) error {
+	path := fmt.Sprintf("/nodes/%s/config", node)
+	args := []string{"set", path, "--description", description}
+	if description == "" {
+		args = []string{"set", path, "--delete", "description"}
+	}
+	_, err := c.pvesh(args...)
+	return err
+}
+
 // GetNodes retrieves a list of all nodes in the cluster
 func (c *ShellClient) GetNodes() ([]string, error) {
 	output, err := c.pvesh("get", "/nodes")
@@ -256,6 +287,87 @@ func (c *ShellClient) GetStorageContent(node, storage string) ([]StorageContentI
 	return content, nil
 }
 
+// GetNodeRRDData retrieves a node's RRD statistics using pvesh
+func (c *ShellClient) GetNodeRRDData(node, timeframe, cf string) ([]RRDPoint, error) {
+	return c.getRRDData(fmt.Sprintf("/nodes/%s/rrddata", node), timeframe, cf)
+}
+
+// GetVMRRDData retrieves a VM's or container's RRD statistics using pvesh
+func (c *ShellClient) GetVMRRDData(node, vmType string, vmid int, timeframe, cf string) ([]RRDPoint, error) {
+	return c.getRRDData(fmt.Sprintf("/nodes/%s/%s/%d/rrddata", node, GuestType(vmType), vmid), timeframe, cf)
+}
+
+// getRRDData fetches an rrddata endpoint
+func (c *ShellClient) getRRDData(path, timeframe, cf string) ([]RRDPoint, error) {
+	output, err := c.pvesh("get", path, "--timeframe", timeframe, "--cf", cf)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []RRDPoint
+	if err := json.Unmarshal(output, &points); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rrd data: %w", err)
+	}
+
+	return points, nil
+}
+
+// MigrateVM starts a migration of a VM to the target node and returns the task UPID
+// Running VMs are live-migrated when online is true; containers use restart mode instead
+func (c *ShellClient) MigrateVM(node, vmType string, vmid int, target string, online bool, targetStorage string) (string, error) {
+	guestType := GuestType(vmType)
+	path := fmt.Sprintf("/nodes/%s/%s/%d/migrate", node, guestType, vmid)
+	args := []string{"create", path, "--target", target}
+	if online {
+		if guestType == "lxc" {
+			args = append(args, "--restart", "1")
+		} else {
+			args = append(args, "--online", "1")
+		}
+	}
+	if targetStorage != "" {
+		if guestType == "lxc" {
+			args = append(args, "--target-storage", targetStorage)
+		} else {
+			args = append(args, "--targetstorage", targetStorage)
+			if online {
+				args = append(args, "--with-local-disks", "1")
+			}
+		}
+	}
+
+	output, err := c.pvesh(args...)
+	if err != nil {
+		return "", err
+	}
+
+	var upid string
+	if err := json.Unmarshal(output, &upid); err != nil {
+		return "", fmt.Errorf("failed to unmarshal migration task ID: %w", err)
+	}
+	if upid == "" {
+		return "", fmt.Errorf("migration request returned no task ID")
+	}
+
+	return upid, nil
+}
+
+// GetTaskStatus retrieves the status of a task by UPID
+func (c *ShellClient) GetTaskStatus(node, upid string) (*TaskStatus, error) {
+	path := fmt.Sprintf("/nodes/%s/tasks/%s/status", node, upid)
+	output, err := c.pvesh("get", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var status TaskStatus
+	if err := json.Unmarshal(output, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task status: %w", err)
+	}
+
+	return &status, nil
+}
+
 // GetHostname returns the current Proxmox host's hostname
 func GetHostname() (string, error) {
 	cmd := exec.Command("hostname")
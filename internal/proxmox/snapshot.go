@@ -0,0 +1,110 @@
+package proxmox
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion is the version of the cluster snapshot file format
+// Bump it whenever a Cluster, Node or VM field is renamed or removed
+const SnapshotVersion = 1
+
+// Snapshot is an offline recording of collected cluster data
+// It holds everything the analyzer uses, including VM ConfigMeta, placement
+// constraints (hostcpumodel, withvm, without) and node HostState
+type Snapshot struct {
+	SnapshotVersion int       `json:"snapshot_version"`
+	RecordedAt      time.Time `json:"recorded_at"`
+	MigsugVersion   string    `json:"migsug_version"`
+	Cluster         *Cluster  `json:"cluster"`
+}
+
+// NewSnapshot wraps a collected cluster in a snapshot
+func NewSnapshot(cluster *Cluster, version string) *Snapshot {
+	return &Snapshot{
+		SnapshotVersion: SnapshotVersion,
+		RecordedAt:      time.Now(),
+		MigsugVersion:   version,
+		Cluster:         cluster,
+	}
+}
+
+// WriteSnapshot serializes a snapshot as indented JSON to w
+func WriteSnapshot(w io.Writer, snap *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		return fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot parses a snapshot from r and validates its version
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var snap Snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	if snap.SnapshotVersion == 0 {
+		return nil, fmt.Errorf("not a migsug snapshot (missing snapshot_version)")
+	}
+	if snap.SnapshotVersion > SnapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is newer than supported version %d", snap.SnapshotVersion, SnapshotVersion)
+	}
+	if snap.Cluster == nil {
+		return nil, fmt.Errorf("snapshot contains no cluster data")
+	}
+	return &snap, nil
+}
+
+// SaveSnapshot records a cluster to path
+// Paths ending in .gz are gzip-compressed
+func SaveSnapshot(path string, cluster *Cluster, version string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer f.Close()
+
+	snap := NewSnapshot(cluster, version)
+	if !strings.HasSuffix(path, ".gz") {
+		return WriteSnapshot(f, snap)
+	}
+
+	gz := gzip.NewWriter(f)
+	if err := WriteSnapshot(gz, snap); err != nil {
+		gz.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot recorded by SaveSnapshot
+// Gzip-compressed files are detected by their .gz extension
+func LoadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return ReadSnapshot(r)
+}
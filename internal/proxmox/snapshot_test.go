@@ -0,0 +1,159 @@
+package proxmox
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// snapshotTestCluster has a value in every field the analyzer reads,
+// including config metadata, placement constraints and the host state
+func snapshotTestCluster() *Cluster {
+	const gb = int64(1024 * 1024 * 1024)
+	return &Cluster{
+		Nodes: []Node{
+			{
+				Name:        "pve1",
+				Status:      "online",
+				CPUCores:    64,
+				CPUSockets:  2,
+				CPUModel:    "AMD EPYC 7763 64-Core Processor",
+				CPUMHz:      2450,
+				CPUUsage:    0.42,
+				LoadAverage: []float64{12.5, 10.1, 9.8},
+				MaxMem:      512 * gb,
+				UsedMem:     300 * gb,
+				MaxDisk:     8192 * gb,
+				UsedDisk:    2048 * gb,
+				Uptime:      86400,
+				PVEVersion:  "pve-manager/8.2.4",
+				Storages: []StoragePool{
+					{Name: "ceph-vm", Type: "rbd", Shared: true, Content: "images", Total: 100000 * gb, Used: 20000 * gb},
+					{Name: "kv0001storage", Type: "lvmthin", Content: "images,rootdir", Total: 8192 * gb, Used: 2048 * gb},
+				},
+				AllowProvisioning: true,
+				HostState:         2,
+				ConfigMeta:        map[string]string{"hostprovision": "true", "hoststate": "2", "maxstorage": "92"},
+				VMs: []VM{
+					{
+						VMID: 100, Name: "db1", Node: "pve1", Status: "running", Type: "qemu",
+						CPUCores: 8, CPUUsage: 37.5, MaxMem: 32 * gb, UsedMem: 20 * gb, MaxDisk: 200 * gb, UsedDisk: 90 * gb, Uptime: 3600,
+						ConfigMeta:   map[string]string{"ctime": "1700000000", "hostcpumodel": "EPYC", "without": "db2", "group": "db"},
+						CreationTime: 1700000000,
+						HostCPUModel: "EPYC",
+						WithoutVM:    []string{"db2"},
+						Group:        "db",
+						GroupMode:    "spread",
+						GroupMax:     1,
+						Disks: []VMDisk{
+							{Key: "scsi0", Storage: "kv0001storage", StorageType: "lvmthin", Size: 200 * gb},
+							{Key: "scsi1", Storage: "ceph-vm", StorageType: "rbd", Size: 500 * gb, Shared: true},
+						},
+					},
+					{
+						VMID: 101, Name: "ct1", Node: "pve1", Status: "stopped", Type: "lxc",
+						CPUCores: 2, MaxMem: 4 * gb, MaxDisk: 16 * gb, UsedDisk: 3 * gb,
+						NoMigrate:  true,
+						ConfigMeta: map[string]string{"nomigrate": "true"},
+						WithVM:     []string{"db1"},
+						Disks:      []VMDisk{{Key: "rootfs", Storage: "kv0001storage", StorageType: "lvmthin", Size: 16 * gb}},
+					},
+				},
+			},
+			{
+				Name:      "pve2",
+				Status:    "offline",
+				HostState: -1,
+			},
+		},
+		TotalVMs:     2,
+		TotalVCPUs:   10,
+		RunningVMs:   1,
+		StoppedVMs:   1,
+		TotalCPUs:    64,
+		TotalRAM:     512 * gb,
+		TotalStorage: 8192 * gb,
+		UsedStorage:  2048 * gb,
+		CollectedAt:  time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC),
+		CPUMetric:    "avg-day",
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		cluster *Cluster
+	}{
+		{"json", "cluster.json", snapshotTestCluster()},
+		{"gzip", "cluster.json.gz", snapshotTestCluster()},
+		{"empty cluster", "empty.json", &Cluster{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := SaveSnapshot(path, tt.cluster, "test"); err != nil {
+				t.Fatalf("SaveSnapshot: %v", err)
+			}
+			snap, err := LoadSnapshot(path)
+			if err != nil {
+				t.Fatalf("LoadSnapshot: %v", err)
+			}
+			if snap.SnapshotVersion != SnapshotVersion || snap.MigsugVersion != "test" {
+				t.Errorf("snapshot version %d from migsug %q, want %d from %q", snap.SnapshotVersion, snap.MigsugVersion, SnapshotVersion, "test")
+			}
+			if !reflect.DeepEqual(snap.Cluster, tt.cluster) {
+				t.Errorf("loaded cluster differs from the saved one:\n got %+v\nwant %+v", snap.Cluster, tt.cluster)
+			}
+		})
+	}
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "cluster"},
+		{"no version", `{"cluster": {}}`},
+		{"newer version", fmt.Sprintf(`{"snapshot_version": %d, "cluster": {}}`, SnapshotVersion+1)},
+		{"no cluster", fmt.Sprintf(`{"snapshot_version": %d}`, SnapshotVersion)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadSnapshot(bytes.NewBufferString(tt.data)); err == nil {
+				t.Errorf("ReadSnapshot accepted %s", tt.data)
+			}
+		})
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadSnapshot(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadSnapshot accepted a missing file")
+	}
+
+	// A plain JSON snapshot saved under a .gz name is not gzip data
+	plain := filepath.Join(dir, "plain.json")
+	if err := SaveSnapshot(plain, snapshotTestCluster(), "test"); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := filepath.Join(dir, "plain.json.gz")
+	if err := os.WriteFile(fake, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(fake); err == nil {
+		t.Error("LoadSnapshot accepted uncompressed data in a .gz file")
+	}
+}
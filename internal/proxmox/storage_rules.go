@@ -0,0 +1,119 @@
+package proxmox
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// StorageRule selects storage pools by name, type, shared flag and content
+// Empty fields match any pool; Exclude drops matching pools instead of counting them
+type StorageRule struct {
+	Name    string `yaml:"name,omitempty" json:"name,omitempty"`       // filepath.Match glob on the storage ID
+	Type    string `yaml:"type,omitempty" json:"type,omitempty"`       // Storage plugin type (dir, lvmthin, zfspool, rbd, ...)
+	Shared  *bool  `yaml:"shared,omitempty" json:"shared,omitempty"`   // Match only shared (true) or local (false) pools
+	Content string `yaml:"content,omitempty" json:"content,omitempty"` // Content type the pool must allow (images, rootdir, ...)
+	Exclude bool   `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// DefaultStorageRules counts the kv*storage* pools, the naming used by our clusters
+var DefaultStorageRules = []StorageRule{{Name: "kv*storage*"}}
+
+// StorageRules selects the storage pools counted as node storage
+// The first matching rule decides; pools matching no rule are not counted.
+// Set from the config file before collecting
+var StorageRules = DefaultStorageRules
+
+// Matches returns true if the pool satisfies every field set in the rule
+func (r StorageRule) Matches(pool StoragePool) bool {
+	if r.Name != "" {
+		if ok, _ := filepath.Match(r.Name, pool.Name); !ok {
+			return false
+		}
+	}
+	if r.Type != "" && r.Type != pool.Type {
+		return false
+	}
+	if r.Shared != nil && *r.Shared != pool.Shared {
+		return false
+	}
+	if r.Content != "" && !pool.HasContent(r.Content) {
+		return false
+	}
+	return true
+}
+
+// Validate checks the rule's name glob
+func (r StorageRule) Validate() error {
+	if _, err := filepath.Match(r.Name, ""); err != nil {
+		return fmt.Errorf("bad name pattern %q", r.Name)
+	}
+	return nil
+}
+
+// String describes the rule, e.g. "include name=kv*storage* type=dir"
+func (r StorageRule) String() string {
+	parts := []string{"include"}
+	if r.Exclude {
+		parts[0] = "exclude"
+	}
+	if r.Name != "" {
+		parts = append(parts, "name="+r.Name)
+	}
+	if r.Type != "" {
+		parts = append(parts, "type="+r.Type)
+	}
+	if r.Shared != nil {
+		parts = append(parts, fmt.Sprintf("shared=%t", *r.Shared))
+	}
+	if r.Content != "" {
+		parts = append(parts, "content="+r.Content)
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "all")
+	}
+	return strings.Join(parts, " ")
+}
+
+// SelectStorage returns true if the pool is counted as node storage under StorageRules
+// An empty rule list counts every pool
+func SelectStorage(pool StoragePool) bool {
+	if len(StorageRules) == 0 {
+		return true
+	}
+	for _, rule := range StorageRules {
+		if rule.Matches(pool) {
+			return !rule.Exclude
+		}
+	}
+	return false
+}
+
+// sharedStorageTypes are storage types that are shared even without the shared flag
+var sharedStorageTypes = map[string]bool{
+	"rbd": true, "cephfs": true, "nfs": true, "cifs": true, "glusterfs": true,
+}
+
+// storagePoolFromResource builds a pool from a /cluster/resources storage entry
+func storagePoolFromResource(res ClusterResource) StoragePool {
+	return StoragePool{
+		Name:    res.Storage,
+		Type:    res.PluginType,
+		Shared:  res.Shared == 1 || sharedStorageTypes[res.PluginType],
+		Content: res.Content,
+		Total:   res.MaxDisk,
+		Used:    res.Disk,
+	}
+}
+
+// storagePoolFromInfo builds a pool from a /nodes/{node}/storage entry
+func storagePoolFromInfo(info StorageInfo) StoragePool {
+	return StoragePool{
+		Name:    info.Storage,
+		Type:    info.Type,
+		Shared:  info.Shared == 1 || sharedStorageTypes[info.Type],
+		Content: info.Content,
+		Total:   info.Total,
+		Used:    info.Used,
+	}
+}
@@ -1,6 +1,10 @@
 package proxmox
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Node represents a Proxmox node in the cluster
 type Node struct {
@@ -10,24 +14,31 @@ type Node struct {
 	CPUSockets  int       // Physical CPU sockets
 	CPUModel    string    // CPU model name
 	CPUMHz      float64   // CPU frequency in MHz
-	CPUUsage    float64   // Percentage 0-100
+	CPUUsage    float64   // Fraction 0-1, like /cluster/resources (VM.CPUUsage is a percentage)
 	LoadAverage []float64 // 1, 5, 15 minute load averages
 	MaxMem      int64     // bytes
 	UsedMem     int64     // bytes
-	MaxDisk     int64     // bytes
-	UsedDisk    int64     // bytes
+	MaxDisk     int64     // bytes - sum of the counted storage pools
+	UsedDisk    int64     // bytes - sum of the counted storage pools
 	SwapTotal   int64     // bytes - total swap configured
 	SwapUsed    int64     // bytes - swap currently in use
 	VMs         []VM
 	Uptime      int64  // seconds
 	PVEVersion  string // Proxmox VE version
 
+	// Storage pools selected by StorageRules, sorted by name
+	Storages []StoragePool
+
 	// Node status indicators (parsed from config and VMs)
 	HasOSD            bool              // True if node has VMs with name matching osd*.cloudwm.com
 	AllowProvisioning bool              // True if node config has hostprovision=true
 	HasOldVMs         bool              // True if node has P flag and VMs older than 90 days (C flag)
 	HostState         int               // Host state from config (0-3). -1 means not set. 0=maintenance, 3=blocked (no migrations)
 	ConfigMeta        map[string]string // All key=value pairs from node config comment line
+
+	// What-if simulation markers (see analyzer.WhatIf); never set on collected data
+	Simulated bool // Hypothetical node that doesn't exist
+	Removed   bool // Node assumed decommissioned: receives no VMs, its VMs move away
 }
 
 // IsMigrationBlocked returns true if the host state blocks migrations
@@ -70,7 +81,11 @@ func (n *Node) GetStatusIndicators() string {
 func (n *Node) GetStatusWithIndicators() string {
 	// Build status with hoststate (if set, i.e. >= 0)
 	status := n.Status
-	if n.HasHostState() {
+	if n.Simulated {
+		status = "simulated"
+	} else if n.Removed {
+		status = "removed"
+	} else if n.HasHostState() {
 		if n.HostState == 1 {
 			// Maintenance mode - show "maint" instead of "online/1"
 			status = "maint"
@@ -111,6 +126,21 @@ type VM struct {
 	HostCPUModel string   // Required CPU model substring (from hostcpumodel=value) - VM can only run on hosts with this in CPU model
 	WithVM       []string // VM names that must be on the same host (from withvm=name1,name2)
 	WithoutVM    []string // VM names that must NOT be on the same host (from without=name1,name2)
+	Group        string   // Affinity group name (from group=name)
+	GroupMode    string   // Affinity group mode, spread or pack (from groupmode=value)
+	GroupMax     int      // Members of the group allowed per host in spread mode (from groupmax=N)
+
+	// Disks parsed from the config file, one entry per volume
+	Disks []VMDisk
+}
+
+// VMDisk is one disk or container volume of a VM
+type VMDisk struct {
+	Key         string // Config key (scsi0, virtio1, rootfs, mp0, ...)
+	Storage     string // Storage ID the volume lives on
+	StorageType string // Storage plugin type of that storage (dir, lvmthin, ...)
+	Size        int64  // Allocated size in bytes
+	Shared      bool   // Storage is shared between nodes, the volume stays in place on migration
 }
 
 // Cluster represents the entire Proxmox cluster
@@ -124,6 +154,11 @@ type Cluster struct {
 	TotalRAM     int64 // Total RAM across all nodes
 	TotalStorage int64 // Total storage across all nodes
 	UsedStorage  int64 // Used storage across all nodes
+
+	CollectedAt time.Time // When this data was collected from Proxmox
+
+	// CPU statistic the CPUUsage values hold (see CollectCPUHistory); "" = current usage
+	CPUMetric string
 }
 
 // ClusterResource represents a resource from the Proxmox cluster/resources API
@@ -143,6 +178,11 @@ type ClusterResource struct {
 	Disk     int64   `json:"disk,omitempty"`
 	Uptime   int64   `json:"uptime,omitempty"`
 	Template int     `json:"template,omitempty"`
+
+	// Storage resources only
+	PluginType string `json:"plugintype,omitempty"` // Storage type (dir, lvmthin, rbd, ...)
+	Shared     int    `json:"shared,omitempty"`     // 1 if the storage is shared between nodes
+	Content    string `json:"content,omitempty"`    // Allowed content types, comma separated
 }
 
 // NodeStatus represents detailed node status
@@ -219,6 +259,31 @@ type StorageInfo struct {
 	Shared  int    `json:"shared"`
 }
 
+// StoragePool is one storage counted towards a node's storage capacity
+type StoragePool struct {
+	Name    string
+	Type    string // Storage plugin type (dir, lvmthin, zfspool, rbd, ...)
+	Shared  bool   // Available on several nodes
+	Content string // Allowed content types, comma separated
+	Total   int64  // bytes
+	Used    int64  // bytes
+}
+
+// Free returns the unused bytes of the pool
+func (p StoragePool) Free() int64 {
+	return p.Total - p.Used
+}
+
+// HasContent returns true if the pool allows the given content type
+func (p StoragePool) HasContent(content string) bool {
+	for _, c := range strings.Split(p.Content, ",") {
+		if strings.TrimSpace(c) == content {
+			return true
+		}
+	}
+	return false
+}
+
 // StorageContentItem represents a volume in storage content
 // Used to get actual disk usage for thin-provisioned VMs
 type StorageContentItem struct {
@@ -230,6 +295,54 @@ type StorageContentItem struct {
 	VolID   string `json:"volid"`   // Volume ID (e.g., "storage:vmid/vm-vmid-disk-0.qcow2")
 }
 
+// RRDPoint is one sample of a node's or VM's RRD statistics (from .../rrddata)
+// Gaps in the data come without values, so CPU is nil for them
+type RRDPoint struct {
+	Time   int64    `json:"time"`             // Unix timestamp of the end of the interval
+	CPU    *float64 `json:"cpu,omitempty"`    // CPU usage as a fraction 0-1 of the node's CPUs or the VM's vCPUs
+	MaxCPU float64  `json:"maxcpu,omitempty"` // CPUs of the node or vCPUs of the VM
+}
+
+// TaskStatus represents the status of a Proxmox task (from /nodes/{node}/tasks/{upid}/status)
+type TaskStatus struct {
+	UPID       string `json:"upid"`
+	Node       string `json:"node"`
+	Type       string `json:"type"`       // Task type, e.g. "qmigrate"
+	ID         string `json:"id"`         // Task object ID (VMID for migrations)
+	Status     string `json:"status"`     // "running" or "stopped"
+	ExitStatus string `json:"exitstatus"` // "OK" on success, error message otherwise (only when stopped)
+	StartTime  int64  `json:"starttime"`
+}
+
+// IsRunning returns true if the task has not finished yet
+func (t *TaskStatus) IsRunning() bool {
+	return t.Status == "running"
+}
+
+// Succeeded returns true if the task finished successfully
+func (t *TaskStatus) Succeeded() bool {
+	return t.Status == "stopped" && t.ExitStatus == "OK"
+}
+
+// ParseUPIDNode returns the node name embedded in a task UPID
+// UPID format: UPID:{node}:{pid}:{pstart}:{starttime}:{type}:{id}:{user}:
+func ParseUPIDNode(upid string) string {
+	parts := strings.Split(upid, ":")
+	if len(parts) < 2 || parts[0] != "UPID" {
+		return ""
+	}
+	return parts[1]
+}
+
+// GuestType returns the API path segment for a VM type ("qemu" or "lxc")
+// Unknown or empty types are treated as qemu
+func GuestType(vmType string) string {
+	if vmType == "lxc" {
+		return "lxc"
+	}
+	return "qemu"
+}
+
 // GetCPUPercent returns CPU usage as a percentage
 func (n *Node) GetCPUPercent() float64 {
 	return n.CPUUsage * 100
@@ -287,6 +400,51 @@ func (v *VM) GetEffectiveDisk() int64 {
 	return v.MaxDisk
 }
 
+// GetDiskBytes estimates the bytes one disk occupies
+// Local disks are scaled by the thin provisioning ratio of the VM's local disks;
+// UsedDisk is read from local storages only. Shared disks count their allocated size.
+func (v *VM) GetDiskBytes(disk VMDisk) int64 {
+	if disk.Shared {
+		return disk.Size
+	}
+	var allocated int64
+	for _, d := range v.Disks {
+		if !d.Shared {
+			allocated += d.Size
+		}
+	}
+	if v.UsedDisk > 0 && v.UsedDisk < allocated {
+		return int64(float64(disk.Size) * float64(v.UsedDisk) / float64(allocated))
+	}
+	return disk.Size
+}
+
+// GetLocalDisk returns the disk usage on node-local storage
+// Disks on shared storage don't use the node's storage and aren't copied on migration.
+// Falls back to GetEffectiveDisk when the VM's disks are unknown.
+func (v *VM) GetLocalDisk() int64 {
+	if len(v.Disks) == 0 {
+		return v.GetEffectiveDisk()
+	}
+	var local int64
+	for _, disk := range v.Disks {
+		if !disk.Shared {
+			local += v.GetDiskBytes(disk)
+		}
+	}
+	return local
+}
+
+// HasSharedDisks returns true if at least one disk is on shared storage
+func (v *VM) HasSharedDisks() bool {
+	for _, disk := range v.Disks {
+		if disk.Shared {
+			return true
+		}
+	}
+	return false
+}
+
 // FormatBytes converts bytes to human-readable format
 func FormatBytes(bytes int64) string {
 	const unit = 1024
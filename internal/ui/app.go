@@ -9,12 +9,15 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/executor"
+	"github.com/yourusername/migsug/internal/export"
+	"github.com/yourusername/migsug/internal/history"
 	"github.com/yourusername/migsug/internal/proxmox"
 	"github.com/yourusername/migsug/internal/ui/components"
 	"github.com/yourusername/migsug/internal/ui/views"
 )
 
-const refreshInterval = 180 // seconds
+const defaultRefreshInterval = 180 // seconds
 
 // ViewType represents the current view
 type ViewType int
@@ -29,6 +32,10 @@ const (
 	ViewHostDetail // Shows VMs added/removed on a specific host
 	ViewError
 	ViewHelp
+	ViewExecuteConfirm  // Confirmation screen before executing a plan
+	ViewExecution       // Live progress of executing migrations
+	ViewClusterOverview // Summary of all cluster profiles, switches the active cluster
+	ViewTrends          // Growth per host and VM, limit forecasts and VM moves from the history database
 )
 
 // SortColumn represents which column to sort by
@@ -113,12 +120,46 @@ type Model struct {
 	selectedVMNode     string // Node where the VM is located
 
 	// Auto-refresh state
+	refreshInterval  int    // seconds between auto-refreshes (0 = off)
 	refreshCountdown int    // seconds until next refresh
 	refreshing       bool   // true when actively refreshing data
 	refreshProgress  string // progress message during refresh
 	refreshCurrent   int    // current progress count
 	refreshTotal     int    // total items to refresh
 
+	// Offline mode: cluster data was loaded from this snapshot file (no client)
+	snapshotPath string
+
+	// Capacity limits for migration targets (node ConfigMeta overrides apply on top)
+	policy analyzer.CapacityPolicy
+
+	// Constraints every analysis starts from (exclusions and limits from the config file)
+	defaultConstraints analyzer.MigrationConstraints
+
+	// Budgets of the global balance optimizer (criteriaState.Optimize turns it on)
+	optimizer analyzer.OptimizerSettings
+
+	// Failure simulation shown on the dashboard
+	failureNodes int                       // Nodes lost at once (K)
+	failures     *analyzer.FailureAnalysis // nil until computed for the current cluster
+	hideFailures bool                      // Panel toggled off with 'f'
+
+	// What-if simulation re-applied to the refreshed data of the cluster it was applied to
+	whatIf *analyzer.WhatIf
+
+	// History database: every refresh is recorded, the trends view reads it
+	history     *history.Store
+	historyName string          // Cluster name the initial data is recorded under without a profile
+	trends      *history.Report // nil until loaded
+	trendsState views.TrendsState
+
+	// Multi-cluster state (profiles from the config file)
+	profiles      []ClusterProfile
+	activeProfile string                   // Profile the current cluster belongs to
+	clusters      map[string]*clusterEntry // Other loaded clusters by profile name
+	clusterCursor int
+	clusterStatus string // Message shown below the cluster overview
+
 	// Cluster balance analysis state
 	balanceStartTime      time.Time // When balance analysis started (for timer display)
 	balanceReturnView     ViewType  // View to return to after Balance Cluster analysis (ESC)
@@ -127,6 +168,27 @@ type Model struct {
 
 	// Results view return destination
 	resultsReturnView ViewType // View to return to when ESC is pressed in results view
+
+	// Export status shown below the results view
+	exportStatus string
+
+	// Plan execution state
+	executeConfirm     views.ExecuteConfirmState // Limits edited on the confirmation screen
+	exec               *executor.Executor        // Running or finished execution (nil if none)
+	executionScrollPos int
+}
+
+// criteriaModes maps criteria view cursor positions to modes
+// Mode order: ModeAll, ModeBalanceCluster, ModeVCPU, ModeCPUUsage, ModeRAM, ModeStorage, ModeCreationDate, ModeSpecific
+var criteriaModes = []analyzer.MigrationMode{
+	analyzer.ModeAll,
+	analyzer.ModeBalanceCluster,
+	analyzer.ModeVCPU,
+	analyzer.ModeCPUUsage,
+	analyzer.ModeRAM,
+	analyzer.ModeStorage,
+	analyzer.ModeCreationDate,
+	analyzer.ModeSpecific,
 }
 
 // NewModel creates a new application model
@@ -148,13 +210,123 @@ func NewModelWithVersion(cluster *proxmox.Cluster, client proxmox.ProxmoxClient,
 		},
 		width:            80,
 		height:           24,
-		refreshCountdown: refreshInterval,
+		refreshInterval:  defaultRefreshInterval,
+		refreshCountdown: defaultRefreshInterval,
+		policy:           analyzer.DefaultCapacityPolicy,
+		optimizer:        analyzer.DefaultOptimizerSettings,
+		executeConfirm: views.ExecuteConfirmState{
+			Limits:   executor.DefaultLimits,
+			Estimate: analyzer.DefaultEstimateSettings,
+		},
+	}
+}
+
+// SetExecutionLimits sets the default concurrency limits offered on the execute confirmation screen
+func (m *Model) SetExecutionLimits(limits executor.Limits) {
+	m.executeConfirm.Limits = limits
+}
+
+// SetEstimateSettings sets the network and storage throughput used for migration time estimates
+func (m *Model) SetEstimateSettings(settings analyzer.EstimateSettings) {
+	m.executeConfirm.Estimate = settings
+}
+
+// SetCapacityPolicy sets the capacity limits and affinity groups used by all analysis modes
+func (m *Model) SetCapacityPolicy(policy analyzer.CapacityPolicy) {
+	m.policy = policy
+}
+
+// SetOptimizerSettings sets the time budget of the global balance optimizer
+// and whether the cluster-wide balance starts with it
+func (m *Model) SetOptimizerSettings(settings analyzer.OptimizerSettings) {
+	m.optimizer = settings
+	m.criteriaState.Optimize = settings.Enabled
+}
+
+// SetFailureNodes sets how many nodes the dashboard's failure simulation loses at once
+func (m *Model) SetFailureNodes(k int) {
+	m.failureNodes = k
+}
+
+// SetWhatIf sets the what-if simulation the initial cluster was built with,
+// so refreshes keep the hypothetical and removed nodes
+func (m *Model) SetWhatIf(w *analyzer.WhatIf) {
+	m.whatIf = w
+}
+
+// markWhatIf flags a result computed on a what-if cluster, so it can't be executed
+func (m *Model) markWhatIf() {
+	if m.result != nil && m.result.WhatIf == nil {
+		m.result.WhatIf = analyzer.ClusterWhatIf(m.cluster)
 	}
 }
 
+// estimateResult predicts the current result's migration time under the default execution limits
+func (m *Model) estimateResult() {
+	if m.result == nil {
+		return
+	}
+	limits := m.executeConfirm.Limits
+	m.result.Estimate = m.executeConfirm.Estimate.EstimateResult(m.result, limits.PerSource, limits.PerTarget)
+}
+
+// capacityLimitsSummary describes the active limits for the dashboard
+func (m Model) capacityLimitsSummary() string {
+	summary := m.policy.Summary()
+	overrides := 0
+	if m.cluster != nil {
+		for i := range m.cluster.Nodes {
+			if analyzer.HasNodeOverrides(&m.cluster.Nodes[i]) {
+				overrides++
+			}
+		}
+	}
+	if overrides > 0 {
+		summary += fmt.Sprintf("  (%d nodes override)", overrides)
+	}
+	return summary
+}
+
+// SetRefreshInterval sets the dashboard auto-refresh interval in seconds (0 disables it)
+func (m *Model) SetRefreshInterval(seconds int) {
+	m.refreshInterval = seconds
+	if m.snapshotPath == "" {
+		m.refreshCountdown = seconds
+	}
+}
+
+// SetDefaultMode preselects a migration mode in the host detail and criteria views
+func (m *Model) SetDefaultMode(mode analyzer.MigrationMode) {
+	for i := range criteriaModes {
+		if getMigrationModeFromIndex(i) == mode {
+			m.dashboardHostDetailModeIdx = i
+		}
+		if criteriaModes[i] == mode {
+			m.criteriaState.SelectedMode = mode
+			m.criteriaState.CursorPosition = i
+		}
+	}
+}
+
+// SetDefaultConstraints sets the exclusions and limits every analysis starts from
+// Mode-specific fields and the source node are filled in per analysis
+func (m *Model) SetDefaultConstraints(constraints analyzer.MigrationConstraints) {
+	m.defaultConstraints = constraints
+	m.criteriaState.ExcludeNodes = constraints.ExcludeNodes
+	m.criteriaState.Strategy = constraints.Strategy
+	m.criteriaState.Budget = constraints.BudgetSummary()
+}
+
+// SetSnapshot puts the model in offline mode: cluster data comes from a
+// snapshot file, auto-refresh is disabled and 'r' reloads the file
+func (m *Model) SetSnapshot(path string) {
+	m.snapshotPath = path
+	m.refreshCountdown = 0
+}
+
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tickCmd()
+	return tea.Batch(tickCmd(), m.startFailureAnalysis())
 }
 
 // tickCmd returns a command that sends a tick message every second
@@ -170,6 +342,7 @@ type tickMsg time.Time
 // refreshCompleteMsg is sent when cluster data refresh is complete
 type refreshCompleteMsg struct {
 	cluster *proxmox.Cluster
+	profile string // Active cluster profile when the refresh started
 	err     error
 }
 
@@ -200,7 +373,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tickMsg:
 		// Only decrement countdown on dashboard view
-		if m.currentView == ViewDashboard && !m.refreshing {
+		if m.currentView == ViewDashboard && !m.refreshing && m.snapshotPath == "" && m.refreshInterval > 0 {
 			m.refreshCountdown--
 			if m.refreshCountdown <= 0 {
 				// Start refresh with initial progress info
@@ -226,12 +399,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case refreshCompleteMsg:
 		m.refreshing = false
-		m.refreshCountdown = refreshInterval
+		if msg.profile != m.activeProfile {
+			// Cluster was switched while refreshing - drop the stale data
+			return m, nil
+		}
+		if m.snapshotPath == "" {
+			m.refreshCountdown = m.refreshInterval
+		}
 		m.refreshProgress = ""
 		m.refreshCurrent = 0
 		m.refreshTotal = 0
 		if msg.err == nil && msg.cluster != nil {
-			m.cluster = msg.cluster
+			if m.whatIf != nil && analyzer.ClusterWhatIf(m.cluster) != nil {
+				m.cluster = m.whatIf.Apply(msg.cluster)
+			} else {
+				m.cluster = msg.cluster
+			}
 			// Re-apply current sort order to new data
 			m.sortNodes()
 			// Keep selection valid
@@ -241,6 +424,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.selectedNodeIdx < 0 {
 				m.selectedNodeIdx = 0
 			}
+			return m, m.startFailureAnalysis()
+		}
+		return m, nil
+
+	case failureAnalysisMsg:
+		// Ignore results for a cluster that was refreshed or switched meanwhile
+		if msg.cluster == m.cluster {
+			m.failures = msg.analysis
+		}
+		return m, nil
+
+	case trendsLoadedMsg:
+		m.trendsState.Loading = false
+		m.trends = msg.report
+		if msg.err != nil {
+			m.trendsState.Err = msg.err.Error()
+		}
+		return m, nil
+
+	case clusterLoadedMsg:
+		if m.clusters != nil {
+			m.clusters[msg.name] = &clusterEntry{client: msg.client, cluster: msg.cluster, err: msg.err}
 		}
 		return m, nil
 
@@ -250,16 +455,61 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loading = false
 		return m, nil
 
+	case exportCompleteMsg:
+		if msg.err != nil {
+			m.exportStatus = fmt.Sprintf("Export failed: %v", msg.err)
+		} else {
+			m.exportStatus = "Plan exported to " + msg.path
+		}
+		return m, nil
+
 	case analysisCompleteMsg:
 		m.result = msg.result
+		m.estimateResult()
+		m.markWhatIf()
+		m.exportStatus = ""
 		m.currentView = ViewResults
 		m.loading = false
 		m.resultsScrollPos = 0
 		m.resultsCursorPos = 0
 		return m, nil
 
+	case drainCompleteMsg:
+		m.result = msg.drain.Evacuate
+		m.estimateResult()
+		m.markWhatIf()
+		if msg.err != nil {
+			m.exportStatus = fmt.Sprintf("Writing the return plan failed: %v", msg.err)
+		} else {
+			m.exportStatus = fmt.Sprintf("Return plan (%d migrations back to %s) saved to %s",
+				len(msg.drain.Return.Suggestions), msg.drain.Node, msg.path)
+		}
+		m.currentView = ViewResults
+		m.loading = false
+		m.resultsScrollPos = 0
+		m.resultsCursorPos = 0
+		return m, tea.ClearScreen
+
+	case hostStateSetMsg:
+		if msg.err != nil {
+			m.exportStatus = fmt.Sprintf("Setting hoststate failed: %v", msg.err)
+			return m, nil
+		}
+		was := "was not set"
+		if msg.previous >= 0 {
+			was = fmt.Sprintf("was %d", msg.previous)
+		}
+		m.exportStatus = fmt.Sprintf("Set hoststate=%d on %s (%s)", msg.state, msg.node, was)
+		if node := proxmox.GetNodeByName(m.cluster, msg.node); node != nil {
+			node.HostState = msg.state
+		}
+		return m, nil
+
 	case clusterBalanceCompleteMsg:
 		m.result = msg.result
+		m.estimateResult()
+		m.markWhatIf()
+		m.exportStatus = ""
 		m.sourceNode = msg.sourceNode
 		m.currentView = ViewResults
 		m.loading = false
@@ -275,6 +525,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) refreshClusterData() tea.Cmd {
 	// Get current node count for progress display
 	nodeCount := len(m.cluster.Nodes)
+	profile := m.activeProfile
+	store, historyName := m.history, m.historyCluster()
 
 	return func() tea.Msg {
 		// Note: We can't easily send progress updates from here in Bubble Tea
@@ -282,8 +534,19 @@ func (m Model) refreshClusterData() tea.Cmd {
 		// During refresh, we just show "Refreshing X nodes..."
 		_ = nodeCount // Used for context
 
+		if m.snapshotPath != "" {
+			snap, err := proxmox.LoadSnapshot(m.snapshotPath)
+			if err != nil {
+				return refreshCompleteMsg{profile: profile, err: err}
+			}
+			return refreshCompleteMsg{cluster: snap.Cluster, profile: profile}
+		}
+
 		cluster, err := proxmox.CollectClusterData(m.client)
-		return refreshCompleteMsg{cluster: cluster, err: err}
+		if err == nil {
+			recordHistory(store, historyName, cluster)
+		}
+		return refreshCompleteMsg{cluster: cluster, profile: profile, err: err}
 	}
 }
 
@@ -292,6 +555,10 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Global keys
 	switch msg.String() {
 	case "ctrl+c", "q":
+		// Don't quit on "q" while migrations are still being scheduled
+		if msg.String() == "q" && m.currentView == ViewExecution && m.exec != nil && !m.exec.Done() {
+			break
+		}
 		if m.currentView != ViewCriteria || !m.criteriaState.InputFocused {
 			if m.showMigrationLogics {
 				m.showMigrationLogics = false
@@ -339,6 +606,14 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleHostDetailKeys(msg)
 	case ViewError:
 		return m.handleErrorKeys(msg)
+	case ViewExecuteConfirm:
+		return m.handleExecuteConfirmKeys(msg)
+	case ViewExecution:
+		return m.handleExecutionKeys(msg)
+	case ViewClusterOverview:
+		return m.handleClusterOverviewKeys(msg)
+	case ViewTrends:
+		return m.handleTrendsKeys(msg)
 	}
 
 	return m, nil
@@ -403,6 +678,12 @@ func (m Model) handleDashboardKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.refreshCurrent = 0
 			return m, m.refreshClusterData()
 		}
+	case "c":
+		// Cluster overview and switcher (needs profiles in the config file)
+		if len(m.profiles) > 0 {
+			m.clusterCursor = 0
+			return m.openClusterOverview(false)
+		}
 	case "b", "B":
 		// Balance cluster mode - cluster-wide balancing
 		m.loading = true
@@ -412,6 +693,28 @@ func (m Model) handleDashboardKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.resultsReturnView = ViewDashboard // Return to main dashboard on ESC
 		m.isBalanceClusterRun = true
 		return m, m.startClusterBalanceAnalysis()
+	case "f", "F":
+		// Show or hide the failure simulation panel
+		m.hideFailures = !m.hideFailures
+	case "t", "T":
+		// Trends, limit forecasts and VM moves from the history database
+		m.trendsState.Section = views.TrendsSectionHosts
+		return m.openTrends()
+	case "d", "D":
+		// Drain the selected host for maintenance: evacuation plan plus a return plan file
+		m.sourceNode = m.cluster.Nodes[m.selectedNodeIdx].Name
+		m.loading = true
+		m.loadingMsg = "Planning drain of " + m.sourceNode
+		m.resultsReturnView = ViewDashboard // Return to main dashboard on ESC
+		m.isBalanceClusterRun = false
+		return m, m.startDrainAnalysis()
+	case "a", "A":
+		// Constraint audit - list placement rule violations and plan repairs
+		m.loading = true
+		m.loadingMsg = "Auditing placement constraints"
+		m.resultsReturnView = ViewDashboard // Return to main dashboard on ESC
+		m.isBalanceClusterRun = false
+		return m, m.startConstraintAudit()
 	}
 	return m, nil
 }
@@ -658,6 +961,10 @@ func (m Model) startMigrationFromHostDetail() (tea.Model, tea.Cmd) {
 			SelectedVMs:    make(map[int]bool),
 			CursorPosition: m.dashboardHostDetailModeIdx,
 			InputFocused:   true, // Start with input focused
+			CPUMetric:      m.criteriaState.CPUMetric,
+			Strategy:       m.criteriaState.Strategy,
+			Optimize:       m.criteriaState.Optimize,
+			Budget:         m.criteriaState.Budget,
 		}
 		m.isBalanceClusterRun = false // Not a balance cluster run
 		// Stay in the same view but with input focused
@@ -668,6 +975,10 @@ func (m Model) startMigrationFromHostDetail() (tea.Model, tea.Cmd) {
 		m.criteriaState = views.CriteriaState{
 			SelectedMode: selectedMode,
 			SelectedVMs:  make(map[int]bool),
+			CPUMetric:    m.criteriaState.CPUMetric,
+			Strategy:     m.criteriaState.Strategy,
+			Optimize:     m.criteriaState.Optimize,
+			Budget:       m.criteriaState.Budget,
 		}
 		m.vmCursorIdx = 0
 		m.currentView = ViewVMSelection
@@ -680,6 +991,10 @@ func (m Model) startMigrationFromHostDetail() (tea.Model, tea.Cmd) {
 		m.criteriaState = views.CriteriaState{
 			SelectedMode: selectedMode,
 			SelectedVMs:  make(map[int]bool),
+			CPUMetric:    m.criteriaState.CPUMetric,
+			Strategy:     m.criteriaState.Strategy,
+			Optimize:     m.criteriaState.Optimize,
+			Budget:       m.criteriaState.Budget,
 		}
 		m.loading = true
 		m.loadingMsg = "Analyzing migrations"
@@ -761,6 +1076,7 @@ func (m Model) handleCriteriaKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleCriteriaInput(msg)
 	}
 
+	m.criteriaState.ErrorMessage = ""
 	switch msg.String() {
 	case "up", "k":
 		if m.criteriaState.CursorPosition > 0 {
@@ -770,20 +1086,22 @@ func (m Model) handleCriteriaKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.criteriaState.CursorPosition < 7 { // 8 modes total (0-7)
 			m.criteriaState.CursorPosition++
 		}
+	case "h", "H":
+		// Cycle the CPU usage the analysis is based on; the RRD history needs the API
+		if m.client == nil {
+			m.criteriaState.ErrorMessage = "CPU history needs a connection to Proxmox (cluster loaded from a snapshot)"
+			return m, nil
+		}
+		m.criteriaState.CPUMetric = m.criteriaState.CPUMetric.Next()
+	case "s", "S":
+		// Cycle the placement strategy ranking the targets
+		m.criteriaState.Strategy = analyzer.NextPlacementStrategy(m.criteriaState.Strategy)
+	case "o", "O":
+		// Switch the cluster-wide balance between the greedy passes and the optimizer
+		m.criteriaState.Optimize = !m.criteriaState.Optimize
 	case "enter":
 		// Select mode based on cursor position
-		// Mode order: ModeAll, ModeBalanceCluster, ModeVCPU, ModeCPUUsage, ModeRAM, ModeStorage, ModeCreationDate, ModeSpecific
-		modeMap := []analyzer.MigrationMode{
-			analyzer.ModeAll,
-			analyzer.ModeBalanceCluster,
-			analyzer.ModeVCPU,
-			analyzer.ModeCPUUsage,
-			analyzer.ModeRAM,
-			analyzer.ModeStorage,
-			analyzer.ModeCreationDate,
-			analyzer.ModeSpecific,
-		}
-		m.criteriaState.SelectedMode = modeMap[m.criteriaState.CursorPosition]
+		m.criteriaState.SelectedMode = criteriaModes[m.criteriaState.CursorPosition]
 
 		// ModeAll and ModeBalanceCluster - go directly to analysis (no input needed)
 		if m.criteriaState.SelectedMode == analyzer.ModeAll || m.criteriaState.SelectedMode == analyzer.ModeBalanceCluster {
@@ -1008,6 +1326,11 @@ func (m Model) handleVMSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleResultsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// 0-3 put the node of a drain into that hoststate, like drain --hoststate
+	if key := msg.String(); m.result != nil && m.result.Drain != nil && len(key) == 1 && key >= "0" && key <= "3" {
+		return m.setDrainHostState(int(key[0] - '0'))
+	}
+
 	if m.result == nil || len(m.result.Suggestions) == 0 {
 		switch msg.String() {
 		case "r":
@@ -1180,6 +1503,33 @@ func (m Model) handleResultsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.migrationCommandsScrollPos = 0
 		return m, tea.ClearScreen
 
+	case "x":
+		// Execute plan (after confirmation)
+		if m.client == nil {
+			m.exportStatus = "Cannot execute: cluster data was loaded from a snapshot"
+			return m, nil
+		}
+		if m.result.WhatIf != nil {
+			m.exportStatus = "Cannot execute: plan is a what-if simulation"
+			return m, nil
+		}
+		for _, sug := range m.result.Suggestions {
+			if sug.TargetNode != "NONE" {
+				m.executeConfirm.FocusedLimit = 0
+				m.executeConfirm.RunUnsafe = false
+				m.currentView = ViewExecuteConfirm
+				return m, tea.ClearScreen
+			}
+		}
+
+	case "e", "E":
+		// Export plan to a file (e = JSON, E = YAML)
+		format := export.FormatJSON
+		if msg.String() == "E" {
+			format = export.FormatYAML
+		}
+		return m, m.exportResult(format)
+
 	case "esc":
 		// Reset results view state
 		m.resultsScrollPos = 0
@@ -1203,6 +1553,93 @@ func (m Model) handleResultsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleExecuteConfirmKeys handles the confirmation screen shown before executing a plan
+func (m Model) handleExecuteConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	limit := &m.executeConfirm.Limits.PerSource
+	if m.executeConfirm.FocusedLimit == 1 {
+		limit = &m.executeConfirm.Limits.PerTarget
+	}
+
+	switch msg.String() {
+	case "up", "k", "down", "j", "tab":
+		m.executeConfirm.FocusedLimit = (m.executeConfirm.FocusedLimit + 1) % 2
+	case "+", "=", "right", "l":
+		*limit++
+	case "-", "left", "h":
+		if *limit > 0 {
+			*limit--
+		}
+	case "u", "U":
+		// The unsafe wave only runs when asked for explicitly
+		if m.result.Staged.HasUnsafe() {
+			m.executeConfirm.RunUnsafe = !m.executeConfirm.RunUnsafe
+		}
+	case "y", "Y":
+		if m.result.Staged != nil {
+			m.exec = executor.NewStaged(m.client, m.result.Staged, m.executeConfirm.Limits, m.executeConfirm.RunUnsafe)
+		} else {
+			m.exec = executor.New(m.client, m.result.Suggestions, m.executeConfirm.Limits)
+		}
+		m.exec.Start()
+		m.executionScrollPos = 0
+		m.currentView = ViewExecution
+		return m, tea.ClearScreen
+	case "esc", "n":
+		m.currentView = ViewResults
+		return m, tea.ClearScreen
+	}
+	return m, nil
+}
+
+// handleExecutionKeys handles the live execution progress view
+func (m Model) handleExecutionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.exec == nil {
+		m.currentView = ViewResults
+		return m, tea.ClearScreen
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.executionScrollPos > 0 {
+			m.executionScrollPos--
+		}
+	case "down", "j":
+		m.executionScrollPos++
+	case "pgup":
+		m.executionScrollPos -= 10
+		if m.executionScrollPos < 0 {
+			m.executionScrollPos = 0
+		}
+	case "pgdown":
+		m.executionScrollPos += 10
+	case "c":
+		m.exec.Cancel()
+	case "esc":
+		if !m.exec.Done() {
+			return m, nil
+		}
+		// Cluster layout changed - refresh data in the background
+		m.currentView = ViewResults
+		if !m.refreshing {
+			m.refreshing = true
+			m.refreshProgress = fmt.Sprintf("Refreshing %d nodes", len(m.cluster.Nodes))
+			m.refreshTotal = len(m.cluster.Nodes)
+			m.refreshCurrent = 0
+			return m, tea.Batch(tea.ClearScreen, m.refreshClusterData())
+		}
+		return m, tea.ClearScreen
+	}
+
+	// Keep scroll position within the job list
+	if last := len(m.exec.Snapshot()) - 1; m.executionScrollPos > last {
+		m.executionScrollPos = last
+	}
+	if m.executionScrollPos < 0 {
+		m.executionScrollPos = 0
+	}
+	return m, nil
+}
+
 // buildImpactHostList builds a sorted list of hosts in the impact table
 func (m *Model) buildImpactHostList() []string {
 	var hosts []string
@@ -1533,7 +1970,11 @@ func (m Model) handleMigrationCommandsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		return m, tea.ClearScreen
 	}
 
-	totalLines := len(m.result.Suggestions) + 10 // commands + header/footer
+	planned := len(m.result.PlannedSuggestions())
+	totalLines := planned + 10 // commands + header/footer
+	if m.result.Staged != nil {
+		totalLines += m.result.Staged.Count() - planned + len(m.result.Staged.Waves) + 1 // wave headers
+	}
 	availableHeight := m.height - 4
 	maxScroll := totalLines - availableHeight
 	if maxScroll < 0 {
@@ -1636,7 +2077,7 @@ func (m Model) View() string {
 				break
 			}
 		}
-		return views.RenderVMDetails(vm, m.selectedVMNode, m.selectedVMID, m.width, m.height, m.vmDetailsScrollPos)
+		return views.RenderVMDetails(vm, m.selectedVMNode, m.selectedVMID, m.policy.AffinityGroups, m.width, m.height, m.vmDetailsScrollPos)
 	}
 
 	if m.showHelp {
@@ -1659,10 +2100,19 @@ func (m Model) View() string {
 	switch m.currentView {
 	case ViewDashboard:
 		progress := views.RefreshProgress{
-			Stage:   m.refreshProgress,
-			Current: m.refreshCurrent,
-			Total:   m.refreshTotal,
+			Stage:    m.refreshProgress,
+			Current:  m.refreshCurrent,
+			Total:    m.refreshTotal,
+			Snapshot: m.snapshotPath,
+			Limits:   m.capacityLimitsSummary(),
+		}
+		if !m.hideFailures {
+			progress.Failures = m.failures
 		}
+		if len(m.profiles) > 0 {
+			progress.Cluster = m.activeProfile
+		}
+		progress.WhatIf = analyzer.ClusterWhatIf(m.cluster)
 		sortInfo := views.SortInfo{
 			Column:    int(m.sortColumn),
 			Ascending: m.sortAsc,
@@ -1700,14 +2150,32 @@ func (m Model) View() string {
 	case ViewResults:
 		if m.result != nil {
 			sourceNode := proxmox.GetNodeByName(m.cluster, m.sourceNode)
-			return views.RenderResultsInteractive(m.result, m.cluster, sourceNode, m.version, m.width, m.height, m.resultsScrollPos, m.resultsCursorPos, m.resultsSection, m.impactCursorPos)
+			out := views.RenderResultsInteractive(m.result, m.cluster, sourceNode, m.version, m.width, m.height, m.resultsScrollPos, m.resultsCursorPos, m.resultsSection, m.impactCursorPos)
+			if m.exportStatus != "" {
+				out += "\n" + m.exportStatus
+			}
+			return out
 		}
 		return "No results available"
+	case ViewClusterOverview:
+		return views.RenderClusterOverview(m.clusterOverviewRows(), m.clusterCursor, m.clusterStatus, m.width)
+	case ViewTrends:
+		return views.RenderTrends(m.trends, m.trendsState, m.width, m.height)
 	case ViewHostDetail:
 		if m.result != nil && m.selectedHostName != "" {
 			return views.RenderHostDetailWithReasoningScroll(m.result, m.cluster, m.selectedHostName, m.sourceNode, m.width, m.height, m.hostDetailScrollPos, m.hostDetailCursorPos, m.hostDetailFocusedSection, m.hostDetailReasoningScroll)
 		}
 		return "No host selected"
+	case ViewExecuteConfirm:
+		if m.result != nil {
+			return views.RenderExecuteConfirm(m.result, m.executeConfirm, m.width, m.height)
+		}
+		return "No results available"
+	case ViewExecution:
+		if m.exec != nil {
+			return views.RenderExecution(m.exec, m.width, m.height, m.executionScrollPos)
+		}
+		return "No execution in progress"
 	case ViewError:
 		return fmt.Sprintf("\nError: %v\n\nPress Enter to continue", m.err)
 	default:
@@ -1715,13 +2183,28 @@ func (m Model) View() string {
 	}
 }
 
+// analysisCluster returns the cluster an analysis works on: the collected data,
+// or a copy with the CPU usage from the RRD history chosen in the criteria view
+func (m Model) analysisCluster() (*proxmox.Cluster, error) {
+	metric := m.criteriaState.CPUMetric
+	if metric.IsCurrent() {
+		return m.cluster, nil
+	}
+	if m.client == nil {
+		return nil, fmt.Errorf("CPU history (%s) needs a connection to Proxmox", metric)
+	}
+	return proxmox.CollectCPUHistory(m.client, m.cluster, metric, nil)
+}
+
 // startAnalysis creates analysis command
 func (m Model) startAnalysis() tea.Cmd {
 	return func() tea.Msg {
 		// Build constraints
-		constraints := analyzer.MigrationConstraints{
-			SourceNode: m.sourceNode,
-		}
+		policy := m.policy
+		constraints := m.defaultConstraints
+		constraints.SourceNode = m.sourceNode
+		constraints.Policy = &policy
+		constraints.Strategy = m.criteriaState.Strategy
 
 		// Parse input based on mode
 		var err error
@@ -1782,10 +2265,15 @@ func (m Model) startAnalysis() tea.Cmd {
 		}
 
 		// Run analysis
-		result, err := analyzer.Analyze(m.cluster, constraints)
+		cluster, err := m.analysisCluster()
 		if err != nil {
 			return errMsg{err}
 		}
+		result, err := analyzer.Analyze(cluster, constraints)
+		if err != nil {
+			return errMsg{err}
+		}
+		result.CPUMetric = cluster.CPUMetric
 
 		return analysisCompleteMsg{result}
 	}
@@ -1794,11 +2282,26 @@ func (m Model) startAnalysis() tea.Cmd {
 // startClusterBalanceAnalysis creates cluster-wide balance analysis command
 func (m Model) startClusterBalanceAnalysis() tea.Cmd {
 	return func() tea.Msg {
-		// Run cluster-wide balance analysis
-		result, err := analyzer.AnalyzeClusterWideBalance(m.cluster, nil)
+		// Run cluster-wide balance analysis; a what-if cluster first drains its removed nodes
+		cluster, err := m.analysisCluster()
+		if err != nil {
+			return errMsg{err}
+		}
+		var result *analyzer.AnalysisResult
+		switch {
+		case analyzer.ClusterWhatIf(cluster) != nil:
+			settings := m.optimizer
+			settings.Enabled = m.criteriaState.Optimize
+			result, err = analyzer.AnalyzeWhatIf(cluster, m.balanceConstraints(), settings, nil)
+		case m.criteriaState.Optimize:
+			result, err = analyzer.AnalyzeClusterWideOptimizedWithConstraints(cluster, m.balanceConstraints(), m.optimizer, nil)
+		default:
+			result, err = analyzer.AnalyzeClusterWideBalanceWithConstraints(cluster, m.balanceConstraints(), nil)
+		}
 		if err != nil {
 			return errMsg{err}
 		}
+		result.CPUMetric = cluster.CPUMetric
 
 		// For cluster balance, we don't have a single source node
 		// Use "CLUSTER" as a marker or the first node with migrations
@@ -1811,15 +2314,154 @@ func (m Model) startClusterBalanceAnalysis() tea.Cmd {
 	}
 }
 
+// balanceConstraints returns the constraints of a cluster-wide balance: the
+// capacity policy, the selected strategy, and the excluded nodes and budget from the config file
+func (m Model) balanceConstraints() analyzer.MigrationConstraints {
+	policy := m.policy
+	d := m.defaultConstraints
+	return analyzer.MigrationConstraints{
+		BalanceCluster: true,
+		ExcludeNodes:   d.ExcludeNodes,
+		Policy:         &policy,
+		Strategy:       m.criteriaState.Strategy,
+		MaxMigrations:  d.MaxMigrations,
+		MaxTransfer:    d.MaxTransfer,
+		MaxLiveRAM:     d.MaxLiveRAM,
+		ExcludeVMs:     d.ExcludeVMs,
+	}
+}
+
+// startFailureAnalysis simulates the loss of every combination of failureNodes
+// nodes for the dashboard panel. Clusters too small to simulate get no panel.
+func (m Model) startFailureAnalysis() tea.Cmd {
+	cluster := m.cluster
+	policy := m.policy
+	k := m.failureNodes
+	if cluster == nil || k < 1 {
+		return nil
+	}
+	// The views sort nodes and VMs in place; simulate on a copy
+	snapshot := *cluster
+	snapshot.Nodes = make([]proxmox.Node, len(cluster.Nodes))
+	for i, node := range cluster.Nodes {
+		node.VMs = append([]proxmox.VM(nil), node.VMs...)
+		snapshot.Nodes[i] = node
+	}
+	return func() tea.Msg {
+		analysis, err := analyzer.AnalyzeFailures(&snapshot, policy, k)
+		if err != nil {
+			return failureAnalysisMsg{cluster: cluster}
+		}
+		return failureAnalysisMsg{cluster: cluster, analysis: analysis}
+	}
+}
+
+// startDrainAnalysis plans the drain of the source node and writes the return
+// plan to a JSON file in the working directory
+func (m Model) startDrainAnalysis() tea.Cmd {
+	version := m.version
+	settings := m.executeConfirm.Estimate
+	limits := m.executeConfirm.Limits
+	policy := m.policy
+	constraints := m.defaultConstraints
+	constraints.SourceNode = m.sourceNode
+	constraints.Policy = &policy
+	constraints.Strategy = m.criteriaState.Strategy
+	return func() tea.Msg {
+		cluster, err := m.analysisCluster()
+		if err != nil {
+			return errMsg{err}
+		}
+		drain, err := analyzer.AnalyzeDrain(cluster, constraints)
+		if err != nil {
+			return errMsg{err}
+		}
+		drain.Evacuate.CPUMetric = cluster.CPUMetric
+		drain.Return.CPUMetric = cluster.CPUMetric
+		drain.Return.Estimate = settings.EstimateResult(drain.Return, limits.PerSource, limits.PerTarget)
+		plan := export.NewPlan(drain.Return, cluster, version)
+		path := export.DefaultFileName(plan, export.FormatJSON)
+		err = export.WriteFile(path, plan, export.FormatJSON)
+		return drainCompleteMsg{drain: drain, path: path, err: err}
+	}
+}
+
+// setDrainHostState sets the hoststate of the drained node in the background
+func (m Model) setDrainHostState(state int) (tea.Model, tea.Cmd) {
+	if m.client == nil {
+		m.exportStatus = "Cannot set hoststate: cluster data was loaded from a snapshot"
+		return m, nil
+	}
+	if m.result.WhatIf != nil {
+		m.exportStatus = "Cannot set hoststate: plan is a what-if simulation"
+		return m, nil
+	}
+	client := m.client
+	node := m.result.Drain.Node
+	m.exportStatus = fmt.Sprintf("Setting hoststate=%d on %s...", state, node)
+	return m, func() tea.Msg {
+		previous, err := proxmox.SetNodeHostState(client, node, state)
+		return hostStateSetMsg{node: node, state: state, previous: previous, err: err}
+	}
+}
+
+// startConstraintAudit creates the constraint audit command
+func (m Model) startConstraintAudit() tea.Cmd {
+	return func() tea.Msg {
+		result, err := analyzer.AnalyzeConstraintAudit(m.cluster, m.policy)
+		if err != nil {
+			return errMsg{err}
+		}
+		// Like cluster balance, the audit has no single source node
+		return clusterBalanceCompleteMsg{result: result, sourceNode: "CLUSTER"}
+	}
+}
+
+// exportResult writes the current result to a plan file in the working directory
+func (m Model) exportResult(format export.Format) tea.Cmd {
+	result := m.result
+	cluster := m.cluster
+	version := m.version
+	return func() tea.Msg {
+		plan := export.NewPlan(result, cluster, version)
+		path := export.DefaultFileName(plan, format)
+		err := export.WriteFile(path, plan, format)
+		return exportCompleteMsg{path: path, err: err}
+	}
+}
+
 // Messages
 type errMsg struct {
 	err error
 }
 
+type exportCompleteMsg struct {
+	path string
+	err  error
+}
+
 type analysisCompleteMsg struct {
 	result *analyzer.AnalysisResult
 }
 
+type failureAnalysisMsg struct {
+	cluster  *proxmox.Cluster // Cluster the simulation ran on
+	analysis *analyzer.FailureAnalysis
+}
+
+type drainCompleteMsg struct {
+	drain *analyzer.DrainPlan
+	path  string // Return plan file
+	err   error  // Writing the return plan failed
+}
+
+type hostStateSetMsg struct {
+	node     string
+	state    int
+	previous int // -1 = was not set
+	err      error
+}
+
 type clusterBalanceCompleteMsg struct {
 	result     *analyzer.AnalysisResult
 	sourceNode string
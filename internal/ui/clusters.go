@@ -0,0 +1,244 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/yourusername/migsug/internal/history"
+	"github.com/yourusername/migsug/internal/proxmox"
+	"github.com/yourusername/migsug/internal/ui/views"
+)
+
+// currentClusterName labels the cluster migsug was started with when it isn't a profile
+const currentClusterName = "(current)"
+
+// ClusterProfile is a named cluster the TUI can switch to
+type ClusterProfile struct {
+	Name    string
+	Connect func() (proxmox.ProxmoxClient, error) // Creates and tests a client without prompting
+}
+
+// clusterEntry holds the loaded data of one cluster profile
+type clusterEntry struct {
+	client   proxmox.ProxmoxClient
+	cluster  *proxmox.Cluster
+	snapshot string // Snapshot file the data came from (offline mode)
+	loading  bool
+	err      error
+}
+
+// clusterLoadedMsg is sent when a profile's cluster data has been collected
+type clusterLoadedMsg struct {
+	name    string
+	client  proxmox.ProxmoxClient
+	cluster *proxmox.Cluster
+	err     error
+}
+
+// SetClusterProfiles enables the cluster switcher
+// active is the profile the current cluster data belongs to ("" if none)
+func (m *Model) SetClusterProfiles(profiles []ClusterProfile, active string) {
+	m.profiles = profiles
+	m.activeProfile = active
+	if m.activeProfile == "" {
+		m.activeProfile = currentClusterName
+	}
+	m.clusters = make(map[string]*clusterEntry)
+}
+
+// clusterNames returns the active cluster and all profile names, without duplicates
+func (m Model) clusterNames() []string {
+	names := []string{}
+	if m.findProfile(m.activeProfile) == nil {
+		names = append(names, m.activeProfile)
+	}
+	for _, p := range m.profiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// findProfile returns the profile with the given name, or nil
+func (m Model) findProfile(name string) *ClusterProfile {
+	for i := range m.profiles {
+		if m.profiles[i].Name == name {
+			return &m.profiles[i]
+		}
+	}
+	return nil
+}
+
+// loadProfileCmd connects to a profile and collects its cluster data,
+// recording it in the history database when one is given
+func loadProfileCmd(profile ClusterProfile, store *history.Store) tea.Cmd {
+	return func() tea.Msg {
+		client, err := profile.Connect()
+		if err != nil {
+			return clusterLoadedMsg{name: profile.Name, err: err}
+		}
+		cluster, err := proxmox.CollectClusterData(client)
+		if err == nil {
+			recordHistory(store, profile.Name, cluster)
+		}
+		return clusterLoadedMsg{name: profile.Name, client: client, cluster: cluster, err: err}
+	}
+}
+
+// openClusterOverview shows the overview and loads every profile not loaded yet
+func (m Model) openClusterOverview(reload bool) (tea.Model, tea.Cmd) {
+	m.currentView = ViewClusterOverview
+	m.clusterStatus = ""
+
+	var cmds []tea.Cmd
+	for _, p := range m.profiles {
+		if p.Name == m.activeProfile {
+			continue
+		}
+		entry := m.clusters[p.Name]
+		if entry != nil && (entry.loading || (!reload && entry.err == nil)) {
+			continue
+		}
+		m.clusters[p.Name] = &clusterEntry{loading: true}
+		cmds = append(cmds, loadProfileCmd(p, m.history))
+	}
+	cmds = append(cmds, tea.ClearScreen)
+	return m, tea.Batch(cmds...)
+}
+
+// clusterOverviewRows builds the overview rows, most urgent cluster first
+func (m Model) clusterOverviewRows() []views.ClusterOverviewRow {
+	var rows []views.ClusterOverviewRow
+	for _, name := range m.clusterNames() {
+		row := views.ClusterOverviewRow{Name: name, Active: name == m.activeProfile}
+
+		cluster := m.cluster
+		if !row.Active {
+			cluster = nil
+			if entry := m.clusters[name]; entry != nil {
+				row.Loading = entry.loading
+				if entry.err != nil {
+					row.Err = entry.err.Error()
+				}
+				cluster = entry.cluster
+			}
+		}
+		if cluster != nil && row.Err == "" && !row.Loading {
+			row.Summary = proxmox.GetClusterSummary(cluster)
+			row.CollectedAt = cluster.CollectedAt
+			row.Attention = m.clusterAttention(row.Summary)
+		}
+		rows = append(rows, row)
+	}
+
+	// Clusters needing attention first, then by peak utilization; unloaded last
+	sort.SliceStable(rows, func(i, j int) bool {
+		return clusterUrgency(rows[i]) > clusterUrgency(rows[j])
+	})
+	return rows
+}
+
+// clusterAttention describes why a cluster summary needs attention, using the capacity policy
+func (m Model) clusterAttention(summary map[string]interface{}) string {
+	offline := summary["total_nodes"].(int) - summary["online_nodes"].(int)
+	if offline > 0 {
+		return fmt.Sprintf("%d nodes offline", offline)
+	}
+	if v := summary["mem_percent"].(float64); v > m.policy.MaxRAMPercent {
+		return fmt.Sprintf("RAM above %.0f%% limit", m.policy.MaxRAMPercent)
+	}
+	if v := summary["storage_percent"].(float64); v > m.policy.MaxStoragePercent {
+		return fmt.Sprintf("Storage above %.0f%% limit", m.policy.MaxStoragePercent)
+	}
+	if v := summary["avg_cpu_percent"].(float64); v > m.policy.MaxHostCPUPercent {
+		return fmt.Sprintf("CPU above %.0f%% limit", m.policy.MaxHostCPUPercent)
+	}
+	return ""
+}
+
+// clusterUrgency orders overview rows: attention > peak utilization > errors > not loaded
+func clusterUrgency(row views.ClusterOverviewRow) float64 {
+	if row.Summary == nil {
+		if row.Err != "" {
+			return -1
+		}
+		return -2
+	}
+	peak := row.Summary["avg_cpu_percent"].(float64)
+	for _, key := range []string{"mem_percent", "storage_percent"} {
+		if v := row.Summary[key].(float64); v > peak {
+			peak = v
+		}
+	}
+	if row.Attention != "" {
+		peak += 1000
+	}
+	return peak
+}
+
+// handleClusterOverviewKeys handles keyboard input for the cluster overview
+func (m Model) handleClusterOverviewKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.clusterOverviewRows()
+
+	switch msg.String() {
+	case "up", "k":
+		if m.clusterCursor > 0 {
+			m.clusterCursor--
+		}
+	case "down", "j":
+		if m.clusterCursor < len(rows)-1 {
+			m.clusterCursor++
+		}
+	case "r":
+		return m.openClusterOverview(true)
+	case "enter":
+		if m.clusterCursor < len(rows) {
+			return m.switchCluster(rows[m.clusterCursor].Name)
+		}
+	case "esc":
+		m.currentView = ViewDashboard
+		return m, tea.ClearScreen
+	}
+	return m, nil
+}
+
+// switchCluster makes a loaded cluster the active one and returns to the dashboard
+func (m Model) switchCluster(name string) (tea.Model, tea.Cmd) {
+	if name == m.activeProfile {
+		m.currentView = ViewDashboard
+		return m, tea.ClearScreen
+	}
+	if m.exec != nil && !m.exec.Done() {
+		m.clusterStatus = "Cannot switch clusters while a plan is executing"
+		return m, nil
+	}
+	entry := m.clusters[name]
+	if entry == nil || entry.loading || entry.cluster == nil {
+		m.clusterStatus = fmt.Sprintf("Cluster %s is not loaded yet", name)
+		return m, nil
+	}
+
+	// Keep the current cluster so switching back is instant
+	m.clusters[m.activeProfile] = &clusterEntry{client: m.client, cluster: m.cluster, snapshot: m.snapshotPath}
+
+	m.activeProfile = name
+	m.client = entry.client
+	m.cluster = entry.cluster
+	m.snapshotPath = entry.snapshot
+	m.refreshCountdown = m.refreshInterval
+	if m.snapshotPath != "" {
+		m.refreshCountdown = 0
+	}
+
+	// Results and selections belong to the previous cluster
+	m.result = nil
+	m.exec = nil
+	m.exportStatus = ""
+	m.sourceNode = ""
+	m.selectedNodeIdx = 0
+	m.sortNodes()
+	m.failures = nil
+
+	m.currentView = ViewDashboard
+	return m, tea.Batch(tea.ClearScreen, m.startFailureAnalysis())
+}
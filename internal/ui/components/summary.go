@@ -2,8 +2,11 @@ package components
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/yourusername/migsug/internal/analyzer"
 	"github.com/yourusername/migsug/internal/proxmox"
 )
 
@@ -159,6 +162,200 @@ func RenderMigrationSummaryWithMoves(totalVMs int, totalVCPUs int, totalRAM int6
 	return content
 }
 
+// RenderMigrationEstimate creates the estimated duration row for migration results
+func RenderMigrationEstimate(est *analyzer.PlanEstimate) string {
+	labelStyle := lipgloss.NewStyle()
+	valueStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+	content := "  " + labelStyle.Render("Est. time: ") + valueStyle.Render(fmt.Sprintf("%-10s", analyzer.FormatDuration(est.WallClock)))
+	content += labelStyle.Render("Sequential: ") + valueStyle.Render(fmt.Sprintf("%-10s", analyzer.FormatDuration(est.Sequential)))
+	content += labelStyle.Render("Transfer: ") + valueStyle.Render(fmt.Sprintf("%-14s", FormatBytes(est.Bytes)))
+	content += labelStyle.Render("Concurrency: ") + valueStyle.Render(fmt.Sprintf("%s/source, %s/target",
+		formatConcurrency(est.PerSource), formatConcurrency(est.PerTarget)))
+	if est.Waves > 0 {
+		content += labelStyle.Render("  Waves: ") + valueStyle.Render(fmt.Sprintf("%d", est.Waves))
+	}
+	if n := est.NonConverging(); n > 0 {
+		content += warnStyle.Render(fmt.Sprintf("  ⚠ %d live migrations may not converge", n))
+	}
+	return content
+}
+
+// RenderOptimization creates the optimizer rows of a balance result: the
+// imbalance against the lower bound and the budgets used
+func RenderOptimization(report *analyzer.OptimizationReport) string {
+	labelStyle := lipgloss.NewStyle()
+	valueStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
+
+	content := "  " + labelStyle.Render("Imbalance: ") + valueStyle.Render(fmt.Sprintf("%.1f → %.1f pts", report.Before, report.After))
+	content += labelStyle.Render("  Lower bound: ") + valueStyle.Render(fmt.Sprintf("%.1f", report.LowerBound))
+	content += labelStyle.Render("  Gap: ") + valueStyle.Render(fmt.Sprintf("%.1f pts (%.0f%% of the possible improvement)", report.Gap(), report.Achieved()))
+	content += "\n  " + dimStyle.Render("Optimizer: "+report.BudgetSummary())
+	return content
+}
+
+// RenderBalanceBudget creates the budget rows of a greedy balance result:
+// the imbalance reached against the migrations and transfer spent
+func RenderBalanceBudget(budget *analyzer.BalanceBudget) string {
+	labelStyle := lipgloss.NewStyle()
+	valueStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+	migrations := fmt.Sprintf("%d", budget.Migrations)
+	if budget.MaxMigrations > 0 {
+		migrations += fmt.Sprintf(" of %d", budget.MaxMigrations)
+	}
+	transfer := proxmox.FormatBytes(budget.Bytes)
+	if budget.MaxBytes > 0 {
+		transfer += " of " + proxmox.FormatBytes(budget.MaxBytes)
+	}
+	content := "  " + labelStyle.Render("Imbalance: ") + valueStyle.Render(fmt.Sprintf("%.1f → %.1f pts", budget.ScoreBefore, budget.ScoreAfter))
+	content += labelStyle.Render("  Migrations: ") + valueStyle.Render(migrations)
+	content += labelStyle.Render("  Transferred: ") + valueStyle.Render(transfer)
+	switch budget.Exhausted {
+	case analyzer.BudgetMigrations:
+		content += warnStyle.Render("  ⚠ Migration budget reached")
+	case analyzer.BudgetTransfer:
+		content += warnStyle.Render("  ⚠ Transfer budget reached")
+	}
+	if restrictions := budget.Restrictions(); restrictions != "" {
+		content += "\n  " + dimStyle.Render("Kept in place: "+restrictions)
+	}
+	return content
+}
+
+// RenderAffinityViolations lists the affinity groups the plan leaves violated
+// Returns an empty string when there are none
+func RenderAffinityViolations(violations []analyzer.AffinityViolation) string {
+	if len(violations) == 0 {
+		return ""
+	}
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+	content := warnStyle.Render(fmt.Sprintf("  ⚠ %d affinity group violations after migration:", len(violations)))
+	for i, v := range violations {
+		if i == maxAffinityViolationLines {
+			content += "\n" + warnStyle.Render(fmt.Sprintf("    ... and %d more", len(violations)-i))
+			break
+		}
+		content += "\n" + warnStyle.Render("    "+v.Message)
+	}
+	return content
+}
+
+// RenderConstraintAudit lists the violations found by a constraint audit and
+// whether the plan repairs them. Returns an empty string for other results.
+func RenderConstraintAudit(audit *analyzer.ConstraintAudit) string {
+	if audit == nil {
+		return ""
+	}
+	goodStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	badStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	if len(audit.Violations) == 0 {
+		return goodStyle.Render("▶ Constraint Audit: no placement constraint violations found")
+	}
+
+	content := "▶ Constraint Audit: " + warnStyle.Render(audit.Summary())
+	for i, v := range audit.Violations {
+		if i == maxAuditViolationLines {
+			content += fmt.Sprintf("\n    ... and %d more", len(audit.Violations)-i)
+			break
+		}
+		if v.Repaired {
+			content += "\n  " + goodStyle.Render("✓") + fmt.Sprintf(" [%s] %s", v.Rule, v.Message)
+		} else {
+			content += "\n  " + badStyle.Render("✗") + fmt.Sprintf(" [%s] %s", v.Rule, v.Message) + warnStyle.Render(" - "+v.Blocked)
+		}
+	}
+	return content
+}
+
+// maxAuditViolationLines limits the violations listed above the suggestions table
+const maxAuditViolationLines = 8
+
+// maxAffinityViolationLines limits the violations listed above the suggestions table
+const maxAffinityViolationLines = 3
+
+// maxFailureScenarioLines limits the non-survivable failures listed on the dashboard
+const maxFailureScenarioLines = 3
+
+// maxStrandedNames limits the stranded VM names listed per failure
+const maxStrandedNames = 3
+
+// RenderFailureSimulation shows which node failures the cluster can't absorb
+// and the worst-case utilization of each remaining node (CPU/RAM/storage %)
+func RenderFailureSimulation(analysis *analyzer.FailureAnalysis, width int) string {
+	goodStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	badStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	failed := analysis.NonSurvivable()
+	summary := goodStyle.Render(analysis.Summary())
+	if len(failed) > 0 {
+		summary = badStyle.Render(analysis.Summary())
+	}
+	content := titleStyle.Render("▶ Failure Simulation: ") + summary
+
+	for i, scenario := range failed {
+		if i == maxFailureScenarioLines {
+			content += "\n" + badStyle.Render(fmt.Sprintf("    ... and %d more", len(failed)-i))
+			break
+		}
+		var names []string
+		for j, vm := range scenario.Stranded {
+			if j == maxStrandedNames {
+				names = append(names, fmt.Sprintf("+%d", len(scenario.Stranded)-j))
+				break
+			}
+			names = append(names, vm.VMName)
+		}
+		stranded := fmt.Sprintf("%d VMs have", len(scenario.Stranded))
+		if len(scenario.Stranded) == 1 {
+			stranded = "1 VM has"
+		}
+		content += "\n" + badStyle.Render(fmt.Sprintf("  ✗ %s down: %s nowhere to go (%s)",
+			scenario.Name(), stranded, strings.Join(names, ", ")))
+	}
+
+	if len(analysis.WorstCase) == 0 {
+		return content
+	}
+	content += "\n" + labelStyle.Render("  Worst case per host (CPU/RAM/Storage %, failed node):")
+	line := " "
+	lineWidth := 1
+	for _, w := range analysis.WorstCase {
+		entry := fmt.Sprintf("%s %.0f/%.0f/%.0f", w.Node, w.After.CPUPercent, w.After.RAMPercent, w.After.StoragePercent)
+		if len(w.Failed) > 0 {
+			entry += fmt.Sprintf(" (%s)", strings.Join(w.Failed, "+"))
+		}
+		if lineWidth+2+len(entry) > width && lineWidth > 1 {
+			content += "\n" + line
+			line, lineWidth = " ", 1
+		}
+		color := "2"
+		if worst := math.Max(w.After.CPUPercent, math.Max(w.After.RAMPercent, w.After.StoragePercent)); worst >= 90 {
+			color = "1"
+		} else if worst >= 75 {
+			color = "3"
+		}
+		line += " " + lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(entry)
+		lineWidth += 2 + len(entry)
+	}
+	return content + "\n" + line
+}
+
+// formatConcurrency formats a concurrency limit (0 = unlimited)
+func formatConcurrency(n int) string {
+	if n <= 0 {
+		return "∞"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
 // RenderMigrationSummaryContent creates just the content row for migration results (no title)
 func RenderMigrationSummaryContent(totalVMs int, totalVCPUs int, totalRAM int64, totalStorage int64) string {
 	labelStyle := lipgloss.NewStyle()
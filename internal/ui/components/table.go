@@ -334,7 +334,13 @@ func RenderNodeTableWideWithScroll(nodes []proxmox.Node, selectedIdx int, width
 
 			// Status with indicators and color based on hoststate
 			statusColor := "2" // green for online
-			if node.Status != "online" {
+			if node.Simulated {
+				// What-if node that doesn't exist: magenta
+				statusColor = "13"
+			} else if node.Removed {
+				// What-if decommissioned node: red
+				statusColor = "1"
+			} else if node.Status != "online" {
 				statusColor = "9" // bright red for offline
 			} else if node.HostState == 1 {
 				// Maintenance mode: grey
@@ -857,9 +863,10 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 		colRAM      = 8
 		colUsedDisk = 9 // Used/actual disk
 		colMaxDisk  = 9 // Max/allocated disk
+		colXfer     = 9 // Estimated data transfer (local disks + live RAM)
 	)
 
-	totalWidth := colVMID + colName + colTo + colState + colCPU + colHCPU + colVMCPU + colVCPU + colRAM + colUsedDisk + colMaxDisk + 10
+	totalWidth := colVMID + colName + colTo + colState + colCPU + colHCPU + colVMCPU + colVCPU + colRAM + colUsedDisk + colMaxDisk + colXfer + 11
 
 	// Highlight style for selected row
 	selectedStyle := lipgloss.NewStyle().
@@ -889,7 +896,7 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 	}
 
 	// Header (with 2-char prefix for alignment, +2 for scrollbar)
-	header := fmt.Sprintf("  %*s %-*s %-*s %-*s %*s %*s %*s %*s %*s %*s %*s",
+	header := fmt.Sprintf("  %*s %-*s %-*s %-*s %*s %*s %*s %*s %*s %*s %*s %*s",
 		colVMID, "VMID",
 		colName, "Name",
 		colTo, "To",
@@ -900,7 +907,8 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 		colVCPU, "vCPU",
 		colRAM, "RAM",
 		colUsedDisk, "Used",
-		colMaxDisk, "Max")
+		colMaxDisk, "Max",
+		colXfer, "Xfer")
 	if needsScrollbar {
 		sb.WriteString(headerStyle.Render(header) + "  \n")
 		sb.WriteString("  " + strings.Repeat("─", totalWidth) + "  \n")
@@ -921,8 +929,11 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 		isSelected := (i == cursorPos)
 
 		// State: "On" for running, "Off" for stopped
+		// Running containers are marked: they migrate in restart mode with downtime
 		stateStr := "Off"
-		if sug.Status == "running" {
+		if sug.NeedsRestart() {
+			stateStr = "On*"
+		} else if sug.Status == "running" {
 			stateStr = "On"
 		}
 
@@ -942,7 +953,7 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 		}
 		hCpuStr := fmt.Sprintf("%.1f", hCpuPercent)
 
-		row := fmt.Sprintf("%*d %-*s %-*s %-*s %*s %*s %*s %*d %*s %*s %*s",
+		row := fmt.Sprintf("%*d %-*s %-*s %-*s %*s %*s %*s %*d %*s %*s %*s %*s",
 			colVMID, sug.VMID,
 			colName, truncate(sug.VMName, colName),
 			colTo, truncate(sug.TargetNode, colTo),
@@ -954,6 +965,7 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 			colRAM, FormatRAMShort(sug.RAM),
 			colUsedDisk, FormatStorageG(sug.UsedDisk),
 			colMaxDisk, FormatStorageG(sug.MaxDisk),
+			colXfer, FormatStorageG(sug.Transfer),
 		)
 
 		// Scrollbar character for this row
@@ -1007,6 +1019,18 @@ func RenderSuggestionTableWithCursor(suggestions []analyzer.MigrationSuggestion,
 		sb.WriteString("  " + strings.Repeat("─", totalWidth) + "\n")
 	}
 
+	// Warn about LXC containers that will be stopped during migration
+	restartCount := 0
+	for _, sug := range suggestions {
+		if sug.TargetNode != "NONE" && sug.NeedsRestart() {
+			restartCount++
+		}
+	}
+	if restartCount > 0 {
+		warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+		sb.WriteString(warnStyle.Render(fmt.Sprintf("  * %d running LXC container(s) can't live-migrate and will be restarted on the target (brief downtime)", restartCount)) + "\n")
+	}
+
 	return sb.String()
 }
 
@@ -1078,7 +1102,7 @@ func RenderImpactTableBalanced(sourceBefore, sourceAfter analyzer.NodeState, tar
 		}
 		for _, node := range cluster.Nodes {
 			if node.Name == nodeName {
-				return node.GetStatusIndicators()
+				return whatIfIndicators(&node)
 			}
 		}
 		return ""
@@ -1277,7 +1301,7 @@ func RenderImpactTableWithCursorBalanced(sourceBefore, sourceAfter analyzer.Node
 		}
 		for _, node := range cluster.Nodes {
 			if node.Name == nodeName {
-				return node.GetStatusIndicators()
+				return whatIfIndicators(&node)
 			}
 		}
 		return ""
@@ -1498,3 +1522,16 @@ func truncate(s string, maxLen int) string {
 	}
 	return s[:maxLen-3] + "..."
 }
+
+// whatIfIndicators returns the node's status indicators, plus "sim" or
+// "removed" for the nodes of a what-if simulation
+func whatIfIndicators(node *proxmox.Node) string {
+	indicators := node.GetStatusIndicators()
+	switch {
+	case node.Simulated:
+		indicators += ",sim"
+	case node.Removed:
+		indicators += ",removed"
+	}
+	return strings.TrimPrefix(indicators, ",")
+}
@@ -0,0 +1,27 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Color themes selectable in the config file
+const (
+	ThemeDefault = "default" // Colors as detected for the terminal
+	ThemeMono    = "mono"    // No colors, only bold/underline (for logs and limited terminals)
+)
+
+// ApplyTheme sets the color theme for all views
+// Must be called before the program starts rendering
+func ApplyTheme(name string) error {
+	switch name {
+	case "", ThemeDefault:
+		return nil
+	case ThemeMono:
+		lipgloss.SetColorProfile(termenv.Ascii)
+		return nil
+	}
+	return fmt.Errorf("unknown theme %q (available: %s, %s)", name, ThemeDefault, ThemeMono)
+}
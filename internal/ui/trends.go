@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/yourusername/migsug/internal/history"
+	"github.com/yourusername/migsug/internal/proxmox"
+	"github.com/yourusername/migsug/internal/ui/views"
+)
+
+// trendsWindows are the history windows the trends view cycles through, in days
+var trendsWindows = []int{7, 30, 90}
+
+// trendsLoadedMsg is sent when the trends report has been read from the history database
+type trendsLoadedMsg struct {
+	report *history.Report
+	err    error
+}
+
+// SetHistory enables recording refreshes and profile loads in the history
+// database and the trends view. name is the cluster the initial data is
+// recorded under when it doesn't belong to a profile.
+func (m *Model) SetHistory(store *history.Store, name string) {
+	m.history = store
+	m.historyName = name
+}
+
+// historyCluster returns the name the active cluster is recorded under
+func (m Model) historyCluster() string {
+	if m.activeProfile == "" || m.activeProfile == currentClusterName {
+		return m.historyName
+	}
+	return m.activeProfile
+}
+
+// recordHistory adds collected cluster data to the history database, if enabled
+// Safe to call from commands: the store serializes writers.
+func recordHistory(store *history.Store, name string, cluster *proxmox.Cluster) {
+	if store == nil || cluster == nil {
+		return
+	}
+	if err := store.Record(name, cluster); err != nil {
+		log.Printf("Failed to record history of %s: %v", name, err)
+	}
+}
+
+// openTrends shows the trends view and reads the report in the background
+func (m Model) openTrends() (tea.Model, tea.Cmd) {
+	m.currentView = ViewTrends
+	if m.trendsState.Days == 0 {
+		m.trendsState.Days = trendsWindows[1]
+	}
+	m.trendsState.ScrollPos = 0
+	if m.history == nil {
+		m.trends = nil
+		m.trendsState.Err = "History is disabled (history_file: off in the config file)"
+		return m, tea.ClearScreen
+	}
+	m.trendsState.Loading = true
+	m.trendsState.Err = ""
+	return m, tea.Batch(tea.ClearScreen, m.loadTrendsCmd())
+}
+
+// loadTrendsCmd builds the trends report of the active cluster
+func (m Model) loadTrendsCmd() tea.Cmd {
+	store, name, policy := m.history, m.historyCluster(), m.policy
+	since := time.Now().AddDate(0, 0, -m.trendsState.Days)
+	return func() tea.Msg {
+		report, err := store.Report(name, since, policy)
+		return trendsLoadedMsg{report: report, err: err}
+	}
+}
+
+// handleTrendsKeys handles keyboard input for the trends view
+func (m Model) handleTrendsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := views.TrendsRows(m.trends, m.trendsState.Section)
+	pageSize := views.TrendsVisibleRows(m.height)
+	maxScroll := max(rows-pageSize, 0)
+
+	switch msg.String() {
+	case "tab":
+		m.trendsState.Section = (m.trendsState.Section + 1) % views.TrendsSectionCount
+		m.trendsState.ScrollPos = 0
+	case "shift+tab":
+		m.trendsState.Section = (m.trendsState.Section + views.TrendsSectionCount - 1) % views.TrendsSectionCount
+		m.trendsState.ScrollPos = 0
+	case "up", "k":
+		m.trendsState.ScrollPos = max(min(m.trendsState.ScrollPos, maxScroll)-1, 0)
+	case "down", "j":
+		m.trendsState.ScrollPos = min(m.trendsState.ScrollPos+1, maxScroll)
+	case "pgup":
+		m.trendsState.ScrollPos = max(min(m.trendsState.ScrollPos, maxScroll)-pageSize, 0)
+	case "pgdown":
+		m.trendsState.ScrollPos = min(m.trendsState.ScrollPos+pageSize, maxScroll)
+	case "home":
+		m.trendsState.ScrollPos = 0
+	case "end":
+		m.trendsState.ScrollPos = maxScroll
+	case "w":
+		next := trendsWindows[0]
+		for i, days := range trendsWindows {
+			if days == m.trendsState.Days {
+				next = trendsWindows[(i+1)%len(trendsWindows)]
+			}
+		}
+		m.trendsState.Days = next
+		return m.openTrends()
+	case "r":
+		return m.openTrends()
+	case "esc":
+		m.currentView = ViewDashboard
+		return m, tea.ClearScreen
+	}
+	return m, nil
+}
@@ -0,0 +1,98 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ClusterOverviewRow is one cluster profile on the overview screen
+type ClusterOverviewRow struct {
+	Name        string
+	Active      bool   // Cluster currently shown on the dashboard
+	Loading     bool   // Data is being collected
+	Err         string // Connection or collection error
+	Summary     map[string]interface{}
+	CollectedAt time.Time
+	Attention   string // Why the cluster needs attention (empty = OK)
+}
+
+// RenderClusterOverview renders all cluster profiles side by side
+// Rows are expected in attention order, most urgent first
+func RenderClusterOverview(rows []ClusterOverviewRow, cursor int, status string, width int) string {
+	var sb strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	focusStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("4"))
+
+	if width < 80 {
+		width = 100
+	}
+
+	sb.WriteString(titleStyle.Render("Cluster Overview") + "\n")
+	sb.WriteString(strings.Repeat("━", width) + "\n\n")
+
+	sb.WriteString(headerStyle.Render(fmt.Sprintf("  %-20s %-9s %-7s %-7s %-7s %-9s %-16s %s",
+		"Cluster", "Nodes", "VMs", "CPU%", "RAM%", "Storage%", "Collected", "Status")) + "\n")
+
+	for i, row := range rows {
+		marker := "  "
+		if row.Active {
+			marker = "▸ "
+		}
+		name := row.Name
+		if len(name) > 20 {
+			name = name[:19] + "…"
+		}
+
+		var line, status string
+		var statusStyle lipgloss.Style
+		switch {
+		case row.Loading:
+			line = fmt.Sprintf("%s%-20s %-9s %-7s %-7s %-7s %-9s %-16s ", marker, name, "-", "-", "-", "-", "-", "-")
+			status, statusStyle = "⟳ Loading...", dimStyle
+		case row.Err != "":
+			line = fmt.Sprintf("%s%-20s %-9s %-7s %-7s %-7s %-9s %-16s ", marker, name, "-", "-", "-", "-", "-", "-")
+			status, statusStyle = "✗ "+row.Err, errorStyle
+		case row.Summary == nil:
+			line = fmt.Sprintf("%s%-20s %-9s %-7s %-7s %-7s %-9s %-16s ", marker, name, "-", "-", "-", "-", "-", "-")
+			status, statusStyle = "Not loaded", dimStyle
+		default:
+			nodes := fmt.Sprintf("%v/%v", row.Summary["online_nodes"], row.Summary["total_nodes"])
+			line = fmt.Sprintf("%s%-20s %-9s %-7v %-7.1f %-7.1f %-9.1f %-16s ", marker, name, nodes,
+				row.Summary["total_vms"], row.Summary["avg_cpu_percent"], row.Summary["mem_percent"],
+				row.Summary["storage_percent"], row.CollectedAt.Local().Format("2006-01-02 15:04"))
+			if row.Attention != "" {
+				status, statusStyle = "⚠ "+row.Attention, warnStyle
+			} else {
+				status, statusStyle = "✓ OK", okStyle
+			}
+		}
+
+		// Keep long errors on one line
+		if maxStatus := width - len([]rune(line)); maxStatus > 10 && len([]rune(status)) > maxStatus {
+			status = string([]rune(status)[:maxStatus-1]) + "…"
+		}
+
+		if i == cursor {
+			sb.WriteString(focusStyle.Render(line+status) + "\n")
+		} else {
+			sb.WriteString(line + statusStyle.Render(status) + "\n")
+		}
+	}
+
+	sb.WriteString("\n")
+	if status != "" {
+		sb.WriteString(warnStyle.Render(status) + "\n")
+	}
+	sb.WriteString(dimStyle.Render("▸ = active cluster │ ↑/↓: Navigate │ Enter: Switch to cluster │ r: Reload all │ Esc: Back"))
+
+	return sb.String()
+}
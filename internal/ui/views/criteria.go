@@ -28,7 +28,11 @@ type CriteriaState struct {
 	ExcludeNodes   []string
 	CursorPosition int
 	InputFocused   bool
-	ErrorMessage   string // Validation error message to display
+	ErrorMessage   string                     // Validation error message to display
+	CPUMetric      proxmox.CPUMetric          // CPU usage the analysis uses: current or an RRD history statistic
+	Strategy       analyzer.PlacementStrategy // Ranks the targets that pass the hard checks (nil = default)
+	Optimize       bool                       // Balance the cluster with the global optimizer instead of the greedy passes
+	Budget         string                     // Cluster-wide balance budget from the config file ("" = unlimited)
 }
 
 // RenderCriteria renders the criteria selection view (without node data)
@@ -153,6 +157,38 @@ func RenderCriteriaFull(state CriteriaState, sourceNode string, node *proxmox.No
 		}
 	}
 
+	// CPU usage placement decisions are based on
+	metricStr := "current"
+	if !state.CPUMetric.IsCurrent() {
+		metricStr = state.CPUMetric.String() + " (RRD history)"
+	}
+	sb.WriteString("\n  CPU usage: " + valueStyle.Render(metricStr) + " " + dimStyle.Render("(h: current, hour/day/week avg or p95)") + "\n")
+
+	// Placement strategy ranking the targets
+	strategy := state.Strategy
+	if strategy == nil {
+		strategy = analyzer.DefaultStrategy
+	}
+	var strategyNames []string
+	for _, s := range analyzer.PlacementStrategies {
+		strategyNames = append(strategyNames, s.Name())
+	}
+	sb.WriteString("  Strategy:  " + valueStyle.Render(strategy.Name()) + " " + dimStyle.Render("(s: "+strings.Join(strategyNames, ", ")+")") + "\n")
+	sb.WriteString("             " + dimStyle.Render(strategy.Description()) + "\n")
+
+	// Engine of the cluster-wide balance (dashboard 'b')
+	engine := "greedy"
+	if state.Optimize {
+		engine = "optimizer"
+	}
+	sb.WriteString("  Balance:   " + valueStyle.Render(engine) + " " + dimStyle.Render("(o: greedy, optimizer - cluster-wide balance)") + "\n")
+	if state.Budget != "" {
+		sb.WriteString("             " + dimStyle.Render("Budget: "+state.Budget) + "\n")
+	}
+	if !state.InputFocused && state.ErrorMessage != "" {
+		sb.WriteString("  " + lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true).Render("⚠ "+state.ErrorMessage) + "\n")
+	}
+
 	sb.WriteString("\n")
 	sb.WriteString(borderStyle.Render(strings.Repeat(criteriaBoxThin, width)) + "\n\n")
 
@@ -232,7 +268,7 @@ func RenderCriteriaFull(state CriteriaState, sourceNode string, node *proxmox.No
 	if state.InputFocused {
 		sb.WriteString(helpStyle.Render("Type value │ Enter: Confirm │ Esc: Cancel input"))
 	} else {
-		sb.WriteString(helpStyle.Render("↑/↓: Navigate │ Enter: Select mode │ Esc: Back to host selection │ q: Quit"))
+		sb.WriteString(helpStyle.Render("↑/↓: Navigate │ Enter: Select mode │ h: CPU usage │ s: Strategy │ o: Balance │ Esc: Back to host selection │ q: Quit"))
 	}
 
 	return sb.String()
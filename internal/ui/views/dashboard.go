@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/yourusername/migsug/internal/analyzer"
 	"github.com/yourusername/migsug/internal/proxmox"
 	"github.com/yourusername/migsug/internal/ui/components"
 )
@@ -50,6 +51,9 @@ func RenderDashboardWithHeight(cluster *proxmox.Cluster, selectedIdx int, width,
 	if version != "" && version != "dev" {
 		title += " " + versionStyle.Render("v"+version)
 	}
+	if progress.Cluster != "" {
+		title += " " + versionStyle.Render("│ Cluster: "+progress.Cluster)
+	}
 	sb.WriteString(titleStyle.Render(title) + "\n")
 
 	// Graphical top border
@@ -60,6 +64,14 @@ func RenderDashboardWithHeight(cluster *proxmox.Cluster, selectedIdx int, width,
 	sb.WriteString(renderEnhancedClusterSummary(cluster, width))
 	sb.WriteString("\n")
 
+	// Failure simulation panel
+	failureLines := 0
+	if progress.Failures != nil {
+		panel := components.RenderFailureSimulation(progress.Failures, width)
+		sb.WriteString(panel + "\n\n")
+		failureLines = lipgloss.Height(panel) + 1
+	}
+
 	// Instructions
 	sb.WriteString("Select source node to migrate from:\n\n")
 
@@ -74,9 +86,17 @@ func RenderDashboardWithHeight(cluster *proxmox.Cluster, selectedIdx int, width,
 	// - Separator: 1 line
 	// - Refresh status: 1 line
 	// - Status flags legend: 1 line
+	// - Capacity limits (if set): 1 line
 	// - Help text: 1 line
-	// Total: 15 lines
-	fixedOverhead := 15
+	// - Failure simulation panel (if shown)
+	// Total: 15 lines (16 with limits)
+	fixedOverhead := 15 + failureLines
+	if progress.Limits != "" {
+		fixedOverhead++
+	}
+	if progress.WhatIf != nil {
+		fixedOverhead++
+	}
 	maxVisibleNodes := height - fixedOverhead
 	if maxVisibleNodes < 3 {
 		maxVisibleNodes = 3
@@ -124,6 +144,9 @@ func RenderDashboardWithHeight(cluster *proxmox.Cluster, selectedIdx int, width,
 		} else {
 			sb.WriteString(refreshStyle.Render("⟳ Refreshing cluster data...") + "\n")
 		}
+	} else if progress.Snapshot != "" {
+		sb.WriteString(refreshStyle.Render(fmt.Sprintf("⏸ Offline snapshot %s, collected %s", progress.Snapshot, cluster.CollectedAt.Local().Format("2006-01-02 15:04"))) + "  ")
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0")).Render("(Press 'r' to reload)") + "\n")
 	} else if countdown > 0 {
 		sb.WriteString(refreshStyle.Render(fmt.Sprintf("⟳ Auto-refresh in %ds", countdown)) + "  ")
 		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0")).Render("(Press 'r' to refresh now)") + "\n")
@@ -131,22 +154,41 @@ func RenderDashboardWithHeight(cluster *proxmox.Cluster, selectedIdx int, width,
 
 	// Status flags legend
 	flagStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
-	sb.WriteString(flagStyle.Render("Status flags: O=OSD, P=Provisioning Enabled, C=Create Date 90+ days") + "\n")
+	sb.WriteString(flagStyle.Render(fmt.Sprintf("Status flags: O=OSD, P=Provisioning Enabled, C=Create Date %d+ days", proxmox.RecentlyCreatedThresholdDays)) + "\n")
+	if progress.Limits != "" {
+		sb.WriteString(flagStyle.Render("Capacity limits: "+progress.Limits) + "\n")
+	}
+	if progress.WhatIf != nil {
+		sb.WriteString(whatIfStyle.Render("⚗ What-if simulation: "+progress.WhatIf.Summary()+" - not real nodes, plans can't be executed") + "\n")
+	}
 
 	// Help text
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
-	sb.WriteString(helpStyle.Render("↑/↓/PgUp/PgDn/Home/End: Navigate │ 1-8: Sort columns │ Enter: Select │ B: Balance Cluster │ r: Refresh │ q: Quit"))
+	help := "↑/↓/PgUp/PgDn/Home/End: Navigate │ 1-8: Sort columns │ Enter: Select │ B: Balance Cluster │ D: Drain │ A: Audit │ F: Failures │ T: Trends │ r: Refresh │ q: Quit"
+	if progress.Cluster != "" {
+		help = strings.Replace(help, "r: Refresh", "r: Refresh │ c: Clusters", 1)
+	}
+	sb.WriteString(helpStyle.Render(help))
 
 	return sb.String()
 }
 
 // RefreshProgress contains progress info for display
 type RefreshProgress struct {
-	Stage   string
-	Current int
-	Total   int
+	Stage    string
+	Current  int
+	Total    int
+	Snapshot string // Snapshot file the data was loaded from (offline mode)
+	Limits   string // Active capacity limits shown below the status flags (empty = hidden)
+	Cluster  string // Active cluster profile; enables the 'c' switcher hint (empty = single cluster)
+
+	Failures *analyzer.FailureAnalysis // Failure simulation panel (nil = hidden)
+	WhatIf   *analyzer.WhatIfInfo      // Hypothetical nodes in the cluster (nil = real cluster)
 }
 
+// whatIfStyle marks simulated data on the dashboard and in the results
+var whatIfStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Bold(true)
+
 // SortInfo contains sorting information for display
 type SortInfo struct {
 	Column    int // 0-7 for columns 1-8
@@ -180,6 +222,9 @@ func RenderDashboardWithSort(cluster *proxmox.Cluster, selectedIdx int, width in
 	if version != "" && version != "dev" {
 		title += " " + versionStyle.Render("v"+version)
 	}
+	if progress.Cluster != "" {
+		title += " " + versionStyle.Render("│ Cluster: "+progress.Cluster)
+	}
 	sb.WriteString(titleStyle.Render(title) + "\n")
 
 	// Graphical top border
@@ -216,6 +261,9 @@ func RenderDashboardWithSort(cluster *proxmox.Cluster, selectedIdx int, width in
 		} else {
 			sb.WriteString(refreshStyle.Render("⟳ Refreshing cluster data...") + "\n")
 		}
+	} else if progress.Snapshot != "" {
+		sb.WriteString(refreshStyle.Render(fmt.Sprintf("⏸ Offline snapshot %s, collected %s", progress.Snapshot, cluster.CollectedAt.Local().Format("2006-01-02 15:04"))) + "  ")
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0")).Render("(Press 'r' to reload)") + "\n")
 	} else if countdown > 0 {
 		sb.WriteString(refreshStyle.Render(fmt.Sprintf("⟳ Auto-refresh in %ds", countdown)) + "  ")
 		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0")).Render("(Press 'r' to refresh now)") + "\n")
@@ -223,11 +271,21 @@ func RenderDashboardWithSort(cluster *proxmox.Cluster, selectedIdx int, width in
 
 	// Status flags legend
 	flagStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
-	sb.WriteString(flagStyle.Render("Status flags: O=OSD, P=Provisioning Enabled, C=Create Date 90+ days") + "\n")
+	sb.WriteString(flagStyle.Render(fmt.Sprintf("Status flags: O=OSD, P=Provisioning Enabled, C=Create Date %d+ days", proxmox.RecentlyCreatedThresholdDays)) + "\n")
+	if progress.Limits != "" {
+		sb.WriteString(flagStyle.Render("Capacity limits: "+progress.Limits) + "\n")
+	}
+	if progress.WhatIf != nil {
+		sb.WriteString(whatIfStyle.Render("⚗ What-if simulation: "+progress.WhatIf.Summary()+" - not real nodes, plans can't be executed") + "\n")
+	}
 
 	// Help text
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
-	sb.WriteString(helpStyle.Render("↑/↓/PgUp/PgDn/Home/End: Navigate │ 1-8: Sort columns │ Enter: Select │ B: Balance Cluster │ r: Refresh │ q: Quit"))
+	help := "↑/↓/PgUp/PgDn/Home/End: Navigate │ 1-8: Sort columns │ Enter: Select │ B: Balance Cluster │ D: Drain │ A: Audit │ F: Failures │ T: Trends │ r: Refresh │ q: Quit"
+	if progress.Cluster != "" {
+		help = strings.Replace(help, "r: Refresh", "r: Refresh │ c: Clusters", 1)
+	}
+	sb.WriteString(helpStyle.Render(help))
 
 	return sb.String()
 }
@@ -469,6 +527,23 @@ func RenderDashboardHostDetailWithInput(node *proxmox.Node, cluster *proxmox.Clu
 	sb.WriteString(labelStyle.Render("Storage: ") + valueStyle.Render(storageValStr) + " " + lipgloss.NewStyle().Foreground(lipgloss.Color(storageColor)).Render(storagePctStr))
 	sb.WriteString("\n")
 
+	// Row 3: per-pool storage, only when more than one pool is counted
+	poolLines := 0
+	if len(node.Storages) > 1 {
+		var pools []string
+		for _, pool := range node.Storages {
+			desc := pool.Type
+			if pool.Shared {
+				desc += ", shared"
+			}
+			pools = append(pools, fmt.Sprintf("%s (%s) %.0f/%.0f GiB", pool.Name, desc,
+				float64(pool.Used)/(1024*1024*1024), float64(pool.Total)/(1024*1024*1024)))
+		}
+		sb.WriteString(labelStyle.Render("  Pools: ") + dimStyle.Render(strings.Join(pools, " │ ")))
+		sb.WriteString("\n")
+		poolLines = 1
+	}
+
 	// Mode options - "Balance Cluster" is last
 	modes := []struct {
 		name string
@@ -488,7 +563,7 @@ func RenderDashboardHostDetailWithInput(node *proxmox.Node, cluster *proxmox.Clu
 	// Reserve: title(2) + border(1) + cluster summary(2) + source node header(1) + host info(2) + blank(1)
 	//          + VM header+sep(2) + VM closing(1) + modes header+sep(2) + modes(len) + modes closing(1)
 	//          + input area(3) + help(1) + buffer(2)
-	fixedOverhead := 2 + 1 + 2 + 1 + 2 + 1 + 2 + 1 + 2 + len(modes) + 1 + 3 + 1 + 2
+	fixedOverhead := 2 + 1 + 2 + 1 + 2 + poolLines + 1 + 2 + 1 + 2 + len(modes) + 1 + 3 + 1 + 2
 	availableHeight := height - fixedOverhead
 
 	// VM list height - use all available space (no cap)
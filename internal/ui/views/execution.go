@@ -0,0 +1,253 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/yourusername/migsug/internal/analyzer"
+	"github.com/yourusername/migsug/internal/executor"
+)
+
+// ExecuteConfirmState holds the settings edited on the confirmation screen
+type ExecuteConfirmState struct {
+	Limits       executor.Limits
+	FocusedLimit int                       // 0 = per source, 1 = per target
+	Estimate     analyzer.EstimateSettings // Throughput used for the duration estimate
+	RunUnsafe    bool                      // Also run the wave that could not be ordered within the limits
+}
+
+// RenderExecuteConfirm renders the confirmation screen shown before a plan is executed
+func RenderExecuteConfirm(result *analyzer.AnalysisResult, state ExecuteConfirmState, width, height int) string {
+	var sb strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	warnStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
+	focusStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("4"))
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+
+	sb.WriteString(titleStyle.Render("Execute Migration Plan") + "\n")
+	sb.WriteString(strings.Repeat("━", width) + "\n\n")
+
+	var runnable []analyzer.MigrationSuggestion
+	var waves []int // Wave of each runnable migration
+	skipped := 0
+	for _, sug := range result.Suggestions {
+		if sug.TargetNode == "" || sug.TargetNode == "NONE" {
+			skipped++
+			continue
+		}
+		if result.Staged == nil {
+			runnable = append(runnable, sug)
+			waves = append(waves, 0)
+		}
+	}
+	unsafe := 0
+	if result.Staged != nil {
+		for i, wave := range result.Staged.Waves {
+			if wave.Unsafe && !state.RunUnsafe {
+				unsafe += len(wave.Migrations)
+				continue
+			}
+			for _, m := range wave.Migrations {
+				runnable = append(runnable, m.MigrationSuggestion)
+				waves = append(waves, i)
+			}
+		}
+	}
+
+	sb.WriteString(warnStyle.Render(fmt.Sprintf("This will start %d migrations on the cluster.", len(runnable))) + "\n")
+	if skipped > 0 {
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("%d VMs without a target will be skipped.", skipped)) + "\n")
+	}
+	if result.Staged != nil {
+		sb.WriteString(valueStyle.Render(fmt.Sprintf("Execution order: %s. Each wave starts when the previous one has finished.", result.Staged.Summary())) + "\n")
+		if result.Staged.HasUnsafe() {
+			sb.WriteString(warnStyle.Render("The last wave could not be ordered within the capacity limits.") + "\n")
+			if state.RunUnsafe {
+				sb.WriteString(warnStyle.Render("It will run anyway and may push hosts past the limits (u: skip it).") + "\n")
+			} else {
+				sb.WriteString(dimStyle.Render(fmt.Sprintf("Migrations of that wave skipped: %d (u: run them anyway).", unsafe)) + "\n")
+			}
+		}
+	}
+	restarts := 0
+	for _, sug := range runnable {
+		if sug.NeedsRestart() {
+			restarts++
+		}
+	}
+	if restarts > 0 {
+		sb.WriteString(warnStyle.Render(fmt.Sprintf("%d running LXC containers will be restarted on the target (downtime).", restarts)) + "\n")
+	}
+	sb.WriteString("\n")
+
+	// Concurrency limits
+	sb.WriteString(headerStyle.Render("Concurrency limits") + "\n")
+	limitLabels := []string{"Per source node", "Per target node"}
+	limitValues := []int{state.Limits.PerSource, state.Limits.PerTarget}
+	for i, label := range limitLabels {
+		value := "unlimited"
+		if limitValues[i] > 0 {
+			value = fmt.Sprintf("%d", limitValues[i])
+		}
+		line := fmt.Sprintf("  %-18s %s", label+":", value)
+		if i == state.FocusedLimit {
+			sb.WriteString(focusStyle.Render(line) + "\n")
+		} else {
+			sb.WriteString(valueStyle.Render(line) + "\n")
+		}
+	}
+
+	// Estimate follows the limits as they are adjusted
+	est := state.Estimate.EstimateResult(result, state.Limits.PerSource, state.Limits.PerTarget)
+	sb.WriteString(valueStyle.Render(fmt.Sprintf("  %-18s %s (%s one at a time)", "Estimated time:",
+		analyzer.FormatDuration(est.WallClock), analyzer.FormatDuration(est.Sequential))) + "\n")
+	sb.WriteString("\n")
+
+	// Migration list
+	sb.WriteString(headerStyle.Render(fmt.Sprintf("%-5s %-8s %-24s %-10s %-14s %-14s %-8s", "Wave", "VMID", "Name", "Mode", "Source", "Target", "Est.")) + "\n")
+	maxRows := height - 21
+	if maxRows < 3 {
+		maxRows = 3
+	}
+	for i, sug := range runnable {
+		if i >= maxRows {
+			sb.WriteString(dimStyle.Render(fmt.Sprintf("  ... and %d more", len(runnable)-maxRows)) + "\n")
+			break
+		}
+		mode := "offline"
+		if sug.NeedsRestart() {
+			mode = "restart"
+		} else if sug.Status == "running" {
+			mode = "live"
+		}
+		sb.WriteString(valueStyle.Render(fmt.Sprintf("%-5d %-8d %-24s %-10s %-14s %-14s %-8s",
+			waves[i]+1, sug.VMID, truncateString(sug.VMName, 24), mode, sug.SourceNode, sug.TargetNode,
+			analyzer.FormatDuration(est.Migrations[i].Duration))) + "\n")
+	}
+
+	sb.WriteString("\n")
+	help := "↑/↓: Select limit  +/-: Adjust (0 = unlimited)  y: Start migrations  Esc: Cancel"
+	if result.Staged.HasUnsafe() {
+		help = "↑/↓: Select limit  +/-: Adjust (0 = unlimited)  u: Unsafe wave  y: Start migrations  Esc: Cancel"
+	}
+	sb.WriteString(dimStyle.Render(help))
+
+	return sb.String()
+}
+
+// RenderExecution renders live progress of a running (or finished) plan execution
+func RenderExecution(exec *executor.Executor, width, height, scrollPos int) string {
+	var sb strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("15"))
+	runningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	jobs := exec.Snapshot()
+	counts := executor.Counts(jobs)
+
+	title := "Executing Migrations"
+	if exec.Done() {
+		title = "Migrations Finished"
+	} else if exec.Cancelled() {
+		title = "Executing Migrations (cancelling)"
+	}
+	sb.WriteString(titleStyle.Render(title) + "\n")
+	sb.WriteString(strings.Repeat("━", width) + "\n\n")
+
+	limits := exec.Limits()
+	sb.WriteString(valueStyle.Render(fmt.Sprintf("Elapsed: %s   Done: %d   Running: %d   Pending: %d   Failed: %d   Cancelled: %d",
+		formatElapsed(exec.Elapsed()), counts[executor.JobSucceeded], counts[executor.JobRunning],
+		counts[executor.JobPending], counts[executor.JobFailed], counts[executor.JobCancelled])) + "\n")
+	sb.WriteString(dimStyle.Render(fmt.Sprintf("Limits: %s per source, %s per target",
+		formatLimit(limits.PerSource), formatLimit(limits.PerTarget))) + "\n\n")
+
+	sb.WriteString(headerStyle.Render(fmt.Sprintf("%-5s %-8s %-20s %-12s %-12s %-10s %-8s %s", "Wave", "VMID", "Name", "Source", "Target", "Status", "Time", "Details")) + "\n")
+
+	// Build rows, then apply scrolling
+	lines := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		sug := job.Suggestion
+		style := dimStyle
+		switch job.State {
+		case executor.JobRunning:
+			style = runningStyle
+		case executor.JobSucceeded:
+			style = okStyle
+		case executor.JobFailed:
+			style = failStyle
+		}
+		elapsed := ""
+		if !job.StartedAt.IsZero() {
+			elapsed = formatElapsed(job.Elapsed())
+		}
+		detail := job.Error
+		if detail == "" && job.State == executor.JobRunning {
+			detail = job.UPID
+		}
+		line := fmt.Sprintf("%-5d %-8d %-20s %-12s %-12s %-10s %-8s %s",
+			job.Wave+1, sug.VMID, truncateString(sug.VMName, 20), truncateString(sug.SourceNode, 12), truncateString(sug.TargetNode, 12),
+			job.State, elapsed, detail)
+		if width > 0 && len(line) > width {
+			line = line[:width]
+		}
+		lines = append(lines, style.Render(line))
+	}
+
+	availableHeight := height - 10
+	if availableHeight < 5 {
+		availableHeight = 5
+	}
+	startLine := scrollPos
+	if startLine > len(lines)-availableHeight {
+		startLine = len(lines) - availableHeight
+	}
+	if startLine < 0 {
+		startLine = 0
+	}
+	endLine := startLine + availableHeight
+	if endLine > len(lines) {
+		endLine = len(lines)
+	}
+	for i := startLine; i < endLine; i++ {
+		sb.WriteString(lines[i] + "\n")
+	}
+	if len(lines) > availableHeight {
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("Lines %d-%d of %d (↑/↓ to scroll)", startLine+1, endLine, len(lines))) + "\n")
+	}
+
+	sb.WriteString("\n")
+	if exec.Done() {
+		sb.WriteString(dimStyle.Render("↑/↓: Scroll  Esc: Back to results"))
+	} else {
+		sb.WriteString(dimStyle.Render("↑/↓: Scroll  c: Cancel remaining (running migrations continue on Proxmox untracked)"))
+	}
+
+	return sb.String()
+}
+
+// formatElapsed formats a duration as m:ss or h:mm:ss
+func formatElapsed(d time.Duration) string {
+	secs := int(d.Seconds())
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, (secs%3600)/60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// formatLimit formats a concurrency limit (0 = unlimited)
+func formatLimit(n int) string {
+	if n <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d", n)
+}
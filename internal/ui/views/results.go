@@ -78,8 +78,27 @@ func RenderResultsWithSource(result *analyzer.AnalysisResult, cluster *proxmox.C
 		sb.WriteString("\n")
 	}
 
+	// Constraint audit findings
+	auditLines := 0
+	if audit := components.RenderConstraintAudit(result.Audit); audit != "" {
+		sb.WriteString(audit + "\n\n")
+		auditLines = lipgloss.Height(audit) + 1
+	}
+	if result.WhatIf != nil {
+		sb.WriteString(whatIfStyle.Render("⚗ What-if simulation: "+result.WhatIf.Summary()+" - simulated nodes don't exist, this plan can't be executed") + "\n\n")
+		auditLines += 2
+	}
+	if result.CPUMetric != "" {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Render("CPU usage: "+result.CPUMetric+" from the RRD history") + "\n\n")
+		auditLines += 2
+	}
+
 	// Summary
 	if len(result.Suggestions) == 0 {
+		if result.Audit != nil {
+			sb.WriteString("No migrations planned.\n\n")
+			return sb.String()
+		}
 		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
 		sb.WriteString(errorStyle.Render("No migration suggestions generated.") + "\n")
 		sb.WriteString("This might mean:\n")
@@ -98,10 +117,24 @@ func RenderResultsWithSource(result *analyzer.AnalysisResult, cluster *proxmox.C
 		result.ImprovementInfo,
 		result.MovementsTried,
 	))
+	if result.Estimate != nil {
+		sb.WriteString("\n" + components.RenderMigrationEstimate(result.Estimate))
+	}
+	if result.Optimization != nil {
+		sb.WriteString("\n" + components.RenderOptimization(result.Optimization))
+	}
+	if result.Budget != nil {
+		sb.WriteString("\n" + components.RenderBalanceBudget(result.Budget))
+	}
+	affinityLines := 0
+	if affinity := components.RenderAffinityViolations(result.AffinityViolations); affinity != "" {
+		sb.WriteString("\n" + affinity)
+		affinityLines = lipgloss.Height(affinity)
+	}
 	sb.WriteString("\n\n")
 
 	// Calculate visible rows based on terminal height and number of target nodes
-	maxVisible := calculateVisibleRowsWithTargets(height, activeTargets)
+	maxVisible := calculateVisibleRowsWithTargets(height-affinityLines-auditLines, activeTargets)
 
 	// Suggestions table with scrolling (includes closing dashes)
 	sb.WriteString(components.RenderSuggestionTableWithCursor(result.Suggestions, scrollPos, maxVisible, cursorPos))
@@ -181,8 +214,27 @@ func RenderResultsInteractive(result *analyzer.AnalysisResult, cluster *proxmox.
 		sb.WriteString("\n")
 	}
 
+	// Constraint audit findings
+	auditLines := 0
+	if audit := components.RenderConstraintAudit(result.Audit); audit != "" {
+		sb.WriteString(audit + "\n\n")
+		auditLines = lipgloss.Height(audit) + 1
+	}
+	if result.WhatIf != nil {
+		sb.WriteString(whatIfStyle.Render("⚗ What-if simulation: "+result.WhatIf.Summary()+" - simulated nodes don't exist, this plan can't be executed") + "\n\n")
+		auditLines += 2
+	}
+	if result.CPUMetric != "" {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Render("CPU usage: "+result.CPUMetric+" from the RRD history") + "\n\n")
+		auditLines += 2
+	}
+
 	// No suggestions case
 	if len(result.Suggestions) == 0 {
+		if result.Audit != nil {
+			sb.WriteString("No migrations planned.\n\n")
+			return sb.String()
+		}
 		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
 		sb.WriteString(errorStyle.Render("No migration suggestions generated.") + "\n")
 		sb.WriteString("This might mean:\n")
@@ -201,10 +253,24 @@ func RenderResultsInteractive(result *analyzer.AnalysisResult, cluster *proxmox.
 		result.TotalRAM,
 		result.TotalStorage,
 	))
+	if result.Estimate != nil {
+		sb.WriteString("\n" + components.RenderMigrationEstimate(result.Estimate))
+	}
+	if result.Optimization != nil {
+		sb.WriteString("\n" + components.RenderOptimization(result.Optimization))
+	}
+	if result.Budget != nil {
+		sb.WriteString("\n" + components.RenderBalanceBudget(result.Budget))
+	}
+	affinityLines := 0
+	if affinity := components.RenderAffinityViolations(result.AffinityViolations); affinity != "" {
+		sb.WriteString("\n" + affinity)
+		affinityLines = lipgloss.Height(affinity)
+	}
 	sb.WriteString("\n\n")
 
 	// Calculate visible rows
-	maxVisible := calculateVisibleRowsWithTargets(height, activeTargets)
+	maxVisible := calculateVisibleRowsWithTargets(height-affinityLines-auditLines, activeTargets)
 
 	// Suggestions table
 	if focusedSection == 0 {
@@ -255,7 +321,11 @@ func RenderResultsInteractive(result *analyzer.AnalysisResult, cluster *proxmox.
 
 	// Help text with TAB instruction
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
-	sb.WriteString("\n" + helpStyle.Render("Tab: Switch section  ↑/↓: Navigate  Enter: Details  m: Commands  r: New Analysis  Esc: Back  q: Quit"))
+	help := "Tab: Switch section  ↑/↓: Navigate  Enter: Details  m: Commands  x: Execute  e/E: Export JSON/YAML  r: New Analysis  Esc: Back  q: Quit"
+	if result.Drain != nil {
+		help = "Tab: Switch section  ↑/↓: Navigate  Enter: Details  m: Commands  x: Execute  e/E: Export JSON/YAML  0-3: Set hoststate  r: New Analysis  Esc: Back  q: Quit"
+	}
+	sb.WriteString("\n" + helpStyle.Render(help))
 
 	return sb.String()
 }
@@ -426,7 +496,7 @@ type VMListItem struct {
 	CPUUsage  float64 // CPU usage percentage
 	VCPUs     int
 	RAM       int64
-	Storage   int64  // Effective disk (UsedDisk if available, else MaxDisk)
+	Storage   int64  // Local disk, as counted against the target
 	UsedDisk  int64  // Actual disk usage (thin provisioning)
 	MaxDisk   int64  // Allocated/provisioned disk size
 	Direction string // "←" for out, "→" for in, "✗" for cannot migrate, "" for staying
@@ -545,7 +615,7 @@ func RenderHostDetailWithReasoningScroll(result *analyzer.AnalysisResult, cluste
 					CPUUsage: vm.CPUUsage,
 					VCPUs:    vm.CPUCores,
 					RAM:      vm.MaxMem,
-					Storage:  vm.GetEffectiveDisk(),
+					Storage:  vm.GetLocalDisk(),
 					UsedDisk: vm.UsedDisk,
 					MaxDisk:  vm.MaxDisk,
 				}
@@ -583,7 +653,7 @@ func RenderHostDetailWithReasoningScroll(result *analyzer.AnalysisResult, cluste
 					CPUUsage: vm.CPUUsage,
 					VCPUs:    vm.CPUCores,
 					RAM:      vm.MaxMem,
-					Storage:  vm.GetEffectiveDisk(),
+					Storage:  vm.GetLocalDisk(),
 					UsedDisk: vm.UsedDisk,
 					MaxDisk:  vm.MaxDisk,
 				}
@@ -1033,7 +1103,19 @@ func renderMigrationReasoningLines(vm VMListItem, currentHost string) []string {
 		if details.ScoreBreakdown.HeadroomScore != 0 {
 			scoreStr += fmt.Sprintf(", Headroom: %.1f", details.ScoreBreakdown.HeadroomScore)
 		}
+		if details.ScoreBreakdown.Strategy != "" {
+			scoreStr += " (" + details.ScoreBreakdown.Strategy + " strategy)"
+		}
 		lines = append(lines, "  "+valueStyle.Render(scoreStr))
+
+		// Terms of the strategy's score: value × weight
+		var terms []string
+		for _, c := range details.ScoreBreakdown.Components {
+			terms = append(terms, fmt.Sprintf("%s %.1f×%g", c.Name, c.Value, c.Weight))
+		}
+		if len(terms) > 0 {
+			lines = append(lines, "  "+dimStyle.Render(strings.Join(terms, " + ")))
+		}
 	}
 
 	// Host CPU info - show source and target
@@ -1235,12 +1317,12 @@ func calculateVisibleRowsWithTargets(height, numTargets int) int {
 	// - Title + border + blank: 3 lines
 	// - Cluster summary + blank: 3 lines
 	// - Source node summary + blank: 4 lines
-	// - Migration summary + 2 blanks: 3 lines
+	// - Migration summary + time estimate + 2 blanks: 4 lines
 	// - Suggestions table header + separator: 2 lines
 	// - Suggestions table closing dashes: 1 line
 	// - Scroll info (below table): 1 line
 	// - Help text + buffer: 2 lines
-	headerOverhead := 3 + 3 + 4 + 3 + 2 + 1 + 1 + 2 // = 19 lines
+	headerOverhead := 3 + 3 + 4 + 4 + 2 + 1 + 1 + 2 // = 20 lines
 
 	// Content area (after header)
 	contentHeight := height - headerOverhead
@@ -1520,7 +1602,8 @@ func RenderVMSelectionWithHeight(vms []proxmox.VM, selectedVMs map[int]bool, cur
 }
 
 // RenderVMDetails renders the VM details overlay showing config file and collected data
-func RenderVMDetails(vm *proxmox.VM, nodeName string, vmid int, width, height, scrollPos int) string {
+// groups are the affinity groups from the config file
+func RenderVMDetails(vm *proxmox.VM, nodeName string, vmid int, groups []analyzer.AffinityGroup, width, height, scrollPos int) string {
 	var sb strings.Builder
 
 	// Styles
@@ -1568,8 +1651,8 @@ func RenderVMDetails(vm *proxmox.VM, nodeName string, vmid int, width, height, s
 			ramStr = fmt.Sprintf("%s used / %s allocated", components.FormatRAMShort(vm.UsedMem), components.FormatRAMShort(vm.MaxMem))
 		}
 
-		// Storage - use actual thin provisioning size
-		storageStr := components.FormatStorageG(vm.GetEffectiveDisk())
+		// Storage - local disks, as counted against the target
+		storageStr := components.FormatStorageG(vm.GetLocalDisk())
 
 		lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render("vCPUs:"), valueStyle.Render(fmt.Sprintf("%d", vm.CPUCores))))
 		lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render("CPU Usage:"), valueStyle.Render(fmt.Sprintf("%.1f%%", vm.CPUUsage))))
@@ -1602,6 +1685,21 @@ func RenderVMDetails(vm *proxmox.VM, nodeName string, vmid int, width, height, s
 		if len(vm.WithoutVM) > 0 {
 			lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render("WithoutVM:"), warnStyle.Render(strings.Join(vm.WithoutVM, ", ")+" (cannot be on same host)")))
 		}
+		for _, group := range groups {
+			if group.Contains(*vm) && group.Name != vm.Group {
+				lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render("Group:"), warnStyle.Render(group.String()+" (config file)")))
+			}
+		}
+		if vm.Group != "" {
+			group := vm.Group
+			if vm.GroupMode != "" {
+				group += ", " + vm.GroupMode
+			}
+			if vm.GroupMax > 0 {
+				group += fmt.Sprintf(", max %d per host", vm.GroupMax)
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s", labelStyle.Render("Group:"), warnStyle.Render(group+" (affinity group)")))
+		}
 
 		// Creation time
 		if vm.CreationTime > 0 {
@@ -1720,7 +1818,8 @@ func RenderMigrationCommands(result *analyzer.AnalysisResult, sourceNode string,
 
 	// Note about pvesh commands
 	sb.WriteString(noteStyle.Render("Note: Using pvesh API commands (works from any cluster node).") + "\n")
-	sb.WriteString(noteStyle.Render("      Running VMs use --online 1 for live migration.") + "\n\n")
+	sb.WriteString(noteStyle.Render("      Running VMs use --online 1 for live migration.") + "\n")
+	sb.WriteString(noteStyle.Render("      Running LXC containers use --restart 1 (stopped and restarted on the target).") + "\n\n")
 
 	// Build command list
 	lines := []string{}
@@ -1733,47 +1832,59 @@ func RenderMigrationCommands(result *analyzer.AnalysisResult, sourceNode string,
 		}
 	}
 
-	// Generate commands using pvesh (works from any node)
-	lines = append(lines, headerStyle.Render("# Individual migration commands:"))
-	for _, sug := range result.Suggestions {
-		if sug.TargetNode == "NONE" {
-			lines = append(lines, dimStyle.Render(fmt.Sprintf("# VMID %d (%s) - No suitable target found", sug.VMID, sug.VMName)))
-			continue
-		}
-
-		// Generate pvesh command (works from any cluster node)
-		// Format: pvesh create /nodes/{sourceNode}/qemu/{vmid}/migrate --target {targetNode} [--online 1]
-		cmd := fmt.Sprintf("pvesh create /nodes/%s/qemu/%d/migrate --target %s", sug.SourceNode, sug.VMID, sug.TargetNode)
-		if sug.Status == "running" {
-			cmd += " --online 1"
-		}
-
-		// Add comment with VM name
+	// commandLine renders a pvesh command (works from any cluster node) with the VM name and mode
+	commandLine := func(sug analyzer.MigrationSuggestion, temporary bool) string {
 		comment := fmt.Sprintf("  # %s", sug.VMName)
-		if sug.Status == "running" {
+		if sug.NeedsRestart() {
+			comment += " (restart, downtime)"
+		} else if sug.Status == "running" {
 			comment += " (live)"
 		} else {
 			comment += " (offline)"
 		}
+		if temporary {
+			comment += " - temporary, continues to its target later"
+		}
+		return cmdStyle.Render(sug.MigrateCommand()) + dimStyle.Render(comment)
+	}
 
-		lines = append(lines, cmdStyle.Render(cmd)+dimStyle.Render(comment))
+	var cmdParts []string
+	if result.Staged != nil {
+		// Waves in execution order: each wave runs after the previous one finished
+		lines = append(lines, headerStyle.Render("# Migration commands by wave ("+result.Staged.Summary()+"):"))
+		for i, wave := range result.Staged.Waves {
+			lines = append(lines, headerStyle.Render("# "+wave.Title(i)))
+			for _, m := range wave.Migrations {
+				if wave.Unsafe {
+					// Commented out and left out of the one-liner: review before running
+					lines = append(lines, dimStyle.Render("# "+m.MigrateCommand()+"  # "+m.VMName+" - unsafe, may push hosts past the limits"))
+					continue
+				}
+				lines = append(lines, commandLine(m.MigrationSuggestion, m.Temporary))
+				cmdParts = append(cmdParts, m.MigrateCommand())
+			}
+		}
+		for _, sug := range result.PlannedSuggestions() {
+			if sug.TargetNode == "NONE" {
+				lines = append(lines, dimStyle.Render(fmt.Sprintf("# VMID %d (%s) - No suitable target found", sug.VMID, sug.VMName)))
+			}
+		}
+	} else {
+		lines = append(lines, headerStyle.Render("# Individual migration commands:"))
+		for _, sug := range result.Suggestions {
+			if sug.TargetNode == "NONE" {
+				lines = append(lines, dimStyle.Render(fmt.Sprintf("# VMID %d (%s) - No suitable target found", sug.VMID, sug.VMName)))
+				continue
+			}
+			lines = append(lines, commandLine(sug, false))
+			cmdParts = append(cmdParts, sug.MigrateCommand())
+		}
 	}
 
 	lines = append(lines, "")
 	lines = append(lines, headerStyle.Render("# One-liner to migrate all (sequential):"))
 
 	// Build one-liner for all migrations
-	var cmdParts []string
-	for _, sug := range result.Suggestions {
-		if sug.TargetNode == "NONE" {
-			continue
-		}
-		cmd := fmt.Sprintf("pvesh create /nodes/%s/qemu/%d/migrate --target %s", sug.SourceNode, sug.VMID, sug.TargetNode)
-		if sug.Status == "running" {
-			cmd += " --online 1"
-		}
-		cmdParts = append(cmdParts, cmd)
-	}
 	if len(cmdParts) > 0 {
 		oneLiner := strings.Join(cmdParts, " && ")
 		// Split long one-liner into multiple lines for readability
@@ -1795,7 +1906,7 @@ func RenderMigrationCommands(result *analyzer.AnalysisResult, sourceNode string,
 	lines = append(lines, headerStyle.Render("# Summary:"))
 	successCount := 0
 	failedCount := 0
-	for _, sug := range result.Suggestions {
+	for _, sug := range result.PlannedSuggestions() {
 		if sug.TargetNode == "NONE" {
 			failedCount++
 		} else {
@@ -0,0 +1,162 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/yourusername/migsug/internal/history"
+)
+
+// Trends view sections, cycled with Tab
+const (
+	TrendsSectionHosts = iota
+	TrendsSectionVMs
+	TrendsSectionMoves
+	TrendsSectionCount
+)
+
+// TrendsState holds the trends view settings and scroll position
+type TrendsState struct {
+	Days      int // History window
+	Section   int // TrendsSection*
+	ScrollPos int
+	Loading   bool
+	Err       string
+}
+
+// TrendsRows returns the number of rows of a section, for scrolling
+func TrendsRows(report *history.Report, section int) int {
+	if report == nil {
+		return 0
+	}
+	switch section {
+	case TrendsSectionHosts:
+		return len(report.Hosts)
+	case TrendsSectionVMs:
+		return len(report.VMs)
+	case TrendsSectionMoves:
+		return len(report.Moves)
+	}
+	return 0
+}
+
+// TrendsVisibleRows returns how many table rows fit on the screen
+// Reserve: title(2) + summary(2) + tabs(2) + table header(1) + scroll info(2) + help(1)
+func TrendsVisibleRows(height int) int {
+	return max(height-10, 5)
+}
+
+// RenderTrends renders the growth per host and VM, the limit forecasts and the VM moves
+func RenderTrends(report *history.Report, state TrendsState, width, height int) string {
+	var sb strings.Builder
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	focusStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("4"))
+
+	if width < 80 {
+		width = 100
+	}
+
+	sb.WriteString(titleStyle.Render(fmt.Sprintf("Trends (last %d days)", state.Days)) + "\n")
+	sb.WriteString(strings.Repeat("━", width) + "\n")
+
+	help := dimStyle.Render("Tab: Section │ ↑/↓/PgUp/PgDn: Scroll │ w: Window (7/30/90 days) │ r: Reload │ Esc: Back")
+	switch {
+	case state.Loading:
+		sb.WriteString("\n" + dimStyle.Render("⟳ Reading history...") + "\n\n" + help)
+		return sb.String()
+	case state.Err != "":
+		sb.WriteString("\n" + errorStyle.Render("✗ "+state.Err) + "\n\n" + help)
+		return sb.String()
+	case report == nil || report.Collections == 0:
+		sb.WriteString("\n" + dimStyle.Render("No history recorded in this window yet. Every collection and refresh is recorded.") + "\n\n" + help)
+		return sb.String()
+	}
+
+	sb.WriteString(dimStyle.Render(fmt.Sprintf("%s: %d collections from %s to %s", report.Cluster, report.Collections,
+		report.First.Local().Format("2006-01-02 15:04"), report.Last.Local().Format("2006-01-02 15:04"))) + "\n\n")
+
+	// Section tabs
+	labels := []string{
+		fmt.Sprintf("Hosts & forecasts (%d)", len(report.Hosts)),
+		fmt.Sprintf("VM growth (%d)", len(report.VMs)),
+		fmt.Sprintf("VM moves (%d)", len(report.Moves)),
+	}
+	var tabs []string
+	for i, label := range labels {
+		if i == state.Section {
+			tabs = append(tabs, focusStyle.Render(" "+label+" "))
+		} else {
+			tabs = append(tabs, dimStyle.Render(" "+label+" "))
+		}
+	}
+	sb.WriteString(strings.Join(tabs, " ") + "\n\n")
+
+	rows := TrendsRows(report, state.Section)
+	visible := TrendsVisibleRows(height)
+	start := min(state.ScrollPos, max(rows-visible, 0))
+	end := min(start+visible, rows)
+
+	switch state.Section {
+	case TrendsSectionHosts:
+		sb.WriteString(headerStyle.Render(fmt.Sprintf("  %-20s %-16s %-22s %-9s %s",
+			"Host", "CPU/RAM/STO", "Growth/day (pts)", "VMs", "First hard limit")) + "\n")
+		for _, h := range report.Hosts[start:end] {
+			usage := fmt.Sprintf("%.0f%%/%.0f%%/%.0f%%", h.Last.HostCPUPercent(), h.Last.RAMPercent(), h.Last.StoragePercent())
+			growth := fmt.Sprintf("%+.2f/%+.2f/%+.2f", h.HostCPUPerDay, h.RAMPerDay, h.StoragePerDay)
+			vms := fmt.Sprintf("%d", h.Last.VMCount)
+			if change := h.Last.VMCount - h.First.VMCount; change != 0 {
+				vms += fmt.Sprintf(" (%+d)", change)
+			}
+			line := fmt.Sprintf("  %-20s %-16s %-22s %-9s ", truncateString(h.Node, 20), usage, growth, vms)
+
+			limit, style := h.LimitSummary(), okStyle
+			switch next := h.Next(); {
+			case h.Forecasts == nil:
+				limit, style = fmt.Sprintf("needs %s of history", history.FormatDays(history.MinForecastSpan.Hours()/24)), dimStyle
+			case next == nil:
+			case next.Days == 0:
+				style = errorStyle
+			default:
+				limit += next.At.Format(" (2006-01-02)")
+				if next.Days < 30 {
+					style = warnStyle
+				}
+			}
+			sb.WriteString(line + style.Render(limit) + "\n")
+		}
+
+	case TrendsSectionVMs:
+		sb.WriteString(headerStyle.Render(fmt.Sprintf("  %-8s %-24s %-20s %-10s %-14s %s",
+			"VMID", "Name", "Node", "CPU/day", "RAM used/day", "Disk/day")) + "\n")
+		for _, vm := range report.VMs[start:end] {
+			sb.WriteString(fmt.Sprintf("  %-8d %-24s %-20s %-10s %-14s %s\n", vm.VMID, truncateString(vm.Name, 24),
+				truncateString(vm.Last.Node, 20), fmt.Sprintf("%+.2f%%", vm.CPUPerDay),
+				history.FormatBytesPerDay(vm.MemPerDay), history.FormatBytesPerDay(vm.DiskPerDay)))
+		}
+
+	case TrendsSectionMoves:
+		sb.WriteString(headerStyle.Render(fmt.Sprintf("  %-17s %-17s %-8s %-24s %s",
+			"Seen moved", "Last seen before", "VMID", "Name", "From → To")) + "\n")
+		if rows == 0 {
+			sb.WriteString(dimStyle.Render("  No VM changed hosts in this window") + "\n")
+		}
+		for _, mv := range report.Moves[start:end] {
+			sb.WriteString(fmt.Sprintf("  %-17s %-17s %-8d %-24s %s → %s\n", mv.By.Local().Format("2006-01-02 15:04"),
+				mv.After.Local().Format("2006-01-02 15:04"), mv.VMID, truncateString(mv.Name, 24), mv.From, mv.To))
+		}
+	}
+
+	sb.WriteString("\n")
+	if rows > visible {
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("Showing %d-%d of %d", start+1, end, rows)) + "\n")
+	}
+	sb.WriteString(help)
+	return sb.String()
+}